@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/edwinjordan/MajooTest-Golang/config"
+	"github.com/edwinjordan/MajooTest-Golang/database"
+	"github.com/edwinjordan/MajooTest-Golang/internal/repository/postgres"
+	"github.com/edwinjordan/MajooTest-Golang/service"
+)
+
+// runPurge hard-deletes soft-deleted records past their retention period,
+// see config.LoadRetentionPolicy. With --dry-run it only reports the counts.
+func runPurge(args []string) error {
+	flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only report how many records would be purged")
+	batchSize := flags.Int("batch-size", 0, "rows deleted per statement, overrides RETENTION_PURGE_BATCH_SIZE")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	pool, err := database.SetupPgxPool()
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	policy := config.LoadRetentionPolicy()
+	if *batchSize > 0 {
+		policy.BatchSize = *batchSize
+	}
+
+	retentionService := service.NewRetentionService(postgres.NewRetentionRepository(pool), policy)
+	results, err := retentionService.Purge(context.Background(), *dryRun)
+	for _, result := range results {
+		verb := "purged"
+		if result.DryRun {
+			verb = "would purge"
+		}
+		fmt.Printf("%-10s %s %d rows deleted before %s\n", result.Entity, verb, result.Purged, result.Before.Format("2006-01-02 15:04:05"))
+		for _, table := range slices.Sorted(maps.Keys(result.Owned)) {
+			fmt.Printf("%-10s   with %d %s they owned\n", "", result.Owned[table], table)
+		}
+	}
+	return err
+}
@@ -33,6 +33,10 @@ func Execute(command string, args []string) error {
 		if err := runSeeder(db, target); err != nil {
 			return fmt.Errorf("seeding failed: %w", err)
 		}
+	case "purge":
+		if err := runPurge(args); err != nil {
+			return fmt.Errorf("purge failed: %w", err)
+		}
 	default:
 		return errors.New("unknown command: " + command)
 	}
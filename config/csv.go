@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+// DefaultUserImportEmailDomain completes the email address of imported users
+// whose rows have none, e.g. booker12@example.com
+const DefaultUserImportEmailDomain = "example.com"
+
+// LoadUserImportEmailDomain reads USER_IMPORT_EMAIL_DOMAIN, falling back to
+// DefaultUserImportEmailDomain
+func LoadUserImportEmailDomain() string {
+	if v := strings.TrimPrefix(strings.TrimSpace(os.Getenv("USER_IMPORT_EMAIL_DOMAIN")), "@"); v != "" {
+		return v
+	}
+	return DefaultUserImportEmailDomain
+}
+
+// DefaultCSVStorageDir is where uploads wait for a worker when
+// CSV_STORAGE_DIR is unset
+const DefaultCSVStorageDir = "storage/csv"
+
+// LoadCSVStorageDir reads CSV_STORAGE_DIR, falling back to
+// DefaultCSVStorageDir
+func LoadCSVStorageDir() string {
+	if v := strings.TrimSpace(os.Getenv("CSV_STORAGE_DIR")); v != "" {
+		return v
+	}
+	return DefaultCSVStorageDir
+}
+
+// LoadCSVQueuePolicy reads how the CSV workers of this replica poll the
+// queue from the environment, falling back to domain.DefaultCSVQueuePolicy
+// for unset values
+func LoadCSVQueuePolicy() domain.CSVQueuePolicy {
+	policy := domain.DefaultCSVQueuePolicy()
+
+	if v, err := strconv.Atoi(os.Getenv("CSV_QUEUE_WORKERS")); err == nil && v >= 0 {
+		policy.Workers = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("CSV_QUEUE_POLL_SECONDS")); err == nil && v > 0 {
+		policy.PollInterval = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("CSV_QUEUE_HEARTBEAT_SECONDS")); err == nil && v > 0 {
+		policy.Heartbeat = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("CSV_QUEUE_STALE_SECONDS")); err == nil && v > 0 {
+		policy.StaleAfter = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("CSV_QUEUE_MAX_ATTEMPTS")); err == nil && v > 0 {
+		policy.MaxAttempts = v
+	}
+
+	return policy
+}
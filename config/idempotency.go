@@ -0,0 +1,28 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+// LoadIdempotencyPolicy reads how long Idempotency-Key responses are kept
+// from the environment, falling back to domain.DefaultIdempotencyPolicy for
+// unset values
+func LoadIdempotencyPolicy() domain.IdempotencyPolicy {
+	policy := domain.DefaultIdempotencyPolicy()
+
+	if v, err := strconv.Atoi(os.Getenv("IDEMPOTENCY_TTL_HOURS")); err == nil && v > 0 {
+		policy.TTL = time.Duration(v) * time.Hour
+	}
+	if v, err := strconv.Atoi(os.Getenv("IDEMPOTENCY_LOCK_SECONDS")); err == nil && v > 0 {
+		policy.Lease = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("IDEMPOTENCY_PURGE_INTERVAL_MINUTES")); err == nil && v >= 0 {
+		policy.Interval = time.Duration(v) * time.Minute
+	}
+
+	return policy
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+// LoadModerationRules reads the comment spam rules from the environment,
+// falling back to domain.DefaultModerationRules for unset values
+func LoadModerationRules() domain.ModerationRules {
+	rules := domain.DefaultModerationRules()
+
+	if words := os.Getenv("MODERATION_BLOCKED_WORDS"); words != "" {
+		rules.BlockedWords = strings.Split(words, ",")
+	}
+	if v, err := strconv.Atoi(os.Getenv("MODERATION_MAX_LINKS")); err == nil {
+		rules.MaxLinks = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("MODERATION_NEW_ACCOUNT_HOURS")); err == nil {
+		rules.NewAccountAge = time.Duration(v) * time.Hour
+	}
+	if v, err := strconv.Atoi(os.Getenv("MODERATION_RATE_LIMIT")); err == nil {
+		rules.RateLimit = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("MODERATION_RATE_WINDOW_SECONDS")); err == nil {
+		rules.RateWindow = time.Duration(v) * time.Second
+	}
+
+	return rules
+}
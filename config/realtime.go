@@ -0,0 +1,19 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// DefaultRealtimeBuffer is how many messages a websocket client may fall
+// behind before it is disconnected
+const DefaultRealtimeBuffer = 64
+
+// LoadRealtimeBuffer reads REALTIME_CLIENT_BUFFER, falling back to
+// DefaultRealtimeBuffer
+func LoadRealtimeBuffer() int {
+	if v, err := strconv.Atoi(os.Getenv("REALTIME_CLIENT_BUFFER")); err == nil && v > 0 {
+		return v
+	}
+	return DefaultRealtimeBuffer
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+// LoadRetentionPolicy reads how long soft-deleted records are kept from the
+// environment, falling back to domain.DefaultRetentionPolicy for unset values
+func LoadRetentionPolicy() domain.RetentionPolicy {
+	policy := domain.DefaultRetentionPolicy()
+
+	days := map[domain.TrashEntity]string{
+		domain.TrashUsers:    "RETENTION_USERS_DAYS",
+		domain.TrashPosts:    "RETENTION_POSTS_DAYS",
+		domain.TrashComments: "RETENTION_COMMENTS_DAYS",
+	}
+	for entity, key := range days {
+		if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v >= 0 {
+			policy.Periods[entity] = time.Duration(v) * 24 * time.Hour
+		}
+	}
+	if v, err := strconv.Atoi(os.Getenv("RETENTION_PURGE_BATCH_SIZE")); err == nil && v > 0 {
+		policy.BatchSize = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("RETENTION_PURGE_INTERVAL_MINUTES")); err == nil && v >= 0 {
+		policy.Interval = time.Duration(v) * time.Minute
+	}
+
+	return policy
+}
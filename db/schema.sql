@@ -6,38 +6,86 @@ CREATE TABLE IF NOT EXISTS users (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(), 
     name TEXT NOT NULL,
     email TEXT NOT NULL UNIQUE,
+    username VARCHAR(30),
     password TEXT NOT NULL,
+    employee_id VARCHAR(50) NOT NULL DEFAULT '',
+    department TEXT NOT NULL DEFAULT '',
+    location TEXT NOT NULL DEFAULT '',
+    recovery_code TEXT NOT NULL DEFAULT '',
+    role VARCHAR(20) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin')),
+    version INT NOT NULL DEFAULT 1,
+    import_job_id UUID,
+    import_row INT,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
     deleted_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
 
 );
 
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users(LOWER(username));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_import_row ON users(import_job_id, import_row) WHERE import_job_id IS NOT NULL;
+
 CREATE TABLE IF NOT EXISTS posts (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
     title TEXT NOT NULL,
     content TEXT NOT NULL,
+    content_format VARCHAR(20) NOT NULL DEFAULT 'plain' CHECK (content_format IN ('plain', 'markdown')),
+    content_html TEXT NOT NULL DEFAULT '',
     slug TEXT NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    import_job_id UUID,
+    import_row INT,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
     deleted_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
 );
 
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_import_row ON posts(import_job_id, import_row) WHERE import_job_id IS NOT NULL;
+
 CREATE TABLE IF NOT EXISTS comments (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     post_id UUID REFERENCES posts(id) ON DELETE CASCADE,
     user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES comments(id) ON DELETE CASCADE,
+    depth INT NOT NULL DEFAULT 0 CHECK (depth >= 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'approved' CHECK (status IN ('pending', 'approved', 'rejected', 'spam')),
+    moderation_reason TEXT NOT NULL DEFAULT '',
     body TEXT NOT NULL,
+    content_format VARCHAR(20) NOT NULL DEFAULT 'plain' CHECK (content_format IN ('plain', 'markdown')),
+    content_html TEXT NOT NULL DEFAULT '',
+    version INT NOT NULL DEFAULT 1,
+    import_job_id UUID,
+    import_row INT,
     created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
     updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
     deleted_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
 );
 
+CREATE UNIQUE INDEX IF NOT EXISTS idx_comments_import_row ON comments(import_job_id, import_row) WHERE import_job_id IS NOT NULL;
+
 CREATE TABLE IF NOT EXISTS csv_jobs (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     user_id UUID NOT NULL,
     filename VARCHAR(255) NOT NULL,
-    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'processing', 'completed', 'failed')),
+    type VARCHAR(50) NOT NULL DEFAULT '',
+    delimiter VARCHAR(4) NOT NULL DEFAULT ',',
+    encoding VARCHAR(20) NOT NULL DEFAULT 'utf-8',
+    lazy_quotes BOOLEAN NOT NULL DEFAULT FALSE,
+    has_bom BOOLEAN NOT NULL DEFAULT FALSE,
+    schema JSONB,
+    headers TEXT[] NOT NULL DEFAULT '{}',
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    summary JSONB,
+    file_key TEXT,
+    worker_id TEXT,
+    heartbeat_at TIMESTAMP WITH TIME ZONE,
+    attempts INT NOT NULL DEFAULT 0,
+    checkpoint_row INT NOT NULL DEFAULT 0,
+    checkpoint_offset BIGINT NOT NULL DEFAULT 0,
+    checkpoint_processed BIGINT NOT NULL DEFAULT 0,
+    checkpoint_failed BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'validated', 'cancelled')),
     total_rows BIGINT,
     processed_rows BIGINT,
     failed_rows BIGINT,
@@ -49,4 +97,85 @@ CREATE TABLE IF NOT EXISTS csv_jobs (
     
     -- Add foreign key constraint to users table
     CONSTRAINT fk_csv_jobs_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-);
\ No newline at end of file
+);
+
+CREATE TABLE IF NOT EXISTS csv_job_errors (
+    job_id UUID NOT NULL REFERENCES csv_jobs(id) ON DELETE CASCADE,
+    row_number INT NOT NULL,
+    row_values TEXT[] NOT NULL DEFAULT '{}',
+    error TEXT NOT NULL,
+    field_errors JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (job_id, row_number)
+);
+
+CREATE TABLE IF NOT EXISTS csv_job_rows (
+    job_id UUID NOT NULL REFERENCES csv_jobs(id) ON DELETE CASCADE,
+    row_number INT NOT NULL,
+    failed BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (job_id, row_number)
+);
+
+CREATE TABLE IF NOT EXISTS csv_schemas (
+    name VARCHAR(50) PRIMARY KEY,
+    definition JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS reactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('post', 'comment')),
+    target_id UUID NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CONSTRAINT uq_reactions_user_target_type UNIQUE (user_id, target_type, target_id, type)
+);
+
+CREATE TABLE IF NOT EXISTS comment_moderation_audit (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    moderator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('approve', 'reject', 'spam', 'flag')),
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('comment', 'reply', 'mention')),
+    post_id UUID REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id UUID REFERENCES comments(id) ON DELETE CASCADE,
+    message TEXT NOT NULL DEFAULT '',
+    read_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    on_comment BOOLEAN NOT NULL DEFAULT true,
+    on_reply BOOLEAN NOT NULL DEFAULT true,
+    on_mention BOOLEAN NOT NULL DEFAULT true,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id TEXT NOT NULL DEFAULT '',
+    method VARCHAR(10) NOT NULL,
+    route TEXT NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INT,
+    headers JSONB,
+    body BYTEA,
+    locked_until TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, method, route, key)
+);
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"context"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -15,14 +17,88 @@ type LoginResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// TokenType tells access tokens from refresh tokens, only access tokens
+// authenticate requests
+type TokenType string
+
+const (
+	TokenTypeAccess  TokenType = "access"
+	TokenTypeRefresh TokenType = "refresh"
+)
+
 type JwtClaim struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
+	ID    string    `json:"id"`
+	Email string    `json:"email"`
+	Role  Role      `json:"role,omitempty"`
+	Type  TokenType `json:"typ"`
 	jwt.RegisteredClaims
 }
 
 type RefreshClaim struct {
+	ID    string    `json:"id"`
+	Email string    `json:"email"`
+	Role  Role      `json:"role,omitempty"`
+	Type  TokenType `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// Role grants access to privileged endpoints such as the moderation queue
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// IsValid reports whether r is a supported role
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// OrDefault returns r, or RoleUser when r is empty
+func (r Role) OrDefault() Role {
+	if r == "" {
+		return RoleUser
+	}
+	return r
+}
+
+type callerContextKey struct{}
+
+// Caller is the authenticated user behind the current request
+type Caller struct {
 	ID    string `json:"id"`
 	Email string `json:"email"`
-	jwt.RegisteredClaims
+	Role  Role   `json:"role"`
+}
+
+// HasRole reports whether the caller holds one of roles
+func (c *Caller) HasRole(roles ...Role) bool {
+	if c == nil {
+		return false
+	}
+	for _, role := range roles {
+		if c.Role.OrDefault() == role {
+			return true
+		}
+	}
+	return false
+}
+
+// WithCaller stores the authenticated caller in the context
+func WithCaller(ctx context.Context, caller *Caller) context.Context {
+	return context.WithValue(ctx, callerContextKey{}, caller)
+}
+
+// CallerFromContext returns the authenticated caller, or nil for anonymous requests
+func CallerFromContext(ctx context.Context) *Caller {
+	if caller, ok := ctx.Value(callerContextKey{}).(*Caller); ok {
+		return caller
+	}
+	return nil
 }
@@ -0,0 +1,141 @@
+package domain
+
+// MaxBatchItems is the most items a single batch request may carry
+const MaxBatchItems = 500
+
+// BatchRolledBackReason is reported for the items of an atomic batch that
+// would have succeeded on their own
+const BatchRolledBackReason = "not applied, another item of the atomic batch failed"
+
+// BatchMode decides what happens to a batch when some of its items fail
+type BatchMode string
+
+const (
+	// BatchModeAtomic applies every item in one transaction, or none of them
+	BatchModeAtomic BatchMode = "atomic"
+	// BatchModeBestEffort applies the items that can be applied and reports
+	// the others
+	BatchModeBestEffort BatchMode = "best_effort"
+)
+
+// IsValid reports whether the mode is a known one
+func (m BatchMode) IsValid() bool {
+	switch m {
+	case BatchModeAtomic, BatchModeBestEffort:
+		return true
+	}
+	return false
+}
+
+// OrDefault returns the mode, falling back to atomic when empty
+func (m BatchMode) OrDefault() BatchMode {
+	if m == "" {
+		return BatchModeAtomic
+	}
+	return m
+}
+
+// BatchOp is what a batch item does to its record
+type BatchOp string
+
+const (
+	BatchOpCreate BatchOp = "create"
+	BatchOpUpdate BatchOp = "update"
+	BatchOpDelete BatchOp = "delete"
+)
+
+// IsValid reports whether the operation is a known one
+func (o BatchOp) IsValid() bool {
+	switch o {
+	case BatchOpCreate, BatchOpUpdate, BatchOpDelete:
+		return true
+	}
+	return false
+}
+
+// BatchRequest is the body of the :batch endpoints
+type BatchRequest[Item any] struct {
+	Mode  BatchMode `json:"mode" enums:"atomic,best_effort"`
+	Items []Item    `json:"items"`
+}
+
+// BatchItemResult reports the outcome of the item at Index of the request.
+// Data holds the record as it is after a successful create or update.
+type BatchItemResult[Data any] struct {
+	Index int     `json:"index"`
+	Op    BatchOp `json:"op"`
+	ID    string  `json:"id,omitempty"`
+	OK    bool    `json:"ok"`
+	Data  *Data   `json:"data,omitempty"`
+	Error string  `json:"error,omitempty"`
+}
+
+// Fail marks the item failed with the reason
+func (r *BatchItemResult[Data]) Fail(reason string) {
+	r.OK = false
+	r.Data = nil
+	r.Error = reason
+}
+
+// BatchResult is the response of the :batch endpoints, with one entry per
+// request item in request order
+type BatchResult[Data any] struct {
+	Mode      BatchMode               `json:"mode"`
+	Succeeded int                     `json:"succeeded"`
+	Failed    int                     `json:"failed"`
+	Items     []BatchItemResult[Data] `json:"items"`
+}
+
+// Tally counts the succeeded and failed items
+func (r *BatchResult[Data]) Tally() {
+	r.Succeeded, r.Failed = 0, 0
+	for _, item := range r.Items {
+		if item.OK {
+			r.Succeeded++
+		} else {
+			r.Failed++
+		}
+	}
+}
+
+// PostsBatchItem creates, updates or deletes one post. Create and update
+// take title and content, update and delete take the id and optionally the
+// expected version.
+type PostsBatchItem struct {
+	Op            BatchOp       `json:"op" enums:"create,update,delete"`
+	ID            string        `json:"id,omitempty"`
+	Version       int           `json:"version,omitempty"`
+	Title         string        `json:"title,omitempty"`
+	Content       string        `json:"content,omitempty"`
+	ContentFormat ContentFormat `json:"content_format,omitempty" enums:"plain,markdown"`
+	ContentHTML   string        `json:"-"`
+	UserID        string        `json:"-"`
+}
+
+// UserBatchItem creates, updates or deletes one user. Create takes name,
+// email, password and optionally username, update takes name and email.
+type UserBatchItem struct {
+	Op       BatchOp `json:"op" enums:"create,update,delete"`
+	ID       string  `json:"id,omitempty"`
+	Version  int     `json:"version,omitempty"`
+	Name     string  `json:"name,omitempty"`
+	Email    string  `json:"email,omitempty"`
+	Username string  `json:"username,omitempty"`
+	Password string  `json:"password,omitempty"`
+}
+
+// CommentBatchItem creates, updates or deletes one comment. Create takes
+// post_id or parent_id and the body, update takes the body.
+type CommentBatchItem struct {
+	Op               BatchOp          `json:"op" enums:"create,update,delete"`
+	ID               string           `json:"id,omitempty"`
+	Version          int              `json:"version,omitempty"`
+	PostID           string           `json:"post_id,omitempty"`
+	ParentID         string           `json:"parent_id,omitempty"`
+	UserID           string           `json:"user_id,omitempty"`
+	Body             string           `json:"body,omitempty"`
+	ContentFormat    ContentFormat    `json:"content_format,omitempty" enums:"plain,markdown"`
+	ContentHTML      string           `json:"-"`
+	Status           ModerationStatus `json:"-"`
+	ModerationReason string           `json:"-"`
+}
@@ -2,27 +2,76 @@ package domain
 
 import "time"
 
+// MaxCommentDepth is the deepest a reply can be nested, top level comments
+// have depth 0
+const MaxCommentDepth = 5
+
 type Comment struct {
-	ID        string    `json:"id"`
-	PostID    string    `json:"post_id"`
-	UserID    string    `json:"user_id"`
-	Body      string    `json:"body"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID               string           `json:"id"`
+	PostID           string           `json:"post_id"`
+	UserID           string           `json:"user_id"`
+	ParentID         *string          `json:"parent_id"`
+	Depth            int              `json:"depth"`
+	Status           ModerationStatus `json:"status"`
+	ModerationReason string           `json:"-"`
+	Body             string           `json:"body"`
+	ContentFormat    ContentFormat    `json:"content_format"`
+	ContentHTML      string           `json:"content_html"`
+	Version          int              `json:"version"`
+	CreatedAt        time.Time        `json:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+	Reactions        *ReactionSummary `json:"reactions,omitempty"`
+	ReplyCount       int64            `json:"reply_count"`
+	Replies          []Comment        `json:"replies,omitempty"`
 }
 
 type CreateCommentRequest struct {
-	PostID string `json:"post_id" validate:"required"`
-	UserID string `json:"user_id" validate:"required"`
-	Body   string `json:"body" validate:"required"`
+	PostID           string           `json:"post_id" validate:"required"`
+	UserID           string           `json:"user_id" validate:"required"`
+	ParentID         string           `json:"parent_id"`
+	Body             string           `json:"body" validate:"required"`
+	ContentFormat    ContentFormat    `json:"content_format" validate:"omitempty,oneof=plain markdown"`
+	ContentHTML      string           `json:"-"`
+	Depth            int              `json:"-"`
+	Status           ModerationStatus `json:"-"`
+	ModerationReason string           `json:"-"`
+}
+
+type CreateReplyRequest struct {
+	UserID        string        `json:"user_id"`
+	Body          string        `json:"body" validate:"required"`
+	ContentFormat ContentFormat `json:"content_format" validate:"omitempty,oneof=plain markdown"`
 }
 
 type UpdateCommentRequest struct {
-	PostID string `json:"post_id" validate:"required"`
-	UserID string `json:"user_id" validate:"required"`
-	Body   string `json:"body" validate:"required"`
+	PostID        string        `json:"post_id" validate:"required"`
+	UserID        string        `json:"user_id" validate:"required"`
+	Body          string        `json:"body" validate:"required"`
+	ContentFormat ContentFormat `json:"content_format" validate:"omitempty,oneof=plain markdown"`
 }
 
 type CommentFilter struct {
 	Search string `json:"search" query:"search"`
 }
+
+// CommentThreadFilter paginates the comments of a post. In tree mode the page
+// covers top level comments and RepliesLimit caps the replies shown under
+// each comment, in flat mode it covers every comment of the post.
+type CommentThreadFilter struct {
+	Page         int `json:"page" query:"page"`
+	Limit        int `json:"limit" query:"limit"`
+	RepliesLimit int `json:"replies_limit" query:"replies_limit"`
+}
+
+// Normalize applies defaults and upper bounds to the filter
+func (f *CommentThreadFilter) Normalize() {
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.Limit < 1 || f.Limit > 100 {
+		f.Limit = 20
+	}
+	if f.RepliesLimit < 1 || f.RepliesLimit > 50 {
+		f.RepliesLimit = 5
+	}
+}
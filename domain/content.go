@@ -0,0 +1,26 @@
+package domain
+
+// ContentFormat describes how the raw body of a post or comment is written
+type ContentFormat string
+
+const (
+	ContentFormatPlain    ContentFormat = "plain"
+	ContentFormatMarkdown ContentFormat = "markdown"
+)
+
+// IsValid reports whether the format is one the server knows how to render
+func (f ContentFormat) IsValid() bool {
+	switch f {
+	case ContentFormatPlain, ContentFormatMarkdown:
+		return true
+	}
+	return false
+}
+
+// OrDefault returns the format, falling back to plain text when empty
+func (f ContentFormat) OrDefault() ContentFormat {
+	if f == "" {
+		return ContentFormatPlain
+	}
+	return f
+}
@@ -2,9 +2,11 @@ package domain
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -17,21 +19,197 @@ const (
 	CSVJobStatusProcessing CSVJobStatus = "processing"
 	CSVJobStatusCompleted  CSVJobStatus = "completed"
 	CSVJobStatusFailed     CSVJobStatus = "failed"
+	// CSVJobStatusValidated ends a dry run, nothing was imported
+	CSVJobStatusValidated CSVJobStatus = "validated"
+	// CSVJobStatusCancelled stops a job, rows imported before stay imported
+	CSVJobStatusCancelled CSVJobStatus = "cancelled"
 )
 
+// Finished reports whether a job in this status is done running
+func (s CSVJobStatus) Finished() bool {
+	return s != CSVJobStatusPending && s != CSVJobStatusProcessing
+}
+
 // CSVJob represents a CSV processing job
 type CSVJob struct {
-	ID            string       `json:"id" db:"id"`
-	Filename      string       `json:"filename" db:"filename"`
-	Status        CSVJobStatus `json:"status" db:"status"`
-	TotalRows     int64        `json:"total_rows" db:"total_rows"`
-	ProcessedRows int64        `json:"processed_rows" db:"processed_rows"`
-	FailedRows    int64        `json:"failed_rows" db:"failed_rows"`
-	ErrorMessage  *string      `json:"error_message,omitempty" db:"error_message"`
-	StartedAt     time.Time    `json:"started_at" db:"started_at"`
-	CompletedAt   time.Time    `json:"completed_at" db:"completed_at"`
-	CreatedAt     time.Time    `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time    `json:"updated_at" db:"updated_at"`
+	ID       string     `json:"id" db:"id"`
+	Filename string     `json:"filename" db:"filename"`
+	Type     string     `json:"type" db:"type" example:"users"`
+	Dialect  CSVDialect `json:"dialect"`
+	Schema   *CSVSchema `json:"schema,omitempty"`
+	// Headers is the header row of the file as it was uploaded
+	Headers []string `json:"headers,omitempty"`
+	// DryRun jobs validate the rows without importing them
+	DryRun bool `json:"dry_run" db:"dry_run"`
+	// UserID is the uploader, queued jobs import as them
+	UserID string `json:"user_id,omitempty" db:"user_id"`
+	// FileKey names the upload in the CSVFileStore, jobs with one wait in
+	// the queue until a worker claims them
+	FileKey string `json:"-" db:"file_key"`
+	// WorkerID is the worker that claimed a queued job, only it may write
+	// the progress of the job
+	WorkerID string `json:"-" db:"worker_id"`
+	// Attempts counts the times a worker claimed the job
+	Attempts      int               `json:"attempts" db:"attempts"`
+	HeartbeatAt   *time.Time        `json:"heartbeat_at,omitempty" db:"heartbeat_at"`
+	Checkpoint    CSVCheckpoint     `json:"checkpoint"`
+	Summary       *CSVDryRunSummary `json:"summary,omitempty"`
+	Status        CSVJobStatus      `json:"status" db:"status" enums:"pending,processing,completed,failed,validated,cancelled"`
+	TotalRows     int64             `json:"total_rows" db:"total_rows"`
+	ProcessedRows int64             `json:"processed_rows" db:"processed_rows"`
+	FailedRows    int64             `json:"failed_rows" db:"failed_rows"`
+	ErrorMessage  *string           `json:"error_message,omitempty" db:"error_message"`
+	StartedAt     time.Time         `json:"started_at" db:"started_at"`
+	CompletedAt   time.Time         `json:"completed_at" db:"completed_at"`
+	CreatedAt     time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// CSVCheckpoint is how far a job got. Every row up to Row is done, Offset is
+// where the row after it starts in the decoded file. Processed and Failed
+// count the rows up to Row.
+type CSVCheckpoint struct {
+	Row       int   `json:"row"`
+	Offset    int64 `json:"offset"`
+	Processed int64 `json:"processed_rows"`
+	Failed    int64 `json:"failed_rows"`
+}
+
+// CSVRowOutcome records that a row past the checkpoint of a job is done, so
+// a resumed job does not process it again
+type CSVRowOutcome struct {
+	RowNumber int
+	Failed    bool
+}
+
+// CSVRowKey is the row of a job a record is imported from. Processing
+// passes it in the context of every row, records that keep it are created
+// once per row however often the row runs.
+type CSVRowKey struct {
+	JobID string
+	Row   int
+}
+
+type csvRowContextKey struct{}
+
+// WithCSVRow stores the row being imported in the context
+func WithCSVRow(ctx context.Context, key CSVRowKey) context.Context {
+	return context.WithValue(ctx, csvRowContextKey{}, key)
+}
+
+// CSVRowFromContext returns the row being imported, or nil outside an import
+func CSVRowFromContext(ctx context.Context) *CSVRowKey {
+	if key, ok := ctx.Value(csvRowContextKey{}).(CSVRowKey); ok {
+		return &key
+	}
+	return nil
+}
+
+// CSVEncoding is the character encoding of an uploaded file, rows are
+// transcoded to UTF-8 before they are parsed
+type CSVEncoding string
+
+const (
+	CSVEncodingUTF8    CSVEncoding = "utf-8"
+	CSVEncodingUTF16LE CSVEncoding = "utf-16le"
+	CSVEncodingUTF16BE CSVEncoding = "utf-16be"
+	CSVEncodingLatin1  CSVEncoding = "latin-1"
+)
+
+func (e CSVEncoding) IsValid() bool {
+	switch e {
+	case CSVEncodingUTF8, CSVEncodingUTF16LE, CSVEncodingUTF16BE, CSVEncodingLatin1:
+		return true
+	}
+	return false
+}
+
+// CSVDelimiters are the delimiters sniffed from a file, an upload may name
+// any other single character
+var CSVDelimiters = []rune{',', ';', '\t', '|'}
+
+// CSVDialect describes how a file is read. Fields left empty when uploading
+// are sniffed from the start of the file.
+type CSVDialect struct {
+	Delimiter string      `json:"delimiter" example:";"`
+	Encoding  CSVEncoding `json:"encoding" example:"utf-8"`
+	// LazyQuotes accepts quotes inside unquoted fields, as some spreadsheet
+	// exports write them. Sniffed when nil, so an upload can turn it off.
+	LazyQuotes *bool `json:"lazy_quotes"`
+	// HasBOM is set when the file started with a byte order mark
+	HasBOM bool `json:"has_bom"`
+}
+
+// Comma is the delimiter as the rune encoding/csv expects, 0 when unset
+func (d CSVDialect) Comma() rune {
+	r, _ := utf8.DecodeRuneInString(d.Delimiter)
+	if r == utf8.RuneError {
+		return 0
+	}
+	return r
+}
+
+// Validate checks the dialect asked for by an upload
+func (d CSVDialect) Validate() error {
+	if d.Delimiter != "" {
+		r := d.Comma()
+		if utf8.RuneCountInString(d.Delimiter) != 1 || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return fmt.Errorf("%w: delimiter must be a single character other than a quote or line break", ErrBadParamInput)
+		}
+	}
+	if d.Encoding != "" && !d.Encoding.IsValid() {
+		return fmt.Errorf("%w: encoding must be utf-8, utf-16le, utf-16be or latin-1", ErrBadParamInput)
+	}
+	return nil
+}
+
+// CSVUploadOptions are the choices an upload makes for all of its files
+type CSVUploadOptions struct {
+	// Type selects the RowProcessor
+	Type string
+	// Dialect overrides what is sniffed from the files
+	Dialect CSVDialect
+	// Schema validates the rows, SchemaName refers to a saved one instead
+	Schema     *CSVSchema
+	SchemaName string
+	// DryRun validates the files without importing anything, the jobs end
+	// as validated with a summary
+	DryRun bool
+}
+
+// CSVDryRunErrors is how many failed rows a dry run summary lists, all of
+// them are in the error report of the job
+const CSVDryRunErrors = 50
+
+// CSVRowAction is what importing a row does
+type CSVRowAction string
+
+const (
+	CSVRowInsert CSVRowAction = "insert"
+	CSVRowUpdate CSVRowAction = "update"
+)
+
+// Kinds of row failures counted by a dry run
+const (
+	CSVErrorParse      = "parse"
+	CSVErrorSchema     = "schema"
+	CSVErrorValidation = "validation"
+	CSVErrorConflict   = "conflict"
+	CSVErrorNotFound   = "not_found"
+	CSVErrorOther      = "other"
+)
+
+// CSVDryRunSummary is what importing a file would do
+type CSVDryRunSummary struct {
+	Rows        int64 `json:"rows"`
+	WouldInsert int64 `json:"would_insert"`
+	WouldUpdate int64 `json:"would_update"`
+	Failed      int64 `json:"failed"`
+	// ErrorsByType counts the failed rows by kind: parse, schema,
+	// validation, conflict, not_found or other
+	ErrorsByType map[string]int64 `json:"errors_by_type"`
+	// Errors are the first failed rows of the file
+	Errors []CSVJobError `json:"errors"`
 }
 
 // CSVProcessingResult represents the result of processing a single CSV row
@@ -40,6 +218,12 @@ type CSVProcessingResult struct {
 	Success   bool                   `json:"success"`
 	Data      map[string]interface{} `json:"data,omitempty"`
 	Error     string                 `json:"error,omitempty"`
+	// Errors holds the columns that failed the schema of the job
+	Errors FieldErrors `json:"errors,omitempty"`
+	// ErrorType is the kind of failure, Action what a dry run found the
+	// row would do
+	ErrorType string       `json:"error_type,omitempty"`
+	Action    CSVRowAction `json:"action,omitempty"`
 }
 
 // CSVProcessingProgress represents real-time progress of CSV processing
@@ -53,19 +237,61 @@ type CSVProcessingProgress struct {
 	Message       string       `json:"message,omitempty"`
 }
 
+// CSVEventProgress is the type of the events of a job that is still running,
+// its last event is typed with the status the job ended in
+const CSVEventProgress = "progress"
+
+// CSVProgressEvent is pushed to the clients streaming the progress of a job.
+// ID is when it was published in Unix milliseconds, a client resumes after
+// the last one it saw. The last event of a job carries its final counts and
+// the summary of a dry run.
+type CSVProgressEvent struct {
+	ID       int64                 `json:"id"`
+	JobID    string                `json:"job_id"`
+	Type     string                `json:"type" enums:"progress,completed,failed,validated,cancelled"`
+	Progress CSVProcessingProgress `json:"progress"`
+	Summary  *CSVDryRunSummary     `json:"summary,omitempty"`
+}
+
+// Final reports whether no event of the job follows this one
+func (e CSVProgressEvent) Final() bool {
+	return e.Type != CSVEventProgress
+}
+
 // CSVWorkerJob represents a job sent to CSV worker
 type CSVWorkerJob struct {
 	JobID     string
 	RowNumber int
-	Data      []string
-	Headers   []string
+	// Offset is where the next row starts in the decoded file
+	Offset  int64
+	Data    []string
+	Headers []string
 }
 
 // CSVWorkerResult represents result from CSV worker
 type CSVWorkerResult struct {
 	JobID     string
 	RowNumber int
+	Offset    int64
+	Data      []string
 	Result    CSVProcessingResult
+	// Resumed rows were done before the job was resumed and only count
+	// towards its progress
+	Resumed bool
+}
+
+// MaxCSVJobErrors is how many failed rows are kept per job, the ones after
+// are only counted
+const MaxCSVJobErrors = 10000
+
+// CSVJobError is a row that failed, with its values as they were uploaded
+type CSVJobError struct {
+	JobID     string      `json:"job_id"`
+	RowNumber int         `json:"row_number" example:"42"`
+	Values    []string    `json:"values"`
+	Error     string      `json:"error" example:"invalid request: E-mail: must be an email address"`
+	Errors    FieldErrors `json:"errors,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
 }
 
 // CSVUploadRequest represents CSV upload request
@@ -79,20 +305,95 @@ type CSVUploadResponse struct {
 	Message string   `json:"message"`
 }
 
+// RowProcessor imports the rows of one type of CSV file. CheckHeaders runs
+// once per file before any row, ProcessRow runs concurrently for every row
+// with the values keyed by their header. Dry runs call ValidateRow instead,
+// which checks the row as ProcessRow would and says whether it would insert
+// or update, without writing anything.
+type RowProcessor interface {
+	CheckHeaders(headers []string) error
+	ProcessRow(ctx context.Context, row map[string]string) error
+	ValidateRow(ctx context.Context, row map[string]string) (CSVRowAction, error)
+}
+
+// CSVFileStore keeps uploaded files until a worker has processed them, every
+// replica must see the same files
+type CSVFileStore interface {
+	Save(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// CSVQueuePolicy is how the workers of a replica take uploads off the queue
+type CSVQueuePolicy struct {
+	// Workers is how many jobs the replica processes at once
+	Workers int
+	// PollInterval is how long an idle worker waits before looking again
+	PollInterval time.Duration
+	// Heartbeat is how often a worker reports it is still busy, a job whose
+	// last heartbeat is older than StaleAfter goes back to the queue
+	Heartbeat  time.Duration
+	StaleAfter time.Duration
+	// MaxAttempts fails a job whose workers kept dying on it
+	MaxAttempts int
+}
+
+// DefaultCSVQueuePolicy runs two workers per replica that poll every two
+// seconds and heartbeat every ten, giving up on a silent worker after a
+// minute and on a job after three attempts
+func DefaultCSVQueuePolicy() CSVQueuePolicy {
+	return CSVQueuePolicy{
+		Workers:      2,
+		PollInterval: 2 * time.Second,
+		Heartbeat:    10 * time.Second,
+		StaleAfter:   time.Minute,
+		MaxAttempts:  3,
+	}
+}
+
 // CSVRepository interface for CSV operations
 type CSVRepository interface {
 	CreateJob(ctx context.Context, job *CSVJob) error
 	GetJobByID(ctx context.Context, id uuid.UUID) (*CSVJob, error)
-	GetJobsByUserID(ctx context.Context) ([]*CSVJob, error)
-	UpdateJobProgress(ctx context.Context, jobID string, processedRows, failedRows int64) error
-	UpdateJobStatus(ctx context.Context, jobID string, status CSVJobStatus, errorMessage *string) error
-	CompleteJob(ctx context.Context, jobID string, totalRows, processedRows, failedRows int64) error
+	GetJobsByUserID(ctx context.Context, userID string) ([]*CSVJob, error)
+	UpdateJobProgress(ctx context.Context, jobID, workerID string, processedRows, failedRows int64) error
+	UpdateJobStatus(ctx context.Context, jobID, workerID string, status CSVJobStatus, errorMessage *string) error
+	CompleteJob(ctx context.Context, jobID, workerID string, totalRows, processedRows, failedRows int64) error
+	CompleteDryRun(ctx context.Context, jobID, workerID string, summary *CSVDryRunSummary) error
+	ClaimJob(ctx context.Context, workerID string) (*CSVJob, error)
+	HeartbeatJob(ctx context.Context, jobID, workerID string) error
+	ReleaseJob(ctx context.Context, jobID, workerID string) error
+	SaveCheckpoint(ctx context.Context, jobID, workerID string, checkpoint CSVCheckpoint, done []CSVRowOutcome) error
+	ListDoneRows(ctx context.Context, jobID string) ([]CSVRowOutcome, error)
+	ResumeJob(ctx context.Context, id uuid.UUID) (*CSVJob, error)
+	CancelJob(ctx context.Context, id uuid.UUID) (*CSVJob, error)
+	RetryJob(ctx context.Context, id uuid.UUID) (*CSVJob, error)
+	DeleteJob(ctx context.Context, id uuid.UUID) (*CSVJob, error)
+	RequeueStaleJobs(ctx context.Context, staleBefore time.Time, maxAttempts int) (requeued, failed int, err error)
+	SetJobHeaders(ctx context.Context, jobID string, headers []string) error
+	AddJobErrors(ctx context.Context, errs []CSVJobError) error
+	ListJobErrors(ctx context.Context, jobID uuid.UUID, limit, offset int) ([]CSVJobError, int, error)
+	SaveSchema(ctx context.Context, schema *CSVSchema) error
+	GetSchema(ctx context.Context, name string) (*CSVSchema, error)
+	ListSchemas(ctx context.Context) ([]CSVSchema, error)
+	DeleteSchema(ctx context.Context, name string) error
 }
 
 // CSVService interface for CSV processing operations
 type CSVService interface {
-	UploadAndProcessCSV(ctx context.Context, files []*multipart.FileHeader) (*CSVUploadResponse, error)
+	UploadAndProcessCSV(ctx context.Context, opts CSVUploadOptions, files []*multipart.FileHeader) (*CSVUploadResponse, error)
 	GetJobProgress(ctx context.Context, jobID uuid.UUID) (*CSVProcessingProgress, error)
 	GetUserJobs(ctx context.Context) ([]*CSVJob, error)
-	ProcessCSVFile(ctx context.Context, jobID string, reader io.Reader) error
+	GetJobErrors(ctx context.Context, jobID uuid.UUID, page, limit int) (*PaginatedResponse, error)
+	StreamProgress(ctx context.Context, jobID uuid.UUID, lastEventID int64) (<-chan CSVProgressEvent, error)
+	ResumeJob(ctx context.Context, jobID uuid.UUID) (*CSVJob, error)
+	CancelJob(ctx context.Context, jobID uuid.UUID) (*CSVJob, error)
+	RetryJob(ctx context.Context, jobID uuid.UUID, failedRows bool) (*CSVJob, error)
+	DeleteJob(ctx context.Context, jobID uuid.UUID) error
+	WriteJobErrorsCSV(ctx context.Context, jobID uuid.UUID, w io.Writer) error
+	ProcessCSVFile(ctx context.Context, job *CSVJob, reader io.Reader) error
+	SaveSchema(ctx context.Context, schema *CSVSchema) error
+	GetSchema(ctx context.Context, name string) (*CSVSchema, error)
+	ListSchemas(ctx context.Context) ([]CSVSchema, error)
+	DeleteSchema(ctx context.Context, name string) error
 }
@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CSVFieldType is what the values of a column must parse as
+type CSVFieldType string
+
+const (
+	CSVFieldString CSVFieldType = "string"
+	CSVFieldInt    CSVFieldType = "int"
+	CSVFieldDate   CSVFieldType = "date"
+	CSVFieldEmail  CSVFieldType = "email"
+	CSVFieldEnum   CSVFieldType = "enum"
+	CSVFieldUUID   CSVFieldType = "uuid"
+)
+
+func (t CSVFieldType) IsValid() bool {
+	switch t {
+	case CSVFieldString, CSVFieldInt, CSVFieldDate, CSVFieldEmail, CSVFieldEnum, CSVFieldUUID:
+		return true
+	}
+	return false
+}
+
+// CSVTransform rewrites a value before it is validated
+type CSVTransform string
+
+const (
+	CSVTransformTrim     CSVTransform = "trim"
+	CSVTransformLower    CSVTransform = "lower"
+	CSVTransformUpper    CSVTransform = "upper"
+	CSVTransformCollapse CSVTransform = "collapse_spaces"
+)
+
+func (t CSVTransform) IsValid() bool {
+	switch t {
+	case CSVTransformTrim, CSVTransformLower, CSVTransformUpper, CSVTransformCollapse:
+		return true
+	}
+	return false
+}
+
+// DefaultCSVDateFormat is the layout of date columns without a format,
+// dates are handed to processors in this layout whatever the file used
+const DefaultCSVDateFormat = "2006-01-02"
+
+// CSVColumn maps a column of the file onto a field processors read, and
+// says what its values must look like
+type CSVColumn struct {
+	// Column is the header in the file, matched case-insensitively
+	Column string `json:"column" example:"E-mail"`
+	// Field is the name processors see, the column name when empty
+	Field    string       `json:"field,omitempty" example:"email"`
+	Type     CSVFieldType `json:"type,omitempty" enums:"string,int,date,email,enum,uuid" example:"email"`
+	Required bool         `json:"required,omitempty"`
+	// Default replaces an empty value, a required column with a default
+	// may be left out of the file
+	Default string `json:"default,omitempty"`
+	// Pattern is a regular expression the whole value must match
+	Pattern string `json:"pattern,omitempty"`
+	// Enum lists the values of an enum column
+	Enum []string `json:"enum,omitempty"`
+	// Format is the Go layout of a date column, e.g. 02/01/2006
+	Format    string         `json:"format,omitempty"`
+	Transform []CSVTransform `json:"transform,omitempty" enums:"trim,lower,upper,collapse_spaces"`
+}
+
+// FieldName is the name processors see the column under
+func (c CSVColumn) FieldName() string {
+	if c.Field != "" {
+		return c.Field
+	}
+	return strings.ToLower(strings.TrimSpace(c.Column))
+}
+
+// CSVSchema validates and coerces the rows of a file before they are
+// processed. Columns of the file it does not name reach processors as they
+// are.
+type CSVSchema struct {
+	Name    string      `json:"name,omitempty" example:"hr_export"`
+	Columns []CSVColumn `json:"columns"`
+}
+
+var csvSchemaNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,50}$`)
+
+// IsValidCSVSchemaName reports whether name can name a saved schema
+func IsValidCSVSchemaName(name string) bool {
+	return csvSchemaNamePattern.MatchString(name)
+}
+
+// Validate checks a schema before it is saved or used, every mistake is
+// reported against the column it was found in
+func (s *CSVSchema) Validate() error {
+	var errs FieldErrors
+	if len(s.Columns) == 0 {
+		errs = append(errs, FieldError{Field: "columns", Message: "must list at least one column"})
+	}
+
+	columns, fields := map[string]bool{}, map[string]bool{}
+	for i, c := range s.Columns {
+		at := fmt.Sprintf("columns[%d]", i)
+		name := strings.ToLower(strings.TrimSpace(c.Column))
+		switch {
+		case name == "":
+			errs = append(errs, FieldError{Field: at + ".column", Message: "is required"})
+		case columns[name]:
+			errs = append(errs, FieldError{Field: at + ".column", Message: "is listed twice"})
+		}
+		columns[name] = true
+		if fields[c.FieldName()] {
+			errs = append(errs, FieldError{Field: at + ".field", Message: "is mapped twice"})
+		}
+		fields[c.FieldName()] = true
+
+		if c.Type != "" && !c.Type.IsValid() {
+			errs = append(errs, FieldError{Field: at + ".type", Message: "must be string, int, date, email, enum or uuid"})
+		}
+		if c.Type == CSVFieldEnum && len(c.Enum) == 0 {
+			errs = append(errs, FieldError{Field: at + ".enum", Message: "must list the values of an enum column"})
+		}
+		if c.Pattern != "" {
+			if _, err := regexp.Compile(c.Pattern); err != nil {
+				errs = append(errs, FieldError{Field: at + ".pattern", Message: "is not a valid regular expression"})
+			}
+		}
+		for _, t := range c.Transform {
+			if !t.IsValid() {
+				errs = append(errs, FieldError{Field: at + ".transform", Message: "must be trim, lower, upper or collapse_spaces"})
+				break
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
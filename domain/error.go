@@ -11,12 +11,26 @@ var (
 	ErrConflict = errors.New("your Item already exist")
 	// ErrBadParamInput will throw if the given request-body or params is not valid
 	ErrBadParamInput = errors.New("given Param is not valid")
+	// ErrForbidden will throw if the caller lacks the role the action needs
+	ErrForbidden = errors.New("you are not allowed to do this")
+	// ErrPreconditionFailed will throw if the item changed since the version the caller expected
+	ErrPreconditionFailed = errors.New("item was modified since the expected version")
+	// ErrInvalidCredentials will throw if a login names an unknown user or the wrong password
+	ErrInvalidCredentials = errors.New("invalid email or password")
 	// ErrUserNotFound
 	ErrUserNotFound = errors.New("user not found")
+	// ErrCommentTooDeep will throw if a reply would exceed the maximum thread depth
+	ErrCommentTooDeep = errors.New("comment thread is nested too deeply")
 	// ErrCSVJobNotFound will throw if the CSV job is not found
 	ErrCSVJobNotFound = errors.New("CSV job not found")
 	// ErrCSVFileInvalid will throw if the CSV file is invalid
 	ErrCSVFileInvalid = errors.New("invalid CSV file")
+	// ErrCSVUnknownType will throw if no row processor is registered for the CSV type
+	ErrCSVUnknownType = errors.New("unknown CSV type")
+	// ErrCSVJobState will throw if the CSV job cannot do what was asked in its current status
+	ErrCSVJobState = errors.New("CSV job cannot do this now")
+	// ErrCSVRowImported will throw if the row of a CSV job being imported created its record before
+	ErrCSVRowImported = errors.New("CSV row was imported already")
 	// ErrCSVProcessingFailed will throw if CSV processing fails
 	ErrCSVProcessingFailed = errors.New("CSV processing failed")
 )
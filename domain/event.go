@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// EventType names something that happened in the application that other
+// parts of it may react to, e.g. sending notifications
+type EventType string
+
+const (
+	EventPostCreated    EventType = "post.created"
+	EventPostUpdated    EventType = "post.updated"
+	EventPostDeleted    EventType = "post.deleted"
+	EventCommentCreated EventType = "comment.created"
+	EventCommentUpdated EventType = "comment.updated"
+	EventCommentDeleted EventType = "comment.deleted"
+)
+
+// PostEvents and CommentEvents list every event type published for posts
+// and comments, for subscribers interested in all of them
+var (
+	PostEvents    = []EventType{EventPostCreated, EventPostUpdated, EventPostDeleted}
+	CommentEvents = []EventType{EventCommentCreated, EventCommentUpdated, EventCommentDeleted}
+)
+
+// Event is published after the change it describes has been stored.
+// ParentCommentID is only set for replies.
+type Event struct {
+	Type            EventType `json:"type"`
+	ActorID         string    `json:"actor_id"`
+	PostID          string    `json:"post_id,omitempty"`
+	CommentID       string    `json:"comment_id,omitempty"`
+	ParentCommentID string    `json:"parent_comment_id,omitempty"`
+	Body            string    `json:"body,omitempty"`
+	OccurredAt      time.Time `json:"occurred_at"`
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header clients set to make a POST safe
+// to retry
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader marks a response replayed from an earlier request
+// with the same key
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// MaxIdempotencyKeyLength is the longest Idempotency-Key accepted
+const MaxIdempotencyKeyLength = 255
+
+var (
+	// ErrIdempotencyInFlight will throw if a request with the same key is still being processed
+	ErrIdempotencyInFlight = errors.New("a request with this idempotency key is still in progress")
+	// ErrIdempotencyKeyReused will throw if a key is sent again with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+)
+
+// IdempotencyPolicy is how long responses are kept for replay, and how long a
+// request may hold its key before a retry can take it over, which covers
+// replicas that died mid-request
+type IdempotencyPolicy struct {
+	TTL      time.Duration
+	Lease    time.Duration
+	Interval time.Duration
+}
+
+// DefaultIdempotencyPolicy keeps responses for a day, lets a retry take over
+// a key after two minutes without a response, and purges hourly
+func DefaultIdempotencyPolicy() IdempotencyPolicy {
+	return IdempotencyPolicy{
+		TTL:      24 * time.Hour,
+		Lease:    2 * time.Minute,
+		Interval: time.Hour,
+	}
+}
+
+// IdempotencyRecord is a request made with an Idempotency-Key and, once it
+// completed, the response to replay. Keys are scoped to the caller and the
+// route, RequestHash fingerprints the payload.
+type IdempotencyRecord struct {
+	Key         string
+	UserID      string
+	Method      string
+	Route       string
+	RequestHash string
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// IsCompleted reports whether the record holds a response to replay
+func (r *IdempotencyRecord) IsCompleted() bool {
+	return r.CompletedAt != nil
+}
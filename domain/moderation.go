@@ -0,0 +1,125 @@
+package domain
+
+import "time"
+
+// ModerationStatus is the review state of a comment. Only approved comments
+// are publicly visible.
+type ModerationStatus string
+
+const (
+	ModerationStatusPending  ModerationStatus = "pending"
+	ModerationStatusApproved ModerationStatus = "approved"
+	ModerationStatusRejected ModerationStatus = "rejected"
+	ModerationStatusSpam     ModerationStatus = "spam"
+)
+
+// IsValid reports whether s is a supported moderation status
+func (s ModerationStatus) IsValid() bool {
+	switch s {
+	case ModerationStatusPending, ModerationStatusApproved, ModerationStatusRejected, ModerationStatusSpam:
+		return true
+	}
+	return false
+}
+
+// ModerationAction is a moderator decision on a comment
+type ModerationAction string
+
+const (
+	ModerationActionApprove ModerationAction = "approve"
+	ModerationActionReject  ModerationAction = "reject"
+	ModerationActionSpam    ModerationAction = "spam"
+	// ModerationActionFlag is recorded when the spam rules hold a new comment back
+	ModerationActionFlag ModerationAction = "flag"
+)
+
+// Status returns the status a comment ends up in after the action, or "" for
+// actions moderators cannot take
+func (a ModerationAction) Status() ModerationStatus {
+	switch a {
+	case ModerationActionApprove:
+		return ModerationStatusApproved
+	case ModerationActionReject:
+		return ModerationStatusRejected
+	case ModerationActionSpam:
+		return ModerationStatusSpam
+	}
+	return ""
+}
+
+// ModerationRules configures the automatic screening of new comments
+type ModerationRules struct {
+	// BlockedWords marks a comment as spam when its body contains any of them
+	BlockedWords []string
+	// MaxLinks holds a comment for review when it has more links than this,
+	// a negative value disables the check
+	MaxLinks int
+	// NewAccountAge holds comments of accounts younger than this for review
+	NewAccountAge time.Duration
+	// RateLimit marks a comment as spam when its author already posted this
+	// many comments within RateWindow, zero disables the check
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+// DefaultModerationRules are used when no rule is configured
+func DefaultModerationRules() ModerationRules {
+	return ModerationRules{
+		MaxLinks:      2,
+		NewAccountAge: 24 * time.Hour,
+		RateLimit:     5,
+		RateWindow:    time.Minute,
+	}
+}
+
+// ModerationDecision is the outcome of screening a comment
+type ModerationDecision struct {
+	Status ModerationStatus `json:"status"`
+	Reason string           `json:"reason"`
+}
+
+// ModerationAudit records a status change of a comment. ModeratorID is nil
+// for decisions made by the spam rules.
+type ModerationAudit struct {
+	ID          string           `json:"id"`
+	CommentID   string           `json:"comment_id"`
+	ModeratorID *string          `json:"moderator_id"`
+	Action      ModerationAction `json:"action"`
+	FromStatus  ModerationStatus `json:"from_status"`
+	ToStatus    ModerationStatus `json:"to_status"`
+	Reason      string           `json:"reason"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+type ModerationQueueFilter struct {
+	Status ModerationStatus `json:"status" query:"status"`
+	Page   int              `json:"page" query:"page"`
+	Limit  int              `json:"limit" query:"limit"`
+}
+
+// Normalize applies defaults and upper bounds to the filter, the queue lists
+// pending comments unless another status is asked for
+func (f *ModerationQueueFilter) Normalize() {
+	if f.Status == "" {
+		f.Status = ModerationStatusPending
+	}
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.Limit < 1 || f.Limit > 100 {
+		f.Limit = 20
+	}
+}
+
+type BulkModerationRequest struct {
+	CommentIDs []string         `json:"comment_ids" validate:"required,min=1,max=100"`
+	Action     ModerationAction `json:"action" validate:"required,oneof=approve reject spam"`
+	Reason     string           `json:"reason"`
+}
+
+// BulkModerationResult lists the audit records written by a bulk action and
+// the requested comments that do not exist
+type BulkModerationResult struct {
+	Audits   []ModerationAudit `json:"audits"`
+	NotFound []string          `json:"not_found"`
+}
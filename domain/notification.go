@@ -0,0 +1,78 @@
+package domain
+
+import "time"
+
+// NotificationType is why a user was notified
+type NotificationType string
+
+const (
+	NotificationComment NotificationType = "comment"
+	NotificationReply   NotificationType = "reply"
+	NotificationMention NotificationType = "mention"
+)
+
+type Notification struct {
+	ID        string           `json:"id"`
+	UserID    string           `json:"user_id"`
+	ActorID   *string          `json:"actor_id"`
+	Type      NotificationType `json:"type"`
+	PostID    *string          `json:"post_id"`
+	CommentID *string          `json:"comment_id"`
+	Message   string           `json:"message"`
+	ReadAt    *time.Time       `json:"read_at"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+type NotificationFilter struct {
+	UnreadOnly bool `json:"unread" query:"unread"`
+	Page       int  `json:"page" query:"page"`
+	Limit      int  `json:"limit" query:"limit"`
+}
+
+// Normalize applies defaults and upper bounds to the filter
+func (f *NotificationFilter) Normalize() {
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.Limit < 1 || f.Limit > 100 {
+		f.Limit = 20
+	}
+}
+
+// NotificationPage is a page of a user's inbox together with the number of
+// notifications they have not read yet
+type NotificationPage struct {
+	Data        []Notification `json:"data"`
+	Pagination  PaginationInfo `json:"pagination"`
+	UnreadCount int            `json:"unread_count"`
+}
+
+// NotificationPreferences lets a user opt out of notification types, users
+// without stored preferences get every notification
+type NotificationPreferences struct {
+	OnComment bool `json:"on_comment"`
+	OnReply   bool `json:"on_reply"`
+	OnMention bool `json:"on_mention"`
+}
+
+// DefaultNotificationPreferences enables every notification type
+func DefaultNotificationPreferences() NotificationPreferences {
+	return NotificationPreferences{OnComment: true, OnReply: true, OnMention: true}
+}
+
+// Allows reports whether the preferences let a notification of type t through
+func (p NotificationPreferences) Allows(t NotificationType) bool {
+	switch t {
+	case NotificationComment:
+		return p.OnComment
+	case NotificationReply:
+		return p.OnReply
+	case NotificationMention:
+		return p.OnMention
+	}
+	return false
+}
+
+type MarkNotificationsReadRequest struct {
+	IDs []string `json:"ids" validate:"required,min=1"`
+}
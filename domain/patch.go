@@ -0,0 +1,272 @@
+package domain
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// MergePatchContentType is the media type of RFC 7396 JSON merge-patch
+// documents
+const MergePatchContentType = "application/merge-patch+json"
+
+// FieldError describes why one member of a request was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FieldErrors is returned when a request fails per-field validation, it
+// matches ErrBadParamInput with errors.Is
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, fe := range e {
+		if fe.Field == "" {
+			parts = append(parts, fe.Message)
+			continue
+		}
+		parts = append(parts, fe.Field+": "+fe.Message)
+	}
+	return "invalid request: " + strings.Join(parts, "; ")
+}
+
+func (e FieldErrors) Unwrap() error {
+	return ErrBadParamInput
+}
+
+// PostsPatch holds the members of a merge-patch on a post, nil fields are
+// left untouched. ContentHTML is filled in by the service whenever the
+// content or its format changes.
+type PostsPatch struct {
+	Title         *string        `json:"title,omitempty"`
+	Content       *string        `json:"content,omitempty"`
+	ContentFormat *ContentFormat `json:"content_format,omitempty" enums:"plain,markdown"`
+	ContentHTML   *string        `json:"-"`
+	Version       int            `json:"version,omitempty"`
+}
+
+// IsEmpty reports whether the patch changes nothing
+func (p *PostsPatch) IsEmpty() bool {
+	return p.Title == nil && p.Content == nil && p.ContentFormat == nil
+}
+
+// ParsePostsPatch decodes and validates a merge-patch document for a post
+func ParsePostsPatch(doc []byte) (*PostsPatch, error) {
+	mp, err := decodeMergePatch(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := new(PostsPatch)
+	patch.Title = mp.text("title")
+	patch.Content = mp.text("content")
+	patch.ContentFormat = mp.format("content_format")
+	patch.Version = mp.version()
+	return patch, mp.finish()
+}
+
+// UserPatch holds the members of a merge-patch on a user, nil fields are
+// left untouched. Department and location may be set to "" to clear them.
+type UserPatch struct {
+	Name       *string `json:"name,omitempty"`
+	Email      *string `json:"email,omitempty"`
+	Username   *string `json:"username,omitempty"`
+	Department *string `json:"department,omitempty"`
+	Location   *string `json:"location,omitempty"`
+	Version    int     `json:"version,omitempty"`
+}
+
+// IsEmpty reports whether the patch changes nothing
+func (p *UserPatch) IsEmpty() bool {
+	return p.Name == nil && p.Email == nil && p.Username == nil && p.Department == nil && p.Location == nil
+}
+
+// ParseUserPatch decodes and validates a merge-patch document for a user
+func ParseUserPatch(doc []byte) (*UserPatch, error) {
+	mp, err := decodeMergePatch(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := new(UserPatch)
+	patch.Name = mp.text("name")
+	patch.Email = mp.email("email")
+	patch.Username = mp.username("username")
+	patch.Department = mp.optionalText("department")
+	patch.Location = mp.optionalText("location")
+	patch.Version = mp.version()
+	return patch, mp.finish()
+}
+
+// CommentPatch holds the members of a merge-patch on a comment, nil fields
+// are left untouched. ContentHTML is filled in by the service whenever the
+// body or its format changes, Status and ModerationReason when the new body
+// screens into another moderation status.
+type CommentPatch struct {
+	Body             *string           `json:"body,omitempty"`
+	ContentFormat    *ContentFormat    `json:"content_format,omitempty" enums:"plain,markdown"`
+	ContentHTML      *string           `json:"-"`
+	Status           *ModerationStatus `json:"-"`
+	ModerationReason *string           `json:"-"`
+	Version          int               `json:"version,omitempty"`
+}
+
+// IsEmpty reports whether the patch changes nothing
+func (p *CommentPatch) IsEmpty() bool {
+	return p.Body == nil && p.ContentFormat == nil
+}
+
+// ParseCommentPatch decodes and validates a merge-patch document for a
+// comment
+func ParseCommentPatch(doc []byte) (*CommentPatch, error) {
+	mp, err := decodeMergePatch(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := new(CommentPatch)
+	patch.Body = mp.text("body")
+	patch.ContentFormat = mp.format("content_format")
+	patch.Version = mp.version()
+	return patch, mp.finish()
+}
+
+// mergePatch is a merge-patch document taken apart one member at a time.
+// Members are columns that cannot be NULL, so null, which RFC 7396 uses to
+// remove a member, is rejected.
+type mergePatch struct {
+	members map[string]json.RawMessage
+	errs    FieldErrors
+}
+
+func decodeMergePatch(doc []byte) (*mergePatch, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &members); err != nil || members == nil {
+		return nil, FieldErrors{{Message: "body must be a JSON object"}}
+	}
+	return &mergePatch{members: members}, nil
+}
+
+// take removes field from the document, returning its raw value when it was
+// supplied with something other than null
+func (mp *mergePatch) take(field string) (json.RawMessage, bool) {
+	raw, ok := mp.members[field]
+	if !ok {
+		return nil, false
+	}
+	delete(mp.members, field)
+
+	if string(raw) == "null" {
+		mp.reject(field, "cannot be removed")
+		return nil, false
+	}
+	return raw, true
+}
+
+func (mp *mergePatch) reject(field, message string) {
+	mp.errs = append(mp.errs, FieldError{Field: field, Message: message})
+}
+
+func (mp *mergePatch) text(field string) *string {
+	raw, ok := mp.take(field)
+	if !ok {
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		mp.reject(field, "must be a string")
+		return nil
+	}
+	if strings.TrimSpace(value) == "" {
+		mp.reject(field, "must not be empty")
+		return nil
+	}
+	return &value
+}
+
+// optionalText reads a string member that may be empty
+func (mp *mergePatch) optionalText(field string) *string {
+	raw, ok := mp.take(field)
+	if !ok {
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		mp.reject(field, "must be a string")
+		return nil
+	}
+	return &value
+}
+
+func (mp *mergePatch) username(field string) *string {
+	value := mp.text(field)
+	if value == nil {
+		return nil
+	}
+	if !IsValidUsername(*value) {
+		mp.reject(field, "must be 3 to 30 letters, digits or underscores")
+		return nil
+	}
+	return value
+}
+
+func (mp *mergePatch) email(field string) *string {
+	value := mp.text(field)
+	if value == nil {
+		return nil
+	}
+	if !IsValidEmail(*value) {
+		mp.reject(field, "must be an email address")
+		return nil
+	}
+	return value
+}
+
+func (mp *mergePatch) format(field string) *ContentFormat {
+	value := mp.text(field)
+	if value == nil {
+		return nil
+	}
+	format := ContentFormat(*value)
+	if !format.IsValid() {
+		mp.reject(field, "must be plain or markdown")
+		return nil
+	}
+	return &format
+}
+
+// version reads the optional version member, which works like If-Match
+func (mp *mergePatch) version() int {
+	raw, ok := mp.take("version")
+	if !ok {
+		return 0
+	}
+
+	var value int
+	if err := json.Unmarshal(raw, &value); err != nil || value <= 0 {
+		mp.reject("version", "must be a positive integer")
+		return 0
+	}
+	return value
+}
+
+// finish rejects the members nobody took and reports every field error
+func (mp *mergePatch) finish() error {
+	unknown := make([]string, 0, len(mp.members))
+	for field := range mp.members {
+		unknown = append(unknown, field)
+	}
+	sort.Strings(unknown)
+	for _, field := range unknown {
+		mp.reject(field, "cannot be changed")
+	}
+
+	if len(mp.errs) > 0 {
+		return mp.errs
+	}
+	return nil
+}
@@ -3,34 +3,46 @@ package domain
 import "time"
 
 type Posts struct {
-	ID        string    `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	Slug      string    `json:"slug"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            string           `json:"id"`
+	UserID        *string          `json:"user_id"`
+	Title         string           `json:"title"`
+	Content       string           `json:"content"`
+	ContentFormat ContentFormat    `json:"content_format"`
+	ContentHTML   string           `json:"content_html"`
+	Slug          string           `json:"slug"`
+	Version       int              `json:"version"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	CommentCount  int64            `json:"comment_count"`
+	Reactions     *ReactionSummary `json:"reactions,omitempty"`
 }
 
 type CreatePostsRequest struct {
-	Title   string `json:"title" validate:"required"`
-	Content string `json:"content" validate:"required"`
-	Slug    string `json:"slug" `
+	Title         string        `json:"title" validate:"required"`
+	Content       string        `json:"content" validate:"required"`
+	ContentFormat ContentFormat `json:"content_format" validate:"omitempty,oneof=plain markdown"`
+	ContentHTML   string        `json:"-"`
+	Slug          string        `json:"slug" `
+	UserID        string        `json:"-"`
 }
 
 type CreatePostsRequestSwagger struct {
-	Title   string `json:"title" validate:"required"`
-	Content string `json:"content" validate:"required"`
+	Title         string        `json:"title" validate:"required"`
+	Content       string        `json:"content" validate:"required"`
+	ContentFormat ContentFormat `json:"content_format" enums:"plain,markdown"`
 }
 
 type UpdatePostsRequest struct {
-	Title   string `json:"title" validate:"required"`
-	Content string `json:"content" validate:"required"`
-	Slug    string `json:"slug" validate:"required"`
+	Title         string        `json:"title" validate:"required"`
+	Content       string        `json:"content" validate:"required"`
+	ContentFormat ContentFormat `json:"content_format" validate:"omitempty,oneof=plain markdown"`
+	Slug          string        `json:"slug" validate:"required"`
 }
 
 type UpdatePostsRequestSwagger struct {
-	Title   string `json:"title" validate:"required"`
-	Content string `json:"content" validate:"required"`
+	Title         string        `json:"title" validate:"required"`
+	Content       string        `json:"content" validate:"required"`
+	ContentFormat ContentFormat `json:"content_format" enums:"plain,markdown"`
 }
 
 type PostsFilter struct {
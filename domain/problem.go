@@ -0,0 +1,50 @@
+package domain
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// ProblemCode identifies a kind of error. Codes are stable, clients may
+// branch on them where the human readable title and detail may change.
+type ProblemCode string
+
+const (
+	ProblemBadRequest           ProblemCode = "bad_request"
+	ProblemValidationFailed     ProblemCode = "validation_failed"
+	ProblemUnauthorized         ProblemCode = "unauthorized"
+	ProblemInvalidCredentials   ProblemCode = "invalid_credentials"
+	ProblemForbidden            ProblemCode = "forbidden"
+	ProblemNotFound             ProblemCode = "not_found"
+	ProblemMethodNotAllowed     ProblemCode = "method_not_allowed"
+	ProblemRequestTimeout       ProblemCode = "request_timeout"
+	ProblemConflict             ProblemCode = "conflict"
+	ProblemAlreadyExists        ProblemCode = "already_exists"
+	ProblemRequestInProgress    ProblemCode = "request_in_progress"
+	ProblemVersionMismatch      ProblemCode = "version_mismatch"
+	ProblemPayloadTooLarge      ProblemCode = "payload_too_large"
+	ProblemUnsupportedMediaType ProblemCode = "unsupported_media_type"
+	ProblemInvalidReference     ProblemCode = "invalid_reference"
+	ProblemIdempotencyKeyReused ProblemCode = "idempotency_key_reused"
+	ProblemThreadTooDeep        ProblemCode = "thread_too_deep"
+	ProblemPreconditionRequired ProblemCode = "precondition_required"
+	ProblemRateLimited          ProblemCode = "rate_limited"
+	ProblemInternal             ProblemCode = "internal_error"
+)
+
+// Type is the URI reference identifying the problem type
+func (c ProblemCode) Type() string {
+	return "urn:problem-type:" + string(c)
+}
+
+// Problem is an RFC 7807 problem details document, the body of every error
+// response. Code, RequestID, TraceID and Errors are extension members.
+type Problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Instance  string      `json:"instance,omitempty"`
+	Code      ProblemCode `json:"code"`
+	RequestID string      `json:"request_id,omitempty"`
+	TraceID   string      `json:"trace_id,omitempty"`
+	Errors    FieldErrors `json:"errors,omitempty"`
+}
@@ -0,0 +1,57 @@
+package domain
+
+import "time"
+
+// ReactionTarget is the kind of content a reaction is attached to
+type ReactionTarget string
+
+const (
+	ReactionTargetPost    ReactionTarget = "post"
+	ReactionTargetComment ReactionTarget = "comment"
+)
+
+// ReactionType is the emoji or like a user leaves on a post or comment
+type ReactionType string
+
+const (
+	ReactionTypeLike  ReactionType = "like"
+	ReactionTypeLove  ReactionType = "love"
+	ReactionTypeLaugh ReactionType = "laugh"
+	ReactionTypeWow   ReactionType = "wow"
+	ReactionTypeSad   ReactionType = "sad"
+	ReactionTypeAngry ReactionType = "angry"
+)
+
+// IsValid reports whether the reaction type is supported
+func (t ReactionType) IsValid() bool {
+	switch t {
+	case ReactionTypeLike, ReactionTypeLove, ReactionTypeLaugh,
+		ReactionTypeWow, ReactionTypeSad, ReactionTypeAngry:
+		return true
+	}
+	return false
+}
+
+// Reaction is a single user's reaction of one type to a post or comment
+type Reaction struct {
+	ID         string         `json:"id"`
+	UserID     string         `json:"user_id"`
+	TargetType ReactionTarget `json:"target_type"`
+	TargetID   string         `json:"target_id"`
+	Type       ReactionType   `json:"type"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// ReactionSummary aggregates the reactions on a post or comment
+type ReactionSummary struct {
+	Counts map[ReactionType]int64 `json:"counts"`
+	Mine   []ReactionType         `json:"mine"`
+}
+
+// NewReactionSummary returns an empty summary ready to be filled
+func NewReactionSummary() *ReactionSummary {
+	return &ReactionSummary{
+		Counts: map[ReactionType]int64{},
+		Mine:   []ReactionType{},
+	}
+}
@@ -0,0 +1,59 @@
+package domain
+
+import "time"
+
+// RetentionPolicy is how long soft-deleted records of every entity are kept
+// before the purge job deletes them for good. A zero period keeps them forever.
+type RetentionPolicy struct {
+	Periods   map[TrashEntity]time.Duration
+	BatchSize int
+	Interval  time.Duration
+}
+
+// DefaultRetentionPolicy keeps deleted users for 90 days and deleted posts
+// and comments for 30, purging hourly in batches of 500
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		Periods: map[TrashEntity]time.Duration{
+			TrashUsers:    90 * 24 * time.Hour,
+			TrashPosts:    30 * 24 * time.Hour,
+			TrashComments: 30 * 24 * time.Hour,
+		},
+		BatchSize: 500,
+		Interval:  time.Hour,
+	}
+}
+
+// PurgeOrder lists the entities children first, so a record is purged before
+// the ones its foreign keys point to
+var PurgeOrder = []TrashEntity{TrashComments, TrashPosts, TrashUsers}
+
+// PurgeResult is what a purge run did, or in a dry run would do, to the
+// expired records of one entity
+type PurgeResult struct {
+	Entity TrashEntity `json:"entity"`
+	Before time.Time   `json:"before"`
+	Purged int         `json:"purged"`
+	// Owned counts the rows of other tables that belonged to the purged
+	// records and went with them, by table
+	Owned  map[string]int `json:"owned,omitempty"`
+	DryRun bool           `json:"dry_run"`
+}
+
+// PurgeCount is how many expired records of an entity were, or would be,
+// purged and how many rows they owned, by table
+type PurgeCount struct {
+	Records int
+	Owned   map[string]int
+}
+
+// Add counts what a purge batch deleted into the result
+func (r *PurgeResult) Add(count PurgeCount) {
+	r.Purged += count.Records
+	for table, n := range count.Owned {
+		if r.Owned == nil {
+			r.Owned = map[string]int{}
+		}
+		r.Owned[table] += n
+	}
+}
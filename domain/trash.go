@@ -0,0 +1,66 @@
+package domain
+
+import "time"
+
+// TrashEntity names a kind of record that is soft-deleted and can be restored
+type TrashEntity string
+
+const (
+	TrashUsers    TrashEntity = "users"
+	TrashPosts    TrashEntity = "posts"
+	TrashComments TrashEntity = "comments"
+)
+
+// TrashEntities lists every entity kept in the trash
+var TrashEntities = []TrashEntity{TrashUsers, TrashPosts, TrashComments}
+
+func (e TrashEntity) IsValid() bool {
+	switch e {
+	case TrashUsers, TrashPosts, TrashComments:
+		return true
+	}
+	return false
+}
+
+// TrashItem is a soft-deleted record. Label is the user's name, the post's
+// title or the start of the comment's body; ParentID is the post of a comment.
+type TrashItem struct {
+	Entity    TrashEntity `json:"entity"`
+	ID        string      `json:"id"`
+	Label     string      `json:"label"`
+	OwnerID   *string     `json:"owner_id"`
+	ParentID  *string     `json:"parent_id,omitempty"`
+	DeletedAt time.Time   `json:"deleted_at"`
+}
+
+type TrashFilter struct {
+	Page  int `json:"page" query:"page"`
+	Limit int `json:"limit" query:"limit"`
+}
+
+// Normalize applies defaults and upper bounds to the filter
+func (f *TrashFilter) Normalize() {
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.Limit < 1 || f.Limit > 100 {
+		f.Limit = 20
+	}
+}
+
+// TrashSummary counts the soft-deleted records of every entity
+type TrashSummary map[TrashEntity]int
+
+// RestoredPost is a restored post together with the number of its comments
+// that were deleted with it and came back
+type RestoredPost struct {
+	Posts
+	RestoredComments int `json:"restored_comments"`
+}
+
+// RestoredComment is a restored comment together with the number of its
+// replies that were deleted with it and came back
+type RestoredComment struct {
+	Comment
+	RestoredReplies int `json:"restored_replies"`
+}
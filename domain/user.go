@@ -1,23 +1,64 @@
 package domain
 
 import (
+	"net/mail"
+	"regexp"
 	"time"
 )
 
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
+// IsValidUsername reports whether name can be used as a username, i.e. what
+// follows the @ of a mention
+func IsValidUsername(name string) bool {
+	return usernamePattern.MatchString(name)
+}
+
+// IsValidEmail reports whether email is a bare email address
+func IsValidEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
 type User struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Email      string    `json:"email"`
+	Username   string    `json:"username"`
+	Department string    `json:"department,omitempty"`
+	Location   string    `json:"location,omitempty"`
+	Role       Role      `json:"role"`
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
 
 type CreateUserRequest struct {
 	Name     string `json:"name" validate:"required"`
 	Email    string `json:"email" validate:"required,email"`
+	Username string `json:"username"`
 	Password string `json:"password" validate:"required,password"`
 }
 
+// UserImport is a user from an HR export. Importing it creates the user, or
+// updates the one with the same username or email. Password and
+// RecoveryCode are stored hashed, the password only for new users.
+// Existing users keep their email and recovery code unless
+// UpdateCredentials is set.
+type UserImport struct {
+	Username     string
+	Email        string
+	Name         string
+	EmployeeID   string
+	Department   string
+	Location     string
+	Password     string
+	RecoveryCode string
+	// UpdateCredentials lets the import replace the email and recovery code
+	// of an existing user
+	UpdateCredentials bool
+}
+
 type UpdateUserRequest struct {
 	Name  string `json:"name" validate:"required"`
 	Email string `json:"email" validate:"required,email"`
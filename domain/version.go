@@ -0,0 +1,26 @@
+package domain
+
+import "context"
+
+type expectedVersionContextKey struct{}
+
+// WithExpectedVersion stores the version the caller expects the item being
+// changed to be at, typically taken from an If-Match header
+func WithExpectedVersion(ctx context.Context, version int) context.Context {
+	return context.WithValue(ctx, expectedVersionContextKey{}, version)
+}
+
+// ExpectedVersion returns the version stored by WithExpectedVersion, if any
+func ExpectedVersion(ctx context.Context) (int, bool) {
+	version, ok := ctx.Value(expectedVersionContextKey{}).(int)
+	return version, ok
+}
+
+// CheckVersion returns ErrPreconditionFailed when the caller expects a
+// version other than current. A zero expected version matches anything.
+func CheckVersion(ctx context.Context, current int) error {
+	if expected, ok := ExpectedVersion(ctx); ok && expected != 0 && expected != current {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
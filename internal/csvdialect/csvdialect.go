@@ -0,0 +1,258 @@
+// Package csvdialect works out how an uploaded CSV file is written: its
+// encoding, delimiter and whether it needs lenient quote handling.
+package csvdialect
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	stdunicode "unicode"
+	"unicode/utf8"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+const (
+	// sampleSize is how much of the start of a file is sniffed
+	sampleSize = 64 * 1024
+	// sampleLines is how many lines must agree on a delimiter
+	sampleLines = 20
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// Open fills the fields of d left empty from the start of r and returns r as
+// UTF-8 without a byte order mark, ready for NewReader
+func Open(r io.Reader, d domain.CSVDialect) (io.Reader, domain.CSVDialect, error) {
+	if err := d.Validate(); err != nil {
+		return nil, d, err
+	}
+
+	br := bufio.NewReaderSize(r, sampleSize)
+	raw, err := br.Peek(sampleSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, d, err
+	}
+	whole := errors.Is(err, io.EOF)
+	// Peeked bytes are only valid until the next read
+	raw = bytes.Clone(raw)
+
+	bom := detectBOM(raw)
+	d.HasBOM = bom != ""
+	if d.Encoding == "" {
+		d.Encoding = bom
+	}
+	if d.Encoding == "" {
+		d.Encoding = detectEncoding(raw)
+	}
+	if d.HasBOM && bom == d.Encoding {
+		if _, err := br.Discard(bomLength(bom)); err != nil {
+			return nil, d, err
+		}
+		raw = raw[bomLength(bom):]
+	}
+
+	decoded := transform.NewReader(br, decoder(d.Encoding))
+	if d.Delimiter != "" && d.LazyQuotes != nil {
+		return decoded, d, nil
+	}
+
+	sample, _, err := transform.Bytes(decoder(d.Encoding), trimPartialRune(raw, d.Encoding))
+	if err != nil {
+		return nil, d, err
+	}
+	lines := completeLines(sample, whole)
+	if d.Delimiter == "" {
+		d.Delimiter = string(detectDelimiter(lines))
+	}
+	if d.LazyQuotes == nil {
+		lazy := needsLazyQuotes(lines, d.Comma())
+		d.LazyQuotes = &lazy
+	}
+	return decoded, d, nil
+}
+
+// NewReader returns a csv.Reader for a file opened with Open
+func NewReader(r io.Reader, d domain.CSVDialect) *csv.Reader {
+	reader := csv.NewReader(r)
+	if comma := d.Comma(); comma != 0 {
+		reader.Comma = comma
+	}
+	reader.LazyQuotes = d.LazyQuotes != nil && *d.LazyQuotes
+	// Spaces after the delimiter are common, but trimming them would merge
+	// the empty fields of a file separated by tabs
+	reader.TrimLeadingSpace = !stdunicode.IsSpace(reader.Comma)
+	return reader
+}
+
+func detectBOM(raw []byte) domain.CSVEncoding {
+	switch {
+	case bytes.HasPrefix(raw, bomUTF8):
+		return domain.CSVEncodingUTF8
+	case bytes.HasPrefix(raw, bomUTF16LE):
+		return domain.CSVEncodingUTF16LE
+	case bytes.HasPrefix(raw, bomUTF16BE):
+		return domain.CSVEncodingUTF16BE
+	}
+	return ""
+}
+
+func bomLength(e domain.CSVEncoding) int {
+	if e == domain.CSVEncodingUTF8 {
+		return len(bomUTF8)
+	}
+	return len(bomUTF16LE)
+}
+
+// detectEncoding tells UTF-16 without a byte order mark by its zero bytes,
+// mostly ASCII text has one in every pair. What is not valid UTF-8 is taken
+// for Latin-1, which any byte sequence is.
+func detectEncoding(raw []byte) domain.CSVEncoding {
+	var evenZeros, oddZeros int
+	for i, b := range raw {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZeros++
+		} else {
+			oddZeros++
+		}
+	}
+	pairs := len(raw) / 2
+	switch {
+	case pairs > 0 && oddZeros > pairs/2:
+		return domain.CSVEncodingUTF16LE
+	case pairs > 0 && evenZeros > pairs/2:
+		return domain.CSVEncodingUTF16BE
+	case utf8.Valid(trimPartialRune(raw, domain.CSVEncodingUTF8)):
+		return domain.CSVEncodingUTF8
+	}
+	return domain.CSVEncodingLatin1
+}
+
+// trimPartialRune drops a character cut in half by the end of the sample
+func trimPartialRune(raw []byte, e domain.CSVEncoding) []byte {
+	switch e {
+	case domain.CSVEncodingUTF16LE, domain.CSVEncodingUTF16BE:
+		return raw[:len(raw)-len(raw)%2]
+	case domain.CSVEncodingUTF8:
+		for i := 1; i < utf8.UTFMax && i <= len(raw); i++ {
+			if utf8.RuneStart(raw[len(raw)-i]) {
+				if !utf8.FullRune(raw[len(raw)-i:]) {
+					return raw[:len(raw)-i]
+				}
+				break
+			}
+		}
+	}
+	return raw
+}
+
+func decoder(e domain.CSVEncoding) transform.Transformer {
+	switch e {
+	case domain.CSVEncodingUTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder()
+	case domain.CSVEncodingUTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewDecoder()
+	case domain.CSVEncodingLatin1:
+		return charmap.ISO8859_1.NewDecoder()
+	}
+	return transform.Nop
+}
+
+// completeLines splits the sample into lines, leaving out the last one when
+// the sample ends inside it rather than at the end of the file
+func completeLines(sample []byte, whole bool) [][]byte {
+	lines := bytes.Split(bytes.ReplaceAll(sample, []byte("\r\n"), []byte("\n")), []byte("\n"))
+	if !whole && len(lines) > 1 {
+		lines = lines[:len(lines)-1]
+	}
+	var nonEmpty [][]byte
+	for _, line := range lines {
+		if len(bytes.TrimSpace(line)) > 0 {
+			nonEmpty = append(nonEmpty, line)
+		}
+		if len(nonEmpty) == sampleLines {
+			break
+		}
+	}
+	return nonEmpty
+}
+
+// detectDelimiter picks the candidate found the same number of times on
+// every line, the one found most often when several are. Without such a
+// candidate it falls back to the one on most lines, and to a comma for a
+// single column.
+func detectDelimiter(lines [][]byte) rune {
+	best, bestCount, bestLines := ',', 0, 0
+	for _, candidate := range domain.CSVDelimiters {
+		consistent, linesWith := true, 0
+		count := -1
+		for _, line := range lines {
+			n := countOutsideQuotes(line, candidate)
+			if n > 0 {
+				linesWith++
+			}
+			if count >= 0 && n != count {
+				consistent = false
+			}
+			count = n
+		}
+		if count <= 0 {
+			continue
+		}
+		score := linesWith
+		if consistent {
+			score += len(lines) + 1
+		}
+		if score > bestLines || (score == bestLines && count > bestCount) {
+			best, bestCount, bestLines = candidate, count, score
+		}
+	}
+	return best
+}
+
+// countOutsideQuotes counts delimiter in line, skipping quoted fields
+func countOutsideQuotes(line []byte, delimiter rune) int {
+	n, quoted := 0, false
+	for _, r := range string(line) {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == delimiter && !quoted:
+			n++
+		}
+	}
+	return n
+}
+
+// needsLazyQuotes reports whether strict parsing of the sample trips over
+// quotes that do not enclose a whole field
+func needsLazyQuotes(lines [][]byte, comma rune) bool {
+	reader := csv.NewReader(bytes.NewReader(bytes.Join(lines, []byte("\n"))))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = !stdunicode.IsSpace(comma)
+	for {
+		_, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return false
+		}
+		if errors.Is(err, csv.ErrBareQuote) || errors.Is(err, csv.ErrQuote) {
+			return true
+		}
+		if err != nil {
+			return false
+		}
+	}
+}
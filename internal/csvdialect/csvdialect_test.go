@@ -0,0 +1,126 @@
+package csvdialect_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/csvdialect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, r io.Reader, override domain.CSVDialect) ([][]string, domain.CSVDialect) {
+	t.Helper()
+	decoded, dialect, err := csvdialect.Open(r, override)
+	require.NoError(t, err)
+	records, err := csvdialect.NewReader(decoded, dialect).ReadAll()
+	require.NoError(t, err)
+	return records, dialect
+}
+
+func utf16LE(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return b
+}
+
+func TestOpen(t *testing.T) {
+	strict, lazy := false, true
+
+	t.Run("Reads the semicolon separated HR export", func(t *testing.T) {
+		file, err := os.Open("../../csvdata/username-password-recovery-code.csv")
+		require.NoError(t, err)
+		defer file.Close()
+
+		records, dialect := readAll(t, file, domain.CSVDialect{})
+
+		assert.Equal(t, domain.CSVDialect{Delimiter: ";", Encoding: domain.CSVEncodingUTF8, LazyQuotes: &strict}, dialect)
+		require.Len(t, records, 6)
+		assert.Equal(t, "Identifier", records[0][1])
+		assert.Equal(t, []string{"smith79", "5079", "09ja61", "js5079", "Jamie", "Smith", "Engineering", "Manchester"}, records[5])
+	})
+
+	tests := []struct {
+		name     string
+		input    []byte
+		override domain.CSVDialect
+		dialect  domain.CSVDialect
+		records  [][]string
+	}{
+		{
+			name:    "Strips the UTF-8 byte order mark",
+			input:   []byte("\xEF\xBB\xBFname,email\nAna,ana@example.com\n"),
+			dialect: domain.CSVDialect{Delimiter: ",", Encoding: domain.CSVEncodingUTF8, HasBOM: true, LazyQuotes: &strict},
+			records: [][]string{{"name", "email"}, {"Ana", "ana@example.com"}},
+		},
+		{
+			name:    "Transcodes UTF-16 with a byte order mark",
+			input:   append([]byte{0xFF, 0xFE}, utf16LE("name\tcity\r\nZoë\tKöln\r\n")...),
+			dialect: domain.CSVDialect{Delimiter: "\t", Encoding: domain.CSVEncodingUTF16LE, HasBOM: true, LazyQuotes: &strict},
+			records: [][]string{{"name", "city"}, {"Zoë", "Köln"}},
+		},
+		{
+			name:    "Transcodes UTF-16 without a byte order mark",
+			input:   utf16LE("name|city\nAna|Porto\n"),
+			dialect: domain.CSVDialect{Delimiter: "|", Encoding: domain.CSVEncodingUTF16LE, LazyQuotes: &strict},
+			records: [][]string{{"name", "city"}, {"Ana", "Porto"}},
+		},
+		{
+			name:    "Takes what is not UTF-8 for Latin-1",
+			input:   []byte("name;city\nJos\xe9;M\xe1laga\n"),
+			dialect: domain.CSVDialect{Delimiter: ";", Encoding: domain.CSVEncodingLatin1, LazyQuotes: &strict},
+			records: [][]string{{"name", "city"}, {"José", "Málaga"}},
+		},
+		{
+			name:    "Ignores delimiters inside quotes",
+			input:   []byte("title;content\n\"Hello, world\";\"a, b, c\"\n\"Bye\";\"x, y\"\n"),
+			dialect: domain.CSVDialect{Delimiter: ";", Encoding: domain.CSVEncodingUTF8, LazyQuotes: &strict},
+			records: [][]string{{"title", "content"}, {"Hello, world", "a, b, c"}, {"Bye", "x, y"}},
+		},
+		{
+			name:    "Turns on lazy quotes for bare quotes",
+			input:   []byte("title,content\n12\" pizza,round\n"),
+			dialect: domain.CSVDialect{Delimiter: ",", Encoding: domain.CSVEncodingUTF8, LazyQuotes: &lazy},
+			records: [][]string{{"title", "content"}, {"12\" pizza", "round"}},
+		},
+		{
+			name:     "Keeps what the upload asked for",
+			input:    []byte("a;b,c\nd;e,f\n"),
+			override: domain.CSVDialect{Delimiter: ","},
+			dialect:  domain.CSVDialect{Delimiter: ",", Encoding: domain.CSVEncodingUTF8, LazyQuotes: &strict},
+			records:  [][]string{{"a;b", "c"}, {"d;e", "f"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, dialect := readAll(t, bytes.NewReader(tt.input), tt.override)
+
+			assert.Equal(t, tt.dialect, dialect)
+			assert.Equal(t, tt.records, records)
+		})
+	}
+
+	t.Run("Keeps lazy quotes off when the upload turns them off", func(t *testing.T) {
+		decoded, dialect, err := csvdialect.Open(strings.NewReader("title,content\n12\" pizza,round\n"), domain.CSVDialect{LazyQuotes: &strict})
+		require.NoError(t, err)
+
+		assert.Equal(t, &strict, dialect.LazyQuotes)
+		_, err = csvdialect.NewReader(decoded, dialect).ReadAll()
+		assert.ErrorIs(t, err, csv.ErrBareQuote)
+	})
+
+	t.Run("Rejects an unknown encoding", func(t *testing.T) {
+		_, _, err := csvdialect.Open(strings.NewReader("a,b\n"), domain.CSVDialect{Encoding: "ebcdic"})
+
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+	})
+}
@@ -0,0 +1,151 @@
+// Package csvprogress pushes the progress of CSV jobs to the clients
+// streaming it. The replica processing a job publishes, the clients of
+// every replica receive.
+package csvprogress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/logging"
+	"github.com/edwinjordan/MajooTest-Golang/internal/pgnotify"
+)
+
+// NotifyChannel is the Postgres channel progress travels on between replicas
+const NotifyChannel = "csv_progress"
+
+// Notifier sends a payload to the brokers of every replica
+type Notifier interface {
+	Notify(ctx context.Context, channel, payload string) error
+}
+
+// Broker routes the progress events of a job to its subscribers. Every event
+// is the whole state of the job, so a subscriber that has not taken the
+// previous one yet only gets the latest instead of holding up the others.
+type Broker struct {
+	notifier Notifier
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan domain.CSVProgressEvent]struct{}
+	lastID      map[string]int64
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[string]map[chan domain.CSVProgressEvent]struct{}),
+		lastID:      make(map[string]int64),
+	}
+}
+
+// WithNotifier sends events through n instead of delivering them directly,
+// so clients connected to any replica receive them. Every replica, this one
+// included, must feed what n receives back into Receive.
+func (b *Broker) WithNotifier(n Notifier) *Broker {
+	b.notifier = n
+	return b
+}
+
+// Subscribe returns the events of a job published from now on and the
+// function that ends the subscription
+func (b *Broker) Subscribe(jobID string) (<-chan domain.CSVProgressEvent, func()) {
+	events := make(chan domain.CSVProgressEvent, 1)
+
+	b.mu.Lock()
+	if b.subscribers[jobID] == nil {
+		b.subscribers[jobID] = make(map[chan domain.CSVProgressEvent]struct{})
+	}
+	b.subscribers[jobID][events] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers[jobID], events)
+			if len(b.subscribers[jobID]) == 0 {
+				delete(b.subscribers, jobID)
+			}
+			close(events)
+		})
+	}
+}
+
+// Publish numbers event and sends it to the subscribers of its job on every
+// replica. The ids of a job grow with every event this process publishes.
+func (b *Broker) Publish(ctx context.Context, event domain.CSVProgressEvent) {
+	b.mu.Lock()
+	event.ID = time.Now().UnixMilli()
+	if last := b.lastID[event.JobID]; event.ID <= last {
+		event.ID = last + 1
+	}
+	if event.Final() {
+		delete(b.lastID, event.JobID)
+	} else {
+		b.lastID[event.JobID] = event.ID
+	}
+	b.mu.Unlock()
+
+	if b.notifier == nil {
+		b.Deliver(event)
+		return
+	}
+	if err := b.notify(ctx, event); err != nil {
+		// At least the clients of this replica hear about it
+		b.Deliver(event)
+		logging.LogError(ctx, err, "csv_progress_notify")
+	}
+}
+
+func (b *Broker) notify(ctx context.Context, event domain.CSVProgressEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if len(payload) > pgnotify.MaxPayload && event.Summary != nil {
+		// The counts fit, the errors are served by the job
+		summary := *event.Summary
+		summary.Errors = nil
+		event.Summary = &summary
+		if payload, err = json.Marshal(event); err != nil {
+			return err
+		}
+	}
+	if err := b.notifier.Notify(ctx, NotifyChannel, string(payload)); err != nil {
+		return fmt.Errorf("notify replicas: %w", err)
+	}
+	return nil
+}
+
+// Receive is the pgnotify handler for events sent by WithNotifier
+func (b *Broker) Receive(_ context.Context, payload string) {
+	var event domain.CSVProgressEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return
+	}
+	b.Deliver(event)
+}
+
+// Deliver hands event to the subscribers of its job on this replica only
+func (b *Broker) Deliver(event domain.CSVProgressEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for events := range b.subscribers[event.JobID] {
+		select {
+		case events <- event:
+		default:
+			// Only Deliver sends, under the lock, so there is room once
+			// the stale event is taken out
+			select {
+			case <-events:
+			default:
+			}
+			events <- event
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package csvprogress_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/csvprogress"
+	"github.com/edwinjordan/MajooTest-Golang/internal/pgnotify"
+)
+
+type failingNotifier struct{}
+
+func (failingNotifier) Notify(context.Context, string, string) error {
+	return errors.New("connection refused")
+}
+
+type recordingNotifier struct {
+	payloads []string
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, _ string, payload string) error {
+	n.payloads = append(n.payloads, payload)
+	return nil
+}
+
+func progress(jobID string, processed int64) domain.CSVProgressEvent {
+	return domain.CSVProgressEvent{
+		JobID:    jobID,
+		Type:     domain.CSVEventProgress,
+		Progress: domain.CSVProcessingProgress{JobID: jobID, ProcessedRows: processed},
+	}
+}
+
+func receive(t *testing.T, events <-chan domain.CSVProgressEvent) domain.CSVProgressEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	default:
+		t.Fatal("expected an event")
+		return domain.CSVProgressEvent{}
+	}
+}
+
+func TestBroker_Publish(t *testing.T) {
+	t.Run("Delivers the events of a job to its subscribers", func(t *testing.T) {
+		broker := csvprogress.NewBroker()
+		events, unsubscribe := broker.Subscribe("job-1")
+		defer unsubscribe()
+		other, unsubscribeOther := broker.Subscribe("job-2")
+		defer unsubscribeOther()
+
+		broker.Publish(context.Background(), progress("job-1", 10))
+
+		assert.Equal(t, int64(10), receive(t, events).Progress.ProcessedRows)
+		assert.Empty(t, other)
+	})
+
+	t.Run("Keeps only the latest event for a subscriber that is behind", func(t *testing.T) {
+		broker := csvprogress.NewBroker()
+		events, unsubscribe := broker.Subscribe("job-1")
+		defer unsubscribe()
+
+		broker.Publish(context.Background(), progress("job-1", 10))
+		broker.Publish(context.Background(), progress("job-1", 20))
+		final := progress("job-1", 30)
+		final.Type = string(domain.CSVJobStatusCompleted)
+		broker.Publish(context.Background(), final)
+
+		event := receive(t, events)
+		assert.Equal(t, string(domain.CSVJobStatusCompleted), event.Type)
+		assert.Empty(t, events)
+	})
+
+	t.Run("Numbers the events of a job in order", func(t *testing.T) {
+		broker := csvprogress.NewBroker()
+		events, unsubscribe := broker.Subscribe("job-1")
+		defer unsubscribe()
+
+		var ids []int64
+		for i := int64(0); i < 5; i++ {
+			broker.Publish(context.Background(), progress("job-1", i))
+			ids = append(ids, receive(t, events).ID)
+		}
+
+		for i := 1; i < len(ids); i++ {
+			assert.Greater(t, ids[i], ids[i-1])
+		}
+	})
+
+	t.Run("Sends events through the notifier", func(t *testing.T) {
+		notifier := &recordingNotifier{}
+		broker := csvprogress.NewBroker().WithNotifier(notifier)
+		events, unsubscribe := broker.Subscribe("job-1")
+		defer unsubscribe()
+
+		broker.Publish(context.Background(), progress("job-1", 10))
+
+		assert.Empty(t, events, "events come back through Receive")
+		require.Len(t, notifier.payloads, 1)
+		broker.Receive(context.Background(), notifier.payloads[0])
+		assert.Equal(t, int64(10), receive(t, events).Progress.ProcessedRows)
+	})
+
+	t.Run("Leaves the errors of a large summary out of the notification", func(t *testing.T) {
+		notifier := &recordingNotifier{}
+		broker := csvprogress.NewBroker().WithNotifier(notifier)
+		final := progress("job-1", 10)
+		final.Type = string(domain.CSVJobStatusValidated)
+		final.Summary = &domain.CSVDryRunSummary{Rows: 10, Failed: 1}
+		for i := 0; i < domain.CSVDryRunErrors; i++ {
+			final.Summary.Errors = append(final.Summary.Errors, domain.CSVJobError{Error: strings.Repeat("x", 200)})
+		}
+
+		broker.Publish(context.Background(), final)
+
+		require.Len(t, notifier.payloads, 1)
+		assert.LessOrEqual(t, len(notifier.payloads[0]), pgnotify.MaxPayload)
+		var sent domain.CSVProgressEvent
+		require.NoError(t, json.Unmarshal([]byte(notifier.payloads[0]), &sent))
+		assert.Equal(t, int64(1), sent.Summary.Failed)
+		assert.Empty(t, sent.Summary.Errors)
+	})
+
+	t.Run("Delivers locally when the notifier fails", func(t *testing.T) {
+		broker := csvprogress.NewBroker().WithNotifier(failingNotifier{})
+		events, unsubscribe := broker.Subscribe("job-1")
+		defer unsubscribe()
+
+		broker.Publish(context.Background(), progress("job-1", 10))
+
+		assert.Equal(t, int64(10), receive(t, events).Progress.ProcessedRows)
+	})
+}
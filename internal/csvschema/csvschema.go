@@ -0,0 +1,183 @@
+// Package csvschema validates and coerces CSV rows against a
+// domain.CSVSchema before they reach a row processor.
+package csvschema
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/google/uuid"
+)
+
+// Schema is a validated domain.CSVSchema, ready to check rows concurrently
+type Schema struct {
+	columns []column
+	byName  map[string]*column
+}
+
+type column struct {
+	domain.CSVColumn
+	name    string
+	field   string
+	pattern *regexp.Regexp
+}
+
+// Compile validates s and prepares it for Apply
+func Compile(s *domain.CSVSchema) (*Schema, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	compiled := &Schema{columns: make([]column, len(s.Columns)), byName: map[string]*column{}}
+	for i, c := range s.Columns {
+		col := column{
+			CSVColumn: c,
+			name:      strings.ToLower(strings.TrimSpace(c.Column)),
+			field:     c.FieldName(),
+		}
+		if c.Pattern != "" {
+			// Anchored so the pattern has to match the whole value
+			col.pattern = regexp.MustCompile(`^(?:` + c.Pattern + `)$`)
+		}
+		compiled.columns[i] = col
+		compiled.byName[col.name] = &compiled.columns[i]
+	}
+	return compiled, nil
+}
+
+// CheckHeaders fails when the file lacks a required column that has no
+// default. headers are expected trimmed and lower case.
+func (s *Schema) CheckHeaders(headers []string) error {
+	present := make(map[string]bool, len(headers))
+	for _, h := range headers {
+		present[h] = true
+	}
+	var missing []string
+	for _, c := range s.columns {
+		if c.Required && c.Default == "" && !present[c.name] {
+			missing = append(missing, c.Column)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing column %s", domain.ErrCSVFileInvalid, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Fields returns the names processors see for the headers of a file, the
+// fields of the schema followed by those of its columns the file leaves out
+func (s *Schema) Fields(headers []string) []string {
+	fields := make([]string, 0, len(headers)+len(s.columns))
+	seen := map[string]bool{}
+	for _, h := range headers {
+		if c, ok := s.byName[h]; ok {
+			fields = append(fields, c.field)
+			seen[h] = true
+			continue
+		}
+		fields = append(fields, h)
+	}
+	for _, c := range s.columns {
+		if !seen[c.name] {
+			fields = append(fields, c.field)
+		}
+	}
+	return fields
+}
+
+// Apply validates and coerces a row keyed by header and returns it keyed by
+// field. Problems are returned as domain.FieldErrors, one per column.
+func (s *Schema) Apply(row map[string]string) (map[string]string, error) {
+	out := make(map[string]string, len(row))
+	for header, value := range row {
+		if _, ok := s.byName[header]; !ok {
+			out[header] = value
+		}
+	}
+
+	var errs domain.FieldErrors
+	for _, c := range s.columns {
+		value, err := c.apply(row[c.name])
+		if err != "" {
+			errs = append(errs, domain.FieldError{Field: c.Column, Message: err})
+			continue
+		}
+		out[c.field] = value
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return out, nil
+}
+
+// apply runs the transforms, default and checks of the column on value. It
+// returns why the value was rejected, or the value to process.
+func (c *column) apply(value string) (string, string) {
+	for _, t := range c.Transform {
+		switch t {
+		case domain.CSVTransformTrim:
+			value = strings.TrimSpace(value)
+		case domain.CSVTransformLower:
+			value = strings.ToLower(value)
+		case domain.CSVTransformUpper:
+			value = strings.ToUpper(value)
+		case domain.CSVTransformCollapse:
+			value = strings.Join(strings.Fields(value), " ")
+		}
+	}
+	if value == "" {
+		value = c.Default
+	}
+	if value == "" {
+		if c.Required {
+			return "", "is required"
+		}
+		return "", ""
+	}
+
+	if c.pattern != nil && !c.pattern.MatchString(value) {
+		return "", "does not match " + c.Pattern
+	}
+
+	switch c.Type {
+	case domain.CSVFieldInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", "must be a whole number"
+		}
+		return strconv.FormatInt(n, 10), ""
+	case domain.CSVFieldDate:
+		format := c.Format
+		if format == "" {
+			format = domain.DefaultCSVDateFormat
+		}
+		d, err := time.Parse(format, value)
+		if err != nil {
+			return "", "must be a date like " + format
+		}
+		return d.Format(domain.DefaultCSVDateFormat), ""
+	case domain.CSVFieldEmail:
+		if !domain.IsValidEmail(value) {
+			return "", "must be an email address"
+		}
+	case domain.CSVFieldUUID:
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return "", "must be a UUID"
+		}
+		return id.String(), ""
+	case domain.CSVFieldEnum:
+		for _, allowed := range c.Enum {
+			if value == allowed {
+				return value, ""
+			}
+		}
+		return "", "must be one of " + strings.Join(c.Enum, ", ")
+	}
+	return value, ""
+}
@@ -0,0 +1,96 @@
+package csvschema_test
+
+import (
+	"testing"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/csvschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hrSchema(t *testing.T) *csvschema.Schema {
+	t.Helper()
+	schema, err := csvschema.Compile(&domain.CSVSchema{Columns: []domain.CSVColumn{
+		{Column: "Username", Required: true, Pattern: `[a-z]+[0-9]*`, Transform: []domain.CSVTransform{domain.CSVTransformLower}},
+		{Column: "Identifier", Field: "employee_id", Type: domain.CSVFieldInt},
+		{Column: "E-mail", Field: "email", Type: domain.CSVFieldEmail, Required: true},
+		{Column: "Start date", Field: "started_on", Type: domain.CSVFieldDate, Format: "02/01/2006"},
+		{Column: "Department", Type: domain.CSVFieldEnum, Enum: []string{"Sales", "Depot", "Engineering"}, Default: "Sales"},
+	}})
+	require.NoError(t, err)
+	return schema
+}
+
+func TestSchema_Apply(t *testing.T) {
+	schema := hrSchema(t)
+
+	t.Run("Coerces values and maps them onto fields", func(t *testing.T) {
+		row, err := schema.Apply(map[string]string{
+			"username":   "Booker12",
+			"identifier": "09012",
+			"e-mail":     "rachel@example.com",
+			"start date": "27/09/2025",
+			"department": "",
+			"location":   "Manchester",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"username":    "booker12",
+			"employee_id": "9012",
+			"email":       "rachel@example.com",
+			"started_on":  "2025-09-27",
+			"department":  "Sales",
+			"location":    "Manchester",
+		}, row)
+	})
+
+	t.Run("Reports every failing column", func(t *testing.T) {
+		_, err := schema.Apply(map[string]string{
+			"username":   "booker.12",
+			"identifier": "12a",
+			"start date": "2025-09-27",
+			"department": "HR",
+		})
+
+		var fieldErrs domain.FieldErrors
+		require.ErrorAs(t, err, &fieldErrs)
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+		assert.Equal(t, domain.FieldErrors{
+			{Field: "Username", Message: "does not match [a-z]+[0-9]*"},
+			{Field: "Identifier", Message: "must be a whole number"},
+			{Field: "E-mail", Message: "is required"},
+			{Field: "Start date", Message: "must be a date like 02/01/2006"},
+			{Field: "Department", Message: "must be one of Sales, Depot, Engineering"},
+		}, fieldErrs)
+	})
+}
+
+func TestSchema_Headers(t *testing.T) {
+	schema := hrSchema(t)
+
+	assert.NoError(t, schema.CheckHeaders([]string{"username", "e-mail"}))
+	assert.ErrorIs(t, schema.CheckHeaders([]string{"username", "identifier"}), domain.ErrCSVFileInvalid)
+	assert.Equal(t,
+		[]string{"username", "email", "location", "employee_id", "started_on", "department"},
+		schema.Fields([]string{"username", "e-mail", "location"}),
+	)
+}
+
+func TestCompile_RejectsInvalidSchemas(t *testing.T) {
+	_, err := csvschema.Compile(&domain.CSVSchema{Columns: []domain.CSVColumn{
+		{Column: "status", Type: domain.CSVFieldEnum},
+		{Column: "Status", Type: "decimal", Pattern: "("},
+	}})
+
+	var fieldErrs domain.FieldErrors
+	require.ErrorAs(t, err, &fieldErrs)
+	assert.Equal(t, domain.FieldErrors{
+		{Field: "columns[0].enum", Message: "must list the values of an enum column"},
+		{Field: "columns[1].column", Message: "is listed twice"},
+		{Field: "columns[1].field", Message: "is mapped twice"},
+		{Field: "columns[1].type", Message: "must be string, int, date, email, enum or uuid"},
+		{Field: "columns[1].pattern", Message: "is not a valid regular expression"},
+	}, fieldErrs)
+}
@@ -0,0 +1,53 @@
+// Package events dispatches domain events to the parts of the application
+// that react to them, e.g. notifications, without the publishing service
+// knowing about them.
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/logging"
+)
+
+// Handler reacts to a published event
+type Handler func(ctx context.Context, event domain.Event) error
+
+// Bus is an in-process publish/subscribe dispatcher. Handlers run
+// synchronously in the order they subscribed; a failing handler is logged
+// and does not stop the others or fail the publisher.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[domain.EventType][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[domain.EventType][]Handler)}
+}
+
+// Subscribe registers handler for every event of the given types
+func (b *Bus) Subscribe(handler Handler, types ...domain.EventType) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, t := range types {
+		b.handlers[t] = append(b.handlers[t], handler)
+	}
+}
+
+// Publish hands event to its subscribers. The change the event describes is
+// already stored, so handlers run detached from the request's cancellation.
+func (b *Bus) Publish(ctx context.Context, event domain.Event) {
+	b.mu.RLock()
+	handlers := b.handlers[event.Type]
+	b.mu.RUnlock()
+
+	ctx = context.WithoutCancel(ctx)
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			logging.LogError(ctx, err, "handle_event", slog.String("event", string(event.Type)))
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Package filestore keeps uploaded files until they are processed.
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+// Local stores files in a directory. Replicas that process each other's
+// uploads must mount the same directory.
+type Local struct {
+	dir string
+}
+
+// NewLocal stores files in dir, creating it when it does not exist
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &Local{dir: dir}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("%w: invalid file key %q", domain.ErrBadParamInput, key)
+	}
+	return filepath.Join(l.dir, key), nil
+}
+
+// Save writes r under key. The file appears once it is complete, a crash
+// while writing leaves only a temporary file behind.
+func (l *Local) Save(ctx context.Context, key string, r io.Reader) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(l.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Open returns the file saved under key, domain.ErrNotFound when there is
+// none
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: file %s", domain.ErrNotFound, key)
+	}
+	return f, err
+}
+
+// Delete removes the file saved under key, deleting a missing file is not
+// an error
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package filestore_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/filestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := filestore.NewLocal(dir)
+	require.NoError(t, err)
+
+	t.Run("Reads back what was saved", func(t *testing.T) {
+		require.NoError(t, store.Save(ctx, "job.csv", strings.NewReader("name\nAna\n")))
+
+		f, err := store.Open(ctx, "job.csv")
+		require.NoError(t, err)
+		defer f.Close()
+		content, err := io.ReadAll(f)
+		require.NoError(t, err)
+		assert.Equal(t, "name\nAna\n", string(content))
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1, "no temporary file is left behind")
+	})
+
+	t.Run("Reports a missing file as not found", func(t *testing.T) {
+		require.NoError(t, store.Delete(ctx, "job.csv"))
+		require.NoError(t, store.Delete(ctx, "job.csv"))
+
+		_, err := store.Open(ctx, "job.csv")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+
+	t.Run("Keeps keys inside its directory", func(t *testing.T) {
+		for _, key := range []string{"", "../job.csv", "a/b.csv", ".hidden"} {
+			assert.ErrorIs(t, store.Save(ctx, key, strings.NewReader("x")), domain.ErrBadParamInput, key)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+// Package pgnotify carries messages between replicas of the service over
+// Postgres LISTEN/NOTIFY, so state kept in memory by one process, e.g.
+// websocket subscriptions, can react to changes made through another.
+package pgnotify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/internal/logging"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MaxPayload is the largest payload Postgres accepts in a notification
+const MaxPayload = 7999
+
+// reconnectDelay is how long Run waits before listening again after the
+// connection was lost
+const reconnectDelay = 2 * time.Second
+
+// Handler receives the payload of a notification
+type Handler func(ctx context.Context, payload string)
+
+// Listener dispatches notifications on a dedicated connection to the
+// handlers registered for their channel
+type Listener struct {
+	pool *pgxpool.Pool
+
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewListener(pool *pgxpool.Pool) *Listener {
+	return &Listener{pool: pool, handlers: make(map[string][]Handler)}
+}
+
+// Handle registers handler for notifications sent on channel. Channels
+// registered after Run started are picked up on the next reconnect.
+func (l *Listener) Handle(channel string, handler Handler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.handlers[channel] = append(l.handlers[channel], handler)
+}
+
+// Notify sends payload to every listener of channel, this process included.
+// Postgres only delivers it once the surrounding transaction, if any, commits.
+func (l *Listener) Notify(ctx context.Context, channel, payload string) error {
+	if len(payload) > MaxPayload {
+		return fmt.Errorf("pgnotify: payload of %d bytes exceeds %d", len(payload), MaxPayload)
+	}
+	_, err := l.pool.Exec(ctx, `SELECT pg_notify($1, $2)`, channel, payload)
+	return err
+}
+
+// Run listens until ctx is done, reconnecting whenever the connection is
+// lost. Notifications sent while disconnected are not replayed.
+func (l *Listener) Run(ctx context.Context) {
+	for {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		logging.LogError(ctx, err, "pgnotify_listen", slog.Duration("retry_in", reconnectDelay))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+func (l *Listener) listen(ctx context.Context) error {
+	pooled, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// A listening connection must not go back to the pool, its
+	// subscriptions would leak into whoever acquires it next
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	l.mu.RLock()
+	channels := make([]string, 0, len(l.handlers))
+	for channel := range l.handlers {
+		channels = append(channels, channel)
+	}
+	l.mu.RUnlock()
+
+	for _, channel := range channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		l.mu.RLock()
+		handlers := l.handlers[notification.Channel]
+		l.mu.RUnlock()
+
+		for _, handler := range handlers {
+			handler(ctx, notification.Payload)
+		}
+	}
+}
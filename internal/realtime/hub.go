@@ -0,0 +1,320 @@
+// Package realtime pushes post and comment events to connected clients. A
+// client subscribes to channels, "post:<id>" for activity on a post and
+// "user:<id>" for activity by a user, and receives every event published
+// on them for as long as it keeps up.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/pgnotify"
+	"github.com/google/uuid"
+)
+
+// NotifyChannel is the Postgres channel events travel on between replicas
+const NotifyChannel = "realtime_events"
+
+// MaxSubscriptions caps the channels a single client can subscribe to
+const MaxSubscriptions = 50
+
+const (
+	MessageEvent        = "event"
+	MessageSubscribed   = "subscribed"
+	MessageUnsubscribed = "unsubscribed"
+	MessageError        = "error"
+)
+
+var (
+	// ErrSlowConsumer is why a client whose buffer filled up was dropped
+	ErrSlowConsumer = errors.New("client is not keeping up with its messages")
+	// ErrInvalidChannel is returned for channel names that are not
+	// "post:<uuid>" or "user:<uuid>"
+	ErrInvalidChannel = errors.New("invalid channel")
+	// ErrTooManySubscriptions is returned once a client holds MaxSubscriptions
+	ErrTooManySubscriptions = errors.New("too many subscriptions")
+)
+
+// Message is what the hub writes to clients
+type Message struct {
+	Type    string        `json:"type"`
+	Channel string        `json:"channel,omitempty"`
+	Event   *domain.Event `json:"event,omitempty"`
+	Message string        `json:"message,omitempty"`
+}
+
+func PostChannel(id string) string { return "post:" + id }
+func UserChannel(id string) string { return "user:" + id }
+
+// ParseChannel splits a channel name into its kind, "post" or "user", and id
+func ParseChannel(channel string) (kind, id string, err error) {
+	kind, id, ok := strings.Cut(channel, ":")
+	if !ok || (kind != "post" && kind != "user") {
+		return "", "", ErrInvalidChannel
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return "", "", ErrInvalidChannel
+	}
+	return kind, id, nil
+}
+
+// channelsFor returns the channels event is delivered on
+func channelsFor(event domain.Event) []string {
+	var channels []string
+	if event.PostID != "" {
+		channels = append(channels, PostChannel(event.PostID))
+	}
+	if event.ActorID != "" {
+		channels = append(channels, UserChannel(event.ActorID))
+	}
+	return channels
+}
+
+// Notifier sends a payload to the hubs of every replica
+type Notifier interface {
+	Notify(ctx context.Context, channel, payload string) error
+}
+
+// Client is one connection's view of the hub. Messages for it queue in a
+// bounded buffer; the connection drains it through Send.
+type Client struct {
+	mu       sync.Mutex
+	send     chan []byte
+	closed   bool
+	err      error
+	channels map[string]struct{}
+}
+
+// Send yields the messages to write to the connection. It is closed once the
+// client is unregistered, after which Err tells whether it was dropped.
+func (c *Client) Send() <-chan []byte {
+	return c.send
+}
+
+// Err returns ErrSlowConsumer if the hub dropped the client
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// deliver queues msg without blocking and reports whether there was room
+func (c *Client) deliver(msg []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return true
+	}
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Client) close(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		c.err = err
+		close(c.send)
+	}
+}
+
+// Hub routes events to the clients subscribed to their channels. A client
+// whose buffer is full when a message arrives is dropped rather than
+// allowed to hold up delivery to everybody else.
+type Hub struct {
+	bufferSize int
+	notifier   Notifier
+
+	mu       sync.RWMutex
+	channels map[string]map[*Client]struct{}
+	clients  map[*Client]struct{}
+}
+
+// NewHub creates a hub buffering up to bufferSize messages per client
+func NewHub(bufferSize int) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	return &Hub{
+		bufferSize: bufferSize,
+		channels:   make(map[string]map[*Client]struct{}),
+		clients:    make(map[*Client]struct{}),
+	}
+}
+
+// WithNotifier sends events through n instead of delivering them directly,
+// so clients connected to any replica receive them. Every replica, this one
+// included, must feed what n receives back into Receive.
+func (h *Hub) WithNotifier(n Notifier) *Hub {
+	h.notifier = n
+	return h
+}
+
+func (h *Hub) Register() *Client {
+	client := &Client{
+		send:     make(chan []byte, h.bufferSize),
+		channels: make(map[string]struct{}),
+	}
+
+	h.mu.Lock()
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+	return client
+}
+
+// Unregister removes client from all its channels and closes its Send channel
+func (h *Hub) Unregister(client *Client) {
+	h.drop(client, nil)
+}
+
+func (h *Hub) drop(client *Client, reason error) {
+	h.mu.Lock()
+	for channel := range client.channels {
+		h.removeLocked(client, channel)
+	}
+	delete(h.clients, client)
+	h.mu.Unlock()
+
+	client.close(reason)
+}
+
+// Subscribe adds client to channel, which must be a valid channel name
+func (h *Hub) Subscribe(client *Client, channel string) error {
+	if _, _, err := ParseChannel(channel); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client]; !ok {
+		return nil
+	}
+	if _, ok := client.channels[channel]; ok {
+		return nil
+	}
+	if len(client.channels) >= MaxSubscriptions {
+		return ErrTooManySubscriptions
+	}
+	if h.channels[channel] == nil {
+		h.channels[channel] = make(map[*Client]struct{})
+	}
+	h.channels[channel][client] = struct{}{}
+	client.channels[channel] = struct{}{}
+	return nil
+}
+
+func (h *Hub) Unsubscribe(client *Client, channel string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.removeLocked(client, channel)
+}
+
+func (h *Hub) removeLocked(client *Client, channel string) {
+	delete(client.channels, channel)
+	if subscribers, ok := h.channels[channel]; ok {
+		delete(subscribers, client)
+		if len(subscribers) == 0 {
+			delete(h.channels, channel)
+		}
+	}
+}
+
+// Reply queues a message for a single client, e.g. a subscription ack
+func (h *Hub) Reply(client *Client, msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if !client.deliver(payload) {
+		h.drop(client, ErrSlowConsumer)
+	}
+	return nil
+}
+
+// HandleEvent is the events.Bus handler feeding the hub
+func (h *Hub) HandleEvent(ctx context.Context, event domain.Event) error {
+	if h.notifier == nil {
+		h.Broadcast(event)
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if len(payload) > pgnotify.MaxPayload {
+		// Clients refetch the content, what they need is to know it changed
+		event.Body = ""
+		if payload, err = json.Marshal(event); err != nil {
+			return err
+		}
+	}
+	if err := h.notifier.Notify(ctx, NotifyChannel, string(payload)); err != nil {
+		// At least the clients of this replica hear about it
+		h.Broadcast(event)
+		return fmt.Errorf("notify replicas: %w", err)
+	}
+	return nil
+}
+
+// Receive is the pgnotify handler for events sent by WithNotifier
+func (h *Hub) Receive(_ context.Context, payload string) {
+	var event domain.Event
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return
+	}
+	h.Broadcast(event)
+}
+
+// Broadcast delivers event to the clients subscribed to its channels on
+// this replica only
+func (h *Hub) Broadcast(event domain.Event) {
+	var slow []*Client
+
+	for _, channel := range channelsFor(event) {
+		payload, err := json.Marshal(Message{Type: MessageEvent, Channel: channel, Event: &event})
+		if err != nil {
+			continue
+		}
+
+		h.mu.RLock()
+		for client := range h.channels[channel] {
+			if !client.deliver(payload) {
+				slow = append(slow, client)
+			}
+		}
+		h.mu.RUnlock()
+	}
+
+	for _, client := range slow {
+		h.drop(client, ErrSlowConsumer)
+	}
+}
+
+// Close disconnects every client, e.g. on shutdown
+func (h *Hub) Close() {
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		h.Unregister(client)
+	}
+}
@@ -0,0 +1,150 @@
+package realtime_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/realtime"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingNotifier struct{}
+
+func (failingNotifier) Notify(context.Context, string, string) error {
+	return errors.New("connection refused")
+}
+
+type recordingNotifier struct {
+	payloads []string
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, _ string, payload string) error {
+	n.payloads = append(n.payloads, payload)
+	return nil
+}
+
+func receive(t *testing.T, client *realtime.Client) realtime.Message {
+	t.Helper()
+	select {
+	case payload := <-client.Send():
+		var msg realtime.Message
+		require.NoError(t, json.Unmarshal(payload, &msg))
+		return msg
+	default:
+		t.Fatal("expected a message")
+		return realtime.Message{}
+	}
+}
+
+func TestHub_Broadcast(t *testing.T) {
+	postID := uuid.New().String()
+	actorID := uuid.New().String()
+
+	hub := realtime.NewHub(4)
+	postClient := hub.Register()
+	userClient := hub.Register()
+	otherClient := hub.Register()
+	require.NoError(t, hub.Subscribe(postClient, realtime.PostChannel(postID)))
+	require.NoError(t, hub.Subscribe(userClient, realtime.UserChannel(actorID)))
+	require.NoError(t, hub.Subscribe(otherClient, realtime.PostChannel(uuid.New().String())))
+
+	hub.Broadcast(domain.Event{Type: domain.EventCommentCreated, ActorID: actorID, PostID: postID})
+
+	msg := receive(t, postClient)
+	assert.Equal(t, realtime.MessageEvent, msg.Type)
+	assert.Equal(t, realtime.PostChannel(postID), msg.Channel)
+	assert.Equal(t, domain.EventCommentCreated, msg.Event.Type)
+
+	msg = receive(t, userClient)
+	assert.Equal(t, realtime.UserChannel(actorID), msg.Channel)
+
+	assert.Empty(t, otherClient.Send())
+}
+
+func TestHub_DropsSlowConsumers(t *testing.T) {
+	postID := uuid.New().String()
+
+	hub := realtime.NewHub(2)
+	slow := hub.Register()
+	fast := hub.Register()
+	require.NoError(t, hub.Subscribe(slow, realtime.PostChannel(postID)))
+	require.NoError(t, hub.Subscribe(fast, realtime.PostChannel(postID)))
+
+	for i := 0; i < 3; i++ {
+		hub.Broadcast(domain.Event{Type: domain.EventPostUpdated, PostID: postID})
+		receive(t, fast)
+	}
+
+	// The two buffered messages are still drained before the channel closes
+	<-slow.Send()
+	<-slow.Send()
+	_, open := <-slow.Send()
+	assert.False(t, open)
+	assert.ErrorIs(t, slow.Err(), realtime.ErrSlowConsumer)
+	assert.NoError(t, fast.Err())
+}
+
+func TestHub_Subscribe(t *testing.T) {
+	hub := realtime.NewHub(1)
+	client := hub.Register()
+
+	assert.ErrorIs(t, hub.Subscribe(client, "post:not-a-uuid"), realtime.ErrInvalidChannel)
+	assert.ErrorIs(t, hub.Subscribe(client, "group:"+uuid.New().String()), realtime.ErrInvalidChannel)
+
+	for i := 0; i < realtime.MaxSubscriptions; i++ {
+		require.NoError(t, hub.Subscribe(client, realtime.PostChannel(uuid.New().String())))
+	}
+	assert.ErrorIs(t, hub.Subscribe(client, realtime.PostChannel(uuid.New().String())), realtime.ErrTooManySubscriptions)
+}
+
+func TestHub_HandleEvent(t *testing.T) {
+	postID := uuid.New().String()
+
+	t.Run("Fans out through the notifier and delivers what it receives", func(t *testing.T) {
+		notifier := &recordingNotifier{}
+		hub := realtime.NewHub(1).WithNotifier(notifier)
+		client := hub.Register()
+		require.NoError(t, hub.Subscribe(client, realtime.PostChannel(postID)))
+
+		err := hub.HandleEvent(context.Background(), domain.Event{Type: domain.EventPostCreated, PostID: postID, Body: "hello"})
+
+		require.NoError(t, err)
+		require.Len(t, notifier.payloads, 1)
+		assert.Empty(t, client.Send())
+
+		hub.Receive(context.Background(), notifier.payloads[0])
+		msg := receive(t, client)
+		assert.Equal(t, "hello", msg.Event.Body)
+	})
+
+	t.Run("Leaves out bodies too large for a notification", func(t *testing.T) {
+		notifier := &recordingNotifier{}
+		hub := realtime.NewHub(1).WithNotifier(notifier)
+
+		body := make([]byte, 10000)
+		for i := range body {
+			body[i] = 'a'
+		}
+		err := hub.HandleEvent(context.Background(), domain.Event{Type: domain.EventPostCreated, PostID: postID, Body: string(body)})
+
+		require.NoError(t, err)
+		require.Len(t, notifier.payloads, 1)
+		assert.NotContains(t, notifier.payloads[0], "aaaa")
+	})
+
+	t.Run("Still delivers locally when notifying fails", func(t *testing.T) {
+		hub := realtime.NewHub(1).WithNotifier(failingNotifier{})
+		client := hub.Register()
+		require.NoError(t, hub.Subscribe(client, realtime.PostChannel(postID)))
+
+		err := hub.HandleEvent(context.Background(), domain.Event{Type: domain.EventPostDeleted, PostID: postID})
+
+		assert.Error(t, err)
+		assert.Equal(t, domain.EventPostDeleted, receive(t, client).Event.Type)
+	})
+}
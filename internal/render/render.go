@@ -0,0 +1,161 @@
+// Package render turns user supplied post and comment bodies into HTML that
+// is safe to embed in a page.
+package render
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+// DefaultCacheSize is the number of rendered documents kept in memory
+const DefaultCacheSize = 1024
+
+// Renderer converts content to sanitized HTML and caches the output
+type Renderer struct {
+	markdown goldmark.Markdown
+	policy   *bluemonday.Policy
+
+	mu      sync.Mutex
+	size    int
+	entries map[[32]byte]*list.Element
+	order   *list.List
+}
+
+type cacheEntry struct {
+	key  [32]byte
+	html string
+}
+
+// NewRenderer creates a renderer keeping up to cacheSize rendered documents
+func NewRenderer(cacheSize int) *Renderer {
+	if cacheSize <= 0 {
+		cacheSize = DefaultCacheSize
+	}
+
+	return &Renderer{
+		markdown: goldmark.New(goldmark.WithExtensions(
+			extension.Table,
+			extension.Strikethrough,
+			extension.Linkify,
+			extension.TaskList,
+		)),
+		policy:  newPolicy(),
+		size:    cacheSize,
+		entries: make(map[[32]byte]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// newPolicy builds the allowlist applied to every rendered document. Raw HTML
+// written inside markdown is dropped by goldmark, the policy is the second line
+// of defence for anything the renderer itself emits.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements(
+		"p", "br", "hr", "blockquote", "pre", "code",
+		"strong", "em", "del", "ul", "ol", "li",
+		"h1", "h2", "h3", "h4", "h5", "h6",
+		"table", "thead", "tbody", "tr", "th", "td",
+	)
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowStandardURLs()
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	p.AllowAttrs("align").Matching(bluemonday.Direction).OnElements("th", "td")
+	p.AllowAttrs("class").Matching(bluemonday.SpaceSeparatedTokens).OnElements("code")
+	p.AllowAttrs("type").Matching(bluemonday.SpaceSeparatedTokens).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// Render returns sanitized HTML for raw written in the given format
+func (r *Renderer) Render(format domain.ContentFormat, raw string) (string, error) {
+	format = format.OrDefault()
+	if !format.IsValid() {
+		return "", fmt.Errorf("%w: unsupported content format %q", domain.ErrBadParamInput, format)
+	}
+
+	key := sha256.Sum256([]byte(string(format) + "\x00" + raw))
+	if cached, ok := r.lookup(key); ok {
+		return cached, nil
+	}
+
+	var out string
+	switch format {
+	case domain.ContentFormatMarkdown:
+		var buf bytes.Buffer
+		if err := r.markdown.Convert([]byte(raw), &buf); err != nil {
+			return "", fmt.Errorf("render markdown: %w", err)
+		}
+		out = r.policy.Sanitize(buf.String())
+	default:
+		out = renderPlain(raw)
+	}
+
+	r.store(key, out)
+	return out, nil
+}
+
+// renderPlain escapes text and keeps the author's paragraphs and line breaks
+func renderPlain(raw string) string {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	var b strings.Builder
+	for _, para := range strings.Split(raw, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(para), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+func (r *Renderer) lookup(key [32]byte) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	el, ok := r.entries[key]
+	if !ok {
+		return "", false
+	}
+	r.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).html, true
+}
+
+func (r *Renderer) store(key [32]byte, out string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if el, ok := r.entries[key]; ok {
+		r.order.MoveToFront(el)
+		return
+	}
+
+	r.entries[key] = r.order.PushFront(&cacheEntry{key: key, html: out})
+	for r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+var defaultRenderer = NewRenderer(DefaultCacheSize)
+
+// Content renders raw with the shared process wide renderer
+func Content(format domain.ContentFormat, raw string) (string, error) {
+	return defaultRenderer.Render(format, raw)
+}
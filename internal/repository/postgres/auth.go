@@ -8,6 +8,7 @@ import (
 	"github.com/edwinjordan/MajooTest-Golang/utils"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -26,26 +27,31 @@ func (a *AuthRepository) AuthenticateUser(ctx context.Context, email, password s
 	var (
 		id             uuid.UUID
 		name, emailDB  string
+		role           domain.Role
 		hashedPassword string
 	)
 
 	query := `
-		SELECT id, name, email, password
+		SELECT id, name, email, role, password
 		FROM users
 		WHERE email = $1 AND deleted_at IS NULL`
 
-	err := a.Conn.QueryRow(ctx, query, email).Scan(&id, &name, &emailDB, &hashedPassword)
+	err := a.Conn.QueryRow(ctx, query, email).Scan(&id, &name, &emailDB, &role, &hashedPassword)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrInvalidCredentials
+	}
 	if err != nil {
-		return nil, errors.New("invalid email or password")
+		return nil, err
 	}
 
 	if !utils.ComparePassword(password, hashedPassword) {
-		return nil, errors.New("invalid email or password")
+		return nil, domain.ErrInvalidCredentials
 	}
 
 	return &domain.User{
 		ID:    id.String(),
 		Name:  name,
 		Email: emailDB,
+		Role:  role,
 	}, nil
 }
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// foreignKeyViolation is the SQLSTATE Postgres reports for a reference to a
+// missing row
+const foreignKeyViolation = "23503"
+
+// applyBatch runs the queued statements in a transaction and lets read fill
+// in results[i] from the result of the i-th statement. A statement matching
+// no row is a failure of its item only. Every item runs under a savepoint,
+// an item breaking a constraint is rolled back to it and fails alone. Best
+// effort batches commit what succeeded, atomic batches are rolled back when
+// any item failed.
+//
+// An error returned here means nothing was written.
+func applyBatch[Data any](
+	ctx context.Context,
+	conn *pgxpool.Pool,
+	batch *pgx.Batch,
+	atomic bool,
+	results []domain.BatchItemResult[Data],
+	read func(row pgx.Row, result *domain.BatchItemResult[Data]) error,
+) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rolling back is a no-op once the transaction is committed
+	defer tx.Rollback(ctx)
+
+	failed := false
+	for i, query := range batch.QueuedQueries {
+		if err := applyBatchItem(ctx, tx, query, &results[i], read); err != nil {
+			return err
+		}
+		failed = failed || !results[i].OK
+	}
+
+	if atomic && failed {
+		for i := range results {
+			if results[i].OK {
+				results[i].Fail(domain.BatchRolledBackReason)
+			}
+		}
+		return nil
+	}
+	return tx.Commit(ctx)
+}
+
+// applyBatchItem runs the statement of one item under a savepoint, together
+// with it in one round trip
+func applyBatchItem[Data any](
+	ctx context.Context,
+	tx pgx.Tx,
+	query *pgx.QueuedQuery,
+	result *domain.BatchItemResult[Data],
+	read func(row pgx.Row, result *domain.BatchItemResult[Data]) error,
+) error {
+	item := &pgx.Batch{}
+	item.Queue(`SAVEPOINT batch_item`)
+	item.Queue(query.SQL, query.Arguments...)
+	item.Queue(`RELEASE SAVEPOINT batch_item`)
+
+	br := tx.SendBatch(ctx, item)
+	_, err := br.Exec()
+	if err == nil {
+		err = read(br.QueryRow(), result)
+	}
+	if closeErr := br.Close(); err == nil {
+		err = closeErr
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || !isItemError(pgErr) {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `ROLLBACK TO SAVEPOINT batch_item`); err != nil {
+		return err
+	}
+	result.Fail(batchConstraintReason(pgErr))
+	return nil
+}
+
+// isItemError reports whether pgErr is caused by the values of an item,
+// data exceptions and constraint violations, rather than by the database
+func isItemError(pgErr *pgconn.PgError) bool {
+	return strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23")
+}
+
+// batchConstraintReason explains why Postgres refused the values of an item
+func batchConstraintReason(pgErr *pgconn.PgError) string {
+	switch pgErr.Code {
+	case uniqueViolation:
+		return "conflicts with an existing item"
+	case foreignKeyViolation:
+		return "refers to an item that does not exist"
+	}
+	if strings.HasPrefix(pgErr.Code, "22") {
+		return "contains an invalid value"
+	}
+	return "breaks a constraint"
+}
+
+// batchMissReason explains why an update or delete matched no row
+func batchMissReason(entity string, version int) string {
+	if version > 0 {
+		return entity + " not found or modified since version " + strconv.Itoa(version)
+	}
+	return entity + " not found"
+}
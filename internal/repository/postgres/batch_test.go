@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edwinjordan/MajooTest-Golang/database"
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+// testPool connects to the Postgres of .env, the test is skipped without one
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	if err := godotenv.Load("../../../.env"); err != nil {
+		t.Skip("needs Postgres, .env with DATABASE_URL not found: ", err)
+	}
+	conn, err := database.SetupPgxPool()
+	require.NoError(t, err, "failed to connect to Postgres via DATABASE_URL")
+	t.Cleanup(conn.Close)
+	return conn
+}
+
+func TestApplyBatch_AtomicDuplicateKey(t *testing.T) {
+	conn := testPool(t)
+	ctx := context.Background()
+	email := "batch-" + t.Name() + "@example.com"
+	insert := `INSERT INTO users (name, email, password) VALUES ($1, $2, 'x') RETURNING id`
+
+	batch := &pgx.Batch{}
+	batch.Queue(insert, "First", email)
+	batch.Queue(insert, "Second", email)
+	batch.Queue(insert, "Third", "other-"+email)
+	results := make([]domain.BatchItemResult[string], batch.Len())
+	for i := range results {
+		results[i] = domain.BatchItemResult[string]{Index: i, Op: domain.BatchOpCreate}
+	}
+
+	err := applyBatch(ctx, conn, batch, true, results, func(row pgx.Row, result *domain.BatchItemResult[string]) error {
+		var id string
+		if err := row.Scan(&id); err != nil {
+			return err
+		}
+		result.OK, result.ID, result.Data = true, id, &id
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.False(t, results[0].OK)
+	assert.Equal(t, domain.BatchRolledBackReason, results[0].Error)
+	assert.False(t, results[1].OK)
+	assert.Equal(t, "conflicts with an existing item", results[1].Error)
+	assert.False(t, results[2].OK)
+	assert.Equal(t, domain.BatchRolledBackReason, results[2].Error)
+
+	var count int
+	require.NoError(t, conn.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE email IN ($1, $2)`, email, "other-"+email).Scan(&count))
+	assert.Equal(t, 0, count, "the atomic batch is rolled back")
+}
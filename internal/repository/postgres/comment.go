@@ -2,13 +2,14 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/edwinjordan/MajooTest-Golang/domain"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -25,23 +26,49 @@ func NewCommentRepository(conn *pgxpool.Pool) *CommentRepository {
 
 func (r *CommentRepository) CreateComment(ctx context.Context, comment *domain.CreateCommentRequest) (*domain.Comment, error) {
 	query := `
-		INSERT INTO comments (post_id, user_id, body, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
+		INSERT INTO comments (post_id, user_id, parent_id, depth, status, moderation_reason, body, content_format, content_html, import_job_id, import_row, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+		ON CONFLICT (import_job_id, import_row) WHERE import_job_id IS NOT NULL DO NOTHING
 		RETURNING id`
 
+	var parentID *string
+	if comment.ParentID != "" {
+		parentID = &comment.ParentID
+	}
+
+	status := comment.Status
+	if status == "" {
+		status = domain.ModerationStatusApproved
+	}
+
+	var importJob *string
+	var importRow *int
+	if key := domain.CSVRowFromContext(ctx); key != nil {
+		importJob, importRow = &key.JobID, &key.Row
+	}
+
+	format := comment.ContentFormat.OrDefault()
 	var id uuid.UUID
-	err := r.Conn.QueryRow(ctx, query, comment.PostID, comment.UserID, comment.Body).Scan(&id)
+	err := r.Conn.QueryRow(ctx, query, comment.PostID, comment.UserID, parentID, comment.Depth, status, comment.ModerationReason, comment.Body, format, comment.ContentHTML, importJob, importRow).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCSVRowImported
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	return &domain.Comment{
-		ID:        id.String(),
-		PostID:    comment.PostID,
-		UserID:    comment.UserID,
-		Body:      comment.Body,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:            id.String(),
+		PostID:        comment.PostID,
+		UserID:        comment.UserID,
+		ParentID:      parentID,
+		Depth:         comment.Depth,
+		Status:        status,
+		Body:          comment.Body,
+		ContentFormat: format,
+		ContentHTML:   comment.ContentHTML,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
 	}, nil
 }
 
@@ -51,11 +78,17 @@ func (u *CommentRepository) GetCommentList(ctx context.Context, filter *domain.C
 			u.id,
 			u.post_id,
 			u.user_id,
+			u.parent_id,
+			u.depth,
+			u.status,
 			u.body,
+			u.content_format,
+			u.content_html,
+			u.version,
 			u.created_at,
 			u.updated_at
 		FROM comments u
-        WHERE u.deleted_at is NULL`
+        WHERE u.deleted_at is NULL AND u.status = 'approved'`
 
 	var args []interface{}
 	var conditions []string
@@ -65,7 +98,7 @@ func (u *CommentRepository) GetCommentList(ctx context.Context, filter *domain.C
 	}
 
 	if len(conditions) > 0 {
-		query += strings.Join(conditions, " AND ")
+		query += " AND " + strings.Join(conditions, " AND ")
 	}
 	rows, err := u.Conn.Query(ctx, query, args...)
 	if err != nil {
@@ -81,7 +114,13 @@ func (u *CommentRepository) GetCommentList(ctx context.Context, filter *domain.C
 			&comment.ID,
 			&comment.PostID,
 			&comment.UserID,
+			&comment.ParentID,
+			&comment.Depth,
+			&comment.Status,
 			&comment.Body,
+			&comment.ContentFormat,
+			&comment.ContentHTML,
+			&comment.Version,
 			&comment.CreatedAt,
 			&comment.UpdatedAt,
 		)
@@ -94,6 +133,8 @@ func (u *CommentRepository) GetCommentList(ctx context.Context, filter *domain.C
 	return comments, nil
 }
 
+// GetComment returns a live approved comment, comments held back by
+// moderation are only seen through ModerationRepository.GetComment
 func (u *CommentRepository) GetComment(ctx context.Context, id uuid.UUID) (*domain.Comment, error) {
 	tracer := otel.Tracer("repo.comments")
 	ctx, span := tracer.Start(ctx, "CommentRepository.GetComment")
@@ -104,11 +145,17 @@ func (u *CommentRepository) GetComment(ctx context.Context, id uuid.UUID) (*doma
 			id,
 			post_id,
 			user_id,
+			parent_id,
+			depth,
+			status,
 			body,
+			content_format,
+			content_html,
+			version,
 			created_at,
 			updated_at
 		FROM comments
-		WHERE id = $1 AND deleted_at IS NULL`
+		WHERE id = $1 AND status = 'approved' AND deleted_at IS NULL`
 
 	span.SetAttributes(attribute.String("query.statement", query))
 	span.SetAttributes(attribute.String("query.parameter", id.String()))
@@ -119,10 +166,19 @@ func (u *CommentRepository) GetComment(ctx context.Context, id uuid.UUID) (*doma
 		&comment.ID,
 		&comment.PostID,
 		&comment.UserID,
+		&comment.ParentID,
+		&comment.Depth,
+		&comment.Status,
 		&comment.Body,
+		&comment.ContentFormat,
+		&comment.ContentHTML,
+		&comment.Version,
 		&comment.CreatedAt,
 		&comment.UpdatedAt,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
 	if err != nil {
 		span.RecordError(err)
 		//	u.Metrics.UserRepoCalls.WithLabelValues("GetUser", "error").Inc()
@@ -133,26 +189,45 @@ func (u *CommentRepository) GetComment(ctx context.Context, id uuid.UUID) (*doma
 	return &comment, nil
 }
 
-func (u *CommentRepository) UpdateComment(ctx context.Context, id uuid.UUID, comment *domain.Comment) (*domain.Comment, error) {
-	query := `
+// updateCommentQuery takes the status the new body was screened into, empty
+// to keep it, and only reaches approved comments so an edit cannot release
+// a held one. The reason is written whenever the status changes.
+const updateCommentQuery = `
 		UPDATE comments
 		SET body = $1,
+			content_format = $2,
+			content_html = $3,
+			status = COALESCE(NULLIF($6, ''), status),
+			moderation_reason = CASE WHEN COALESCE(NULLIF($6, ''), status) = status THEN moderation_reason ELSE $7 END,
+			version = version + 1,
 			updated_at = NOW()
-		WHERE id = $2 AND deleted_at IS NULL
-		RETURNING id, post_id, user_id, body, created_at, updated_at`
+		WHERE id = $4 AND status = 'approved' AND deleted_at IS NULL AND ($5 = 0 OR version = $5)
+		RETURNING id, post_id, user_id, parent_id, depth, status, body, content_format, content_html, version, created_at, updated_at`
+
+func (u *CommentRepository) UpdateComment(ctx context.Context, id uuid.UUID, comment *domain.Comment) (*domain.Comment, error) {
+	query := updateCommentQuery
 
 	var updatedComment domain.Comment
-	err := u.Conn.QueryRow(ctx, query, comment.Body, id).Scan(
+	err := u.Conn.QueryRow(ctx, query, comment.Body, comment.ContentFormat.OrDefault(), comment.ContentHTML, id, comment.Version, comment.Status, comment.ModerationReason).Scan(
 		&updatedComment.ID,
 		&updatedComment.PostID,
 		&updatedComment.UserID,
+		&updatedComment.ParentID,
+		&updatedComment.Depth,
+		&updatedComment.Status,
 		&updatedComment.Body,
+		&updatedComment.ContentFormat,
+		&updatedComment.ContentHTML,
+		&updatedComment.Version,
 		&updatedComment.CreatedAt,
 		&updatedComment.UpdatedAt,
 	)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, domain.ErrUserNotFound
+		if errors.Is(err, pgx.ErrNoRows) && comment.Version > 0 {
+			return nil, domain.ErrPreconditionFailed
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
 		}
 		return nil, err
 	}
@@ -160,20 +235,484 @@ func (u *CommentRepository) UpdateComment(ctx context.Context, id uuid.UUID, com
 	return &updatedComment, nil
 }
 
-func (u *CommentRepository) DeleteComment(ctx context.Context, id uuid.UUID) error {
+// PatchComment updates only the columns supplied in patch. A non-zero
+// version must match the stored one, otherwise domain.ErrPreconditionFailed
+// is returned.
+func (u *CommentRepository) PatchComment(ctx context.Context, id uuid.UUID, version int, patch *domain.CommentPatch) (*domain.Comment, error) {
+	tracer := otel.Tracer("repo.comments")
+	ctx, span := tracer.Start(ctx, "CommentRepository.PatchComment")
+	defer span.End()
+
+	var set assignments
+	if patch.Body != nil {
+		set.set("body", *patch.Body)
+	}
+	if patch.ContentFormat != nil {
+		set.set("content_format", *patch.ContentFormat)
+	}
+	if patch.ContentHTML != nil {
+		set.set("content_html", *patch.ContentHTML)
+	}
+	if patch.Status != nil {
+		set.set("status", *patch.Status)
+		set.set("moderation_reason", patch.ModerationReason)
+	}
+
+	idArg, versionArg := set.arg(id), set.arg(version)
 	query := `
 		UPDATE comments
-		SET deleted_at = NOW()
-		WHERE id = $1 AND deleted_at IS NULL`
+		SET ` + set.String() + `,
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = ` + idArg + ` AND status = 'approved' AND deleted_at IS NULL AND (` + versionArg + ` = 0 OR version = ` + versionArg + `)
+		RETURNING id, post_id, user_id, parent_id, depth, status, body, content_format, content_html, version, created_at, updated_at`
 
-	result, err := u.Conn.Exec(ctx, query, id)
+	span.SetAttributes(attribute.String("query.statement", query))
+	var comment domain.Comment
+	err := u.Conn.QueryRow(ctx, query, set.args...).Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.UserID,
+		&comment.ParentID,
+		&comment.Depth,
+		&comment.Status,
+		&comment.Body,
+		&comment.ContentFormat,
+		&comment.ContentHTML,
+		&comment.Version,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+	)
 	if err != nil {
-		return err
+		span.RecordError(err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			if version > 0 {
+				return nil, domain.ErrPreconditionFailed
+			}
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
 	}
 
-	if result.RowsAffected() == 0 {
-		return domain.ErrUserNotFound
+	return &comment, nil
+}
+
+// DeleteComment soft-deletes a comment and its live replies, stamping them
+// with the comment's deleted_at so RestoreComment can bring them back. A
+// non-zero version must match the stored one, otherwise
+// domain.ErrPreconditionFailed is returned.
+func (u *CommentRepository) DeleteComment(ctx context.Context, id uuid.UUID, version int) error {
+	var postID string
+	if err := u.Conn.QueryRow(ctx, deleteCommentQuery, id, version).Scan(&postID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			if version > 0 {
+				return domain.ErrPreconditionFailed
+			}
+			return domain.ErrUserNotFound
+		}
+		return err
 	}
 
 	return nil
 }
+
+// deleteCommentQuery takes the comment id and the version it must be at,
+// zero for any, and returns the post of the deleted comment
+const deleteCommentQuery = `
+		WITH RECURSIVE target AS (
+			UPDATE comments
+			SET deleted_at = NOW()
+			WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)
+			RETURNING id, post_id, deleted_at
+		), replies AS (
+			SELECT c.id, t.deleted_at
+			FROM comments c
+			JOIN target t ON c.parent_id = t.id
+			WHERE c.deleted_at IS NULL
+			UNION ALL
+			SELECT c.id, r.deleted_at
+			FROM comments c
+			JOIN replies r ON c.parent_id = r.id
+			WHERE c.deleted_at IS NULL
+		), replies_deleted AS (
+			UPDATE comments c
+			SET deleted_at = r.deleted_at
+			FROM replies r
+			WHERE c.id = r.id
+		)
+		SELECT post_id FROM target`
+
+// RestoreComment undeletes a comment and the replies deleted along with it,
+// returning the comment and how many replies came back. It returns
+// domain.ErrNotFound unless the comment exists and is deleted, and
+// domain.ErrConflict while its post or parent comment is still deleted.
+func (u *CommentRepository) RestoreComment(ctx context.Context, id uuid.UUID) (*domain.Comment, int, error) {
+	tracer := otel.Tracer("repo.comments")
+	ctx, span := tracer.Start(ctx, "CommentRepository.RestoreComment")
+	defer span.End()
+
+	checkQuery := `
+		SELECT
+			EXISTS (SELECT 1 FROM posts p WHERE p.id = c.post_id AND p.deleted_at IS NULL),
+			c.parent_id IS NULL OR EXISTS (SELECT 1 FROM comments pc WHERE pc.id = c.parent_id AND pc.deleted_at IS NULL)
+		FROM comments c
+		WHERE c.id = $1 AND c.deleted_at IS NOT NULL`
+
+	var postLive, parentLive bool
+	if err := u.Conn.QueryRow(ctx, checkQuery, id).Scan(&postLive, &parentLive); err != nil {
+		span.RecordError(err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, 0, domain.ErrNotFound
+		}
+		return nil, 0, err
+	}
+	if !postLive {
+		return nil, 0, fmt.Errorf("%w: restore the post of the comment first", domain.ErrConflict)
+	}
+	if !parentLive {
+		return nil, 0, fmt.Errorf("%w: restore the parent comment first", domain.ErrConflict)
+	}
+
+	query := `
+		WITH RECURSIVE tree AS (
+			SELECT id, deleted_at FROM comments WHERE id = $1 AND deleted_at IS NOT NULL
+			UNION ALL
+			SELECT c.id, c.deleted_at
+			FROM comments c
+			JOIN tree t ON c.parent_id = t.id
+			WHERE c.deleted_at = t.deleted_at
+		), restored AS (
+			UPDATE comments c
+			SET deleted_at = NULL
+			FROM tree
+			WHERE c.id = tree.id
+			RETURNING c.id, c.post_id, c.user_id, c.parent_id, c.depth, c.status, c.body, c.content_format, c.content_html, c.version, c.created_at, c.updated_at
+		)
+		SELECT restored.*, (SELECT COUNT(*) - 1 FROM restored)
+		FROM restored
+		WHERE id = $1`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	var comment domain.Comment
+	var replies int
+	err := u.Conn.QueryRow(ctx, query, id).Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.UserID,
+		&comment.ParentID,
+		&comment.Depth,
+		&comment.Status,
+		&comment.Body,
+		&comment.ContentFormat,
+		&comment.ContentHTML,
+		&comment.Version,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+		&replies,
+	)
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, 0, domain.ErrNotFound
+		}
+		return nil, 0, err
+	}
+
+	return &comment, replies, nil
+}
+
+// GetCommentThreads returns a page of top level comments of a post together
+// with their replies, walking the reply tree with a recursive CTE. Every
+// comment carries its direct reply count; at most filter.RepliesLimit replies
+// are returned per comment. Rows are ordered parents first. The total number
+// of top level comments is returned for pagination.
+func (u *CommentRepository) GetCommentThreads(ctx context.Context, postID uuid.UUID, filter *domain.CommentThreadFilter) ([]domain.Comment, int, error) {
+	tracer := otel.Tracer("repo.comments")
+	ctx, span := tracer.Start(ctx, "CommentRepository.GetCommentThreads")
+	defer span.End()
+
+	countQuery := `
+		SELECT COUNT(*)
+		FROM comments
+		WHERE post_id = $1 AND parent_id IS NULL AND status = 'approved' AND deleted_at IS NULL`
+
+	var total int
+	if err := u.Conn.QueryRow(ctx, countQuery, postID).Scan(&total); err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+
+	query := `
+		WITH RECURSIVE thread AS (
+			SELECT root.*
+			FROM (
+				SELECT c.id, c.post_id, c.user_id, c.parent_id, c.depth, c.status, c.body,
+					c.content_format, c.content_html, c.version, c.created_at, c.updated_at
+				FROM comments c
+				WHERE c.post_id = $1 AND c.parent_id IS NULL AND c.status = 'approved' AND c.deleted_at IS NULL
+				ORDER BY c.created_at, c.id
+				LIMIT $2 OFFSET $3
+			) root
+			UNION ALL
+			SELECT reply.*
+			FROM thread t
+			CROSS JOIN LATERAL (
+				SELECT c.id, c.post_id, c.user_id, c.parent_id, c.depth, c.status, c.body,
+					c.content_format, c.content_html, c.version, c.created_at, c.updated_at
+				FROM comments c
+				WHERE c.parent_id = t.id AND c.status = 'approved' AND c.deleted_at IS NULL
+				ORDER BY c.created_at, c.id
+				LIMIT $4
+			) reply
+			WHERE t.depth < $5
+		)
+		SELECT
+			t.id,
+			t.post_id,
+			t.user_id,
+			t.parent_id,
+			t.depth,
+			t.status,
+			t.body,
+			t.content_format,
+			t.content_html,
+			t.version,
+			t.created_at,
+			t.updated_at,
+			(SELECT COUNT(*) FROM comments r WHERE r.parent_id = t.id AND r.status = 'approved' AND r.deleted_at IS NULL) AS reply_count
+		FROM thread t
+		ORDER BY t.depth, t.created_at, t.id`
+
+	offset := (filter.Page - 1) * filter.Limit
+	span.SetAttributes(attribute.String("query.statement", query))
+	span.SetAttributes(attribute.String("query.parameter", postID.String()))
+	rows, err := u.Conn.Query(ctx, query, postID, filter.Limit, offset, filter.RepliesLimit, domain.MaxCommentDepth)
+	if err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var comments []domain.Comment
+	for rows.Next() {
+		var comment domain.Comment
+		err := rows.Scan(
+			&comment.ID,
+			&comment.PostID,
+			&comment.UserID,
+			&comment.ParentID,
+			&comment.Depth,
+			&comment.Status,
+			&comment.Body,
+			&comment.ContentFormat,
+			&comment.ContentHTML,
+			&comment.Version,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+			&comment.ReplyCount,
+		)
+		if err != nil {
+			span.RecordError(err)
+			return nil, 0, err
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+
+	return comments, total, nil
+}
+
+// GetPostComments returns a page of every comment of a post, replies
+// included, oldest first. The total number of comments is returned for
+// pagination.
+func (u *CommentRepository) GetPostComments(ctx context.Context, postID uuid.UUID, filter *domain.CommentThreadFilter) ([]domain.Comment, int, error) {
+	tracer := otel.Tracer("repo.comments")
+	ctx, span := tracer.Start(ctx, "CommentRepository.GetPostComments")
+	defer span.End()
+
+	countQuery := `
+		SELECT COUNT(*)
+		FROM comments
+		WHERE post_id = $1 AND status = 'approved' AND deleted_at IS NULL`
+
+	var total int
+	if err := u.Conn.QueryRow(ctx, countQuery, postID).Scan(&total); err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT
+			c.id,
+			c.post_id,
+			c.user_id,
+			c.parent_id,
+			c.depth,
+			c.status,
+			c.body,
+			c.content_format,
+			c.content_html,
+			c.version,
+			c.created_at,
+			c.updated_at,
+			(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id AND r.status = 'approved' AND r.deleted_at IS NULL) AS reply_count
+		FROM comments c
+		WHERE c.post_id = $1 AND c.status = 'approved' AND c.deleted_at IS NULL
+		ORDER BY c.created_at, c.id
+		LIMIT $2 OFFSET $3`
+
+	offset := (filter.Page - 1) * filter.Limit
+	span.SetAttributes(attribute.String("query.statement", query))
+	span.SetAttributes(attribute.String("query.parameter", postID.String()))
+	rows, err := u.Conn.Query(ctx, query, postID, filter.Limit, offset)
+	if err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var comments []domain.Comment
+	for rows.Next() {
+		var comment domain.Comment
+		err := rows.Scan(
+			&comment.ID,
+			&comment.PostID,
+			&comment.UserID,
+			&comment.ParentID,
+			&comment.Depth,
+			&comment.Status,
+			&comment.Body,
+			&comment.ContentFormat,
+			&comment.ContentHTML,
+			&comment.Version,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+			&comment.ReplyCount,
+		)
+		if err != nil {
+			span.RecordError(err)
+			return nil, 0, err
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+
+	return comments, total, nil
+}
+
+// ApplyCommentBatch creates, updates and deletes comments in one transaction,
+// results[i] reports on items[i]. Items are expected to be validated and
+// screened, see applyBatch for how failures are handled. A comment whose
+// post or parent is gone by the time it is inserted fails on its own.
+func (u *CommentRepository) ApplyCommentBatch(ctx context.Context, items []domain.CommentBatchItem, atomic bool) ([]domain.BatchItemResult[domain.Comment], error) {
+	tracer := otel.Tracer("repo.comments")
+	ctx, span := tracer.Start(ctx, "CommentRepository.ApplyCommentBatch")
+	defer span.End()
+	span.SetAttributes(attribute.Int("batch.size", len(items)), attribute.Bool("batch.atomic", atomic))
+
+	// Replies may leave out the post, it is taken from the parent
+	createQuery := `
+		INSERT INTO comments (post_id, user_id, parent_id, depth, status, moderation_reason, body, content_format, content_html, created_at, updated_at)
+		SELECT p.id, $2, parent.id, COALESCE(parent.depth + 1, 0), $4, $5, $6, $7, $8, NOW(), NOW()
+		FROM posts p
+		LEFT JOIN comments parent ON parent.id = $3 AND parent.post_id = p.id AND parent.status = 'approved' AND parent.deleted_at IS NULL
+		WHERE p.id = COALESCE($1, (SELECT post_id FROM comments WHERE id = $3)) AND p.deleted_at IS NULL
+			AND ($3::uuid IS NULL OR (parent.id IS NOT NULL AND parent.depth < $9))
+		RETURNING id, post_id, depth, version, created_at, updated_at`
+
+	batch := &pgx.Batch{}
+	results := make([]domain.BatchItemResult[domain.Comment], len(items))
+	for i, item := range items {
+		results[i] = domain.BatchItemResult[domain.Comment]{Index: i, Op: item.Op, ID: item.ID}
+		format := item.ContentFormat.OrDefault()
+		switch item.Op {
+		case domain.BatchOpCreate:
+			var postID, parentID *string
+			if item.PostID != "" {
+				postID = &item.PostID
+			}
+			if item.ParentID != "" {
+				parentID = &item.ParentID
+			}
+			status := item.Status
+			if status == "" {
+				status = domain.ModerationStatusApproved
+			}
+			batch.Queue(createQuery, postID, item.UserID, parentID, status, item.ModerationReason, item.Body, format, item.ContentHTML, domain.MaxCommentDepth)
+		case domain.BatchOpUpdate:
+			batch.Queue(updateCommentQuery, item.Body, format, item.ContentHTML, item.ID, item.Version, item.Status, item.ModerationReason)
+		case domain.BatchOpDelete:
+			batch.Queue(deleteCommentQuery, item.ID, item.Version)
+		}
+	}
+
+	err := applyBatch(ctx, u.Conn, batch, atomic, results, func(row pgx.Row, result *domain.BatchItemResult[domain.Comment]) error {
+		item := items[result.Index]
+		var err error
+		switch item.Op {
+		case domain.BatchOpCreate:
+			comment := domain.Comment{
+				UserID:        item.UserID,
+				Status:        item.Status,
+				Body:          item.Body,
+				ContentFormat: item.ContentFormat.OrDefault(),
+				ContentHTML:   item.ContentHTML,
+			}
+			if item.ParentID != "" {
+				comment.ParentID = &item.ParentID
+			}
+			if comment.Status == "" {
+				comment.Status = domain.ModerationStatusApproved
+			}
+			err = row.Scan(&comment.ID, &comment.PostID, &comment.Depth, &comment.Version, &comment.CreatedAt, &comment.UpdatedAt)
+			if errors.Is(err, pgx.ErrNoRows) {
+				result.Fail("post or parent comment not found, or the thread is nested too deeply")
+				return nil
+			}
+			result.ID, result.Data = comment.ID, &comment
+		case domain.BatchOpUpdate:
+			var comment domain.Comment
+			err = row.Scan(
+				&comment.ID,
+				&comment.PostID,
+				&comment.UserID,
+				&comment.ParentID,
+				&comment.Depth,
+				&comment.Status,
+				&comment.Body,
+				&comment.ContentFormat,
+				&comment.ContentHTML,
+				&comment.Version,
+				&comment.CreatedAt,
+				&comment.UpdatedAt,
+			)
+			if errors.Is(err, pgx.ErrNoRows) {
+				result.Fail(batchMissReason("comment", item.Version))
+				return nil
+			}
+			result.Data = &comment
+		case domain.BatchOpDelete:
+			var postID string
+			err = row.Scan(&postID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				result.Fail(batchMissReason("comment", item.Version))
+				return nil
+			}
+			// Only the post is known, it is all a deletion event needs
+			result.Data = &domain.Comment{ID: item.ID, PostID: postID}
+		}
+		result.OK = err == nil
+		return err
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return results, nil
+}
@@ -2,10 +2,13 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/edwinjordan/MajooTest-Golang/domain"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -31,11 +34,15 @@ func (r *csvRepository) CreateJob(ctx context.Context, job *domain.CSVJob) error
 	job.UpdatedAt = now
 
 	query := `
-		INSERT INTO csv_jobs ( filename, status, total_rows, processed_rows, failed_rows, error_message, started_at, completed_at, created_at, updated_at)
-		VALUES ( $1, $2, $3, $4, $5, $6, Now(), Now(), Now(), Now())
+		INSERT INTO csv_jobs ( filename, type, delimiter, encoding, lazy_quotes, has_bom, schema, dry_run, file_key, user_id, status, total_rows, processed_rows, failed_rows, error_message, started_at, completed_at, created_at, updated_at)
+		VALUES ( $1, $2, $3, $4, COALESCE($5, FALSE), $6, $7, $8, NULLIF($9, ''), NULLIF($10, '')::uuid, $11, $12, $13, $14, $15, Now(), Now(), Now(), Now())
 		RETURNING id`
+	schema, err := encodeSchema(job.Schema)
+	if err != nil {
+		return err
+	}
 	var id uuid.UUID
-	err := r.Conn.QueryRow(ctx, query, job.Filename, job.Status, job.TotalRows, job.ProcessedRows, job.FailedRows, job.ErrorMessage).Scan(&id)
+	err = r.Conn.QueryRow(ctx, query, job.Filename, job.Type, job.Dialect.Delimiter, job.Dialect.Encoding, job.Dialect.LazyQuotes, job.Dialect.HasBOM, schema, job.DryRun, job.FileKey, job.UserID, job.Status, job.TotalRows, job.ProcessedRows, job.FailedRows, job.ErrorMessage).Scan(&id)
 
 	if err != nil {
 		return err
@@ -49,72 +56,111 @@ func (r *csvRepository) CreateJob(ctx context.Context, job *domain.CSVJob) error
 	return nil
 }
 
-// GetJobByID retrieves a CSV job by ID
-func (r *csvRepository) GetJobByID(ctx context.Context, id uuid.UUID) (*domain.CSVJob, error) {
-	tracer := otel.Tracer("repo.csv")
-	ctx, span := tracer.Start(ctx, "CSVRepository.GetJobByID")
-	defer span.End()
+// csvJobColumns are the columns scanCSVJob reads, in order
+const csvJobColumns = `id, filename, type, delimiter, encoding, lazy_quotes, has_bom, schema, headers, dry_run, summary,
+		file_key, user_id, worker_id, attempts, checkpoint_row, checkpoint_offset, checkpoint_processed, checkpoint_failed, status, total_rows, processed_rows, failed_rows, error_message,
+		started_at, completed_at, heartbeat_at, created_at, updated_at`
 
-	query := `
-		SELECT
-			id,
-			filename, 
-			status, 
-			total_rows, 
-			processed_rows, 
-			failed_rows, 
-			error_message, 
-			started_at, 
-			completed_at, 
-			created_at, 
-			updated_at
-		FROM csv_jobs
-		WHERE id = $1`
-
-	span.SetAttributes(attribute.String("query.statement", query))
-	span.SetAttributes(attribute.String("query.parameter", id.String()))
-	row := r.Conn.QueryRow(ctx, query, id)
-
-	var job domain.CSVJob
+func scanCSVJob(row pgx.Row) (*domain.CSVJob, error) {
+	var (
+		job             domain.CSVJob
+		schema, summary []byte
+		fileKey         *string
+		userID          *uuid.UUID
+		workerID        *string
+		startedAt       *time.Time
+		completedAt     *time.Time
+	)
 	err := row.Scan(
 		&job.ID,
 		&job.Filename,
+		&job.Type,
+		&job.Dialect.Delimiter,
+		&job.Dialect.Encoding,
+		&job.Dialect.LazyQuotes,
+		&job.Dialect.HasBOM,
+		&schema,
+		&job.Headers,
+		&job.DryRun,
+		&summary,
+		&fileKey,
+		&userID,
+		&workerID,
+		&job.Attempts,
+		&job.Checkpoint.Row,
+		&job.Checkpoint.Offset,
+		&job.Checkpoint.Processed,
+		&job.Checkpoint.Failed,
 		&job.Status,
 		&job.TotalRows,
 		&job.ProcessedRows,
 		&job.FailedRows,
 		&job.ErrorMessage,
-		&job.StartedAt,
-		&job.CompletedAt,
+		&startedAt,
+		&completedAt,
+		&job.HeartbeatAt,
 		&job.CreatedAt,
 		&job.UpdatedAt,
 	)
 	if err != nil {
-		span.RecordError(err)
-		//	u.Metrics.UserRepoCalls.WithLabelValues("GetUser", "error").Inc()
 		return nil, err
 	}
-
-	//u.Metrics.UserRepoCalls.WithLabelValues("GetUser", "success").Inc()
+	if fileKey != nil {
+		job.FileKey = *fileKey
+	}
+	if userID != nil {
+		job.UserID = userID.String()
+	}
+	if workerID != nil {
+		job.WorkerID = *workerID
+	}
+	if startedAt != nil {
+		job.StartedAt = *startedAt
+	}
+	if completedAt != nil {
+		job.CompletedAt = *completedAt
+	}
+	if job.Schema, err = decodeSchema(schema); err != nil {
+		return nil, err
+	}
+	if job.Summary, err = decodeSummary(summary); err != nil {
+		return nil, err
+	}
 	return &job, nil
 }
 
-// GetJobsByUserID retrieves all CSV jobs for a user
-// GetJobsByUserID retrieves all CSV jobs for a user
-func (r *csvRepository) GetJobsByUserID(ctx context.Context) ([]*domain.CSVJob, error) {
+// GetJobByID retrieves a CSV job by ID
+func (r *csvRepository) GetJobByID(ctx context.Context, id uuid.UUID) (*domain.CSVJob, error) {
+	tracer := otel.Tracer("repo.csv")
+	ctx, span := tracer.Start(ctx, "CSVRepository.GetJobByID")
+	defer span.End()
+
+	query := `SELECT ` + csvJobColumns + ` FROM csv_jobs WHERE id = $1`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	span.SetAttributes(attribute.String("query.parameter", id.String()))
+	job, err := scanCSVJob(r.Conn.QueryRow(ctx, query, id))
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetJobsByUserID retrieves the CSV jobs a user uploaded, newest first. An
+// empty userID retrieves every job.
+func (r *csvRepository) GetJobsByUserID(ctx context.Context, userID string) ([]*domain.CSVJob, error) {
 	tracer := otel.Tracer("repo.csv")
 	ctx, span := tracer.Start(ctx, "CSVRepository.GetJobsByUserID")
 	defer span.End()
 
-	query := `
-		SELECT id,  filename, status, total_rows, processed_rows, failed_rows, error_message, started_at, completed_at, created_at, updated_at
-		FROM csv_jobs
-	
-		ORDER BY created_at DESC
-	`
+	query := `SELECT ` + csvJobColumns + ` FROM csv_jobs
+		WHERE $1 = '' OR user_id = NULLIF($1, '')::uuid
+		ORDER BY created_at DESC`
 
 	span.SetAttributes(attribute.String("query.statement", query))
-	rows, err := r.Conn.Query(ctx, query)
+	span.SetAttributes(attribute.String("query.parameter", userID))
+	rows, err := r.Conn.Query(ctx, query, userID)
 	if err != nil {
 		span.RecordError(err)
 		return nil, err
@@ -123,26 +169,12 @@ func (r *csvRepository) GetJobsByUserID(ctx context.Context) ([]*domain.CSVJob,
 
 	var jobs []*domain.CSVJob
 	for rows.Next() {
-		var job domain.CSVJob
-		err := rows.Scan(
-			&job.ID,
-			&job.Filename,
-			&job.Status,
-			&job.TotalRows,
-			&job.ProcessedRows,
-			&job.FailedRows,
-			&job.ErrorMessage,
-			&job.StartedAt,
-			&job.CompletedAt,
-			&job.CreatedAt,
-			&job.UpdatedAt,
-		)
+		job, err := scanCSVJob(rows)
 		if err != nil {
 			span.RecordError(err)
 			return nil, err
 		}
-
-		jobs = append(jobs, &job)
+		jobs = append(jobs, job)
 	}
 	if err := rows.Err(); err != nil {
 		span.RecordError(err)
@@ -151,20 +183,216 @@ func (r *csvRepository) GetJobsByUserID(ctx context.Context) ([]*domain.CSVJob,
 	return jobs, nil
 }
 
+// ClaimJob hands the oldest queued job to workerID and marks it processing.
+// Workers of every replica claim concurrently, SKIP LOCKED lets each take a
+// different job instead of waiting on the same one. domain.ErrNotFound means
+// the queue is empty.
+func (r *csvRepository) ClaimJob(ctx context.Context, workerID string) (*domain.CSVJob, error) {
+	tracer := otel.Tracer("repo.csv")
+	ctx, span := tracer.Start(ctx, "CSVRepository.ClaimJob")
+	defer span.End()
+
+	query := `
+		UPDATE csv_jobs
+		SET status = $2, worker_id = $1, heartbeat_at = NOW(), attempts = attempts + 1,
+			error_message = NULL, started_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM csv_jobs
+			WHERE status = $3 AND file_key IS NOT NULL
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + csvJobColumns
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	job, err := scanCSVJob(r.Conn.QueryRow(ctx, query, workerID, domain.CSVJobStatusProcessing, domain.CSVJobStatusPending))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return job, nil
+}
+
+// HeartbeatJob tells the queue workerID is still processing the job,
+// domain.ErrNotFound means the job was taken away from it
+func (r *csvRepository) HeartbeatJob(ctx context.Context, jobID, workerID string) error {
+	query := `
+		UPDATE csv_jobs
+		SET heartbeat_at = NOW()
+		WHERE id = $1 AND worker_id = $2 AND status = $3
+	`
+
+	tag, err := r.Conn.Exec(ctx, query, jobID, workerID, domain.CSVJobStatusProcessing)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// ReleaseJob puts a job workerID stopped on back in the queue without
+// counting the attempt
+func (r *csvRepository) ReleaseJob(ctx context.Context, jobID, workerID string) error {
+	query := `
+		UPDATE csv_jobs
+		SET status = $3, worker_id = NULL, attempts = GREATEST(attempts - 1, 0), updated_at = NOW()
+		WHERE id = $1 AND worker_id = $2 AND status = $4
+	`
+
+	_, err := r.Conn.Exec(ctx, query, jobID, workerID, domain.CSVJobStatusPending, domain.CSVJobStatusProcessing)
+	return err
+}
+
+// CancelJob stops a pending or processing job, its worker notices on the
+// next heartbeat. Other jobs give domain.ErrNotFound.
+func (r *csvRepository) CancelJob(ctx context.Context, id uuid.UUID) (*domain.CSVJob, error) {
+	tracer := otel.Tracer("repo.csv")
+	ctx, span := tracer.Start(ctx, "CSVRepository.CancelJob")
+	defer span.End()
+
+	query := `
+		UPDATE csv_jobs
+		SET status = $2, worker_id = NULL, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status IN ($3, $4)
+		RETURNING ` + csvJobColumns
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	job, err := scanCSVJob(r.Conn.QueryRow(ctx, query, id, domain.CSVJobStatusCancelled, domain.CSVJobStatusPending, domain.CSVJobStatusProcessing))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return job, nil
+}
+
+// RetryJob queues a failed or cancelled import whose file is kept from the
+// first row again, its errors and checkpoint are dropped. Other jobs give
+// domain.ErrNotFound.
+func (r *csvRepository) RetryJob(ctx context.Context, id uuid.UUID) (*domain.CSVJob, error) {
+	tracer := otel.Tracer("repo.csv")
+	ctx, span := tracer.Start(ctx, "CSVRepository.RetryJob")
+	defer span.End()
+
+	query := `
+		WITH job AS (
+			UPDATE csv_jobs
+			SET status = $2, total_rows = 0, processed_rows = 0, failed_rows = 0, error_message = NULL,
+				completed_at = NULL, worker_id = NULL, heartbeat_at = NULL, attempts = 0,
+				checkpoint_row = 0, checkpoint_offset = 0, checkpoint_processed = 0, checkpoint_failed = 0,
+				updated_at = NOW()
+			WHERE id = $1 AND status IN ($3, $4) AND file_key IS NOT NULL AND NOT dry_run
+			RETURNING *
+		), row_errors AS (
+			DELETE FROM csv_job_errors WHERE job_id IN (SELECT id FROM job)
+		), done_rows AS (
+			DELETE FROM csv_job_rows WHERE job_id IN (SELECT id FROM job)
+		)
+		SELECT ` + csvJobColumns + ` FROM job`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	job, err := scanCSVJob(r.Conn.QueryRow(ctx, query, id, domain.CSVJobStatusPending, domain.CSVJobStatusFailed, domain.CSVJobStatusCancelled))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return job, nil
+}
+
+// DeleteJob deletes a job that is not running with its errors and returns
+// it, so the caller can remove its file. Running jobs give domain.ErrNotFound.
+func (r *csvRepository) DeleteJob(ctx context.Context, id uuid.UUID) (*domain.CSVJob, error) {
+	tracer := otel.Tracer("repo.csv")
+	ctx, span := tracer.Start(ctx, "CSVRepository.DeleteJob")
+	defer span.End()
+
+	query := `DELETE FROM csv_jobs WHERE id = $1 AND status NOT IN ($2, $3) RETURNING ` + csvJobColumns
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	job, err := scanCSVJob(r.Conn.QueryRow(ctx, query, id, domain.CSVJobStatusPending, domain.CSVJobStatusProcessing))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return job, nil
+}
+
+// RequeueStaleJobs puts the jobs whose worker stopped heartbeating
+// before staleBefore back in the queue, or fails them once they were claimed
+// maxAttempts times. It returns how many jobs were requeued and failed.
+func (r *csvRepository) RequeueStaleJobs(ctx context.Context, staleBefore time.Time, maxAttempts int) (int, int, error) {
+	tracer := otel.Tracer("repo.csv")
+	ctx, span := tracer.Start(ctx, "CSVRepository.RequeueStaleJobs")
+	defer span.End()
+
+	query := `
+		UPDATE csv_jobs
+		SET status = CASE WHEN attempts >= $3 THEN $4 ELSE $5 END,
+			error_message = CASE WHEN attempts >= $3 THEN 'worker stopped responding too many times' END,
+			completed_at = CASE WHEN attempts >= $3 THEN NOW() END,
+			worker_id = NULL,
+			updated_at = NOW()
+		WHERE status = $1 AND file_key IS NOT NULL AND heartbeat_at < $2
+		RETURNING status`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	rows, err := r.Conn.Query(ctx, query, domain.CSVJobStatusProcessing, staleBefore, maxAttempts, domain.CSVJobStatusFailed, domain.CSVJobStatusPending)
+	if err != nil {
+		span.RecordError(err)
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	requeued, failed := 0, 0
+	for rows.Next() {
+		var status domain.CSVJobStatus
+		if err := rows.Scan(&status); err != nil {
+			span.RecordError(err)
+			return 0, 0, err
+		}
+		if status == domain.CSVJobStatusFailed {
+			failed++
+		} else {
+			requeued++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return 0, 0, err
+	}
+	return requeued, failed, nil
+}
+
 // UpdateJobProgress updates the progress of a CSV processing job
-func (r *csvRepository) UpdateJobProgress(ctx context.Context, jobID string, processedRows, failedRows int64) error {
+func (r *csvRepository) UpdateJobProgress(ctx context.Context, jobID, workerID string, processedRows, failedRows int64) error {
 	query := `
 		UPDATE csv_jobs
 		SET processed_rows = $2, failed_rows = $3, updated_at = $4
-		WHERE id = $1
+		WHERE id = $1 AND status <> $5 AND COALESCE(worker_id, '') = $6
 	`
 
-	_, err := r.Conn.Exec(ctx, query, jobID, processedRows, failedRows, time.Now())
+	_, err := r.Conn.Exec(ctx, query, jobID, processedRows, failedRows, time.Now(), domain.CSVJobStatusCancelled, workerID)
 	return err
 }
 
-// UpdateJobStatus updates the status of a CSV processing job
-func (r *csvRepository) UpdateJobStatus(ctx context.Context, jobID string, status domain.CSVJobStatus, errorMessage *string) error {
+// UpdateJobStatus updates the status of a CSV processing job. Like the other
+// writes of a running job it only reaches the job while workerID holds it,
+// jobs processed where they were uploaded have no worker.
+func (r *csvRepository) UpdateJobStatus(ctx context.Context, jobID, workerID string, status domain.CSVJobStatus, errorMessage *string) error {
 	now := time.Now()
 
 	var startedAt *time.Time
@@ -177,26 +405,372 @@ func (r *csvRepository) UpdateJobStatus(ctx context.Context, jobID string, statu
 		completedAt = &now
 	}
 
+	// A cancelled job keeps its status, its worker may still be stopping
 	query := `
 		UPDATE csv_jobs
 		SET status = $2, error_message = $3, started_at = COALESCE($4, started_at), completed_at = COALESCE($5, completed_at), updated_at = $6
-		WHERE id = $1
+		WHERE id = $1 AND status <> $7 AND COALESCE(worker_id, '') = $8
 	`
 
-	_, err := r.Conn.Exec(ctx, query, jobID, status, errorMessage, startedAt, completedAt, now)
+	_, err := r.Conn.Exec(ctx, query, jobID, status, errorMessage, startedAt, completedAt, now, domain.CSVJobStatusCancelled, workerID)
 	return err
 }
 
 // CompleteJob marks a CSV processing job as completed
-func (r *csvRepository) CompleteJob(ctx context.Context, jobID string, totalRows, processedRows, failedRows int64) error {
+func (r *csvRepository) CompleteJob(ctx context.Context, jobID, workerID string, totalRows, processedRows, failedRows int64) error {
 	now := time.Now()
 
 	query := `
 		UPDATE csv_jobs
 		SET status = $2, total_rows = $3, processed_rows = $4, failed_rows = $5, completed_at = $6, updated_at = $7
+		WHERE id = $1 AND status <> $8 AND COALESCE(worker_id, '') = $9
+	`
+
+	tag, err := r.Conn.Exec(ctx, query, jobID, domain.CSVJobStatusCompleted, totalRows, processedRows, failedRows, now, now, domain.CSVJobStatusCancelled, workerID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		// The rows done belong to whoever holds the job now
+		return nil
+	}
+	_, err = r.Conn.Exec(ctx, `DELETE FROM csv_job_rows WHERE job_id = $1`, jobID)
+	return err
+}
+
+// SaveCheckpoint moves the checkpoint of a job and records the rows done past
+// it. Rows the checkpoint now covers leave the csv_job_rows ledger.
+func (r *csvRepository) SaveCheckpoint(ctx context.Context, jobID, workerID string, checkpoint domain.CSVCheckpoint, done []domain.CSVRowOutcome) error {
+	tracer := otel.Tracer("repo.csv")
+	ctx, span := tracer.Start(ctx, "CSVRepository.SaveCheckpoint")
+	defer span.End()
+
+	query := `
+		UPDATE csv_jobs
+		SET checkpoint_row = $2, checkpoint_offset = $3, checkpoint_processed = $4, checkpoint_failed = $5, updated_at = NOW()
+		WHERE id = $1 AND COALESCE(worker_id, '') = $6
+	`
+	insert := `
+		INSERT INTO csv_job_rows (job_id, row_number, failed)
+		SELECT $1::uuid, $2::int, $3::boolean
+		WHERE EXISTS (SELECT 1 FROM csv_jobs WHERE id = $1 AND COALESCE(worker_id, '') = $4)
+		ON CONFLICT (job_id, row_number) DO NOTHING`
+	prune := `
+		DELETE FROM csv_job_rows
+		WHERE job_id = $1 AND row_number <= $2
+			AND EXISTS (SELECT 1 FROM csv_jobs WHERE id = $1 AND COALESCE(worker_id, '') = $3)`
+
+	span.SetAttributes(attribute.String("query.statement", query), attribute.Int("batch.size", len(done)))
+	batch := &pgx.Batch{}
+	for _, row := range done {
+		if row.RowNumber > checkpoint.Row {
+			batch.Queue(insert, jobID, row.RowNumber, row.Failed, workerID)
+		}
+	}
+	batch.Queue(query, jobID, checkpoint.Row, checkpoint.Offset, checkpoint.Processed, checkpoint.Failed, workerID)
+	batch.Queue(prune, jobID, checkpoint.Row, workerID)
+	if err := r.Conn.SendBatch(ctx, batch).Close(); err != nil {
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// ListDoneRows returns the rows of a job done past its checkpoint
+func (r *csvRepository) ListDoneRows(ctx context.Context, jobID string) ([]domain.CSVRowOutcome, error) {
+	tracer := otel.Tracer("repo.csv")
+	ctx, span := tracer.Start(ctx, "CSVRepository.ListDoneRows")
+	defer span.End()
+
+	query := `SELECT row_number, failed FROM csv_job_rows WHERE job_id = $1 ORDER BY row_number`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	rows, err := r.Conn.Query(ctx, query, jobID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var done []domain.CSVRowOutcome
+	for rows.Next() {
+		var row domain.CSVRowOutcome
+		if err := rows.Scan(&row.RowNumber, &row.Failed); err != nil {
+			return nil, err
+		}
+		done = append(done, row)
+	}
+	return done, rows.Err()
+}
+
+// ResumeJob puts a failed or cancelled job whose file is kept back in the
+// queue, it continues from its checkpoint. Other jobs give
+// domain.ErrNotFound.
+func (r *csvRepository) ResumeJob(ctx context.Context, id uuid.UUID) (*domain.CSVJob, error) {
+	tracer := otel.Tracer("repo.csv")
+	ctx, span := tracer.Start(ctx, "CSVRepository.ResumeJob")
+	defer span.End()
+
+	query := `
+		UPDATE csv_jobs
+		SET status = $2, error_message = NULL, completed_at = NULL, worker_id = NULL, attempts = 0, updated_at = NOW()
+		WHERE id = $1 AND status IN ($3, $4) AND file_key IS NOT NULL AND NOT dry_run
+		RETURNING ` + csvJobColumns
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	job, err := scanCSVJob(r.Conn.QueryRow(ctx, query, id, domain.CSVJobStatusPending, domain.CSVJobStatusFailed, domain.CSVJobStatusCancelled))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return job, nil
+}
+
+// CompleteDryRun marks a dry run as validated and keeps what the import
+// would have done
+func (r *csvRepository) CompleteDryRun(ctx context.Context, jobID, workerID string, summary *domain.CSVDryRunSummary) error {
+	encoded, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE csv_jobs
+		SET status = $2, summary = $3, total_rows = $4, processed_rows = $4, failed_rows = $5, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status <> $6 AND COALESCE(worker_id, '') = $7
+	`
+
+	_, err = r.Conn.Exec(ctx, query, jobID, domain.CSVJobStatusValidated, encoded, summary.Rows, summary.Failed, domain.CSVJobStatusCancelled, workerID)
+	return err
+}
+
+// SetJobHeaders keeps the header row of the file of a job, for the error
+// report
+func (r *csvRepository) SetJobHeaders(ctx context.Context, jobID string, headers []string) error {
+	query := `
+		UPDATE csv_jobs
+		SET headers = $2, updated_at = NOW()
 		WHERE id = $1
 	`
 
-	_, err := r.Conn.Exec(ctx, query, jobID, domain.CSVJobStatusCompleted, totalRows, processedRows, failedRows, now, now)
+	_, err := r.Conn.Exec(ctx, query, jobID, headers)
 	return err
 }
+
+// AddJobErrors stores failed rows in one round trip
+func (r *csvRepository) AddJobErrors(ctx context.Context, errs []domain.CSVJobError) error {
+	tracer := otel.Tracer("repo.csv")
+	ctx, span := tracer.Start(ctx, "CSVRepository.AddJobErrors")
+	defer span.End()
+
+	query := `
+		INSERT INTO csv_job_errors (job_id, row_number, row_values, error, field_errors, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (job_id, row_number) DO UPDATE
+		SET row_values = EXCLUDED.row_values, error = EXCLUDED.error, field_errors = EXCLUDED.field_errors`
+
+	span.SetAttributes(attribute.String("query.statement", query), attribute.Int("batch.size", len(errs)))
+	batch := &pgx.Batch{}
+	for _, e := range errs {
+		var fieldErrors []byte
+		if len(e.Errors) > 0 {
+			var err error
+			if fieldErrors, err = json.Marshal(e.Errors); err != nil {
+				return err
+			}
+		}
+		values := e.Values
+		if values == nil {
+			values = []string{}
+		}
+		batch.Queue(query, e.JobID, e.RowNumber, values, e.Error, fieldErrors)
+	}
+	if err := r.Conn.SendBatch(ctx, batch).Close(); err != nil {
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// ListJobErrors returns a page of the failed rows of a job in file order,
+// and how many there are in all
+func (r *csvRepository) ListJobErrors(ctx context.Context, jobID uuid.UUID, limit, offset int) ([]domain.CSVJobError, int, error) {
+	tracer := otel.Tracer("repo.csv")
+	ctx, span := tracer.Start(ctx, "CSVRepository.ListJobErrors")
+	defer span.End()
+
+	query := `
+		SELECT job_id, row_number, row_values, error, field_errors, created_at, COUNT(*) OVER ()
+		FROM csv_job_errors
+		WHERE job_id = $1
+		ORDER BY row_number
+		LIMIT $2 OFFSET $3`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	rows, err := r.Conn.Query(ctx, query, jobID, limit, offset)
+	if err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	errs := []domain.CSVJobError{}
+	total := 0
+	for rows.Next() {
+		var (
+			e           domain.CSVJobError
+			fieldErrors []byte
+		)
+		if err := rows.Scan(&e.JobID, &e.RowNumber, &e.Values, &e.Error, &fieldErrors, &e.CreatedAt, &total); err != nil {
+			span.RecordError(err)
+			return nil, 0, err
+		}
+		if len(fieldErrors) > 0 {
+			if err := json.Unmarshal(fieldErrors, &e.Errors); err != nil {
+				return nil, 0, err
+			}
+		}
+		errs = append(errs, e)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+
+	// Past the last page there is no row to read the window count from
+	if len(errs) == 0 && offset > 0 {
+		err := r.Conn.QueryRow(ctx, `SELECT COUNT(*) FROM csv_job_errors WHERE job_id = $1`, jobID).Scan(&total)
+		if err != nil {
+			span.RecordError(err)
+			return nil, 0, err
+		}
+	}
+	return errs, total, nil
+}
+
+// SaveSchema stores a schema under its name, replacing the one saved before
+func (r *csvRepository) SaveSchema(ctx context.Context, schema *domain.CSVSchema) error {
+	tracer := otel.Tracer("repo.csv")
+	ctx, span := tracer.Start(ctx, "CSVRepository.SaveSchema")
+	defer span.End()
+
+	query := `
+		INSERT INTO csv_schemas (name, definition, created_at, updated_at)
+		VALUES ($1, $2, NOW(), NOW())
+		ON CONFLICT (name) DO UPDATE
+		SET definition = EXCLUDED.definition, updated_at = NOW()`
+
+	definition, err := encodeSchema(schema)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(attribute.String("query.statement", query))
+	if _, err := r.Conn.Exec(ctx, query, schema.Name, definition); err != nil {
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// GetSchema returns the schema saved under name, or domain.ErrNotFound
+func (r *csvRepository) GetSchema(ctx context.Context, name string) (*domain.CSVSchema, error) {
+	tracer := otel.Tracer("repo.csv")
+	ctx, span := tracer.Start(ctx, "CSVRepository.GetSchema")
+	defer span.End()
+
+	query := `SELECT definition FROM csv_schemas WHERE name = $1`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	var definition []byte
+	err := r.Conn.QueryRow(ctx, query, name).Scan(&definition)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return decodeSchema(definition)
+}
+
+// ListSchemas returns the saved schemas by name
+func (r *csvRepository) ListSchemas(ctx context.Context) ([]domain.CSVSchema, error) {
+	tracer := otel.Tracer("repo.csv")
+	ctx, span := tracer.Start(ctx, "CSVRepository.ListSchemas")
+	defer span.End()
+
+	query := `SELECT definition FROM csv_schemas ORDER BY name`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	rows, err := r.Conn.Query(ctx, query)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	schemas := []domain.CSVSchema{}
+	for rows.Next() {
+		var definition []byte
+		if err := rows.Scan(&definition); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		schema, err := decodeSchema(definition)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, *schema)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return schemas, nil
+}
+
+// DeleteSchema removes a saved schema, jobs keep the copy they were
+// uploaded with
+func (r *csvRepository) DeleteSchema(ctx context.Context, name string) error {
+	result, err := r.Conn.Exec(ctx, `DELETE FROM csv_schemas WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// encodeSchema turns a schema into JSONB, NULL for none
+func encodeSchema(schema *domain.CSVSchema) ([]byte, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	return json.Marshal(schema)
+}
+
+func decodeSchema(definition []byte) (*domain.CSVSchema, error) {
+	if len(definition) == 0 {
+		return nil, nil
+	}
+	var schema domain.CSVSchema
+	if err := json.Unmarshal(definition, &schema); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func decodeSummary(encoded []byte) (*domain.CSVDryRunSummary, error) {
+	if len(encoded) == 0 {
+		return nil, nil
+	}
+	var summary domain.CSVDryRunSummary
+	if err := json.Unmarshal(encoded, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
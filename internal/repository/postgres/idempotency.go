@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type IdempotencyRepository struct {
+	Conn *pgxpool.Pool
+}
+
+func NewIdempotencyRepository(conn *pgxpool.Pool) *IdempotencyRepository {
+	return &IdempotencyRepository{Conn: conn}
+}
+
+// reserveIdempotencyQuery claims a key. An existing row is only taken over
+// once it expired, or when it is the same request abandoned past its lock.
+const reserveIdempotencyQuery = `
+	INSERT INTO idempotency_keys (user_id, method, route, key, request_hash, locked_until, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (user_id, method, route, key) DO UPDATE
+	SET request_hash = EXCLUDED.request_hash,
+		status_code = NULL,
+		headers = NULL,
+		body = NULL,
+		locked_until = EXCLUDED.locked_until,
+		completed_at = NULL,
+		created_at = now(),
+		expires_at = EXCLUDED.expires_at
+	WHERE idempotency_keys.expires_at < now()
+		OR (idempotency_keys.completed_at IS NULL
+			AND idempotency_keys.locked_until < now()
+			AND idempotency_keys.request_hash = EXCLUDED.request_hash)
+	RETURNING created_at`
+
+// Reserve claims the key of rec for the caller until lockedUntil. When the
+// key is held by another request it returns that record and false instead.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, rec *domain.IdempotencyRecord, lockedUntil time.Time) (*domain.IdempotencyRecord, bool, error) {
+	tracer := otel.Tracer("repo.idempotency")
+	ctx, span := tracer.Start(ctx, "IdempotencyRepository.Reserve")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("query.statement", reserveIdempotencyQuery))
+
+	// The holder may release the key between the two statements, in which
+	// case claiming it again succeeds
+	for attempt := 0; ; attempt++ {
+		err := r.Conn.QueryRow(ctx, reserveIdempotencyQuery,
+			rec.UserID, rec.Method, rec.Route, rec.Key, rec.RequestHash, lockedUntil, rec.ExpiresAt,
+		).Scan(&rec.CreatedAt)
+		if err == nil {
+			return rec, true, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			span.RecordError(err)
+			return nil, false, err
+		}
+
+		existing, err := r.get(ctx, rec)
+		if err == nil {
+			return existing, false, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) || attempt > 0 {
+			span.RecordError(err)
+			return nil, false, err
+		}
+	}
+}
+
+func (r *IdempotencyRepository) get(ctx context.Context, rec *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	query := `
+		SELECT request_hash, status_code, headers, body, completed_at, created_at, expires_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND method = $2 AND route = $3 AND key = $4`
+
+	existing := &domain.IdempotencyRecord{Key: rec.Key, UserID: rec.UserID, Method: rec.Method, Route: rec.Route}
+	var status *int
+	var header []byte
+	err := r.Conn.QueryRow(ctx, query, rec.UserID, rec.Method, rec.Route, rec.Key).Scan(
+		&existing.RequestHash, &status, &header, &existing.Body,
+		&existing.CompletedAt, &existing.CreatedAt, &existing.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if status != nil {
+		existing.StatusCode = *status
+	}
+	if len(header) > 0 {
+		if err := json.Unmarshal(header, &existing.Header); err != nil {
+			return nil, err
+		}
+	}
+	return existing, nil
+}
+
+// Complete stores the response of a reserved request for replay
+func (r *IdempotencyRepository) Complete(ctx context.Context, rec *domain.IdempotencyRecord) error {
+	tracer := otel.Tracer("repo.idempotency")
+	ctx, span := tracer.Start(ctx, "IdempotencyRepository.Complete")
+	defer span.End()
+
+	header, err := json.Marshal(rec.Header)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $5, headers = $6, body = $7, completed_at = now()
+		WHERE user_id = $1 AND method = $2 AND route = $3 AND key = $4
+			AND request_hash = $8 AND completed_at IS NULL`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	_, err = r.Conn.Exec(ctx, query,
+		rec.UserID, rec.Method, rec.Route, rec.Key, rec.StatusCode, header, rec.Body, rec.RequestHash,
+	)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+// Release gives up a reserved key without storing a response, so the next
+// retry runs the request again
+func (r *IdempotencyRepository) Release(ctx context.Context, rec *domain.IdempotencyRecord) error {
+	tracer := otel.Tracer("repo.idempotency")
+	ctx, span := tracer.Start(ctx, "IdempotencyRepository.Release")
+	defer span.End()
+
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND method = $2 AND route = $3 AND key = $4
+			AND request_hash = $5 AND completed_at IS NULL`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	_, err := r.Conn.Exec(ctx, query, rec.UserID, rec.Method, rec.Route, rec.Key, rec.RequestHash)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+// PurgeExpired deletes the keys that expired before the cutoff and returns
+// how many were deleted
+func (r *IdempotencyRepository) PurgeExpired(ctx context.Context, before time.Time) (int, error) {
+	tracer := otel.Tracer("repo.idempotency")
+	ctx, span := tracer.Start(ctx, "IdempotencyRepository.PurgeExpired")
+	defer span.End()
+
+	query := `DELETE FROM idempotency_keys WHERE expires_at < $1`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	tag, err := r.Conn.Exec(ctx, query, before)
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,290 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type ModerationRepository struct {
+	Conn *pgxpool.Pool
+}
+
+func NewModerationRepository(conn *pgxpool.Pool) *ModerationRepository {
+	return &ModerationRepository{Conn: conn}
+}
+
+func (r *ModerationRepository) GetUserCreatedAt(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	query := `SELECT created_at FROM users WHERE id = $1 AND deleted_at IS NULL`
+
+	var createdAt time.Time
+	if err := r.Conn.QueryRow(ctx, query, userID).Scan(&createdAt); err != nil {
+		return time.Time{}, err
+	}
+	return createdAt, nil
+}
+
+// CountCommentsSince counts the comments the user created after since,
+// whatever their moderation status
+func (r *ModerationRepository) CountCommentsSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM comments WHERE user_id = $1 AND created_at > $2`
+
+	var count int
+	if err := r.Conn.QueryRow(ctx, query, userID, since).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetComment returns a live comment whatever its moderation status, unlike
+// CommentRepository.GetComment which only sees approved ones
+func (r *ModerationRepository) GetComment(ctx context.Context, id uuid.UUID) (*domain.Comment, error) {
+	tracer := otel.Tracer("repo.moderation")
+	ctx, span := tracer.Start(ctx, "ModerationRepository.GetComment")
+	defer span.End()
+
+	query := `
+		SELECT
+			id,
+			post_id,
+			user_id,
+			parent_id,
+			depth,
+			status,
+			body,
+			content_format,
+			content_html,
+			version,
+			created_at,
+			updated_at
+		FROM comments
+		WHERE id = $1 AND deleted_at IS NULL`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	span.SetAttributes(attribute.String("query.parameter", id.String()))
+	var comment domain.Comment
+	err := r.Conn.QueryRow(ctx, query, id).Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.UserID,
+		&comment.ParentID,
+		&comment.Depth,
+		&comment.Status,
+		&comment.Body,
+		&comment.ContentFormat,
+		&comment.ContentHTML,
+		&comment.Version,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// GetModerationQueue returns a page of comments in filter.Status, oldest
+// first so moderators work through the backlog in order
+func (r *ModerationRepository) GetModerationQueue(ctx context.Context, filter *domain.ModerationQueueFilter) ([]domain.Comment, int, error) {
+	tracer := otel.Tracer("repo.moderation")
+	ctx, span := tracer.Start(ctx, "ModerationRepository.GetModerationQueue")
+	defer span.End()
+
+	countQuery := `SELECT COUNT(*) FROM comments WHERE status = $1 AND deleted_at IS NULL`
+
+	var total int
+	if err := r.Conn.QueryRow(ctx, countQuery, filter.Status).Scan(&total); err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT
+			id,
+			post_id,
+			user_id,
+			parent_id,
+			depth,
+			status,
+			body,
+			content_format,
+			content_html,
+			version,
+			created_at,
+			updated_at
+		FROM comments
+		WHERE status = $1 AND deleted_at IS NULL
+		ORDER BY created_at, id
+		LIMIT $2 OFFSET $3`
+
+	offset := (filter.Page - 1) * filter.Limit
+	span.SetAttributes(attribute.String("query.statement", query))
+	span.SetAttributes(attribute.String("query.parameter", string(filter.Status)))
+	rows, err := r.Conn.Query(ctx, query, filter.Status, filter.Limit, offset)
+	if err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var comments []domain.Comment
+	for rows.Next() {
+		var comment domain.Comment
+		err := rows.Scan(
+			&comment.ID,
+			&comment.PostID,
+			&comment.UserID,
+			&comment.ParentID,
+			&comment.Depth,
+			&comment.Status,
+			&comment.Body,
+			&comment.ContentFormat,
+			&comment.ContentHTML,
+			&comment.Version,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+		)
+		if err != nil {
+			span.RecordError(err)
+			return nil, 0, err
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+
+	return comments, total, nil
+}
+
+// ApplyModeration moves every live comment in ids to the status of action and
+// writes one audit record per comment in the same statement, so either all
+// of them change or none do. Comments that do not exist are skipped.
+func (r *ModerationRepository) ApplyModeration(
+	ctx context.Context,
+	ids []uuid.UUID,
+	action domain.ModerationAction,
+	moderatorID string,
+	reason string,
+) ([]domain.ModerationAudit, error) {
+	tracer := otel.Tracer("repo.moderation")
+	ctx, span := tracer.Start(ctx, "ModerationRepository.ApplyModeration")
+	defer span.End()
+
+	query := `
+		WITH previous AS (
+			SELECT id, status
+			FROM comments
+			WHERE id = ANY($1) AND deleted_at IS NULL
+			FOR UPDATE
+		), updated AS (
+			UPDATE comments c
+			SET status = $2,
+				moderation_reason = $3,
+				version = c.version + 1,
+				updated_at = NOW()
+			FROM previous p
+			WHERE c.id = p.id
+			RETURNING c.id, p.status AS from_status
+		)
+		INSERT INTO comment_moderation_audit (comment_id, moderator_id, action, from_status, to_status, reason, created_at)
+		SELECT id, $4, $5, from_status, $2, $3, NOW()
+		FROM updated
+		RETURNING id, comment_id, moderator_id, action, from_status, to_status, reason, created_at`
+
+	var moderator *uuid.UUID
+	if parsed, err := uuid.Parse(moderatorID); err == nil {
+		moderator = &parsed
+	}
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	span.SetAttributes(attribute.Int("query.comments", len(ids)))
+	rows, err := r.Conn.Query(ctx, query, ids, action.Status(), reason, moderator, action)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	audits, err := pgx.CollectRows(rows, scanModerationAudit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return audits, nil
+}
+
+// RecordAudit stores a status change made outside ApplyModeration, such as
+// the spam rules holding back a new comment
+func (r *ModerationRepository) RecordAudit(ctx context.Context, audit *domain.ModerationAudit) error {
+	query := `
+		INSERT INTO comment_moderation_audit (comment_id, moderator_id, action, from_status, to_status, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING id, created_at`
+
+	var id uuid.UUID
+	err := r.Conn.QueryRow(ctx, query, audit.CommentID, audit.ModeratorID, audit.Action, audit.FromStatus, audit.ToStatus, audit.Reason).Scan(
+		&id,
+		&audit.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	audit.ID = id.String()
+	return nil
+}
+
+func (r *ModerationRepository) GetModerationAudit(ctx context.Context, commentID uuid.UUID) ([]domain.ModerationAudit, error) {
+	query := `
+		SELECT id, comment_id, moderator_id, action, from_status, to_status, reason, created_at
+		FROM comment_moderation_audit
+		WHERE comment_id = $1
+		ORDER BY created_at, id`
+
+	rows, err := r.Conn.Query(ctx, query, commentID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanModerationAudit)
+}
+
+func scanModerationAudit(row pgx.CollectableRow) (domain.ModerationAudit, error) {
+	var (
+		audit       domain.ModerationAudit
+		id          uuid.UUID
+		commentID   uuid.UUID
+		moderatorID *uuid.UUID
+	)
+	err := row.Scan(
+		&id,
+		&commentID,
+		&moderatorID,
+		&audit.Action,
+		&audit.FromStatus,
+		&audit.ToStatus,
+		&audit.Reason,
+		&audit.CreatedAt,
+	)
+	if err != nil {
+		return audit, err
+	}
+
+	audit.ID = id.String()
+	audit.CommentID = commentID.String()
+	if moderatorID != nil {
+		moderator := moderatorID.String()
+		audit.ModeratorID = &moderator
+	}
+	return audit, nil
+}
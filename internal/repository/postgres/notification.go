@@ -0,0 +1,230 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type NotificationRepository struct {
+	Conn *pgxpool.Pool
+}
+
+func NewNotificationRepository(conn *pgxpool.Pool) *NotificationRepository {
+	return &NotificationRepository{Conn: conn}
+}
+
+// CreateNotifications stores the notifications of one event in a single batch
+func (r *NotificationRepository) CreateNotifications(ctx context.Context, notifications []domain.Notification) error {
+	query := `
+		INSERT INTO notifications (user_id, actor_id, type, post_id, comment_id, message, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())`
+
+	batch := &pgx.Batch{}
+	for _, n := range notifications {
+		batch.Queue(query, n.UserID, n.ActorID, n.Type, n.PostID, n.CommentID, n.Message)
+	}
+	return r.Conn.SendBatch(ctx, batch).Close()
+}
+
+func (r *NotificationRepository) GetNotifications(
+	ctx context.Context,
+	userID uuid.UUID,
+	filter *domain.NotificationFilter,
+) ([]domain.Notification, int, error) {
+	tracer := otel.Tracer("repo.notifications")
+	ctx, span := tracer.Start(ctx, "NotificationRepository.GetNotifications")
+	defer span.End()
+
+	where := `WHERE user_id = $1`
+	if filter.UnreadOnly {
+		where += ` AND read_at IS NULL`
+	}
+
+	var total int
+	if err := r.Conn.QueryRow(ctx, `SELECT COUNT(*) FROM notifications `+where, userID).Scan(&total); err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, user_id, actor_id, type, post_id, comment_id, message, read_at, created_at
+		FROM notifications
+		` + where + `
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3`
+
+	offset := (filter.Page - 1) * filter.Limit
+	span.SetAttributes(attribute.String("query.statement", query))
+	span.SetAttributes(attribute.String("query.parameter", userID.String()))
+	rows, err := r.Conn.Query(ctx, query, userID, filter.Limit, offset)
+	if err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var notifications []domain.Notification
+	for rows.Next() {
+		var n domain.Notification
+		err := rows.Scan(
+			&n.ID,
+			&n.UserID,
+			&n.ActorID,
+			&n.Type,
+			&n.PostID,
+			&n.CommentID,
+			&n.Message,
+			&n.ReadAt,
+			&n.CreatedAt,
+		)
+		if err != nil {
+			span.RecordError(err)
+			return nil, 0, err
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+
+	return notifications, total, nil
+}
+
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`
+
+	var count int
+	if err := r.Conn.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int64, error) {
+	query := `
+		UPDATE notifications
+		SET read_at = NOW()
+		WHERE user_id = $1 AND id = ANY($2) AND read_at IS NULL`
+
+	result, err := r.Conn.Exec(ctx, query, userID, ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	query := `
+		UPDATE notifications
+		SET read_at = NOW()
+		WHERE user_id = $1 AND read_at IS NULL`
+
+	result, err := r.Conn.Exec(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// GetPreferences returns the stored preferences of the given users, keyed by
+// user ID. Users without stored preferences are left out.
+func (r *NotificationRepository) GetPreferences(ctx context.Context, userIDs []uuid.UUID) (map[string]domain.NotificationPreferences, error) {
+	query := `
+		SELECT user_id, on_comment, on_reply, on_mention
+		FROM notification_preferences
+		WHERE user_id = ANY($1)`
+
+	rows, err := r.Conn.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := make(map[string]domain.NotificationPreferences, len(userIDs))
+	for rows.Next() {
+		var (
+			userID uuid.UUID
+			pref   domain.NotificationPreferences
+		)
+		if err := rows.Scan(&userID, &pref.OnComment, &pref.OnReply, &pref.OnMention); err != nil {
+			return nil, err
+		}
+		prefs[userID.String()] = pref
+	}
+	return prefs, rows.Err()
+}
+
+func (r *NotificationRepository) SavePreferences(ctx context.Context, userID uuid.UUID, prefs *domain.NotificationPreferences) error {
+	query := `
+		INSERT INTO notification_preferences (user_id, on_comment, on_reply, on_mention, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET on_comment = EXCLUDED.on_comment,
+			on_reply = EXCLUDED.on_reply,
+			on_mention = EXCLUDED.on_mention,
+			updated_at = NOW()`
+
+	_, err := r.Conn.Exec(ctx, query, userID, prefs.OnComment, prefs.OnReply, prefs.OnMention)
+	return err
+}
+
+// GetUserIDsByUsername resolves lower cased usernames to user IDs, unknown
+// usernames are left out
+func (r *NotificationRepository) GetUserIDsByUsername(ctx context.Context, usernames []string) (map[string]string, error) {
+	query := `
+		SELECT LOWER(username), id
+		FROM users
+		WHERE LOWER(username) = ANY($1) AND deleted_at IS NULL`
+
+	rows, err := r.Conn.Query(ctx, query, usernames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]string, len(usernames))
+	for rows.Next() {
+		var (
+			username string
+			id       uuid.UUID
+		)
+		if err := rows.Scan(&username, &id); err != nil {
+			return nil, err
+		}
+		ids[username] = id.String()
+	}
+	return ids, rows.Err()
+}
+
+// GetPostAuthor returns the author of a post, or "" for posts without one
+func (r *NotificationRepository) GetPostAuthor(ctx context.Context, postID uuid.UUID) (string, error) {
+	return r.author(ctx, `SELECT user_id FROM posts WHERE id = $1 AND deleted_at IS NULL`, postID)
+}
+
+// GetCommentAuthor returns the author of a comment, or "" when it is gone
+func (r *NotificationRepository) GetCommentAuthor(ctx context.Context, commentID uuid.UUID) (string, error) {
+	return r.author(ctx, `SELECT user_id FROM comments WHERE id = $1 AND deleted_at IS NULL`, commentID)
+}
+
+func (r *NotificationRepository) author(ctx context.Context, query string, id uuid.UUID) (string, error) {
+	var author *uuid.UUID
+	err := r.Conn.QueryRow(ctx, query, id).Scan(&author)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if author == nil {
+		return "", nil
+	}
+	return author.String(), nil
+}
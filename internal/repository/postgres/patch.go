@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"strconv"
+	"strings"
+)
+
+// assignments collects the SET list of a partial update, numbering the
+// placeholders in the order the columns are added
+type assignments struct {
+	columns []string
+	args    []interface{}
+}
+
+func (a *assignments) set(column string, value interface{}) {
+	a.args = append(a.args, value)
+	a.columns = append(a.columns, column+" = $"+strconv.Itoa(len(a.args)))
+}
+
+// arg adds a value referenced outside the SET list and returns its placeholder
+func (a *assignments) arg(value interface{}) string {
+	a.args = append(a.args, value)
+	return "$" + strconv.Itoa(len(a.args))
+}
+
+func (a *assignments) String() string {
+	return strings.Join(a.columns, ",\n\t\t\t")
+}
@@ -2,13 +2,13 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"strings"
 
 	"github.com/edwinjordan/MajooTest-Golang/domain"
 	"github.com/edwinjordan/MajooTest-Golang/utils"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -23,35 +23,67 @@ func NewPostsRepository(conn *pgxpool.Pool) *PostsRepository {
 	return &PostsRepository{Conn: conn}
 }
 
+// createPostsQuery skips posts of a CSV row that was imported already
+const createPostsQuery = `
+		INSERT INTO posts (user_id, title, content, content_format, content_html, slug, import_job_id, import_row, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		ON CONFLICT (import_job_id, import_row) WHERE import_job_id IS NOT NULL DO NOTHING
+		RETURNING id, version, created_at, updated_at`
+
 func (r *PostsRepository) CreatePosts(ctx context.Context, post *domain.CreatePostsRequest) (*domain.Posts, error) {
-	query := `
-		INSERT INTO posts (title, content, slug, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		RETURNING id`
+	query := createPostsQuery
+
+	var author *string
+	if post.UserID != "" {
+		author = &post.UserID
+	}
 
+	format := post.ContentFormat.OrDefault()
+	created := domain.Posts{
+		UserID:        author,
+		Title:         post.Title,
+		Content:       post.Content,
+		ContentFormat: format,
+		ContentHTML:   post.ContentHTML,
+		Slug:          utils.Slugify(post.Title),
+	}
+	var importJob *string
+	var importRow *int
+	if key := domain.CSVRowFromContext(ctx); key != nil {
+		importJob, importRow = &key.JobID, &key.Row
+	}
 	var id uuid.UUID
-	err := r.Conn.QueryRow(ctx, query, post.Title, post.Content, utils.Slugify(post.Title)).Scan(&id)
+	err := r.Conn.QueryRow(ctx, query, author, post.Title, post.Content, format, post.ContentHTML, created.Slug, importJob, importRow).Scan(
+		&id,
+		&created.Version,
+		&created.CreatedAt,
+		&created.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCSVRowImported
+	}
 	if err != nil {
 		return nil, err
 	}
+	created.ID = id.String()
 
-	return &domain.Posts{
-		ID:      id.String(),
-		Title:   post.Title,
-		Content: post.Content,
-		Slug:    utils.Slugify(post.Title),
-	}, nil
+	return &created, nil
 }
 
 func (u *PostsRepository) GetPostsList(ctx context.Context, filter *domain.PostsFilter) ([]domain.Posts, error) {
 	query := `
 		SELECT
 			u.id,
+			u.user_id,
 			u.title,
 			u.content,
+			u.content_format,
+			u.content_html,
 			u.slug,
+            u.version,
             u.created_at,
-            u.updated_at
+            u.updated_at,
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = u.id AND c.status = 'approved' AND c.deleted_at IS NULL) AS comment_count
 		FROM posts u
         WHERE u.deleted_at is NULL`
 
@@ -63,7 +95,7 @@ func (u *PostsRepository) GetPostsList(ctx context.Context, filter *domain.Posts
 	}
 
 	if len(conditions) > 0 {
-		query += strings.Join(conditions, " AND ")
+		query += " AND " + strings.Join(conditions, " AND ")
 	}
 	rows, err := u.Conn.Query(ctx, query, args...)
 	if err != nil {
@@ -77,11 +109,16 @@ func (u *PostsRepository) GetPostsList(ctx context.Context, filter *domain.Posts
 		var post domain.Posts
 		err := rows.Scan(
 			&post.ID,
+			&post.UserID,
 			&post.Title,
 			&post.Content,
+			&post.ContentFormat,
+			&post.ContentHTML,
 			&post.Slug,
+			&post.Version,
 			&post.CreatedAt,
 			&post.UpdatedAt,
+			&post.CommentCount,
 		)
 		if err != nil {
 			return nil, err
@@ -100,11 +137,16 @@ func (u *PostsRepository) GetPosts(ctx context.Context, id uuid.UUID) (*domain.P
 	query := `
 		SELECT
 			id,
+			user_id,
 			title,
 			content,
+			content_format,
+			content_html,
 			slug,
+			version,
 			created_at,
-			updated_at
+			updated_at,
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = posts.id AND c.status = 'approved' AND c.deleted_at IS NULL) AS comment_count
 		FROM posts
 		WHERE id = $1 AND deleted_at IS NULL`
 
@@ -115,12 +157,20 @@ func (u *PostsRepository) GetPosts(ctx context.Context, id uuid.UUID) (*domain.P
 	var post domain.Posts
 	err := row.Scan(
 		&post.ID,
+		&post.UserID,
 		&post.Title,
 		&post.Content,
+		&post.ContentFormat,
+		&post.ContentHTML,
 		&post.Slug,
+		&post.Version,
 		&post.CreatedAt,
 		&post.UpdatedAt,
+		&post.CommentCount,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
 	if err != nil {
 		span.RecordError(err)
 		//	u.Metrics.UserRepoCalls.WithLabelValues("GetUser", "error").Inc()
@@ -131,28 +181,52 @@ func (u *PostsRepository) GetPosts(ctx context.Context, id uuid.UUID) (*domain.P
 	return &post, nil
 }
 
-func (u *PostsRepository) UpdatePosts(ctx context.Context, id uuid.UUID, post *domain.Posts) (*domain.Posts, error) {
-	query := `
+// PostExists reports whether a post with the given id exists and has not been
+// soft-deleted
+func (u *PostsRepository) PostExists(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM posts WHERE id = $1 AND deleted_at IS NULL)`
+
+	var exists bool
+	if err := u.Conn.QueryRow(ctx, query, id).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+const updatePostsQuery = `
 		UPDATE posts
 		SET title = $1,
 			content = $2,
-			slug = $3,
+			content_format = $3,
+			content_html = $4,
+			slug = $5,
+			version = version + 1,
 			updated_at = NOW()
-		WHERE id = $4 AND deleted_at IS NULL
-		RETURNING id, title, content, slug, created_at, updated_at`
+		WHERE id = $6 AND deleted_at IS NULL AND ($7 = 0 OR version = $7)
+		RETURNING id, user_id, title, content, content_format, content_html, slug, version, created_at, updated_at`
+
+func (u *PostsRepository) UpdatePosts(ctx context.Context, id uuid.UUID, post *domain.Posts) (*domain.Posts, error) {
+	query := updatePostsQuery
 
 	var updatedPost domain.Posts
-	err := u.Conn.QueryRow(ctx, query, post.Title, post.Content, utils.Slugify(post.Title), id).Scan(
+	err := u.Conn.QueryRow(ctx, query, post.Title, post.Content, post.ContentFormat.OrDefault(), post.ContentHTML, utils.Slugify(post.Title), id, post.Version).Scan(
 		&updatedPost.ID,
+		&updatedPost.UserID,
 		&updatedPost.Title,
 		&updatedPost.Content,
+		&updatedPost.ContentFormat,
+		&updatedPost.ContentHTML,
 		&updatedPost.Slug,
+		&updatedPost.Version,
 		&updatedPost.CreatedAt,
 		&updatedPost.UpdatedAt,
 	)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, domain.ErrUserNotFound
+		if errors.Is(err, pgx.ErrNoRows) && post.Version > 0 {
+			return nil, domain.ErrPreconditionFailed
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
 		}
 		return nil, err
 	}
@@ -160,20 +234,233 @@ func (u *PostsRepository) UpdatePosts(ctx context.Context, id uuid.UUID, post *d
 	return &updatedPost, nil
 }
 
-func (u *PostsRepository) DeletePosts(ctx context.Context, id uuid.UUID) error {
+// PatchPosts updates only the columns supplied in patch, deriving the slug
+// from a new title. A non-zero version must match the stored one, otherwise
+// domain.ErrPreconditionFailed is returned.
+func (u *PostsRepository) PatchPosts(ctx context.Context, id uuid.UUID, version int, patch *domain.PostsPatch) (*domain.Posts, error) {
+	tracer := otel.Tracer("repo.posts")
+	ctx, span := tracer.Start(ctx, "PostsRepository.PatchPosts")
+	defer span.End()
+
+	var set assignments
+	if patch.Title != nil {
+		set.set("title", *patch.Title)
+		set.set("slug", utils.Slugify(*patch.Title))
+	}
+	if patch.Content != nil {
+		set.set("content", *patch.Content)
+	}
+	if patch.ContentFormat != nil {
+		set.set("content_format", *patch.ContentFormat)
+	}
+	if patch.ContentHTML != nil {
+		set.set("content_html", *patch.ContentHTML)
+	}
+
+	idArg, versionArg := set.arg(id), set.arg(version)
 	query := `
 		UPDATE posts
-		SET deleted_at = NOW()
-		WHERE id = $1 AND deleted_at IS NULL`
+		SET ` + set.String() + `,
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = ` + idArg + ` AND deleted_at IS NULL AND (` + versionArg + ` = 0 OR version = ` + versionArg + `)
+		RETURNING id, user_id, title, content, content_format, content_html, slug, version, created_at, updated_at`
 
-	result, err := u.Conn.Exec(ctx, query, id)
+	span.SetAttributes(attribute.String("query.statement", query))
+	var post domain.Posts
+	err := u.Conn.QueryRow(ctx, query, set.args...).Scan(
+		&post.ID,
+		&post.UserID,
+		&post.Title,
+		&post.Content,
+		&post.ContentFormat,
+		&post.ContentHTML,
+		&post.Slug,
+		&post.Version,
+		&post.CreatedAt,
+		&post.UpdatedAt,
+	)
 	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			if version > 0 {
+				return nil, domain.ErrPreconditionFailed
+			}
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &post, nil
+}
+
+// DeletePosts soft-deletes a post together with its live comments, stamping
+// them with the post's deleted_at so RestorePosts can tell them apart from
+// comments deleted on their own. A non-zero version must match the stored
+// one, otherwise domain.ErrPreconditionFailed is returned.
+func (u *PostsRepository) DeletePosts(ctx context.Context, id uuid.UUID, version int) error {
+	var deleted int
+	if err := u.Conn.QueryRow(ctx, deletePostsQuery, id, version).Scan(&deleted); err != nil {
 		return err
 	}
 
-	if result.RowsAffected() == 0 {
+	if deleted == 0 {
+		if version > 0 {
+			return domain.ErrPreconditionFailed
+		}
 		return domain.ErrUserNotFound
 	}
 
 	return nil
 }
+
+// deletePostsQuery takes the post id and the version it must be at, zero
+// for any
+const deletePostsQuery = `
+		WITH post AS (
+			UPDATE posts
+			SET deleted_at = NOW()
+			WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)
+			RETURNING id, deleted_at
+		), comments_deleted AS (
+			UPDATE comments c
+			SET deleted_at = post.deleted_at
+			FROM post
+			WHERE c.post_id = post.id AND c.deleted_at IS NULL
+		)
+		SELECT COUNT(*) FROM post`
+
+// RestorePosts undeletes a post and the comments deleted along with it,
+// returning the post and how many comments came back. It returns
+// domain.ErrNotFound unless the post exists and is deleted.
+func (u *PostsRepository) RestorePosts(ctx context.Context, id uuid.UUID) (*domain.Posts, int, error) {
+	tracer := otel.Tracer("repo.posts")
+	ctx, span := tracer.Start(ctx, "PostsRepository.RestorePosts")
+	defer span.End()
+
+	query := `
+		WITH deleted AS (
+			SELECT id, deleted_at FROM posts WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE
+		), post AS (
+			UPDATE posts p
+			SET deleted_at = NULL, updated_at = NOW()
+			FROM deleted
+			WHERE p.id = deleted.id
+			RETURNING p.id, p.user_id, p.title, p.content, p.content_format, p.content_html, p.slug, p.version, p.created_at, p.updated_at
+		), comments_restored AS (
+			UPDATE comments c
+			SET deleted_at = NULL
+			FROM deleted
+			WHERE c.post_id = deleted.id AND c.deleted_at = deleted.deleted_at
+			RETURNING c.id
+		)
+		SELECT post.*, (SELECT COUNT(*) FROM comments_restored)
+		FROM post`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	var post domain.Posts
+	var restored int
+	err := u.Conn.QueryRow(ctx, query, id).Scan(
+		&post.ID,
+		&post.UserID,
+		&post.Title,
+		&post.Content,
+		&post.ContentFormat,
+		&post.ContentHTML,
+		&post.Slug,
+		&post.Version,
+		&post.CreatedAt,
+		&post.UpdatedAt,
+		&restored,
+	)
+	if err != nil {
+		span.RecordError(err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, 0, domain.ErrNotFound
+		}
+		return nil, 0, err
+	}
+
+	return &post, restored, nil
+}
+
+// ApplyPostsBatch creates, updates and deletes posts in one transaction,
+// results[i] reports on items[i]. Items are expected to be validated, see
+// applyBatch for how failures are handled.
+func (u *PostsRepository) ApplyPostsBatch(ctx context.Context, items []domain.PostsBatchItem, atomic bool) ([]domain.BatchItemResult[domain.Posts], error) {
+	tracer := otel.Tracer("repo.posts")
+	ctx, span := tracer.Start(ctx, "PostsRepository.ApplyPostsBatch")
+	defer span.End()
+	span.SetAttributes(attribute.Int("batch.size", len(items)), attribute.Bool("batch.atomic", atomic))
+
+	batch := &pgx.Batch{}
+	results := make([]domain.BatchItemResult[domain.Posts], len(items))
+	for i, item := range items {
+		results[i] = domain.BatchItemResult[domain.Posts]{Index: i, Op: item.Op, ID: item.ID}
+		format := item.ContentFormat.OrDefault()
+		switch item.Op {
+		case domain.BatchOpCreate:
+			var author *string
+			if item.UserID != "" {
+				author = &item.UserID
+			}
+			batch.Queue(createPostsQuery, author, item.Title, item.Content, format, item.ContentHTML, utils.Slugify(item.Title), nil, nil)
+		case domain.BatchOpUpdate:
+			batch.Queue(updatePostsQuery, item.Title, item.Content, format, item.ContentHTML, utils.Slugify(item.Title), item.ID, item.Version)
+		case domain.BatchOpDelete:
+			batch.Queue(deletePostsQuery, item.ID, item.Version)
+		}
+	}
+
+	err := applyBatch(ctx, u.Conn, batch, atomic, results, func(row pgx.Row, result *domain.BatchItemResult[domain.Posts]) error {
+		item := items[result.Index]
+		var err error
+		switch item.Op {
+		case domain.BatchOpCreate:
+			post := domain.Posts{
+				Title:         item.Title,
+				Content:       item.Content,
+				ContentFormat: item.ContentFormat.OrDefault(),
+				ContentHTML:   item.ContentHTML,
+				Slug:          utils.Slugify(item.Title),
+			}
+			if item.UserID != "" {
+				post.UserID = &item.UserID
+			}
+			err = row.Scan(&post.ID, &post.Version, &post.CreatedAt, &post.UpdatedAt)
+			result.ID, result.Data = post.ID, &post
+		case domain.BatchOpUpdate:
+			var post domain.Posts
+			err = row.Scan(
+				&post.ID,
+				&post.UserID,
+				&post.Title,
+				&post.Content,
+				&post.ContentFormat,
+				&post.ContentHTML,
+				&post.Slug,
+				&post.Version,
+				&post.CreatedAt,
+				&post.UpdatedAt,
+			)
+			if errors.Is(err, pgx.ErrNoRows) {
+				result.Fail(batchMissReason("post", item.Version))
+				return nil
+			}
+			result.Data = &post
+		case domain.BatchOpDelete:
+			var deleted int
+			if err = row.Scan(&deleted); err == nil && deleted == 0 {
+				result.Fail(batchMissReason("post", item.Version))
+				return nil
+			}
+		}
+		result.OK = err == nil
+		return err
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type ReactionRepository struct {
+	Conn *pgxpool.Pool
+}
+
+func NewReactionRepository(conn *pgxpool.Pool) *ReactionRepository {
+	return &ReactionRepository{Conn: conn}
+}
+
+// reactionTargetTables maps a reaction target to the table holding it
+var reactionTargetTables = map[domain.ReactionTarget]string{
+	domain.ReactionTargetPost:    "posts",
+	domain.ReactionTargetComment: "comments",
+}
+
+// reactionTargetVisible limits the targets of a table to the ones users see
+var reactionTargetVisible = map[domain.ReactionTarget]string{
+	domain.ReactionTargetComment: ` AND status = 'approved'`,
+}
+
+func (r *ReactionRepository) TargetExists(ctx context.Context, target domain.ReactionTarget, id uuid.UUID) (bool, error) {
+	table, ok := reactionTargetTables[target]
+	if !ok {
+		return false, fmt.Errorf("%w: unknown reaction target %q", domain.ErrBadParamInput, target)
+	}
+
+	query := `SELECT EXISTS (SELECT 1 FROM ` + table + ` WHERE id = $1 AND deleted_at IS NULL` + reactionTargetVisible[target] + `)`
+
+	var exists bool
+	if err := r.Conn.QueryRow(ctx, query, id).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (r *ReactionRepository) AddReaction(ctx context.Context, reaction *domain.Reaction) error {
+	query := `
+		INSERT INTO reactions (user_id, target_type, target_id, type, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (user_id, target_type, target_id, type) DO NOTHING`
+
+	_, err := r.Conn.Exec(ctx, query, reaction.UserID, reaction.TargetType, reaction.TargetID, reaction.Type)
+	return err
+}
+
+func (r *ReactionRepository) RemoveReaction(ctx context.Context, reaction *domain.Reaction) error {
+	query := `
+		DELETE FROM reactions
+		WHERE user_id = $1 AND target_type = $2 AND target_id = $3 AND type = $4`
+
+	_, err := r.Conn.Exec(ctx, query, reaction.UserID, reaction.TargetType, reaction.TargetID, reaction.Type)
+	return err
+}
+
+// GetReactionSummaries aggregates the reactions of every target in ids with a
+// single query. viewerID may be empty for anonymous callers, in which case no
+// reaction is reported as the viewer's own.
+func (r *ReactionRepository) GetReactionSummaries(
+	ctx context.Context,
+	target domain.ReactionTarget,
+	ids []uuid.UUID,
+	viewerID string,
+) (map[string]*domain.ReactionSummary, error) {
+	tracer := otel.Tracer("repo.reactions")
+	ctx, span := tracer.Start(ctx, "ReactionRepository.GetReactionSummaries")
+	defer span.End()
+
+	summaries := make(map[string]*domain.ReactionSummary, len(ids))
+	if len(ids) == 0 {
+		return summaries, nil
+	}
+
+	var viewer *uuid.UUID
+	if parsed, err := uuid.Parse(viewerID); err == nil {
+		viewer = &parsed
+	}
+
+	query := `
+		SELECT
+			target_id,
+			type,
+			COUNT(*),
+			COALESCE(BOOL_OR(user_id = $3), false)
+		FROM reactions
+		WHERE target_type = $1 AND target_id = ANY($2)
+		GROUP BY target_id, type
+		ORDER BY target_id, type`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	span.SetAttributes(attribute.Int("query.targets", len(ids)))
+	rows, err := r.Conn.Query(ctx, query, target, ids, viewer)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			targetID     uuid.UUID
+			reactionType domain.ReactionType
+			count        int64
+			mine         bool
+		)
+		if err := rows.Scan(&targetID, &reactionType, &count, &mine); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		summary, ok := summaries[targetID.String()]
+		if !ok {
+			summary = domain.NewReactionSummary()
+			summaries[targetID.String()] = summary
+		}
+		summary.Counts[reactionType] = count
+		if mine {
+			summary.Mine = append(summary.Mine, reactionType)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return summaries, nil
+}
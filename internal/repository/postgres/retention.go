@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type RetentionRepository struct {
+	Conn *pgxpool.Pool
+}
+
+func NewRetentionRepository(conn *pgxpool.Pool) *RetentionRepository {
+	return &RetentionRepository{Conn: conn}
+}
+
+// purgeTarget describes how to hard-delete the expired rows of an entity
+// aliased x. expired holds back rows that still have live or not yet expired
+// dependents; leaf additionally holds back rows with any dependents at all,
+// so deleting never cascades to rows the batch did not pick and count. The
+// rows of the owned tables whose user_id is x are deleted and counted with
+// it instead.
+type purgeTarget struct {
+	table          string
+	expired        string
+	leaf           string
+	owned          []string
+	reactionTarget domain.ReactionTarget
+}
+
+var purgeTargets = map[domain.TrashEntity]purgeTarget{
+	domain.TrashComments: {
+		table: "comments",
+		expired: `x.deleted_at < $1 AND NOT EXISTS (
+			SELECT 1 FROM comments r WHERE r.parent_id = x.id AND (r.deleted_at IS NULL OR r.deleted_at >= $1))`,
+		leaf:           `NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = x.id)`,
+		reactionTarget: domain.ReactionTargetComment,
+	},
+	domain.TrashPosts: {
+		table: "posts",
+		expired: `x.deleted_at < $1 AND NOT EXISTS (
+			SELECT 1 FROM comments c WHERE c.post_id = x.id AND (c.deleted_at IS NULL OR c.deleted_at >= $1))`,
+		leaf:           `NOT EXISTS (SELECT 1 FROM comments c WHERE c.post_id = x.id)`,
+		reactionTarget: domain.ReactionTargetPost,
+	},
+	domain.TrashUsers: {
+		table: "users",
+		expired: `x.deleted_at < $1 AND NOT EXISTS (
+			SELECT 1 FROM comments c WHERE c.user_id = x.id AND (c.deleted_at IS NULL OR c.deleted_at >= $1))`,
+		leaf:  `NOT EXISTS (SELECT 1 FROM comments c WHERE c.user_id = x.id)`,
+		owned: []string{"csv_jobs", "reactions", "notifications"},
+	},
+}
+
+func lookupPurgeTarget(entity domain.TrashEntity) (purgeTarget, error) {
+	target, ok := purgeTargets[entity]
+	if !ok {
+		return purgeTarget{}, fmt.Errorf("%w: unknown retention entity %q", domain.ErrBadParamInput, entity)
+	}
+	return target, nil
+}
+
+// scanPurgeCount reads the count of records followed by one count per owned
+// table
+func (target purgeTarget) scanPurgeCount(row pgx.Row) (domain.PurgeCount, error) {
+	var records int
+	owned := make([]int, len(target.owned))
+	dest := []any{&records}
+	for i := range owned {
+		dest = append(dest, &owned[i])
+	}
+	if err := row.Scan(dest...); err != nil {
+		return domain.PurgeCount{}, err
+	}
+
+	count := domain.PurgeCount{Records: records}
+	for i, table := range target.owned {
+		if count.Owned == nil {
+			count.Owned = map[string]int{}
+		}
+		count.Owned[table] = owned[i]
+	}
+	return count, nil
+}
+
+// CountExpired returns how many rows of entity deleted before the cutoff
+// are eligible for purging, and how many rows they own
+func (r *RetentionRepository) CountExpired(ctx context.Context, entity domain.TrashEntity, before time.Time) (domain.PurgeCount, error) {
+	target, err := lookupPurgeTarget(entity)
+	if err != nil {
+		return domain.PurgeCount{}, err
+	}
+
+	counts := `(SELECT COUNT(*) FROM expired)`
+	for _, table := range target.owned {
+		counts += `, (SELECT COUNT(*) FROM ` + table + ` WHERE user_id IN (SELECT id FROM expired))`
+	}
+	query := `
+		WITH expired AS (
+			SELECT x.id FROM ` + target.table + ` x WHERE ` + target.expired + `
+		)
+		SELECT ` + counts
+
+	return target.scanPurgeCount(r.Conn.QueryRow(ctx, query, before))
+}
+
+// PurgeExpired hard-deletes up to limit rows of entity deleted before the
+// cutoff, oldest deletions first, together with the reactions on them and
+// the rows they own. Rows locked by a concurrent purge are skipped. It
+// returns the number of rows deleted; callers repeat until it is zero, as
+// every batch can free rows that were waiting on their dependents.
+func (r *RetentionRepository) PurgeExpired(ctx context.Context, entity domain.TrashEntity, before time.Time, limit int) (domain.PurgeCount, error) {
+	tracer := otel.Tracer("repo.retention")
+	ctx, span := tracer.Start(ctx, "RetentionRepository.PurgeExpired")
+	defer span.End()
+
+	target, err := lookupPurgeTarget(entity)
+	if err != nil {
+		return domain.PurgeCount{}, err
+	}
+
+	reactionsPurged := ``
+	if target.reactionTarget != "" {
+		reactionsPurged = `, reactions_purged AS (
+			DELETE FROM reactions
+			WHERE target_type = '` + string(target.reactionTarget) + `' AND target_id IN (SELECT id FROM purged)
+		)`
+	}
+	// The counts are of the rows each statement deleted, a cascade at the
+	// end of the statement finds nothing left to delete
+	ownedPurged := ``
+	counts := `(SELECT COUNT(*) FROM purged)`
+	for i, table := range target.owned {
+		name := `owned_` + strconv.Itoa(i)
+		ownedPurged += `, ` + name + ` AS (
+			DELETE FROM ` + table + `
+			WHERE user_id IN (SELECT id FROM batch)
+			RETURNING 1
+		)`
+		counts += `, (SELECT COUNT(*) FROM ` + name + `)`
+	}
+
+	query := `
+		WITH batch AS (
+			SELECT x.id
+			FROM ` + target.table + ` x
+			WHERE ` + target.expired + ` AND ` + target.leaf + `
+			ORDER BY x.deleted_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), purged AS (
+			DELETE FROM ` + target.table + `
+			WHERE id IN (SELECT id FROM batch)
+			RETURNING id
+		)` + reactionsPurged + ownedPurged + `
+		SELECT ` + counts
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	purged, err := target.scanPurgeCount(r.Conn.QueryRow(ctx, query, before, limit))
+	if err != nil {
+		span.RecordError(err)
+		return domain.PurgeCount{}, err
+	}
+	return purged, nil
+}
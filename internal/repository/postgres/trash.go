@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type TrashRepository struct {
+	Conn *pgxpool.Pool
+}
+
+func NewTrashRepository(conn *pgxpool.Pool) *TrashRepository {
+	return &TrashRepository{Conn: conn}
+}
+
+// trashQueries selects the soft-deleted rows of an entity as trash items:
+// entity, id, label, owner_id, parent_id, deleted_at
+var trashQueries = map[domain.TrashEntity]string{
+	domain.TrashUsers: `
+		SELECT 'users', id, name, NULL::uuid, NULL::uuid, deleted_at
+		FROM users
+		WHERE deleted_at IS NOT NULL`,
+	domain.TrashPosts: `
+		SELECT 'posts', id, title, user_id, NULL::uuid, deleted_at
+		FROM posts
+		WHERE deleted_at IS NOT NULL`,
+	domain.TrashComments: `
+		SELECT 'comments', id, LEFT(body, 100), user_id, post_id, deleted_at
+		FROM comments
+		WHERE deleted_at IS NOT NULL`,
+}
+
+// GetTrash returns a page of the soft-deleted records of entity, most
+// recently deleted first, with their total count
+func (r *TrashRepository) GetTrash(ctx context.Context, entity domain.TrashEntity, filter *domain.TrashFilter) ([]domain.TrashItem, int, error) {
+	tracer := otel.Tracer("repo.trash")
+	ctx, span := tracer.Start(ctx, "TrashRepository.GetTrash")
+	defer span.End()
+
+	selectQuery, ok := trashQueries[entity]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: unknown trash entity %q", domain.ErrBadParamInput, entity)
+	}
+
+	countQuery := `SELECT COUNT(*) FROM (` + selectQuery + `) t`
+	var total int
+	if err := r.Conn.QueryRow(ctx, countQuery).Scan(&total); err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+
+	query := selectQuery + `
+		ORDER BY deleted_at DESC, id
+		LIMIT $1 OFFSET $2`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	rows, err := r.Conn.Query(ctx, query, filter.Limit, (filter.Page-1)*filter.Limit)
+	if err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+
+	items, err := pgx.CollectRows(rows, scanTrashItem)
+	if err != nil {
+		span.RecordError(err)
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// CountTrash returns the number of soft-deleted records of every entity
+func (r *TrashRepository) CountTrash(ctx context.Context) (domain.TrashSummary, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM users WHERE deleted_at IS NOT NULL),
+			(SELECT COUNT(*) FROM posts WHERE deleted_at IS NOT NULL),
+			(SELECT COUNT(*) FROM comments WHERE deleted_at IS NOT NULL)`
+
+	var users, posts, comments int
+	if err := r.Conn.QueryRow(ctx, query).Scan(&users, &posts, &comments); err != nil {
+		return nil, err
+	}
+	return domain.TrashSummary{
+		domain.TrashUsers:    users,
+		domain.TrashPosts:    posts,
+		domain.TrashComments: comments,
+	}, nil
+}
+
+func scanTrashItem(row pgx.CollectableRow) (domain.TrashItem, error) {
+	var item domain.TrashItem
+	err := row.Scan(
+		&item.Entity,
+		&item.ID,
+		&item.Label,
+		&item.OwnerID,
+		&item.ParentID,
+		&item.DeletedAt,
+	)
+	return item, err
+}
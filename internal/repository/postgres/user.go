@@ -2,7 +2,6 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"strings"
 
@@ -10,12 +9,17 @@ import (
 	"github.com/edwinjordan/MajooTest-Golang/utils"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 )
 
+// uniqueViolation is the SQLSTATE Postgres reports for a duplicate key
+const uniqueViolation = "23505"
+
 type UserRepository struct {
 	Conn *pgxpool.Pool
 }
@@ -24,39 +28,220 @@ func NewUserRepository(conn *pgxpool.Pool) *UserRepository {
 	return &UserRepository{Conn: conn}
 }
 
+// insertUserQuery inserts a user from name, email, password hash, username
+// and the CSV job and row importing it. Without an explicit username one is
+// derived from the email address, with a short random suffix when that one
+// is already taken.
+const insertUserQuery = `
+		WITH candidate AS (
+			SELECT LEFT(LOWER(REGEXP_REPLACE(SPLIT_PART($2, '@', 1), '[^a-zA-Z0-9_]', '_', 'g')), 25) AS name
+		)
+		INSERT INTO users (name, email, password, username, import_job_id, import_row, created_at, updated_at)
+		SELECT $1, $2, $3,
+			CASE
+				WHEN $4 <> '' THEN $4
+				WHEN EXISTS (SELECT 1 FROM users WHERE LOWER(username) = candidate.name) OR LENGTH(candidate.name) < 3
+					THEN candidate.name || '_' || SUBSTR(MD5(RANDOM()::text), 1, 4)
+				ELSE candidate.name
+			END,
+			$5::uuid, $6::int,
+			NOW(), NOW()
+		FROM candidate`
+
+// CreateUser skips users of a CSV row that was imported already
 func (u *UserRepository) CreateUser(ctx context.Context, user *domain.CreateUserRequest) (*domain.User, error) {
-	query := `
-		INSERT INTO users (name, email, password, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		RETURNING id`
+	query := insertUserQuery + `
+		ON CONFLICT (import_job_id, import_row) WHERE import_job_id IS NOT NULL DO NOTHING
+		RETURNING id, username`
 
 	hashedPassword, err := utils.HashPassword(user.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	var id uuid.UUID
+	var (
+		id       uuid.UUID
+		username string
+	)
 
-	err = u.Conn.QueryRow(ctx, query, user.Name, user.Email, hashedPassword).Scan(
+	var importJob *string
+	var importRow *int
+	if key := domain.CSVRowFromContext(ctx); key != nil {
+		importJob, importRow = &key.JobID, &key.Row
+	}
+	err = u.Conn.QueryRow(ctx, query, user.Name, user.Email, hashedPassword, user.Username, importJob, importRow).Scan(
 		&id,
+		&username,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCSVRowImported
+	}
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == "idx_users_username" {
+			return nil, domain.ErrConflict
+		}
 		return nil, err
 	}
 
 	return &domain.User{
-		ID:    id.String(),
-		Name:  user.Name,
-		Email: user.Email,
+		ID:       id.String(),
+		Name:     user.Name,
+		Email:    user.Email,
+		Username: username,
+		Role:     domain.RoleUser,
 	}, nil
 }
 
+// ImportUser upserts a user of an HR export. A live user with the same
+// username, or else the same email, is updated and keeps its password, and
+// its email and recovery code unless user.UpdateCredentials is set;
+// otherwise the user is created. A deleted user holding the username or
+// email makes it fail with domain.ErrConflict.
+func (u *UserRepository) ImportUser(ctx context.Context, user *domain.UserImport) (*domain.User, error) {
+	tracer := otel.Tracer("repo.user")
+	ctx, span := tracer.Start(ctx, "UserRepository.ImportUser")
+	defer span.End()
+
+	query := `
+		WITH existing AS (
+			SELECT id FROM users
+			WHERE deleted_at IS NULL AND (LOWER(username) = LOWER($4) OR email = $2)
+			ORDER BY LOWER(username) = LOWER($4) DESC
+			LIMIT 1
+			FOR UPDATE
+		), updated AS (
+			UPDATE users
+			SET name = $1,
+				email = CASE WHEN $9 THEN $2 ELSE users.email END,
+				employee_id = $5,
+				department = $6,
+				location = $7,
+				recovery_code = CASE WHEN $9 THEN $8 ELSE users.recovery_code END,
+				version = users.version + 1,
+				updated_at = NOW()
+			FROM existing
+			WHERE users.id = existing.id
+			RETURNING users.id, users.name, users.email, users.username, users.department, users.location, users.role, users.version, users.created_at, users.updated_at
+		), inserted AS (
+			INSERT INTO users (name, email, password, username, employee_id, department, location, recovery_code, created_at, updated_at)
+			SELECT $1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW()
+			WHERE NOT EXISTS (SELECT 1 FROM existing)
+			RETURNING id, name, email, username, department, location, role, version, created_at, updated_at
+		)
+		SELECT * FROM updated
+		UNION ALL
+		SELECT * FROM inserted`
+
+	hashedPassword, err := utils.HashPassword(user.Password)
+	if err != nil {
+		return nil, err
+	}
+	var hashedRecoveryCode string
+	if user.RecoveryCode != "" {
+		if hashedRecoveryCode, err = utils.HashPassword(user.RecoveryCode); err != nil {
+			return nil, err
+		}
+	}
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	var imported domain.User
+	scan := func() error {
+		return u.Conn.QueryRow(ctx, query,
+			user.Name, user.Email, hashedPassword, user.Username,
+			user.EmployeeID, user.Department, user.Location, hashedRecoveryCode, user.UpdateCredentials,
+		).Scan(
+			&imported.ID,
+			&imported.Name,
+			&imported.Email,
+			&imported.Username,
+			&imported.Department,
+			&imported.Location,
+			&imported.Role,
+			&imported.Version,
+			&imported.CreatedAt,
+			&imported.UpdatedAt,
+		)
+	}
+
+	// Two rows of the same user imported at once both find nothing to
+	// update, the one that loses the insert updates on the second try
+	err = scan()
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		err = scan()
+	}
+	if err != nil {
+		span.RecordError(err)
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, domain.ErrConflict
+		}
+		return nil, err
+	}
+
+	return &imported, nil
+}
+
+// FindUserByLogin returns the live user ImportUser would update, the one
+// with the username or else the one with the email
+func (u *UserRepository) FindUserByLogin(ctx context.Context, username, email string) (*domain.User, error) {
+	tracer := otel.Tracer("repo.user")
+	ctx, span := tracer.Start(ctx, "UserRepository.FindUserByLogin")
+	defer span.End()
+
+	query := `
+		SELECT
+			id,
+			name,
+			email,
+			username,
+			department,
+			location,
+			role,
+			version,
+			created_at,
+			updated_at
+		FROM users
+		WHERE deleted_at IS NULL AND (($1 <> '' AND LOWER(username) = LOWER($1)) OR email = $2)
+		ORDER BY LOWER(username) = LOWER($1) DESC
+		LIMIT 1`
+
+	span.SetAttributes(attribute.String("query.statement", query))
+	var user domain.User
+	err := u.Conn.QueryRow(ctx, query, username, email).Scan(
+		&user.ID,
+		&user.Name,
+		&user.Email,
+		&user.Username,
+		&user.Department,
+		&user.Location,
+		&user.Role,
+		&user.Version,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 func (u *UserRepository) GetUserList(ctx context.Context, filter *domain.UserFilter) ([]domain.User, error) {
 	query := `
 		SELECT
 			u.id,
 			u.name,
 			u.email,
+			u.username,
+			u.department,
+			u.location,
+			u.role,
+            u.version,
             u.created_at,
             u.updated_at
 		FROM users u
@@ -70,7 +255,7 @@ func (u *UserRepository) GetUserList(ctx context.Context, filter *domain.UserFil
 	}
 
 	if len(conditions) > 0 {
-		query += strings.Join(conditions, " AND ")
+		query += " AND " + strings.Join(conditions, " AND ")
 	}
 	rows, err := u.Conn.Query(ctx, query, args...)
 	if err != nil {
@@ -86,6 +271,11 @@ func (u *UserRepository) GetUserList(ctx context.Context, filter *domain.UserFil
 			&user.ID,
 			&user.Name,
 			&user.Email,
+			&user.Username,
+			&user.Department,
+			&user.Location,
+			&user.Role,
+			&user.Version,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
@@ -108,6 +298,11 @@ func (u *UserRepository) GetUser(ctx context.Context, id uuid.UUID) (*domain.Use
 			id,
 			name,
 			email,
+			username,
+			department,
+			location,
+			role,
+			version,
 			created_at,
 			updated_at
 		FROM users
@@ -122,9 +317,17 @@ func (u *UserRepository) GetUser(ctx context.Context, id uuid.UUID) (*domain.Use
 		&user.ID,
 		&user.Name,
 		&user.Email,
+		&user.Username,
+		&user.Department,
+		&user.Location,
+		&user.Role,
+		&user.Version,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrUserNotFound
+	}
 	if err != nil {
 		span.RecordError(err)
 		//	u.Metrics.UserRepoCalls.WithLabelValues("GetUser", "error").Inc()
@@ -140,20 +343,29 @@ func (u *UserRepository) UpdateUser(ctx context.Context, id uuid.UUID, user *dom
 		UPDATE users
 		SET name = $1,
 			email = $2,
+			version = version + 1,
 			updated_at = NOW()
-		WHERE id = $3 AND deleted_at IS NULL
-		RETURNING id, name, email, created_at, updated_at`
+		WHERE id = $3 AND deleted_at IS NULL AND ($4 = 0 OR version = $4)
+		RETURNING id, name, email, username, department, location, role, version, created_at, updated_at`
 
 	var updatedUser domain.User
-	err := u.Conn.QueryRow(ctx, query, user.Name, user.Email, id).Scan(
+	err := u.Conn.QueryRow(ctx, query, user.Name, user.Email, id, user.Version).Scan(
 		&updatedUser.ID,
 		&updatedUser.Name,
 		&updatedUser.Email,
+		&updatedUser.Username,
+		&updatedUser.Department,
+		&updatedUser.Location,
+		&updatedUser.Role,
+		&updatedUser.Version,
 		&updatedUser.CreatedAt,
 		&updatedUser.UpdatedAt,
 	)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, pgx.ErrNoRows) && user.Version > 0 {
+			return nil, domain.ErrPreconditionFailed
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, domain.ErrUserNotFound
 		}
 		return nil, err
@@ -162,20 +374,216 @@ func (u *UserRepository) UpdateUser(ctx context.Context, id uuid.UUID, user *dom
 	return &updatedUser, nil
 }
 
-func (u *UserRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+// PatchUser updates only the columns supplied in patch. A non-zero version
+// must match the stored one, otherwise domain.ErrPreconditionFailed is
+// returned. A taken email or username gives domain.ErrConflict.
+func (u *UserRepository) PatchUser(ctx context.Context, id uuid.UUID, version int, patch *domain.UserPatch) (*domain.User, error) {
+	tracer := otel.Tracer("repo.user")
+	ctx, span := tracer.Start(ctx, "UserRepository.PatchUser")
+	defer span.End()
+
+	var set assignments
+	if patch.Name != nil {
+		set.set("name", *patch.Name)
+	}
+	if patch.Email != nil {
+		set.set("email", *patch.Email)
+	}
+	if patch.Username != nil {
+		set.set("username", *patch.Username)
+	}
+	if patch.Department != nil {
+		set.set("department", *patch.Department)
+	}
+	if patch.Location != nil {
+		set.set("location", *patch.Location)
+	}
+
+	idArg, versionArg := set.arg(id), set.arg(version)
 	query := `
 		UPDATE users
-		SET deleted_at = NOW()
-		WHERE id = $1 AND deleted_at IS NULL`
+		SET ` + set.String() + `,
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = ` + idArg + ` AND deleted_at IS NULL AND (` + versionArg + ` = 0 OR version = ` + versionArg + `)
+		RETURNING id, name, email, username, department, location, role, version, created_at, updated_at`
 
-	result, err := u.Conn.Exec(ctx, query, id)
+	span.SetAttributes(attribute.String("query.statement", query))
+	var user domain.User
+	err := u.Conn.QueryRow(ctx, query, set.args...).Scan(
+		&user.ID,
+		&user.Name,
+		&user.Email,
+		&user.Username,
+		&user.Department,
+		&user.Location,
+		&user.Role,
+		&user.Version,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
 	if err != nil {
-		return err
+		span.RecordError(err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, domain.ErrConflict
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			if version > 0 {
+				return nil, domain.ErrPreconditionFailed
+			}
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
 	}
 
-	if result.RowsAffected() == 0 {
-		return domain.ErrUserNotFound
+	return &user, nil
+}
+
+// DeleteUser soft-deletes a user. A non-zero version must match the stored
+// one, otherwise domain.ErrPreconditionFailed is returned.
+func (u *UserRepository) DeleteUser(ctx context.Context, id uuid.UUID, version int) error {
+	var deleted uuid.UUID
+	if err := u.Conn.QueryRow(ctx, deleteUserQuery, id, version).Scan(&deleted); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			if version > 0 {
+				return domain.ErrPreconditionFailed
+			}
+			return domain.ErrUserNotFound
+		}
+		return err
 	}
 
 	return nil
 }
+
+// deleteUserQuery takes the user id and the version it must be at, zero for
+// any
+const deleteUserQuery = `
+		UPDATE users
+		SET deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)
+		RETURNING id`
+
+// RestoreUser undeletes a user. It returns domain.ErrNotFound unless the user
+// exists and is deleted.
+func (u *UserRepository) RestoreUser(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL,
+			updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING id, name, email, username, department, location, role, version, created_at, updated_at`
+
+	var user domain.User
+	err := u.Conn.QueryRow(ctx, query, id).Scan(
+		&user.ID,
+		&user.Name,
+		&user.Email,
+		&user.Username,
+		&user.Department,
+		&user.Location,
+		&user.Role,
+		&user.Version,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ApplyUserBatch creates, updates and deletes users in one transaction,
+// results[i] reports on items[i]. Items are expected to be validated, see
+// applyBatch for how failures are handled. A taken email or username fails
+// its item instead of the whole batch.
+func (u *UserRepository) ApplyUserBatch(ctx context.Context, items []domain.UserBatchItem, atomic bool) ([]domain.BatchItemResult[domain.User], error) {
+	tracer := otel.Tracer("repo.user")
+	ctx, span := tracer.Start(ctx, "UserRepository.ApplyUserBatch")
+	defer span.End()
+	span.SetAttributes(attribute.Int("batch.size", len(items)), attribute.Bool("batch.atomic", atomic))
+
+	createQuery := insertUserQuery + `
+		ON CONFLICT DO NOTHING
+		RETURNING id, username, version, created_at, updated_at`
+	updateQuery := `
+		UPDATE users
+		SET name = $1,
+			email = $2,
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = $3 AND deleted_at IS NULL AND ($4 = 0 OR version = $4)
+			AND NOT EXISTS (SELECT 1 FROM users o WHERE o.email = $2 AND o.id <> $3)
+		RETURNING id, name, email, username, department, location, role, version, created_at, updated_at`
+
+	batch := &pgx.Batch{}
+	results := make([]domain.BatchItemResult[domain.User], len(items))
+	for i, item := range items {
+		results[i] = domain.BatchItemResult[domain.User]{Index: i, Op: item.Op, ID: item.ID}
+		switch item.Op {
+		case domain.BatchOpCreate:
+			hashedPassword, err := utils.HashPassword(item.Password)
+			if err != nil {
+				return nil, err
+			}
+			batch.Queue(createQuery, item.Name, item.Email, hashedPassword, item.Username, nil, nil)
+		case domain.BatchOpUpdate:
+			batch.Queue(updateQuery, item.Name, item.Email, item.ID, item.Version)
+		case domain.BatchOpDelete:
+			batch.Queue(deleteUserQuery, item.ID, item.Version)
+		}
+	}
+
+	err := applyBatch(ctx, u.Conn, batch, atomic, results, func(row pgx.Row, result *domain.BatchItemResult[domain.User]) error {
+		item := items[result.Index]
+		var err error
+		switch item.Op {
+		case domain.BatchOpCreate:
+			user := domain.User{Name: item.Name, Email: item.Email, Role: domain.RoleUser}
+			err = row.Scan(&user.ID, &user.Username, &user.Version, &user.CreatedAt, &user.UpdatedAt)
+			if errors.Is(err, pgx.ErrNoRows) {
+				result.Fail("email or username already taken")
+				return nil
+			}
+			result.ID, result.Data = user.ID, &user
+		case domain.BatchOpUpdate:
+			var user domain.User
+			err = row.Scan(
+				&user.ID,
+				&user.Name,
+				&user.Email,
+				&user.Username,
+				&user.Department,
+				&user.Location,
+				&user.Role,
+				&user.Version,
+				&user.CreatedAt,
+				&user.UpdatedAt,
+			)
+			if errors.Is(err, pgx.ErrNoRows) {
+				result.Fail(batchMissReason("user", item.Version) + ", or email already taken")
+				return nil
+			}
+			result.Data = &user
+		case domain.BatchOpDelete:
+			var id uuid.UUID
+			err = row.Scan(&id)
+			if errors.Is(err, pgx.ErrNoRows) {
+				result.Fail(batchMissReason("user", item.Version))
+				return nil
+			}
+		}
+		result.OK = err == nil
+		return err
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+func TestUserRepository_CreateUser_ImportedRow(t *testing.T) {
+	conn := testPool(t)
+	repo := NewUserRepository(conn)
+	jobID := uuid.NewString()
+	ctx := domain.WithCSVRow(context.Background(), domain.CSVRowKey{JobID: jobID, Row: 1})
+	t.Cleanup(func() {
+		conn.Exec(context.Background(), `DELETE FROM users WHERE import_job_id = $1`, jobID)
+	})
+
+	request := &domain.CreateUserRequest{Name: "Ana", Email: "ana-" + jobID + "@example.com", Password: "Secret123!"}
+	created, err := repo.CreateUser(ctx, request)
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+
+	// The job runs the row again after a crash
+	_, err = repo.CreateUser(ctx, request)
+	assert.ErrorIs(t, err, domain.ErrCSVRowImported)
+}
@@ -33,27 +33,21 @@ func NewAuthHandler(e *echo.Group, svc AuthService) {
 //	@Produce        json
 //	@Param          json    body        domain.LoginRequest                     true         "User signin credentials"
 //	@Success        200     {object}    domain.ResponseSingleData[domain.LoginResponse]      "Successfully logged in"
-//	@Failure        400     {object}    domain.ResponseSingleData[domain.Empty]              "Bad request"
-//	@Failure        401     {object}    domain.ResponseSingleData[domain.Empty]              "Unauthorized"
-//	@Failure        500     {object}    domain.ResponseSingleData[domain.Empty]              "Internal server error"
+//	@Failure        400     {object}    domain.Problem                                       "Bad request"
+//	@Failure        401     {object}    domain.Problem                                       "Unauthorized"
+//	@Failure        500     {object}    domain.Problem                                       "Internal server error"
 //	@Router         /api/v1/auth/login [post]
 func (h *AuthHandler) Login(c echo.Context) error {
 	var req domain.LoginRequest
 
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid request payload",
-		})
+		return badRequest("Invalid request payload")
 	}
 
 	ctx := c.Request().Context()
 	result, err := h.Service.Login(ctx, req.Email, req.Password)
 	if err != nil {
-		return c.JSON(http.StatusUnauthorized, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusUnauthorized,
-			Message: "Invalid email or password",
-		})
+		return err
 	}
 
 	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.LoginResponse]{
@@ -0,0 +1,22 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+// batchStatus picks the response status of a processed batch: 200 when every
+// item was applied, 207 when a best effort batch applied only some of them
+// and 422 when an atomic batch was rolled back
+func batchStatus[Data any](result *domain.BatchResult[Data]) (int, string) {
+	switch {
+	case result.Failed == 0:
+		return http.StatusOK, "Batch successfully applied"
+	case result.Mode == domain.BatchModeAtomic:
+		return http.StatusUnprocessableEntity, "Batch rolled back, " + strconv.Itoa(result.Failed) + " items failed"
+	default:
+		return http.StatusMultiStatus, "Batch partially applied, " + strconv.Itoa(result.Failed) + " items failed"
+	}
+}
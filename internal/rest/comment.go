@@ -2,13 +2,14 @@ package rest
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/edwinjordan/MajooTest-Golang/domain"
 	"github.com/edwinjordan/MajooTest-Golang/internal/logging"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"go.opentelemetry.io/otel"
@@ -21,7 +22,13 @@ type CommentService interface {
 	GetCommentList(ctx context.Context, filter *domain.CommentFilter) ([]domain.Comment, error)
 	GetComment(ctx context.Context, id uuid.UUID) (*domain.Comment, error)
 	UpdateComment(ctx context.Context, id uuid.UUID, comment *domain.Comment) (*domain.Comment, error)
+	PatchComment(ctx context.Context, id uuid.UUID, patch *domain.CommentPatch) (*domain.Comment, error)
 	DeleteComment(ctx context.Context, id uuid.UUID) error
+	RestoreComment(ctx context.Context, id uuid.UUID) (*domain.RestoredComment, error)
+	BatchComments(ctx context.Context, req *domain.BatchRequest[domain.CommentBatchItem]) (*domain.BatchResult[domain.Comment], error)
+	ReplyToComment(ctx context.Context, parentID uuid.UUID, reply *domain.CreateReplyRequest) (*domain.Comment, error)
+	GetCommentThreads(ctx context.Context, postID uuid.UUID, filter *domain.CommentThreadFilter) ([]domain.Comment, int, error)
+	GetPostComments(ctx context.Context, postID uuid.UUID, filter *domain.CommentThreadFilter) ([]domain.Comment, int, error)
 }
 
 type CommentHandler struct {
@@ -36,8 +43,22 @@ func NewCommentHandler(e *echo.Group, svc CommentService) {
 	e.GET("", handler.GetCommentList)
 	e.GET("/:id", handler.GetComment)
 	e.POST("", handler.CreateComment)
-	e.PUT("/:id", handler.UpdateComment)
-	e.DELETE("/:id", handler.DeleteComment)
+	e.PUT("/:id", handler.UpdateComment, middleware.IfMatch())
+	e.PATCH("/:id", handler.PatchComment, middleware.IfMatch())
+	e.DELETE("/:id", handler.DeleteComment, middleware.IfMatch())
+	e.POST("/:id/replies", handler.ReplyToComment)
+	e.POST("/:id/restore", handler.RestoreComment, middleware.RequireRole(domain.RoleAdmin))
+	e.POST(`\:batch`, handler.BatchComments)
+}
+
+// NewPostCommentHandler registers the comment routes nested under a post,
+// e.g. GET /posts/:id/comments
+func NewPostCommentHandler(e *echo.Group, svc CommentService) {
+	handler := &CommentHandler{
+		Service: svc,
+	}
+	e.GET("/:id/comments", handler.GetPostComments)
+	e.POST("/:id/comments", handler.CreatePostComment)
 }
 
 // GetComments godoc
@@ -46,7 +67,7 @@ func NewCommentHandler(e *echo.Group, svc CommentService) {
 // @Tags comments
 // @Produce  json
 // @Success 200 {array} domain.Comment
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /comments [get]
 func (h *CommentHandler) GetCommentList(c echo.Context) error {
@@ -59,11 +80,7 @@ func (h *CommentHandler) GetCommentList(c echo.Context) error {
 
 	comments, err := h.Service.GetCommentList(ctx, filter)
 	if err != nil {
-		logging.LogError(ctx, err, "get_comments_list")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseMultipleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to list comments: " + err.Error(),
-		})
+		return forResource("comment", err)
 	}
 	if comments == nil {
 		comments = []domain.Comment{}
@@ -82,8 +99,10 @@ func (h *CommentHandler) GetCommentList(c echo.Context) error {
 // @Tags comments
 // @Produce  json
 // @Param        id   path      int  true  "Post ID"
+// @Param   If-None-Match  header  string  false  "ETag from an earlier response"
 // @Success 200 {array} domain.Comment
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Success 304 "Not modified since the ETag in If-None-Match"
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /comments/{id} [get]
 func (h *CommentHandler) GetComment(c echo.Context) error {
@@ -96,33 +115,18 @@ func (h *CommentHandler) GetComment(c echo.Context) error {
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "invalid UUID")
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid comment ID format",
-		})
+		return badRequest("Invalid comment ID format")
 	}
 
 	span.SetAttributes(attribute.String("comment.id", id.String()))
 	comment, err := h.Service.GetComment(ctx, id)
 	if err != nil {
 		span.RecordError(err)
-		if errors.Is(err, sql.ErrNoRows) {
-			span.SetStatus(codes.Error, "not found")
-			return c.JSON(http.StatusNotFound, domain.ResponseSingleData[domain.Empty]{
-				Code:    http.StatusNotFound,
-				Message: "Comment not found",
-			})
-		}
-
 		span.SetStatus(codes.Error, "service error")
-		logging.LogError(ctx, err, "get_comment")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to get comment: " + err.Error(),
-		})
+		return forResource("comment", err)
 	}
 
-	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.Comment]{
+	return jsonWithETag(c, comment.Version, domain.ResponseSingleData[domain.Comment]{
 		Data:    *comment,
 		Code:    http.StatusOK,
 		Message: "Successfully retrieved comment",
@@ -136,33 +140,42 @@ func (h *CommentHandler) GetComment(c echo.Context) error {
 // @Accept  json
 // @Produce  json
 // @Param   comment  body  domain.CreateCommentRequest  true  "Comment data"
+// @Param   Idempotency-Key  header  string  false  "Replays the first response when the request is retried"
 // @Success 201 {object} domain.CreateCommentRequest
-// @Failure 400 {object} domain.ResponseSingleData[domain.Empty]
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Failure 400 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 409 {object} domain.Problem
+// @Failure 422 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
 // @Router /comments [post]
 func (h *CommentHandler) CreateComment(c echo.Context) error {
 	var comment domain.CreateCommentRequest
 	if err := c.Bind(&comment); err != nil {
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid request payload",
-		})
+		return badRequest("Invalid request payload")
 	}
 
+	return h.createComment(c, &comment)
+}
+
+func (h *CommentHandler) createComment(c echo.Context, comment *domain.CreateCommentRequest) error {
 	ctx := c.Request().Context()
-	createdComment, err := h.Service.CreateComment(ctx, &comment)
+	createdComment, err := h.Service.CreateComment(ctx, comment)
 	if err != nil {
-		logging.LogError(ctx, err, "create_comment")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to create comment: " + err.Error(),
-		})
+		if errors.Is(err, domain.ErrNotFound) {
+			return withDetail(err, "Post or parent comment not found")
+		}
+		return forResource("comment", err)
+	}
+
+	message := "Comment successfully created"
+	if createdComment.Status != "" && createdComment.Status != domain.ModerationStatusApproved {
+		message = "Comment submitted for moderation"
 	}
 
 	return c.JSON(http.StatusCreated, domain.ResponseSingleData[domain.Comment]{
 		Data:    *createdComment,
 		Code:    http.StatusCreated,
-		Message: "Comment successfully created",
+		Message: message,
 	})
 }
 
@@ -174,40 +187,34 @@ func (h *CommentHandler) CreateComment(c echo.Context) error {
 // @Produce  json
 // @Param   id    path  string             true  "Comment ID"
 // @Param   post  body  domain.UpdateCommentRequest  true  "Updated comment data"
+// @Param   If-Match  header  string  false  "ETag of the version being changed"
 // @Success 200 {object} domain.Comment
-// @Failure 400 {object} domain.ResponseSingleData[domain.Empty]
-// @Failure 404 {object} domain.ResponseSingleData[domain.Empty]
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Failure 400 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 412 {object} domain.Problem
+// @Failure 428 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /comments/{id} [put]
 func (h *CommentHandler) UpdateComment(c echo.Context) error {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid post ID format",
-		})
+		return badRequest("Invalid post ID format")
 	}
 
 	var comment domain.Comment
 	if err := c.Bind(&comment); err != nil {
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid request payload",
-		})
+		return badRequest("Invalid request payload")
 	}
 
 	ctx := c.Request().Context()
 	updatedComment, err := h.Service.UpdateComment(ctx, id, &comment)
 	if err != nil {
-		logging.LogError(ctx, err, "update_comment")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to update comment: " + err.Error(),
-		})
+		return forResource("comment", err)
 	}
 
+	c.Response().Header().Set("ETag", middleware.ETag(updatedComment.Version))
 	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.Comment]{
 		Data:    *updatedComment,
 		Code:    http.StatusOK,
@@ -215,34 +222,77 @@ func (h *CommentHandler) UpdateComment(c echo.Context) error {
 	})
 }
 
+// PatchComment godoc
+// @Summary Patch comment
+// @Description Change only the supplied fields of a comment, see RFC 7396. Fields cannot be removed with null.
+// @Tags comments
+// @Accept  application/merge-patch+json
+// @Produce  json
+// @Param   id    path  string  true  "Comment ID"
+// @Param   patch  body  domain.CommentPatch  true  "Fields to change"
+// @Param   If-Match  header  string  false  "ETag of the version being changed"
+// @Success 200 {object} domain.ResponseSingleData[domain.Comment]
+// @Failure 400 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 412 {object} domain.Problem
+// @Failure 415 {object} domain.Problem
+// @Failure 428 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /comments/{id} [patch]
+func (h *CommentHandler) PatchComment(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return badRequest("Invalid comment ID format")
+	}
+
+	doc, err := readMergePatch(c)
+	if err != nil {
+		return invalidPatch(err)
+	}
+	patch, err := domain.ParseCommentPatch(doc)
+	if err != nil {
+		return invalidPatch(err)
+	}
+
+	ctx := c.Request().Context()
+	patched, err := h.Service.PatchComment(ctx, id, patch)
+	if err != nil {
+		return forResource("comment", err)
+	}
+
+	c.Response().Header().Set("ETag", middleware.ETag(patched.Version))
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.Comment]{
+		Data:    *patched,
+		Code:    http.StatusOK,
+		Message: "Comment successfully patched",
+	})
+}
+
 // DeleteComment godoc
 // @Summary Delete comment
 // @Description delete an existing comment entry by ID
 // @Tags comments
 // @Produce  json
 // @Param   id   path  string  true  "Comment ID"
+// @Param   If-Match  header  string  false  "ETag of the version being changed"
 // @Success 204 {object} nil
-// @Failure 404 {object} domain.ResponseSingleData[domain.Empty]
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Failure 404 {object} domain.Problem
+// @Failure 412 {object} domain.Problem
+// @Failure 428 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /comments/{id} [delete]
 func (h *CommentHandler) DeleteComment(c echo.Context) error {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid comment ID format",
-		})
+		return badRequest("Invalid comment ID format")
 	}
 
 	ctx := c.Request().Context()
 	if err := h.Service.DeleteComment(ctx, id); err != nil {
-		logging.LogError(ctx, err, "delete_comment")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to delete comment: " + err.Error(),
-		})
+		return forResource("comment", err)
 	}
 
 	return c.JSON(http.StatusNoContent, domain.ResponseSingleData[domain.Empty]{
@@ -250,3 +300,210 @@ func (h *CommentHandler) DeleteComment(c echo.Context) error {
 		Message: "User successfully deleted",
 	})
 }
+
+// ReplyToComment godoc
+// @Summary Reply to comment
+// @Description create a reply nested under an existing comment
+// @Tags comments
+// @Accept  json
+// @Produce  json
+// @Param   id     path  string                     true  "Parent comment ID"
+// @Param   reply  body  domain.CreateReplyRequest  true  "Reply data"
+// @Success 201 {object} domain.ResponseSingleData[domain.Comment]
+// @Failure 400 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 422 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /comments/{id}/replies [post]
+func (h *CommentHandler) ReplyToComment(c echo.Context) error {
+	parentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return badRequest("Invalid comment ID format")
+	}
+
+	var reply domain.CreateReplyRequest
+	if err := c.Bind(&reply); err != nil {
+		return badRequest("Invalid request payload")
+	}
+	if caller := middleware.GetCallerFromEcho(c); caller != nil {
+		reply.UserID = caller.ID
+	}
+
+	ctx := c.Request().Context()
+	createdReply, err := h.Service.ReplyToComment(ctx, parentID, &reply)
+	if err != nil {
+		return forResource("comment", err)
+	}
+
+	return c.JSON(http.StatusCreated, domain.ResponseSingleData[domain.Comment]{
+		Data:    *createdReply,
+		Code:    http.StatusCreated,
+		Message: "Reply successfully created",
+	})
+}
+
+// GetPostComments godoc
+// @Summary List comments of a post
+// @Description Get the comments of a post oldest first. With tree=true top level comments are paginated and each comment shows up to replies_limit replies nested underneath.
+// @Tags comments
+// @Produce  json
+// @Param   id             path   string  true   "Post ID"
+// @Param   tree           query  bool    false  "Return nested threads"
+// @Param   page           query  int     false  "Page" default(1)
+// @Param   limit          query  int     false  "Comments per page" default(20)
+// @Param   replies_limit  query  int     false  "Replies shown per comment in tree mode" default(5)
+// @Success 200 {object} domain.PaginatedResponse{data=[]domain.Comment}
+// @Failure 400 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /posts/{id}/comments [get]
+func (h *CommentHandler) GetPostComments(c echo.Context) error {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return badRequest("Invalid post ID format")
+	}
+
+	ctx := c.Request().Context()
+	filter := new(domain.CommentThreadFilter)
+	if err := c.Bind(filter); err != nil {
+		logging.LogWarn(ctx, "Failed to bind post comments filter", slog.String("error", err.Error()))
+	}
+
+	list := h.Service.GetPostComments
+	if tree, _ := strconv.ParseBool(c.QueryParam("tree")); tree {
+		list = h.Service.GetCommentThreads
+	}
+
+	comments, total, err := list(ctx, postID, filter)
+	if err != nil {
+		return forResource("comment", err)
+	}
+	if comments == nil {
+		comments = []domain.Comment{}
+	}
+
+	return c.JSON(http.StatusOK, domain.PaginatedResponse{
+		Data: comments,
+		Pagination: domain.PaginationInfo{
+			Page:       filter.Page,
+			Limit:      filter.Limit,
+			Total:      total,
+			TotalPages: (total + filter.Limit - 1) / filter.Limit,
+		},
+	})
+}
+
+// CreatePostComment godoc
+// @Summary Comment on a post
+// @Description create a new comment on the post in the path
+// @Tags comments
+// @Accept  json
+// @Produce  json
+// @Param   id       path  string                      true  "Post ID"
+// @Param   comment  body  domain.CreateCommentRequest  true  "Comment data"
+// @Success 201 {object} domain.ResponseSingleData[domain.Comment]
+// @Failure 400 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 422 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /posts/{id}/comments [post]
+func (h *CommentHandler) CreatePostComment(c echo.Context) error {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return badRequest("Invalid post ID format")
+	}
+
+	var comment domain.CreateCommentRequest
+	if err := c.Bind(&comment); err != nil {
+		return badRequest("Invalid request payload")
+	}
+	comment.PostID = postID.String()
+	if caller := middleware.GetCallerFromEcho(c); caller != nil {
+		comment.UserID = caller.ID
+	}
+
+	return h.createComment(c, &comment)
+}
+
+// RestoreComment godoc
+// @Summary Restore comment
+// @Description Undelete a soft-deleted comment together with the replies deleted with it, once its post and parent are live. Admins only.
+// @Tags comments
+// @Produce  json
+// @Param   id   path  string  true  "Comment ID"
+// @Success 200 {object} domain.ResponseSingleData[domain.RestoredComment]
+// @Failure 400 {object} domain.Problem
+// @Failure 403 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 409 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /comments/{id}/restore [post]
+func (h *CommentHandler) RestoreComment(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return badRequest("Invalid comment ID format")
+	}
+
+	ctx := c.Request().Context()
+	restored, err := h.Service.RestoreComment(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			return withDetail(err, "No deleted comment with this ID")
+		case errors.Is(err, domain.ErrConflict):
+			return withDetail(err, "Restore the post and parent comment of this comment first")
+		}
+		return forResource("comment", err)
+	}
+
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.RestoredComment]{
+		Data:    *restored,
+		Code:    http.StatusOK,
+		Message: "Comment successfully restored",
+	})
+}
+
+// BatchComments godoc
+// @Summary Batch comments
+// @Description Create, update and delete up to 500 comments in one request. An atomic batch applies every item or none, a best_effort batch applies the items it can. Every item is reported by its index, 207 means some items failed and 422 that an atomic batch was rolled back.
+// @Tags comments
+// @Accept  json
+// @Produce  json
+// @Param   batch  body  domain.BatchRequest[domain.CommentBatchItem]  true  "Batch items"
+// @Success 200 {object} domain.ResponseSingleData[domain.BatchResult[domain.Comment]]
+// @Success 207 {object} domain.ResponseSingleData[domain.BatchResult[domain.Comment]]
+// @Failure 400 {object} domain.Problem
+// @Failure 422 {object} domain.ResponseSingleData[domain.BatchResult[domain.Comment]]
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /comments:batch [post]
+func (h *CommentHandler) BatchComments(c echo.Context) error {
+	var req domain.BatchRequest[domain.CommentBatchItem]
+	if err := c.Bind(&req); err != nil {
+		return badRequest("Invalid request payload")
+	}
+
+	if caller := middleware.GetCallerFromEcho(c); caller != nil {
+		for i := range req.Items {
+			if req.Items[i].UserID == "" {
+				req.Items[i].UserID = caller.ID
+			}
+		}
+	}
+
+	ctx := c.Request().Context()
+	result, err := h.Service.BatchComments(ctx, &req)
+	if err != nil {
+		return forResource("comment", err)
+	}
+
+	status, message := batchStatus(result)
+	return c.JSON(status, domain.ResponseSingleData[domain.BatchResult[domain.Comment]]{
+		Data:    *result,
+		Code:    status,
+		Message: message,
+	})
+}
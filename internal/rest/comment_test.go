@@ -38,7 +38,7 @@ func TestCommentCRUD_E2E(t *testing.T) {
 
 	// Wire comment routes (with authentication)
 	commentRepo := postgres.NewCommentRepository(kit.DB)
-	commentSvc := service.NewCommentService(commentRepo)
+	commentSvc := service.NewCommentService(commentRepo, postsRepo)
 	commentGroup := apiV1.Group("/comments", middleware.ValidateUserToken())
 	rest.NewCommentHandler(commentGroup, commentSvc)
 
@@ -185,8 +185,7 @@ func TestCommentCRUD_E2E(t *testing.T) {
 	resp.Body.Close()
 
 	// Get after delete
-	type ErrType domain.ResponseSingleData[domain.Empty]
-	errE, code := doRequest[ErrType](
+	errE, code := doRequest[domain.Problem](
 		t, http.MethodGet,
 		fmt.Sprintf("%s/api/v1/comments/%s", kit.BaseURL, comment.ID),
 		nil,
@@ -209,7 +208,8 @@ func TestCommentCRUD_E2E(t *testing.T) {
 	_, err = kit.DB.Exec(context.Background(), "DELETE from posts where id = $1", postsUpdate.ID)
 	require.NoError(t, err)
 	require.Equal(t, http.StatusNotFound, code)
-	require.Equal(t, "Comment not found", errE.Message)
+	require.Equal(t, "Comment not found", errE.Detail)
+	require.Equal(t, domain.ProblemNotFound, errE.Code)
 
 	// Hard delete, since delete API uses soft delete
 	_, err = kit.DB.Exec(context.Background(), "DELETE from comments where id = $1", comment.ID)
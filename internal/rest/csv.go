@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -14,6 +15,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
 )
 
 // CSVHandler handles CSV-related HTTP requests
@@ -35,19 +37,48 @@ func NewCSVHandler(e *echo.Group, svc domain.CSVService, logger *logrus.Logger)
 	e.GET("/jobs/:job_id", handler.GetJobDetails)
 	e.GET("/jobs/:job_id/progress", handler.GetJobProgress)
 	e.GET("/jobs/:job_id/stream", handler.StreamProgress)
+	e.GET("/jobs/:job_id/errors", handler.GetJobErrors)
+	e.GET("/jobs/:job_id/errors.csv", handler.DownloadJobErrors)
+	e.POST("/jobs/:job_id/resume", handler.ResumeJob)
+	e.POST("/jobs/:job_id/cancel", handler.CancelJob)
+	e.POST("/jobs/:job_id/retry", handler.RetryJob)
+	e.DELETE("/jobs/:job_id", handler.DeleteJob)
+
+	e.GET("/schemas", handler.ListSchemas)
+	e.GET("/schemas/:name", handler.GetSchema)
+	// Saved schemas are shared by every uploader, only admins change them
+	e.PUT("/schemas/:name", handler.SaveSchema, middleware.RequireRole(domain.RoleAdmin))
+	e.DELETE("/schemas/:name", handler.DeleteSchema, middleware.RequireRole(domain.RoleAdmin))
 }
 
 // UploadCSV handles multiple CSV file uploads
 // @Summary Upload and process CSV files
-// @Description Upload multiple CSV files for concurrent processing
+// @Description Upload multiple CSV files for concurrent processing.
+// @Description The type selects how rows are imported: users need name, email and password columns,
+// @Description posts title and content, comments post_id and body. Posts and comments are created as the uploader.
+// @Description hr_users takes the semicolon separated HR export and creates or updates users by username or email,
+// @Description existing users keep their email and recovery code unless the type is hr_users_credentials.
+// @Description Only admins import users.
+// @Description With dry_run the jobs are queued like imports, the rows are validated and nothing is imported.
 // @Tags CSV
 // @Accept multipart/form-data
 // @Produce json
+// @Param type query string true "What the rows hold, e.g. users, hr_users, hr_users_credentials, posts or comments"
+// @Param delimiter query string false "Delimiter of the files, sniffed when left out; comma, semicolon, tab, pipe or a single character"
+// @Param encoding query string false "Encoding of the files, sniffed when left out" Enums(utf-8, utf-16le, utf-16be, latin-1)
+// @Param lazy_quotes query bool false "Accept quotes inside unquoted fields, sniffed when left out"
+// @Param schema formData string false "JSON domain.CSVSchema the rows are validated and coerced with"
+// @Param schema_name query string false "Name of a saved schema to validate the rows with"
+// @Param dry_run query bool false "Validate the files without importing them, the jobs end as validated with a summary of what importing would do"
 // @Param files formData file true "CSV files to upload"
+// @Param Idempotency-Key header string false "Replays the first response when the upload is retried"
 // @Success 200 {object} domain.CSVUploadResponse
-// @Failure 400 {object} domain.ErrorResponse
-// @Failure 401 {object} domain.ErrorResponse
-// @Failure 500 {object} domain.ErrorResponse
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 403 {object} domain.Problem
+// @Failure 409 {object} domain.Problem
+// @Failure 422 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /csv/upload [post]
 func (h *CSVHandler) UploadCSV(c echo.Context) error {
@@ -56,58 +87,68 @@ func (h *CSVHandler) UploadCSV(c echo.Context) error {
 	// Parse multipart form with memory limit (32MB)
 	if err := c.Request().ParseMultipartForm(32 << 20); err != nil {
 		h.logger.WithError(err).Error("Failed to parse multipart form")
-		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-			Message: "Failed to parse form data",
-		})
+		return badRequest("Failed to parse form data")
 	}
 
 	// Get files from form
 	form, err := c.MultipartForm()
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get multipart form")
-		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-			Message: "Failed to get form files",
-		})
+		return badRequest("Failed to get form files")
 	}
 
 	files := form.File["files"]
 	if len(files) == 0 {
-		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-			Message: "No files provided",
-		})
+		return badRequest("No files provided")
 	}
 
 	// Validate file count (max 10 files)
 	if len(files) > 10 {
-		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-			Message: "Maximum 10 files allowed",
-		})
+		return badRequest("Maximum 10 files allowed")
 	}
 
 	// Validate file types
 	for _, file := range files {
 		if !isCSVFile(file.Filename) {
-			return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-				Message: "Only CSV files are allowed",
-			})
+			return badRequest("Only CSV files are allowed")
 		}
 	}
 	// Process CSV files
 
-	response, err := h.csvService.UploadAndProcessCSV(c.Request().Context(), files)
+	opts := domain.CSVUploadOptions{
+		Type: c.QueryParam("type"),
+		Dialect: domain.CSVDialect{
+			Delimiter: csvDelimiterParam(c.QueryParam("delimiter")),
+			Encoding:  domain.CSVEncoding(strings.ToLower(c.QueryParam("encoding"))),
+		},
+	}
+	if v := c.QueryParam("lazy_quotes"); v != "" {
+		lazy, err := strconv.ParseBool(v)
+		if err != nil {
+			return badRequest("lazy_quotes must be true or false")
+		}
+		opts.Dialect.LazyQuotes = &lazy
+	}
+	if v := c.QueryParam("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			return badRequest("dry_run must be true or false")
+		}
+		opts.DryRun = dryRun
+	}
+	opts.SchemaName = c.QueryParam("schema_name")
+	if v := c.FormValue("schema"); v != "" {
+		opts.Schema = &domain.CSVSchema{}
+		if err := json.Unmarshal([]byte(v), opts.Schema); err != nil {
+			return badRequest("schema must be a JSON object with columns")
+		}
+	}
+
+	response, err := h.csvService.UploadAndProcessCSV(c.Request().Context(), opts, files)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to processs CSV files")
 
-		switch err {
-		case domain.ErrBadParamInput:
-			return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-				Message: err.Error(),
-			})
-		default:
-			return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
-				Message: "Failed to process CSV files",
-			})
-		}
+		return err
 	}
 
 	return c.JSON(http.StatusOK, response)
@@ -121,43 +162,30 @@ func (h *CSVHandler) UploadCSV(c echo.Context) error {
 // @Produce json
 // @Param job_id path string true "CSV Job ID"
 // @Success 200 {object} domain.CSVProcessingProgress
-// @Failure 400 {object} domain.ErrorResponse
-// @Failure 401 {object} domain.ErrorResponse
-// @Failure 404 {object} domain.ErrorResponse
-// @Failure 500 {object} domain.ErrorResponse
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /csv/jobs/{job_id}/progress [get]
 func (h *CSVHandler) GetJobProgress(c echo.Context) error {
 	jobID := c.Param("job_id")
 	if jobID == "" {
 		h.logger.Error("Job ID is required")
-		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-			Message: "Job ID is required",
-		})
+		return badRequest("Job ID is required")
 	}
 
 	jid, err := uuid.Parse(jobID)
 	if err != nil {
 		h.logger.WithError(err).WithField("job_id", jobID).Error("Invalid job ID format")
-		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-			Message: "Invalid job ID",
-		})
+		return badRequest("Invalid job ID")
 	}
 
 	progress, err := h.csvService.GetJobProgress(c.Request().Context(), jid)
 	if err != nil {
 		h.logger.WithError(err).WithField("job_id", jobID).Error("Failed to get job progress")
 
-		switch err {
-		case domain.ErrCSVJobNotFound:
-			return c.JSON(http.StatusNotFound, domain.ErrorResponse{
-				Message: "CSV job not found",
-			})
-		default:
-			return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
-				Message: "Failed to get job progress",
-			})
-		}
+		return forResource("CSV job", err)
 	}
 
 	return c.JSON(http.StatusOK, progress)
@@ -165,20 +193,18 @@ func (h *CSVHandler) GetJobProgress(c echo.Context) error {
 
 // GetUserJobs retrieves all CSV jobs for the authenticated user
 // @Summary Get user CSV jobs
-// @Description Get all CSV processing jobs for the authenticated user
+// @Description Get the CSV processing jobs the authenticated user uploaded, admins get every job
 // @Tags CSV
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Success 200 {object} domain.PaginatedResponse{data=[]domain.CSVJob}
-// @Failure 400 {object} domain.ErrorResponse
-// @Failure 401 {object} domain.ErrorResponse
-// @Failure 500 {object} domain.ErrorResponse
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /csv/jobs [get]
 func (h *CSVHandler) GetUserJobs(c echo.Context) error {
-	// Get user ID from context
-
 	// Parse pagination parameters
 	page := 1
 	if pageStr := c.QueryParam("page"); pageStr != "" {
@@ -197,9 +223,7 @@ func (h *CSVHandler) GetUserJobs(c echo.Context) error {
 	jobs, err := h.csvService.GetUserJobs(c.Request().Context())
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get user jobs")
-		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
-			Message: "Failed to get user jobs",
-		})
+		return err
 	}
 
 	// Apply pagination
@@ -238,119 +262,420 @@ func (h *CSVHandler) GetJobDetails(c echo.Context) error {
 	jobID := c.Param("job_id")
 	if jobID == "" {
 		h.logger.Error("Job ID is required")
-		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-			Message: "Job ID is required",
-		})
+		return badRequest("Job ID is required")
 	}
 
 	jid, err := uuid.Parse(jobID)
 	if err != nil {
 		h.logger.WithError(err).WithField("job_id", jobID).Error("Invalid job ID format")
-		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-			Message: "Invalid job ID",
-		})
+		return badRequest("Invalid job ID")
 	}
 
 	progress, err := h.csvService.GetJobProgress(c.Request().Context(), jid)
 	if err != nil {
 		h.logger.WithError(err).WithField("job_id", jobID).Error("Failed to get job details")
 
-		switch err {
-		case domain.ErrCSVJobNotFound:
-			return c.JSON(http.StatusNotFound, domain.ErrorResponse{
-				Message: "CSV job not found",
-			})
-		default:
-			return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
-				Message: "Failed to get job details",
-			})
-		}
+		return forResource("CSV job", err)
 	}
 
 	return c.JSON(http.StatusOK, progress)
 }
-func (h *CSVHandler) StreamProgress(c echo.Context) error {
-	jobID := c.Param("job_id")
-	if jobID == "" {
-		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-			Message: "Job ID is required",
-		})
+
+// GetJobErrors lists the rows a CSV job failed on
+// @Summary Get failed CSV rows
+// @Description Get the rows of a CSV job that could not be read or processed, with their values and why they failed.
+// @Description Row numbers count the rows after the header, starting at 1.
+// @Tags CSV
+// @Produce json
+// @Param job_id path string true "Job ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(50)
+// @Success 200 {object} domain.PaginatedResponse{data=[]domain.CSVJobError}
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /csv/jobs/{job_id}/errors [get]
+func (h *CSVHandler) GetJobErrors(c echo.Context) error {
+	jid, err := uuid.Parse(c.Param("job_id"))
+	if err != nil {
+		return badRequest("Invalid job ID")
 	}
 
-	jid, err := uuid.Parse(jobID)
+	page := 1
+	if pageStr := c.QueryParam("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+
+	limit := 50
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 500 {
+			limit = l
+		}
+	}
+
+	response, err := h.csvService.GetJobErrors(c.Request().Context(), jid, page, limit)
 	if err != nil {
-		h.logger.WithError(err).WithField("job_id", jobID).Error("Invalid job ID format")
-		return c.JSON(http.StatusBadRequest, domain.ErrorResponse{
-			Message: "Invalid job ID",
-		})
+		h.logger.WithError(err).WithField("job_id", jid).Error("Failed to get job errors")
+		return forResource("CSV job", err)
 	}
 
-	// Check if job exists
-	_, err = h.csvService.GetJobProgress(c.Request().Context(), jid)
+	return c.JSON(http.StatusOK, response)
+}
+
+// ResumeJob queues a failed or cancelled CSV job again
+// @Summary Resume a failed CSV job
+// @Description Queue a failed or cancelled CSV job again. It continues after the last row it checkpointed, rows already imported are
+// @Description not imported twice. Only jobs whose uploaded file is kept can be resumed.
+// @Tags CSV
+// @Produce json
+// @Param job_id path string true "Job ID"
+// @Success 202 {object} domain.CSVJob
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 409 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /csv/jobs/{job_id}/resume [post]
+func (h *CSVHandler) ResumeJob(c echo.Context) error {
+	jid, err := uuid.Parse(c.Param("job_id"))
+	if err != nil {
+		return badRequest("Invalid job ID")
+	}
+
+	job, err := h.csvService.ResumeJob(c.Request().Context(), jid)
+	if err != nil {
+		return forResource("CSV job", err)
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// CancelJob stops a pending or processing CSV job
+// @Summary Cancel a CSV job
+// @Description Stop a pending or processing CSV job. Rows imported before it stopped stay imported, the job can be
+// @Description resumed or retried later.
+// @Tags CSV
+// @Produce json
+// @Param job_id path string true "Job ID"
+// @Success 200 {object} domain.CSVJob
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 409 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /csv/jobs/{job_id}/cancel [post]
+func (h *CSVHandler) CancelJob(c echo.Context) error {
+	jid, err := uuid.Parse(c.Param("job_id"))
+	if err != nil {
+		return badRequest("Invalid job ID")
+	}
+
+	job, err := h.csvService.CancelJob(c.Request().Context(), jid)
+	if err != nil {
+		return forResource("CSV job", err)
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+// RetryJob processes a CSV job, or the rows it failed on, again
+// @Summary Retry a CSV job
+// @Description Queue a failed or cancelled CSV job again from its first row, its errors are dropped.
+// @Description With failed_rows a new job imports only the rows a finished job failed on. Jobs that failed more rows
+// @Description than a job stores errors for are refused.
+// @Tags CSV
+// @Produce json
+// @Param job_id path string true "Job ID"
+// @Param failed_rows query bool false "Import only the rows the job failed on, as a new job"
+// @Success 202 {object} domain.CSVJob
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 409 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /csv/jobs/{job_id}/retry [post]
+func (h *CSVHandler) RetryJob(c echo.Context) error {
+	jid, err := uuid.Parse(c.Param("job_id"))
 	if err != nil {
-		if err == domain.ErrCSVJobNotFound {
-			return c.JSON(http.StatusNotFound, domain.ErrorResponse{
-				Message: "CSV job not found",
-			})
+		return badRequest("Invalid job ID")
+	}
+
+	var failedRows bool
+	if v := c.QueryParam("failed_rows"); v != "" {
+		if failedRows, err = strconv.ParseBool(v); err != nil {
+			return badRequest("failed_rows must be true or false")
 		}
-		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
-			Message: "Failed to get job details",
-		})
 	}
 
-	// Set headers for SSE
+	job, err := h.csvService.RetryJob(c.Request().Context(), jid, failedRows)
+	if err != nil {
+		return forResource("CSV job", err)
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// DeleteJob deletes a CSV job that is done running
+// @Summary Delete a CSV job
+// @Description Delete a CSV job with its failed rows and uploaded file. Running jobs must be cancelled first, rows
+// @Description they imported stay imported.
+// @Tags CSV
+// @Param job_id path string true "Job ID"
+// @Success 204
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 409 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /csv/jobs/{job_id} [delete]
+func (h *CSVHandler) DeleteJob(c echo.Context) error {
+	jid, err := uuid.Parse(c.Param("job_id"))
+	if err != nil {
+		return badRequest("Invalid job ID")
+	}
+
+	if err := h.csvService.DeleteJob(c.Request().Context(), jid); err != nil {
+		return forResource("CSV job", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DownloadJobErrors returns the rows a CSV job failed on as a CSV file
+// @Summary Download failed CSV rows
+// @Description Download the rows of a CSV job that failed, with the original headers and delimiter and an error column,
+// @Description ready to be corrected and uploaded again.
+// @Tags CSV
+// @Produce text/csv
+// @Param job_id path string true "Job ID"
+// @Success 200 {file} file
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /csv/jobs/{job_id}/errors.csv [get]
+func (h *CSVHandler) DownloadJobErrors(c echo.Context) error {
+	jid, err := uuid.Parse(c.Param("job_id"))
+	if err != nil {
+		return badRequest("Invalid job ID")
+	}
+
+	// Look the job up first so a missing job is still answered with a problem
+	if _, err := h.csvService.GetJobProgress(c.Request().Context(), jid); err != nil {
+		return forResource("CSV job", err)
+	}
+
 	res := c.Response()
-	res.Header().Set("Content-Type", "text/event-stream")
-	res.Header().Set("Cache-Control", "no-cache")
-	res.Header().Set("Connection", "keep-alive")
-	res.Header().Set("Access-Control-Allow-Origin", "*")
+	res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", jid.String()+"-errors.csv"))
 	res.WriteHeader(http.StatusOK)
 
+	if err := h.csvService.WriteJobErrorsCSV(c.Request().Context(), jid, res); err != nil {
+		// The response has started, all that is left is to log
+		h.logger.WithError(err).WithField("job_id", jid).Error("Failed to write job errors")
+	}
+	return nil
+}
+
+// sseHeartbeat is how often an idle progress stream writes a comment, so
+// proxies do not close it
+const sseHeartbeat = 15 * time.Second
+
+// StreamProgress pushes the progress of a CSV job as server-sent events
+// @Summary Stream CSV job progress
+// @Description Server-sent events with the progress of a CSV job. The first event is its current state, "progress"
+// @Description events follow as rows are processed and the stream ends with one event named after the status the job
+// @Description ended in, completed, failed, validated or cancelled, with its final counts and the summary of a dry run.
+// @Description Reconnecting clients send Last-Event-ID to skip the events they saw. Idle streams get a comment every
+// @Description 15 seconds. The stream is not cut short by the request timeout.
+// @Tags CSV
+// @Produce text/event-stream
+// @Param job_id path string true "Job ID"
+// @Param Last-Event-ID header int false "Id of the last event the client received"
+// @Param last_event_id query int false "Same as Last-Event-ID, for clients that cannot set headers"
+// @Success 200 {object} domain.CSVProgressEvent
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /csv/jobs/{job_id}/stream [get]
+func (h *CSVHandler) StreamProgress(c echo.Context) error {
+	jid, err := uuid.Parse(c.Param("job_id"))
+	if err != nil {
+		return badRequest("Invalid job ID")
+	}
+
+	seen := c.Request().Header.Get("Last-Event-ID")
+	if seen == "" {
+		seen = c.QueryParam("last_event_id")
+	}
+	var lastEventID int64
+	if seen != "" {
+		if lastEventID, err = strconv.ParseInt(seen, 10, 64); err != nil {
+			return badRequest("Last-Event-ID must be the id of an event")
+		}
+	}
+
+	ctx := c.Request().Context()
+	events, err := h.csvService.StreamProgress(ctx, jid, lastEventID)
+	if err != nil {
+		return forResource("CSV job", err)
+	}
+
+	res := c.Response()
 	flusher, ok := res.Writer.(http.Flusher)
 	if !ok {
 		h.logger.Error("Streaming not supported")
-		return c.JSON(http.StatusInternalServerError, domain.ErrorResponse{
-			Message: "Streaming not supported",
-		})
+		return echo.NewHTTPError(http.StatusInternalServerError, "Streaming not supported")
 	}
 
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.Header().Set("Access-Control-Allow-Origin", "*")
+	res.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
 
 	for {
 		select {
-		case <-c.Request().Context().Done():
+		case <-ctx.Done():
 			return nil
-		case <-ticker.C:
-			progress, err := h.csvService.GetJobProgress(c.Request().Context(), jid)
-			if err != nil {
-				h.logger.WithError(err).Error("Failed to get progress during streaming")
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res.Writer, ": heartbeat\n\n"); err != nil {
 				return nil
 			}
-
-			// Marshal progress to JSON and write as SSE data
-			data, err := json.Marshal(progress)
-			if err != nil {
-				h.logger.WithError(err).Error("Failed to marshal progress")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
 				return nil
 			}
-
-			if _, err := fmt.Fprintf(res.Writer, "event: progress\n"); err != nil {
+			data, err := json.Marshal(event)
+			if err != nil {
+				h.logger.WithError(err).Error("Failed to marshal progress")
 				return nil
 			}
-			if _, err := fmt.Fprintf(res.Writer, "data: %s\n\n", data); err != nil {
+			if _, err := fmt.Fprintf(res.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
 				return nil
 			}
 			flusher.Flush()
-
-			// Stop streaming if job is completed or failed
-			if progress.Status == domain.CSVJobStatusCompleted || progress.Status == domain.CSVJobStatusFailed {
-				return nil
-			}
+			heartbeat.Reset(sseHeartbeat)
 		}
 	}
 }
 
+// ListSchemas godoc
+// @Summary List saved CSV schemas
+// @Tags CSV
+// @Produce json
+// @Success 200 {array} domain.CSVSchema
+// @Failure 401 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /csv/schemas [get]
+func (h *CSVHandler) ListSchemas(c echo.Context) error {
+	schemas, err := h.csvService.ListSchemas(c.Request().Context())
+	if err != nil {
+		return forResource("CSV schema", err)
+	}
+	return c.JSON(http.StatusOK, schemas)
+}
+
+// GetSchema godoc
+// @Summary Get a saved CSV schema
+// @Tags CSV
+// @Produce json
+// @Param name path string true "Schema name"
+// @Success 200 {object} domain.CSVSchema
+// @Failure 401 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /csv/schemas/{name} [get]
+func (h *CSVHandler) GetSchema(c echo.Context) error {
+	schema, err := h.csvService.GetSchema(c.Request().Context(), c.Param("name"))
+	if err != nil {
+		return forResource("CSV schema", err)
+	}
+	return c.JSON(http.StatusOK, schema)
+}
+
+// SaveSchema godoc
+// @Summary Save a CSV schema
+// @Description Saves a schema under the name in the path, replacing the one saved before. Uploads refer to it with ?schema_name=.
+// @Description Every column maps a header of the file onto a field, with its type, whether it is required, a pattern, enum values, a default and transforms.
+// @Description Admins only.
+// @Tags CSV
+// @Accept json
+// @Produce json
+// @Param name path string true "Schema name"
+// @Param schema body domain.CSVSchema true "Schema"
+// @Success 200 {object} domain.CSVSchema
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 403 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /csv/schemas/{name} [put]
+func (h *CSVHandler) SaveSchema(c echo.Context) error {
+	var schema domain.CSVSchema
+	if err := c.Bind(&schema); err != nil {
+		return badRequest("Invalid request payload")
+	}
+	schema.Name = c.Param("name")
+
+	if err := h.csvService.SaveSchema(c.Request().Context(), &schema); err != nil {
+		return forResource("CSV schema", err)
+	}
+	return c.JSON(http.StatusOK, schema)
+}
+
+// DeleteSchema godoc
+// @Summary Delete a saved CSV schema
+// @Description Jobs uploaded with the schema keep their copy of it. Admins only.
+// @Tags CSV
+// @Param name path string true "Schema name"
+// @Success 204
+// @Failure 401 {object} domain.Problem
+// @Failure 403 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /csv/schemas/{name} [delete]
+func (h *CSVHandler) DeleteSchema(c echo.Context) error {
+	if err := h.csvService.DeleteSchema(c.Request().Context(), c.Param("name")); err != nil {
+		return forResource("CSV schema", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// csvDelimiterParam lets clients name the delimiters that are awkward to put
+// in a URL
+func csvDelimiterParam(v string) string {
+	switch strings.ToLower(v) {
+	case "comma":
+		return ","
+	case "semicolon":
+		return ";"
+	case "tab", `\t`:
+		return "\t"
+	case "pipe":
+		return "|"
+	}
+	return v
+}
+
 // isCSVFile checks if the filename has a CSV extension
 func isCSVFile(filename string) bool {
 	return len(filename) > 4 && filename[len(filename)-4:] == ".csv"
@@ -0,0 +1,60 @@
+package rest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
+	"github.com/edwinjordan/MajooTest-Golang/service"
+	"github.com/edwinjordan/MajooTest-Golang/utils"
+)
+
+func TestCSVHandler_UploadCSV_UserImports(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	token, _, err := utils.GenerateToken("user-1", "user@example.com", domain.RoleUser)
+	require.NoError(t, err)
+
+	for _, csvType := range []string{"users", "hr_users"} {
+		t.Run(csvType+" needs an admin", func(t *testing.T) {
+			processors := service.NewCSVProcessorRegistry().
+				Register("users", service.NewUserRowProcessor(nil), domain.RoleAdmin).
+				Register("hr_users", service.NewHRUserRowProcessor(nil, "example.com"), domain.RoleAdmin)
+			// Nothing reaches the repository before the role is checked
+			svc := service.NewCSVService(nil, logrus.New()).WithProcessors(processors)
+
+			e := echo.New()
+			e.HTTPErrorHandler = rest.HTTPErrorHandler
+			rest.NewCSVHandler(e.Group("/csv", middleware.ValidateUserToken()), svc, logrus.New())
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, err := writer.CreateFormFile("files", "users.csv")
+			require.NoError(t, err)
+			_, err = part.Write([]byte("name,email,password\nEve,eve@example.com,Password1234\n"))
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+
+			req := httptest.NewRequest(http.MethodPost, "/csv/upload?type="+csvType, body)
+			req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusForbidden, rec.Code)
+			var problem domain.Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, domain.ProblemForbidden, problem.Code)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
+)
+
+// jsonWithETag answers with body tagged by the representation it holds of a
+// resource at version, or with 304 when If-None-Match lists that tag. Bodies
+// carry what the caller reacted with, so they vary with the token.
+func jsonWithETag(c echo.Context, version int, body any) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	header := c.Response().Header()
+	etag := middleware.RepresentationETag(version, encoded)
+	header.Set("ETag", etag)
+	header.Add(echo.HeaderVary, echo.HeaderAuthorization)
+	if middleware.MatchesETag(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSONBlob(http.StatusOK, encoded)
+}
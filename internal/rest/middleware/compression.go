@@ -8,5 +8,9 @@ import (
 func CompressionMiddleware() echo.MiddlewareFunc {
 	return middleware.GzipWithConfig(middleware.GzipConfig{
 		Level: 5,
+		// A websocket takes over the connection, there is no body to compress
+		Skipper: func(c echo.Context) bool {
+			return c.IsWebSocket()
+		},
 	})
 }
@@ -33,6 +33,13 @@ func Cors() echo.MiddlewareFunc {
 			echo.HeaderAccept,
 			echo.HeaderAuthorization,
 			"X-Signature",
+			"If-Match",
+			"If-None-Match",
+			"Idempotency-Key",
+		},
+		ExposeHeaders: []string{
+			"ETag",
+			"Idempotent-Replayed",
 		},
 	})
 }
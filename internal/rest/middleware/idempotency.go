@@ -0,0 +1,287 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+// maxIdempotentBody is the largest request body that is fingerprinted,
+// larger requests with an Idempotency-Key are refused
+const maxIdempotentBody = 64 << 20
+
+// IdempotencyStore claims Idempotency-Keys and keeps the responses to replay,
+// see service.IdempotencyService
+type IdempotencyStore interface {
+	Begin(ctx context.Context, rec *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error)
+	Complete(ctx context.Context, rec *domain.IdempotencyRecord) error
+	Release(ctx context.Context, rec *domain.IdempotencyRecord) error
+}
+
+// Idempotency makes POST requests carrying an Idempotency-Key safe to retry.
+// The first response to a key is stored and replayed for retries from the
+// same caller on the same route; a retry while the first request is still
+// running gets 409, and reusing the key for a different payload gets 422.
+// Errors returned by the handler and responses with a 5xx status are not
+// stored, so those requests run again when retried. Requests without the
+// header pass through untouched.
+func Idempotency(store IdempotencyStore) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			key := req.Header.Get(domain.IdempotencyKeyHeader)
+			if req.Method != http.MethodPost || key == "" {
+				return next(c)
+			}
+			if len(key) > domain.MaxIdempotencyKeyLength {
+				return echo.NewHTTPError(http.StatusBadRequest, "Idempotency-Key is too long")
+			}
+
+			hash, cleanup, err := fingerprintRequest(req)
+			if errors.Is(err, errIdempotentBodyTooLarge) {
+				return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "request body is too large for an idempotent request")
+			}
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			rec := &domain.IdempotencyRecord{
+				Key:         key,
+				UserID:      idempotencyScope(c),
+				Method:      req.Method,
+				Route:       c.Path(),
+				RequestHash: hash,
+			}
+
+			ctx := req.Context()
+			stored, err := store.Begin(ctx, rec)
+			if errors.Is(err, domain.ErrIdempotencyInFlight) {
+				c.Response().Header().Set(echo.HeaderRetryAfter, "1")
+			}
+			if err != nil {
+				return err
+			}
+			if stored != nil {
+				return replay(c, stored)
+			}
+
+			// Whatever happens to the request, the key must not stay claimed
+			// without a response, and the outcome is recorded even when the
+			// client went away
+			ctx = context.WithoutCancel(ctx)
+			completed := false
+			defer func() {
+				if !completed {
+					if err := store.Release(ctx, rec); err != nil {
+						logIdempotencyError(ctx, err, "idempotency_release")
+					}
+				}
+			}()
+
+			before := make(map[string]bool, len(c.Response().Header()))
+			for name := range c.Response().Header() {
+				before[name] = true
+			}
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			if err := next(c); err != nil {
+				return err
+			}
+
+			res := c.Response()
+			if !res.Committed || res.Status >= http.StatusInternalServerError {
+				return nil
+			}
+
+			rec.StatusCode = res.Status
+			rec.Header = replayableHeader(res.Header(), before)
+			rec.Body = recorder.body.Bytes()
+			if err := store.Complete(ctx, rec); err != nil {
+				logIdempotencyError(ctx, err, "idempotency_complete")
+				return nil
+			}
+			completed = true
+			return nil
+		}
+	}
+}
+
+func logIdempotencyError(ctx context.Context, err error, operation string) {
+	slog.ErrorContext(ctx, "Idempotency-Key store failed",
+		slog.String("operation", operation),
+		slog.String("error", err.Error()),
+	)
+}
+
+var errIdempotentBodyTooLarge = errors.New("request body too large")
+
+// fingerprintRequest hashes the query and body of req while spooling the
+// body to a temporary file, which it puts back for the handler; cleanup
+// removes the file. Multipart bodies are hashed part by part, so the
+// boundary, which clients pick anew for every request, is left out.
+func fingerprintRequest(req *http.Request) (fingerprint string, cleanup func(), err error) {
+	h := sha256.New()
+	h.Write([]byte(req.URL.RawQuery))
+	h.Write([]byte{0})
+	cleanup = func() {}
+	if req.Body == nil || req.Body == http.NoBody {
+		return hex.EncodeToString(h.Sum(nil)), cleanup, nil
+	}
+
+	spool, err := os.CreateTemp("", "idempotent-body-*")
+	if err != nil {
+		return "", cleanup, err
+	}
+	cleanup = func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}
+	defer func() {
+		if err != nil {
+			cleanup()
+		}
+	}()
+
+	limited := &io.LimitedReader{R: req.Body, N: maxIdempotentBody + 1}
+	body := io.TeeReader(limited, spool)
+	if mediaType, params, err := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType)); err == nil &&
+		strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		err = hashMultipart(h, multipart.NewReader(body, params["boundary"]))
+		if err != nil && limited.N > 0 {
+			// Not valid multipart, the handler will say so; the raw bytes
+			// still tell retries apart
+			h.Reset()
+			h.Write([]byte(req.URL.RawQuery))
+			h.Write([]byte{0})
+			if _, err := spool.Seek(0, io.SeekStart); err != nil {
+				return "", cleanup, err
+			}
+			if _, err := io.Copy(h, io.MultiReader(spool, body)); err != nil {
+				return "", cleanup, err
+			}
+		}
+		// Whatever follows the last part is kept for the handler
+		if _, err := io.Copy(io.Discard, body); err != nil {
+			return "", cleanup, err
+		}
+	} else if _, err := io.Copy(h, body); err != nil {
+		return "", cleanup, err
+	}
+	if limited.N <= 0 {
+		return "", cleanup, errIdempotentBodyTooLarge
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return "", cleanup, err
+	}
+	req.Body = spool
+	return hex.EncodeToString(h.Sum(nil)), cleanup, nil
+}
+
+// hashMultipart writes the headers that describe every part and its content
+// to h
+func hashMultipart(h hash.Hash, reader *multipart.Reader) error {
+	for {
+		part, err := reader.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, name := range []string{"Content-Disposition", echo.HeaderContentType} {
+			h.Write([]byte(part.Header.Get(name)))
+			h.Write([]byte{0})
+		}
+		if _, err := io.Copy(h, part); err != nil {
+			return err
+		}
+		h.Write([]byte{0})
+	}
+}
+
+// idempotencyScope is who a key belongs to: the caller, or for tokens we did
+// not sign a digest of the token itself
+func idempotencyScope(c echo.Context) string {
+	if caller := GetCallerFromEcho(c); caller != nil {
+		return caller.ID
+	}
+	token, _ := c.Get("user_token").(string)
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:])
+}
+
+// replayableHeader returns the response headers the handler set, leaving out
+// the ones outer middleware sets for every response and the ones describing
+// the transfer rather than the content
+func replayableHeader(header http.Header, before map[string]bool) http.Header {
+	kept := make(http.Header)
+	for name, values := range header {
+		switch {
+		case before[name],
+			name == echo.HeaderContentLength,
+			name == echo.HeaderContentEncoding,
+			name == echo.HeaderVary:
+			continue
+		}
+		kept[name] = append([]string(nil), values...)
+	}
+	return kept
+}
+
+func replay(c echo.Context, stored *domain.IdempotencyRecord) error {
+	header := c.Response().Header()
+	for name, values := range stored.Header {
+		header[name] = values
+	}
+	header.Set(domain.IdempotentReplayedHeader, "true")
+	c.Response().WriteHeader(stored.StatusCode)
+	_, err := c.Response().Write(stored.Body)
+	return err
+}
+
+// responseRecorder keeps a copy of the body written to the response
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(r.ResponseWriter).Hijack()
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
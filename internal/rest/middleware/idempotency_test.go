@@ -0,0 +1,266 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
+)
+
+// memoryIdempotencyStore claims keys like service.IdempotencyService, without
+// leases or expiry
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]domain.IdempotencyRecord
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: map[string]domain.IdempotencyRecord{}}
+}
+
+func idempotencyStoreKey(rec *domain.IdempotencyRecord) string {
+	return rec.UserID + " " + rec.Method + " " + rec.Route + " " + rec.Key
+}
+
+func (s *memoryIdempotencyStore) Begin(ctx context.Context, rec *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.records[idempotencyStoreKey(rec)]
+	switch {
+	case !ok:
+		s.records[idempotencyStoreKey(rec)] = *rec
+		return nil, nil
+	case existing.RequestHash != rec.RequestHash:
+		return nil, domain.ErrIdempotencyKeyReused
+	case !existing.IsCompleted():
+		return nil, domain.ErrIdempotencyInFlight
+	}
+	return &existing, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, rec *domain.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	completed := *rec
+	completed.CompletedAt = &now
+	s.records[idempotencyStoreKey(rec)] = completed
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, rec *domain.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, idempotencyStoreKey(rec))
+	return nil
+}
+
+// multipartUpload builds an upload of content with its own boundary
+func multipartUpload(t *testing.T, boundary, content string) (string, *bytes.Buffer) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	require.NoError(t, writer.SetBoundary(boundary))
+	part, err := writer.CreateFormFile("files", "people.csv")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return writer.FormDataContentType(), body
+}
+
+func TestIdempotency(t *testing.T) {
+	jsonRequest := func(body string) func(t *testing.T) *http.Request {
+		return func(t *testing.T) *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			return req
+		}
+	}
+	uploadRequest := func(boundary, content string) func(t *testing.T) *http.Request {
+		return func(t *testing.T) *http.Request {
+			contentType, body := multipartUpload(t, boundary, content)
+			req := httptest.NewRequest(http.MethodPost, "/posts", body)
+			req.Header.Set(echo.HeaderContentType, contentType)
+			return req
+		}
+	}
+	created := func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderLocation, "/posts/1")
+		return c.JSON(http.StatusCreated, map[string]string{"id": "1"})
+	}
+
+	tests := []struct {
+		name     string
+		handler  echo.HandlerFunc
+		first    func(t *testing.T) *http.Request
+		retry    func(t *testing.T) *http.Request
+		status   int
+		replayed bool
+		calls    int
+	}{
+		{
+			name:     "retry replays status, headers and body",
+			handler:  created,
+			first:    jsonRequest(`{"title":"Hello"}`),
+			retry:    jsonRequest(`{"title":"Hello"}`),
+			status:   http.StatusCreated,
+			replayed: true,
+			calls:    1,
+		},
+		{
+			name:    "reused key gets 422",
+			handler: created,
+			first:   jsonRequest(`{"title":"Hello"}`),
+			retry:   jsonRequest(`{"title":"Bye"}`),
+			status:  http.StatusUnprocessableEntity,
+			calls:   1,
+		},
+		{
+			name: "5xx response is released",
+			handler: func(c echo.Context) error {
+				return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "try again"})
+			},
+			first:  jsonRequest(`{"title":"Hello"}`),
+			retry:  jsonRequest(`{"title":"Hello"}`),
+			status: http.StatusServiceUnavailable,
+			calls:  2,
+		},
+		{
+			name: "handler error is released",
+			handler: func(c echo.Context) error {
+				return errors.New("database is down")
+			},
+			first:  jsonRequest(`{"title":"Hello"}`),
+			retry:  jsonRequest(`{"title":"Hello"}`),
+			status: http.StatusInternalServerError,
+			calls:  2,
+		},
+		{
+			name:     "multipart boundary is not fingerprinted",
+			handler:  created,
+			first:    uploadRequest("first-boundary", "name\nAna\n"),
+			retry:    uploadRequest("second-boundary", "name\nAna\n"),
+			status:   http.StatusCreated,
+			replayed: true,
+			calls:    1,
+		},
+		{
+			name:    "multipart content is fingerprinted",
+			handler: created,
+			first:   uploadRequest("first-boundary", "name\nAna\n"),
+			retry:   uploadRequest("second-boundary", "name\nBea\n"),
+			status:  http.StatusUnprocessableEntity,
+			calls:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = rest.HTTPErrorHandler
+			calls := 0
+			e.POST("/posts", func(c echo.Context) error {
+				calls++
+				return tt.handler(c)
+			}, middleware.Idempotency(newMemoryIdempotencyStore()))
+
+			send := func(req *http.Request) *httptest.ResponseRecorder {
+				req.Header.Set(domain.IdempotencyKeyHeader, "key-1")
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+				return rec
+			}
+			first := send(tt.first(t))
+			retry := send(tt.retry(t))
+
+			assert.Equal(t, tt.status, retry.Code)
+			assert.Equal(t, tt.calls, calls)
+			if tt.replayed {
+				assert.Equal(t, "true", retry.Header().Get(domain.IdempotentReplayedHeader))
+				assert.Equal(t, first.Code, retry.Code)
+				assert.Equal(t, first.Header().Get(echo.HeaderLocation), retry.Header().Get(echo.HeaderLocation))
+				assert.Equal(t, first.Header().Get(echo.HeaderContentType), retry.Header().Get(echo.HeaderContentType))
+				assert.Equal(t, first.Body.String(), retry.Body.String())
+			} else {
+				assert.Empty(t, retry.Header().Get(domain.IdempotentReplayedHeader))
+			}
+		})
+	}
+}
+
+func TestIdempotency_HandlerReadsBody(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = rest.HTTPErrorHandler
+	var got string
+	e.POST("/posts", func(c echo.Context) error {
+		fh, err := c.FormFile("files")
+		if err != nil {
+			return err
+		}
+		file, err := fh.Open()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		content, err := io.ReadAll(file)
+		got = string(content)
+		if err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusCreated)
+	}, middleware.Idempotency(newMemoryIdempotencyStore()))
+
+	contentType, body := multipartUpload(t, "some-boundary", "name\nAna\n")
+	req := httptest.NewRequest(http.MethodPost, "/posts", body)
+	req.Header.Set(echo.HeaderContentType, contentType)
+	req.Header.Set(domain.IdempotencyKeyHeader, "key-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "name\nAna\n", got)
+}
+
+func TestIdempotency_InFlight(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = rest.HTTPErrorHandler
+	var retry *httptest.ResponseRecorder
+	calls := 0
+	e.POST("/posts", func(c echo.Context) error {
+		calls++
+		if retry == nil {
+			// The client retries while the first request is still running
+			req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{"title":"Hello"}`))
+			req.Header.Set(domain.IdempotencyKeyHeader, "key-1")
+			retry = httptest.NewRecorder()
+			e.ServeHTTP(retry, req)
+		}
+		return c.JSON(http.StatusCreated, map[string]string{"id": "1"})
+	}, middleware.Idempotency(newMemoryIdempotencyStore()))
+
+	req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{"title":"Hello"}`))
+	req.Header.Set(domain.IdempotencyKeyHeader, "key-1")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	require.NotNil(t, retry)
+	assert.Equal(t, http.StatusConflict, retry.Code)
+	assert.Equal(t, "1", retry.Header().Get(echo.HeaderRetryAfter))
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+// ETag formats a resource version as a strong entity tag
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// RepresentationETag tags body, the representation of a resource at
+// version. Reaction counts and other parts of it change without a new
+// version, the digest of body tells those apart. If-Match reads the version
+// from it like from ETag.
+func RepresentationETag(version int, body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + strconv.Itoa(version) + "." + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// ParseETag returns the version held by an entity tag as produced by ETag
+// or RepresentationETag, accepting the weak W/ form too
+func ParseETag(tag string) (int, bool) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	opaque, _, _ := strings.Cut(tag[1:len(tag)-1], ".")
+	version, err := strconv.Atoi(opaque)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// MatchesETag reports whether an If-None-Match header value lists etag,
+// comparing weakly as RFC 9110 asks for If-None-Match
+func MatchesETag(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// IfMatch stores the version named by the If-Match header in the request
+// context, see domain.ExpectedVersion, so the service rejects the change when
+// the resource has moved on. "*" matches any version. With
+// REQUIRE_IF_MATCH=true requests without the header are refused with 428.
+func IfMatch() echo.MiddlewareFunc {
+	required, _ := strconv.ParseBool(os.Getenv("REQUIRE_IF_MATCH"))
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := strings.TrimSpace(c.Request().Header.Get("If-Match"))
+			if header == "" {
+				if required {
+					return echo.NewHTTPError(http.StatusPreconditionRequired, "If-Match header is required")
+				}
+				return next(c)
+			}
+			if header == "*" {
+				return next(c)
+			}
+
+			version, ok := ParseETag(header)
+			if !ok {
+				return echo.NewHTTPError(http.StatusPreconditionFailed, "If-Match must name a single version, e.g. \"3\"")
+			}
+
+			req := c.Request()
+			c.SetRequest(req.WithContext(domain.WithExpectedVersion(req.Context(), version)))
+			return next(c)
+		}
+	}
+}
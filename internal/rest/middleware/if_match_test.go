@@ -0,0 +1,42 @@
+package middleware_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
+)
+
+func TestRepresentationETag(t *testing.T) {
+	tag := middleware.RepresentationETag(3, []byte(`{"likes":1}`))
+
+	t.Run("changes with the body at the same version", func(t *testing.T) {
+		assert.NotEqual(t, tag, middleware.RepresentationETag(3, []byte(`{"likes":2}`)))
+	})
+
+	t.Run("holds the version for If-Match", func(t *testing.T) {
+		version, ok := middleware.ParseETag(tag)
+		assert.True(t, ok)
+		assert.Equal(t, 3, version)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		match  bool
+	}{
+		{"same tag", tag, true},
+		{"weak form of the tag", "W/" + tag, true},
+		{"one of several tags", `"1", ` + tag, true},
+		{"any tag", "*", true},
+		{"version only", middleware.ETag(3), false},
+		{"other body", middleware.RepresentationETag(3, []byte(`{"likes":2}`)), false},
+		{"no header", "", false},
+	}
+	for _, tt := range tests {
+		t.Run("If-None-Match with "+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, middleware.MatchesETag(tt.header, tag))
+		})
+	}
+}
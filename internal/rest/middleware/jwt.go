@@ -5,8 +5,14 @@ import (
 	"strings"
 
 	"github.com/labstack/echo/v4"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/utils"
 )
 
+// CallerKey is the echo context key holding the authenticated *domain.Caller
+const CallerKey = "caller"
+
 // ValidateUserToken validates JWT token from Authorization header
 func ValidateUserToken() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -19,8 +25,12 @@ func ValidateUserToken() echo.MiddlewareFunc {
 				// but some code paths might access different representations).
 				auth = c.Request().Header.Get("authorization")
 			}
+			if auth == "" && c.IsWebSocket() {
+				// Browsers cannot set headers when opening a websocket
+				auth = c.QueryParam("access_token")
+			}
 			if auth == "" {
-				return c.JSON(http.StatusUnauthorized, map[string]interface{}{"message": "missing token"})
+				return echo.NewHTTPError(http.StatusUnauthorized, "missing token")
 			}
 
 			// Normalize and extract token
@@ -32,15 +42,32 @@ func ValidateUserToken() echo.MiddlewareFunc {
 			}
 
 			if auth == "" {
-				return c.JSON(http.StatusUnauthorized, map[string]interface{}{"message": "invalid token format"})
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token format")
 			}
 
-			// Perform actual token validation here if you have a signing key/service.
-			// If you have an auth service in the app, call it to validate and parse claims.
-			// For now, store the raw token in the context so handlers can use it.
+			// Only access tokens signed by us get through, a refresh token
+			// must not stand in for one
+			claims, err := utils.ValidateToken(auth)
+			if err != nil || claims.ID == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+			}
 			c.Set("user_token", auth)
 
+			// Expose the token owner to handlers and services, so per-user
+			// features know who is calling
+			caller := &domain.Caller{ID: claims.ID, Email: claims.Email, Role: claims.Role.OrDefault()}
+			c.Set(CallerKey, caller)
+			c.SetRequest(c.Request().WithContext(domain.WithCaller(c.Request().Context(), caller)))
+
 			return next(c)
 		}
 	}
 }
+
+// GetCallerFromEcho returns the authenticated caller set by ValidateUserToken
+func GetCallerFromEcho(c echo.Context) *domain.Caller {
+	if caller, ok := c.Get(CallerKey).(*domain.Caller); ok {
+		return caller
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
+	"github.com/edwinjordan/MajooTest-Golang/utils"
+)
+
+func TestValidateUserToken(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	access, refresh, err := utils.GenerateToken("user-1", "user@example.com", domain.RoleModerator)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		header string
+		query  string
+		status int
+	}{
+		{"access token", "Bearer " + access, "", http.StatusOK},
+		{"raw access token", access, "", http.StatusOK},
+		{"refresh token", "Bearer " + refresh, "", http.StatusUnauthorized},
+		{"unsigned token", "Bearer not-a-jwt", "", http.StatusUnauthorized},
+		{"missing token", "", "", http.StatusUnauthorized},
+		{"websocket access token", "", access, http.StatusOK},
+		{"websocket refresh token", "", refresh, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			var caller *domain.Caller
+			e.GET("/me", func(c echo.Context) error {
+				caller = middleware.GetCallerFromEcho(c)
+				return c.NoContent(http.StatusOK)
+			}, middleware.ValidateUserToken())
+
+			req := httptest.NewRequest(http.MethodGet, "/me?access_token="+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.header)
+			}
+			if tt.query != "" {
+				req.Header.Set(echo.HeaderUpgrade, "websocket")
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusOK {
+				require.NotNil(t, caller)
+				assert.Equal(t, "user-1", caller.ID)
+				assert.Equal(t, domain.RoleModerator, caller.Role)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"log/slog"
+	"net/url"
 	"time"
 
 	echo "github.com/labstack/echo/v4"
@@ -34,7 +35,7 @@ func SlogLoggerMiddleware() echo.MiddlewareFunc {
 
 			// Add query parameters if present
 			if req.URL.RawQuery != "" {
-				args = append(args, slog.String("query", req.URL.RawQuery))
+				args = append(args, slog.String("query", redactQuery(req.URL.Query())))
 			}
 
 			// Log with appropriate level based on status code
@@ -71,3 +72,11 @@ func ColorizeLogging(groups []string, a slog.Attr) slog.Attr {
 	}
 	return a
 }
+
+// redactQuery hides credentials passed in the URL, e.g. the websocket token
+func redactQuery(query url.Values) string {
+	if query.Has("access_token") {
+		query.Set("access_token", "REDACTED")
+	}
+	return query.Encode()
+}
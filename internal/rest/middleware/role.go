@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+// RequireRole only lets callers holding one of roles through. It must run
+// after ValidateUserToken, which resolves the caller from the token.
+func RequireRole(roles ...domain.Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			caller := GetCallerFromEcho(c)
+			if caller == nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+			}
+			if !caller.HasRole(roles...) {
+				return echo.NewHTTPError(http.StatusForbidden, "insufficient role")
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -3,14 +3,22 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
-func TimeoutMiddleware(timeout time.Duration) echo.MiddlewareFunc {
+// TimeoutMiddleware cuts requests short after timeout, except websockets and
+// the routes in longLived, such as event streams, matched by their path
+// pattern
+func TimeoutMiddleware(timeout time.Duration, longLived ...string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			if c.IsWebSocket() || slices.Contains(longLived, c.Path()) {
+				return next(c)
+			}
+
 			ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
 			defer cancel()
 
@@ -0,0 +1,54 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		value  string
+		status int
+	}{
+		{"plain request", "/slow/1", "", "", http.StatusRequestTimeout},
+		{"event stream accepted on a plain route", "/slow/1", echo.HeaderAccept, "text/event-stream", http.StatusRequestTimeout},
+		{"long-lived route", "/slow/1/stream", "", "", http.StatusOK},
+		{"websocket", "/slow/1", echo.HeaderUpgrade, "websocket", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(middleware.TimeoutMiddleware(10*time.Millisecond, "/slow/:id/stream"))
+			slow := func(c echo.Context) error {
+				select {
+				case <-c.Request().Context().Done():
+					return nil
+				case <-time.After(50 * time.Millisecond):
+					return c.NoContent(http.StatusOK)
+				}
+			}
+			e.GET("/slow/:id", slow)
+			e.GET("/slow/:id/stream", slow)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
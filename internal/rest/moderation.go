@@ -0,0 +1,194 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/logging"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type ModerationService interface {
+	GetQueue(ctx context.Context, filter *domain.ModerationQueueFilter) ([]domain.Comment, int, error)
+	GetComment(ctx context.Context, id uuid.UUID) (*domain.Comment, error)
+	Moderate(ctx context.Context, moderatorID string, req *domain.BulkModerationRequest) (*domain.BulkModerationResult, error)
+	GetAuditLog(ctx context.Context, commentID uuid.UUID) ([]domain.ModerationAudit, error)
+}
+
+type ModerationHandler struct {
+	Service ModerationService
+}
+
+// NewModerationHandler registers the moderator routes. The group is expected
+// to only let moderators and admins through, see middleware.RequireRole.
+func NewModerationHandler(e *echo.Group, svc ModerationService) {
+	handler := &ModerationHandler{
+		Service: svc,
+	}
+	e.GET("/comments", handler.GetQueue)
+	e.GET("/comments/:id", handler.GetComment)
+	e.POST("/comments/bulk", handler.Moderate)
+	e.GET("/comments/:id/audit", handler.GetAuditLog)
+}
+
+// GetQueue godoc
+// @Summary Moderation queue
+// @Description List comments awaiting review, oldest first
+// @Tags moderation
+// @Produce  json
+// @Param   status  query  string  false  "Moderation status" Enums(pending, approved, rejected, spam) default(pending)
+// @Param   page    query  int     false  "Page" default(1)
+// @Param   limit   query  int     false  "Comments per page" default(20)
+// @Success 200 {object} domain.PaginatedResponse{data=[]domain.Comment}
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 403 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /moderation/comments [get]
+func (h *ModerationHandler) GetQueue(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	filter := new(domain.ModerationQueueFilter)
+	if err := c.Bind(filter); err != nil {
+		logging.LogWarn(ctx, "Failed to bind moderation queue filter", slog.String("error", err.Error()))
+	}
+
+	comments, total, err := h.Service.GetQueue(ctx, filter)
+	if err != nil {
+		if errors.Is(err, domain.ErrBadParamInput) {
+			return withDetail(err, "Unsupported moderation status")
+		}
+		return err
+	}
+	if comments == nil {
+		comments = []domain.Comment{}
+	}
+
+	return c.JSON(http.StatusOK, domain.PaginatedResponse{
+		Data: comments,
+		Pagination: domain.PaginationInfo{
+			Page:       filter.Page,
+			Limit:      filter.Limit,
+			Total:      total,
+			TotalPages: (total + filter.Limit - 1) / filter.Limit,
+		},
+	})
+}
+
+// GetComment godoc
+// @Summary Get a comment for moderation
+// @Description get a comment whatever its moderation status, pending, rejected and spam comments are hidden everywhere else
+// @Tags moderation
+// @Produce  json
+// @Param   id  path  string  true  "Comment ID"
+// @Success 200 {object} domain.ResponseSingleData[domain.Comment]
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 403 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /moderation/comments/{id} [get]
+func (h *ModerationHandler) GetComment(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return badRequest("Invalid comment ID format")
+	}
+
+	ctx := c.Request().Context()
+	comment, err := h.Service.GetComment(ctx, id)
+	if err != nil {
+		return forResource("comment", err)
+	}
+
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.Comment]{
+		Data:    *comment,
+		Code:    http.StatusOK,
+		Message: "Successfully retrieve comment",
+	})
+}
+
+// Moderate godoc
+// @Summary Bulk moderate comments
+// @Description approve, reject or mark comments as spam in one request, every change is recorded in the audit log
+// @Tags moderation
+// @Accept  json
+// @Produce  json
+// @Param   request  body  domain.BulkModerationRequest  true  "Comments and action"
+// @Success 200 {object} domain.ResponseSingleData[domain.BulkModerationResult]
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 403 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /moderation/comments/bulk [post]
+func (h *ModerationHandler) Moderate(c echo.Context) error {
+	var req domain.BulkModerationRequest
+	if err := c.Bind(&req); err != nil {
+		return badRequest("Invalid request payload")
+	}
+	if len(req.CommentIDs) > 100 {
+		return badRequest("At most 100 comments can be moderated at once")
+	}
+
+	moderatorID := ""
+	if caller := middleware.GetCallerFromEcho(c); caller != nil {
+		moderatorID = caller.ID
+	}
+
+	ctx := c.Request().Context()
+	result, err := h.Service.Moderate(ctx, moderatorID, &req)
+	if err != nil {
+		if errors.Is(err, domain.ErrBadParamInput) {
+			return withDetail(err, "comment_ids must be valid IDs and action one of approve, reject, spam")
+		}
+		return err
+	}
+
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.BulkModerationResult]{
+		Data:    *result,
+		Code:    http.StatusOK,
+		Message: "Comments successfully moderated",
+	})
+}
+
+// GetAuditLog godoc
+// @Summary Moderation audit log
+// @Description List every moderation decision taken on a comment
+// @Tags moderation
+// @Produce  json
+// @Param   id  path  string  true  "Comment ID"
+// @Success 200 {object} domain.ResponseMultipleData[domain.ModerationAudit]
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 403 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /moderation/comments/{id}/audit [get]
+func (h *ModerationHandler) GetAuditLog(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return badRequest("Invalid comment ID format")
+	}
+
+	ctx := c.Request().Context()
+	audits, err := h.Service.GetAuditLog(ctx, id)
+	if err != nil {
+		return forResource("comment", err)
+	}
+	if audits == nil {
+		audits = []domain.ModerationAudit{}
+	}
+
+	return c.JSON(http.StatusOK, domain.ResponseMultipleData[domain.ModerationAudit]{
+		Data:    audits,
+		Code:    http.StatusOK,
+		Message: "Successfully retrieve moderation audit",
+	})
+}
@@ -0,0 +1,261 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/logging"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type NotificationService interface {
+	GetNotifications(ctx context.Context, userID uuid.UUID, filter *domain.NotificationFilter) (*domain.NotificationPage, error)
+	CountUnread(ctx context.Context, userID uuid.UUID) (int, error)
+	MarkRead(ctx context.Context, userID uuid.UUID, ids []string) (int64, error)
+	MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error)
+	GetPreferences(ctx context.Context, userID uuid.UUID) (*domain.NotificationPreferences, error)
+	UpdatePreferences(ctx context.Context, userID uuid.UUID, prefs *domain.NotificationPreferences) (*domain.NotificationPreferences, error)
+}
+
+type NotificationHandler struct {
+	Service NotificationService
+}
+
+// UnreadCount is the body of the unread count endpoint
+type UnreadCount struct {
+	UnreadCount int `json:"unread_count"`
+}
+
+// MarkedRead is the body of the mark read endpoints
+type MarkedRead struct {
+	Updated int64 `json:"updated"`
+}
+
+// NewNotificationHandler registers the inbox of the calling user on the users
+// group, e.g. GET /users/me/notifications
+func NewNotificationHandler(e *echo.Group, svc NotificationService) {
+	handler := &NotificationHandler{
+		Service: svc,
+	}
+	e.GET("/me/notifications", handler.GetNotifications)
+	e.GET("/me/notifications/unread-count", handler.CountUnread)
+	e.POST("/me/notifications/read", handler.MarkRead)
+	e.POST("/me/notifications/read-all", handler.MarkAllRead)
+	e.GET("/me/notification-preferences", handler.GetPreferences)
+	e.PUT("/me/notification-preferences", handler.UpdatePreferences)
+}
+
+// GetNotifications godoc
+// @Summary List my notifications
+// @Description Get the caller's notifications newest first, with the number of unread ones
+// @Tags notifications
+// @Produce  json
+// @Param   unread  query  bool  false  "Only unread notifications"
+// @Param   page    query  int   false  "Page" default(1)
+// @Param   limit   query  int   false  "Notifications per page" default(20)
+// @Success 200 {object} domain.NotificationPage
+// @Failure 401 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /users/me/notifications [get]
+func (h *NotificationHandler) GetNotifications(c echo.Context) error {
+	userID, ok := callerID(c)
+	if !ok {
+		return unauthorized()
+	}
+
+	ctx := c.Request().Context()
+	filter := new(domain.NotificationFilter)
+	if err := c.Bind(filter); err != nil {
+		logging.LogWarn(ctx, "Failed to bind notification filter", slog.String("error", err.Error()))
+	}
+
+	page, err := h.Service.GetNotifications(ctx, userID, filter)
+	if err != nil {
+		return forResource("notification", err)
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+// CountUnread godoc
+// @Summary Count my unread notifications
+// @Tags notifications
+// @Produce  json
+// @Success 200 {object} domain.ResponseSingleData[UnreadCount]
+// @Failure 401 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /users/me/notifications/unread-count [get]
+func (h *NotificationHandler) CountUnread(c echo.Context) error {
+	userID, ok := callerID(c)
+	if !ok {
+		return unauthorized()
+	}
+
+	ctx := c.Request().Context()
+	count, err := h.Service.CountUnread(ctx, userID)
+	if err != nil {
+		return forResource("notification", err)
+	}
+
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[UnreadCount]{
+		Data:    UnreadCount{UnreadCount: count},
+		Code:    http.StatusOK,
+		Message: "Successfully counted unread notifications",
+	})
+}
+
+// MarkRead godoc
+// @Summary Mark notifications as read
+// @Tags notifications
+// @Accept  json
+// @Produce  json
+// @Param   request  body  domain.MarkNotificationsReadRequest  true  "Notification IDs"
+// @Success 200 {object} domain.ResponseSingleData[MarkedRead]
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /users/me/notifications/read [post]
+func (h *NotificationHandler) MarkRead(c echo.Context) error {
+	userID, ok := callerID(c)
+	if !ok {
+		return unauthorized()
+	}
+
+	var req domain.MarkNotificationsReadRequest
+	if err := c.Bind(&req); err != nil {
+		return badRequest("Invalid request payload")
+	}
+
+	ctx := c.Request().Context()
+	updated, err := h.Service.MarkRead(ctx, userID, req.IDs)
+	if err != nil {
+		if errors.Is(err, domain.ErrBadParamInput) {
+			return withDetail(err, "ids must be a non empty list of notification IDs")
+		}
+		return err
+	}
+
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[MarkedRead]{
+		Data:    MarkedRead{Updated: updated},
+		Code:    http.StatusOK,
+		Message: "Notifications marked as read",
+	})
+}
+
+// MarkAllRead godoc
+// @Summary Mark all notifications as read
+// @Tags notifications
+// @Produce  json
+// @Success 200 {object} domain.ResponseSingleData[MarkedRead]
+// @Failure 401 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /users/me/notifications/read-all [post]
+func (h *NotificationHandler) MarkAllRead(c echo.Context) error {
+	userID, ok := callerID(c)
+	if !ok {
+		return unauthorized()
+	}
+
+	ctx := c.Request().Context()
+	updated, err := h.Service.MarkAllRead(ctx, userID)
+	if err != nil {
+		return forResource("notification", err)
+	}
+
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[MarkedRead]{
+		Data:    MarkedRead{Updated: updated},
+		Code:    http.StatusOK,
+		Message: "All notifications marked as read",
+	})
+}
+
+// GetPreferences godoc
+// @Summary Get my notification preferences
+// @Tags notifications
+// @Produce  json
+// @Success 200 {object} domain.ResponseSingleData[domain.NotificationPreferences]
+// @Failure 401 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /users/me/notification-preferences [get]
+func (h *NotificationHandler) GetPreferences(c echo.Context) error {
+	userID, ok := callerID(c)
+	if !ok {
+		return unauthorized()
+	}
+
+	ctx := c.Request().Context()
+	prefs, err := h.Service.GetPreferences(ctx, userID)
+	if err != nil {
+		return forResource("notification", err)
+	}
+
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.NotificationPreferences]{
+		Data:    *prefs,
+		Code:    http.StatusOK,
+		Message: "Successfully retrieved notification preferences",
+	})
+}
+
+// UpdatePreferences godoc
+// @Summary Update my notification preferences
+// @Description replace the caller's notification preferences, omitted types are turned off
+// @Tags notifications
+// @Accept  json
+// @Produce  json
+// @Param   preferences  body  domain.NotificationPreferences  true  "Notification preferences"
+// @Success 200 {object} domain.ResponseSingleData[domain.NotificationPreferences]
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /users/me/notification-preferences [put]
+func (h *NotificationHandler) UpdatePreferences(c echo.Context) error {
+	userID, ok := callerID(c)
+	if !ok {
+		return unauthorized()
+	}
+
+	var prefs domain.NotificationPreferences
+	if err := c.Bind(&prefs); err != nil {
+		return badRequest("Invalid request payload")
+	}
+
+	ctx := c.Request().Context()
+	updated, err := h.Service.UpdatePreferences(ctx, userID, &prefs)
+	if err != nil {
+		return forResource("notification", err)
+	}
+
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.NotificationPreferences]{
+		Data:    *updated,
+		Code:    http.StatusOK,
+		Message: "Notification preferences successfully updated",
+	})
+}
+
+// callerID returns the ID of the authenticated caller, if any
+func callerID(c echo.Context) (uuid.UUID, bool) {
+	caller := middleware.GetCallerFromEcho(c)
+	if caller == nil {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(caller.ID)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func unauthorized() error {
+	return echo.NewHTTPError(http.StatusUnauthorized, "A valid user token is required")
+}
@@ -0,0 +1,41 @@
+package rest
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// maxPatchSize caps the size of a merge-patch document
+const maxPatchSize = 1 << 20
+
+var errUnsupportedPatchType = errors.New("unsupported patch media type")
+
+// readMergePatch returns the body of a PATCH request. Besides
+// application/merge-patch+json plain application/json is accepted, since
+// the document format is the same.
+func readMergePatch(c echo.Context) ([]byte, error) {
+	mediaType, _, err := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if err != nil || (mediaType != domain.MergePatchContentType && mediaType != echo.MIMEApplicationJSON) {
+		return nil, errUnsupportedPatchType
+	}
+	return io.ReadAll(io.LimitReader(c.Request().Body, maxPatchSize))
+}
+
+// invalidPatch rejects a PATCH whose document could not be read or failed
+// validation, listing the offending fields
+func invalidPatch(err error) error {
+	if errors.Is(err, errUnsupportedPatchType) {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "PATCH expects a "+domain.MergePatchContentType+" document")
+	}
+
+	var fieldErrs domain.FieldErrors
+	if !errors.As(err, &fieldErrs) {
+		fieldErrs = domain.FieldErrors{{Message: "Invalid request payload"}}
+	}
+	return fieldErrs
+}
@@ -2,13 +2,13 @@ package rest
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/edwinjordan/MajooTest-Golang/domain"
 	"github.com/edwinjordan/MajooTest-Golang/internal/logging"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"go.opentelemetry.io/otel"
@@ -21,7 +21,10 @@ type PostsService interface {
 	GetPostsList(ctx context.Context, filter *domain.PostsFilter) ([]domain.Posts, error)
 	GetPosts(ctx context.Context, id uuid.UUID) (*domain.Posts, error)
 	UpdatePosts(ctx context.Context, id uuid.UUID, post *domain.Posts) (*domain.Posts, error)
+	PatchPosts(ctx context.Context, id uuid.UUID, patch *domain.PostsPatch) (*domain.Posts, error)
 	DeletePosts(ctx context.Context, id uuid.UUID) error
+	RestorePosts(ctx context.Context, id uuid.UUID) (*domain.RestoredPost, error)
+	BatchPosts(ctx context.Context, req *domain.BatchRequest[domain.PostsBatchItem]) (*domain.BatchResult[domain.Posts], error)
 }
 
 type PostsHandler struct {
@@ -36,8 +39,12 @@ func NewPostsHandler(e *echo.Group, svc PostsService) {
 	e.GET("", handler.GetPostsList)
 	e.GET("/:id", handler.GetPosts)
 	e.POST("", handler.CreatePosts)
-	e.PUT("/:id", handler.UpdatePosts)
-	e.DELETE("/:id", handler.DeletePosts)
+	e.PUT("/:id", handler.UpdatePosts, middleware.IfMatch())
+	e.PATCH("/:id", handler.PatchPosts, middleware.IfMatch())
+	e.DELETE("/:id", handler.DeletePosts, middleware.IfMatch())
+	e.POST("/:id/restore", handler.RestorePosts, middleware.RequireRole(domain.RoleAdmin))
+	// The colon is escaped so echo matches it literally instead of as a path parameter
+	e.POST(`\:batch`, handler.BatchPosts)
 }
 
 // GetPosts godoc
@@ -46,7 +53,7 @@ func NewPostsHandler(e *echo.Group, svc PostsService) {
 // @Tags posts
 // @Produce  json
 // @Success 200 {array} domain.Posts
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /posts [get]
 func (h *PostsHandler) GetPostsList(c echo.Context) error {
@@ -59,11 +66,7 @@ func (h *PostsHandler) GetPostsList(c echo.Context) error {
 
 	posts, err := h.Service.GetPostsList(ctx, filter)
 	if err != nil {
-		logging.LogError(ctx, err, "get_posts_list")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseMultipleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to list posts: " + err.Error(),
-		})
+		return forResource("post", err)
 	}
 	if posts == nil {
 		posts = []domain.Posts{}
@@ -82,8 +85,10 @@ func (h *PostsHandler) GetPostsList(c echo.Context) error {
 // @Tags posts
 // @Produce  json
 // @Param        id   path      int  true  "Post ID"
+// @Param   If-None-Match  header  string  false  "ETag from an earlier response"
 // @Success 200 {array} domain.Posts
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Success 304 "Not modified since the ETag in If-None-Match"
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /posts/{id} [get]
 func (h *PostsHandler) GetPosts(c echo.Context) error {
@@ -96,33 +101,18 @@ func (h *PostsHandler) GetPosts(c echo.Context) error {
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "invalid UUID")
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid post ID format",
-		})
+		return badRequest("Invalid post ID format")
 	}
 
 	span.SetAttributes(attribute.String("post.id", id.String()))
 	post, err := h.Service.GetPosts(ctx, id)
 	if err != nil {
 		span.RecordError(err)
-		if errors.Is(err, sql.ErrNoRows) {
-			span.SetStatus(codes.Error, "not found")
-			return c.JSON(http.StatusNotFound, domain.ResponseSingleData[domain.Empty]{
-				Code:    http.StatusNotFound,
-				Message: "Post not found",
-			})
-		}
-
 		span.SetStatus(codes.Error, "service error")
-		logging.LogError(ctx, err, "get_post")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to get post: " + err.Error(),
-		})
+		return forResource("post", err)
 	}
 
-	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.Posts]{
+	return jsonWithETag(c, post.Version, domain.ResponseSingleData[domain.Posts]{
 		Data:    *post,
 		Code:    http.StatusOK,
 		Message: "Successfully retrieved user",
@@ -136,27 +126,27 @@ func (h *PostsHandler) GetPosts(c echo.Context) error {
 // @Accept  json
 // @Produce  json
 // @Param   post  body  domain.CreatePostsRequestSwagger  true  "Post data"
+// @Param   Idempotency-Key  header  string  false  "Replays the first response when the request is retried"
 // @Success 201 {object} domain.CreatePostsRequest
-// @Failure 400 {object} domain.ResponseSingleData[domain.Empty]
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Failure 400 {object} domain.Problem
+// @Failure 409 {object} domain.Problem
+// @Failure 422 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
 // @Router /posts [post]
 func (h *PostsHandler) CreatePosts(c echo.Context) error {
 	var post domain.CreatePostsRequest
 	if err := c.Bind(&post); err != nil {
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid request payload",
-		})
+		return badRequest("Invalid request payload")
+	}
+
+	if caller := middleware.GetCallerFromEcho(c); caller != nil {
+		post.UserID = caller.ID
 	}
 
 	ctx := c.Request().Context()
 	createdPost, err := h.Service.CreatePosts(ctx, &post)
 	if err != nil {
-		logging.LogError(ctx, err, "create_post")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to create post: " + err.Error(),
-		})
+		return forResource("post", err)
 	}
 
 	return c.JSON(http.StatusCreated, domain.ResponseSingleData[domain.Posts]{
@@ -174,40 +164,34 @@ func (h *PostsHandler) CreatePosts(c echo.Context) error {
 // @Produce  json
 // @Param   id    path  string             true  "Post ID"
 // @Param   post  body  domain.UpdatePostsRequestSwagger  true  "Updated post data"
+// @Param   If-Match  header  string  false  "ETag of the version being changed"
 // @Success 200 {object} domain.Posts
-// @Failure 400 {object} domain.ResponseSingleData[domain.Empty]
-// @Failure 404 {object} domain.ResponseSingleData[domain.Empty]
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Failure 400 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 412 {object} domain.Problem
+// @Failure 428 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /posts/{id} [put]
 func (h *PostsHandler) UpdatePosts(c echo.Context) error {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid post ID format",
-		})
+		return badRequest("Invalid post ID format")
 	}
 
 	var post domain.Posts
 	if err := c.Bind(&post); err != nil {
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid request payload",
-		})
+		return badRequest("Invalid request payload")
 	}
 
 	ctx := c.Request().Context()
 	updatedPost, err := h.Service.UpdatePosts(ctx, id, &post)
 	if err != nil {
-		logging.LogError(ctx, err, "update_post")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to update post: " + err.Error(),
-		})
+		return forResource("post", err)
 	}
 
+	c.Response().Header().Set("ETag", middleware.ETag(updatedPost.Version))
 	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.Posts]{
 		Data:    *updatedPost,
 		Code:    http.StatusOK,
@@ -215,34 +199,77 @@ func (h *PostsHandler) UpdatePosts(c echo.Context) error {
 	})
 }
 
+// PatchPosts godoc
+// @Summary Patch post
+// @Description Change only the supplied fields of a post, see RFC 7396. Fields cannot be removed with null.
+// @Tags posts
+// @Accept  application/merge-patch+json
+// @Produce  json
+// @Param   id    path  string  true  "Post ID"
+// @Param   patch  body  domain.PostsPatch  true  "Fields to change"
+// @Param   If-Match  header  string  false  "ETag of the version being changed"
+// @Success 200 {object} domain.ResponseSingleData[domain.Posts]
+// @Failure 400 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 412 {object} domain.Problem
+// @Failure 415 {object} domain.Problem
+// @Failure 428 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /posts/{id} [patch]
+func (h *PostsHandler) PatchPosts(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return badRequest("Invalid post ID format")
+	}
+
+	doc, err := readMergePatch(c)
+	if err != nil {
+		return invalidPatch(err)
+	}
+	patch, err := domain.ParsePostsPatch(doc)
+	if err != nil {
+		return invalidPatch(err)
+	}
+
+	ctx := c.Request().Context()
+	patched, err := h.Service.PatchPosts(ctx, id, patch)
+	if err != nil {
+		return forResource("post", err)
+	}
+
+	c.Response().Header().Set("ETag", middleware.ETag(patched.Version))
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.Posts]{
+		Data:    *patched,
+		Code:    http.StatusOK,
+		Message: "Post successfully patched",
+	})
+}
+
 // DeleteUser godoc
 // @Summary Delete user
 // @Description delete an existing user entry by ID
 // @Tags user
 // @Produce  json
 // @Param   id   path  string  true  "User ID"
+// @Param   If-Match  header  string  false  "ETag of the version being changed"
 // @Success 204 {object} nil
-// @Failure 404 {object} domain.ResponseSingleData[domain.Empty]
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Failure 404 {object} domain.Problem
+// @Failure 412 {object} domain.Problem
+// @Failure 428 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /users/{id} [delete]
 func (h *UserHandler) DeleteUser(c echo.Context) error {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid user ID format",
-		})
+		return badRequest("Invalid user ID format")
 	}
 
 	ctx := c.Request().Context()
 	if err := h.Service.DeleteUser(ctx, id); err != nil {
-		logging.LogError(ctx, err, "delete_user")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to delete user: " + err.Error(),
-		})
+		return forResource("user", err)
 	}
 
 	return c.JSON(http.StatusNoContent, domain.ResponseSingleData[domain.Empty]{
@@ -257,28 +284,24 @@ func (h *UserHandler) DeleteUser(c echo.Context) error {
 // @Tags posts
 // @Produce  json
 // @Param   id   path  string  true  "Post ID"
+// @Param   If-Match  header  string  false  "ETag of the version being changed"
 // @Success 204 {object} nil
-// @Failure 404 {object} domain.ResponseSingleData[domain.Empty]
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Failure 404 {object} domain.Problem
+// @Failure 412 {object} domain.Problem
+// @Failure 428 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /posts/{id} [delete]
 func (h *PostsHandler) DeletePosts(c echo.Context) error {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid user ID format",
-		})
+		return badRequest("Invalid user ID format")
 	}
 
 	ctx := c.Request().Context()
 	if err := h.Service.DeletePosts(ctx, id); err != nil {
-		logging.LogError(ctx, err, "delete_post")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to delete post: " + err.Error(),
-		})
+		return forResource("post", err)
 	}
 
 	return c.JSON(http.StatusNoContent, domain.ResponseSingleData[domain.Empty]{
@@ -286,3 +309,78 @@ func (h *PostsHandler) DeletePosts(c echo.Context) error {
 		Message: "User successfully deleted",
 	})
 }
+
+// RestorePosts godoc
+// @Summary Restore post
+// @Description Undelete a soft-deleted post together with the comments deleted with it. Admins only.
+// @Tags posts
+// @Produce  json
+// @Param   id   path  string  true  "Post ID"
+// @Success 200 {object} domain.ResponseSingleData[domain.RestoredPost]
+// @Failure 400 {object} domain.Problem
+// @Failure 403 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /posts/{id}/restore [post]
+func (h *PostsHandler) RestorePosts(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return badRequest("Invalid post ID format")
+	}
+
+	ctx := c.Request().Context()
+	restored, err := h.Service.RestorePosts(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return withDetail(err, "No deleted post with this ID")
+		}
+		return forResource("post", err)
+	}
+
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.RestoredPost]{
+		Data:    *restored,
+		Code:    http.StatusOK,
+		Message: "Post successfully restored",
+	})
+}
+
+// BatchPosts godoc
+// @Summary Batch posts
+// @Description Create, update and delete up to 500 posts in one request. An atomic batch applies every item or none, a best_effort batch applies the items it can. Every item is reported by its index, 207 means some items failed and 422 that an atomic batch was rolled back.
+// @Tags posts
+// @Accept  json
+// @Produce  json
+// @Param   batch  body  domain.BatchRequest[domain.PostsBatchItem]  true  "Batch items"
+// @Success 200 {object} domain.ResponseSingleData[domain.BatchResult[domain.Posts]]
+// @Success 207 {object} domain.ResponseSingleData[domain.BatchResult[domain.Posts]]
+// @Failure 400 {object} domain.Problem
+// @Failure 422 {object} domain.ResponseSingleData[domain.BatchResult[domain.Posts]]
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /posts:batch [post]
+func (h *PostsHandler) BatchPosts(c echo.Context) error {
+	var req domain.BatchRequest[domain.PostsBatchItem]
+	if err := c.Bind(&req); err != nil {
+		return badRequest("Invalid request payload")
+	}
+
+	if caller := middleware.GetCallerFromEcho(c); caller != nil {
+		for i := range req.Items {
+			req.Items[i].UserID = caller.ID
+		}
+	}
+
+	ctx := c.Request().Context()
+	result, err := h.Service.BatchPosts(ctx, &req)
+	if err != nil {
+		return forResource("post", err)
+	}
+
+	status, message := batchStatus(result)
+	return c.JSON(status, domain.ResponseSingleData[domain.BatchResult[domain.Posts]]{
+		Data:    *result,
+		Code:    status,
+		Message: message,
+	})
+}
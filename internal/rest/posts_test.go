@@ -128,15 +128,15 @@ func TestPostsCRUD_E2E(t *testing.T) {
 	resp.Body.Close()
 
 	// Get after delete
-	type ErrType domain.ResponseSingleData[domain.Empty]
-	errE, code := doRequest[ErrType](
+	errE, code := doRequest[domain.Problem](
 		t, http.MethodGet,
 		fmt.Sprintf("%s/api/v1/posts/%s", kit.BaseURL, post.ID),
 		nil,
 		map[string]string{"Authorization": "Bearer " + token},
 	)
 	require.Equal(t, http.StatusNotFound, code)
-	require.Equal(t, "Post not found", errE.Message)
+	require.Equal(t, "Post not found", errE.Detail)
+	require.Equal(t, domain.ProblemNotFound, errE.Code)
 
 	// Hard delete, since delete API uses soft delete
 	_, err = kit.DB.Exec(context.Background(), "DELETE from posts where id = $1", post.ID)
@@ -0,0 +1,273 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/logging"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Postgres error codes that are the client's fault
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgNotNullViolation    = "23502"
+	pgCheckViolation      = "23514"
+	pgInvalidText         = "22P02"
+	pgStringTooLong       = "22001"
+)
+
+// resourceError names the resource an error is about, so that its problem
+// reads "Post not found" rather than a generic detail
+type resourceError struct {
+	resource string
+	err      error
+}
+
+func (e *resourceError) Error() string {
+	return e.resource + ": " + e.err.Error()
+}
+
+func (e *resourceError) Unwrap() error {
+	return e.err
+}
+
+// forResource attaches the name of the resource, e.g. "post", to err
+func forResource(resource string, err error) error {
+	return &resourceError{resource: resource, err: err}
+}
+
+// detailError replaces the generic detail of the problem an error maps to
+type detailError struct {
+	detail string
+	err    error
+}
+
+func (e *detailError) Error() string {
+	return e.detail + ": " + e.err.Error()
+}
+
+func (e *detailError) Unwrap() error {
+	return e.err
+}
+
+// withDetail explains err to the client in the handler's own words, the
+// status and code still follow from err
+func withDetail(err error, detail string) error {
+	return &detailError{detail: detail, err: err}
+}
+
+// badRequest rejects a request with the given detail
+func badRequest(detail string) error {
+	return echo.NewHTTPError(http.StatusBadRequest, detail)
+}
+
+// HTTPErrorHandler writes the errors returned by handlers and middleware as
+// RFC 7807 problem details. Errors that are not the client's fault are
+// logged and answered with a generic detail.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	ctx := c.Request().Context()
+	problem := problemFor(err)
+	var detailErr *detailError
+	if errors.As(err, &detailErr) && problem.Status < http.StatusInternalServerError {
+		problem.Detail = detailErr.detail
+	}
+	if problem.Status >= http.StatusInternalServerError {
+		logging.LogError(ctx, err, "http_request")
+	}
+
+	problem.Title = http.StatusText(problem.Status)
+	problem.Type = problem.Code.Type()
+	problem.Instance = c.Request().URL.Path
+	problem.RequestID = middleware.GetRequestIDFromEcho(c)
+	if span := trace.SpanContextFromContext(ctx); span.HasTraceID() {
+		problem.TraceID = span.TraceID().String()
+	}
+
+	var writeErr error
+	if c.Request().Method == http.MethodHead {
+		writeErr = c.NoContent(problem.Status)
+	} else {
+		c.Response().Header().Set(echo.HeaderContentType, domain.ProblemContentType)
+		writeErr = c.JSON(problem.Status, problem)
+	}
+	if writeErr != nil {
+		logging.LogError(ctx, writeErr, "write_problem")
+	}
+}
+
+// problemFor maps an error to its status, code and detail
+func problemFor(err error) *domain.Problem {
+	var (
+		resource  string
+		resErr    *resourceError
+		httpErr   *echo.HTTPError
+		fieldErrs domain.FieldErrors
+		pgErr     *pgconn.PgError
+	)
+	if errors.As(err, &resErr) {
+		resource = resErr.resource
+	}
+
+	switch {
+	case errors.As(err, &fieldErrs):
+		return &domain.Problem{
+			Status: http.StatusBadRequest,
+			Code:   domain.ProblemValidationFailed,
+			Detail: "The request failed validation",
+			Errors: fieldErrs,
+		}
+	case errors.As(err, &httpErr):
+		return &domain.Problem{
+			Status: httpErr.Code,
+			Code:   codeForStatus(httpErr.Code),
+			Detail: httpErrorDetail(httpErr),
+		}
+	case errors.Is(err, domain.ErrNotFound),
+		errors.Is(err, domain.ErrUserNotFound),
+		errors.Is(err, domain.ErrCSVJobNotFound),
+		errors.Is(err, pgx.ErrNoRows):
+		return &domain.Problem{
+			Status: http.StatusNotFound,
+			Code:   domain.ProblemNotFound,
+			Detail: describe(resource, "not found"),
+		}
+	case errors.Is(err, domain.ErrForbidden):
+		return &domain.Problem{Status: http.StatusForbidden, Code: domain.ProblemForbidden, Detail: err.Error()}
+	case errors.Is(err, domain.ErrPreconditionFailed):
+		return &domain.Problem{
+			Status: http.StatusPreconditionFailed,
+			Code:   domain.ProblemVersionMismatch,
+			Detail: describe(resource, "was modified since the version in If-Match"),
+		}
+	case errors.Is(err, domain.ErrConflict):
+		return &domain.Problem{
+			Status: http.StatusConflict,
+			Code:   domain.ProblemAlreadyExists,
+			Detail: describe(resource, "already exists"),
+		}
+	case errors.Is(err, domain.ErrCSVJobState):
+		return &domain.Problem{Status: http.StatusConflict, Code: domain.ProblemConflict, Detail: err.Error()}
+	case errors.Is(err, domain.ErrIdempotencyInFlight):
+		return &domain.Problem{Status: http.StatusConflict, Code: domain.ProblemRequestInProgress, Detail: err.Error()}
+	case errors.Is(err, domain.ErrIdempotencyKeyReused):
+		return &domain.Problem{Status: http.StatusUnprocessableEntity, Code: domain.ProblemIdempotencyKeyReused, Detail: err.Error()}
+	case errors.Is(err, domain.ErrCommentTooDeep):
+		return &domain.Problem{Status: http.StatusUnprocessableEntity, Code: domain.ProblemThreadTooDeep, Detail: err.Error()}
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		return &domain.Problem{Status: http.StatusUnauthorized, Code: domain.ProblemInvalidCredentials, Detail: "Invalid email or password"}
+	case errors.Is(err, domain.ErrBadParamInput), errors.Is(err, domain.ErrCSVFileInvalid),
+		errors.Is(err, domain.ErrCSVUnknownType):
+		return &domain.Problem{Status: http.StatusBadRequest, Code: domain.ProblemValidationFailed, Detail: err.Error()}
+	case errors.As(err, &pgErr):
+		return problemForPgError(pgErr, resource)
+	}
+
+	return &domain.Problem{
+		Status: http.StatusInternalServerError,
+		Code:   domain.ProblemInternal,
+		Detail: "The server could not complete the request",
+	}
+}
+
+// problemForPgError maps the constraint violations a request can cause, any
+// other database error is the server's
+func problemForPgError(pgErr *pgconn.PgError, resource string) *domain.Problem {
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		return &domain.Problem{
+			Status: http.StatusConflict,
+			Code:   domain.ProblemAlreadyExists,
+			Detail: describe(resource, "already exists"),
+		}
+	case pgForeignKeyViolation:
+		return &domain.Problem{
+			Status: http.StatusUnprocessableEntity,
+			Code:   domain.ProblemInvalidReference,
+			Detail: "The request refers to an item that does not exist",
+		}
+	case pgNotNullViolation, pgCheckViolation, pgInvalidText, pgStringTooLong:
+		problem := &domain.Problem{
+			Status: http.StatusBadRequest,
+			Code:   domain.ProblemValidationFailed,
+			Detail: "The request contains an invalid value",
+		}
+		if pgErr.ColumnName != "" {
+			problem.Errors = domain.FieldErrors{{Field: pgErr.ColumnName, Message: "is invalid"}}
+		}
+		return problem
+	}
+	return &domain.Problem{
+		Status: http.StatusInternalServerError,
+		Code:   domain.ProblemInternal,
+		Detail: "The server could not complete the request",
+	}
+}
+
+// codeForStatus is the code of errors that only carry a status, like the
+// ones echo and the middleware return
+func codeForStatus(status int) domain.ProblemCode {
+	switch status {
+	case http.StatusBadRequest:
+		return domain.ProblemBadRequest
+	case http.StatusUnauthorized:
+		return domain.ProblemUnauthorized
+	case http.StatusForbidden:
+		return domain.ProblemForbidden
+	case http.StatusNotFound:
+		return domain.ProblemNotFound
+	case http.StatusMethodNotAllowed:
+		return domain.ProblemMethodNotAllowed
+	case http.StatusRequestTimeout:
+		return domain.ProblemRequestTimeout
+	case http.StatusConflict:
+		return domain.ProblemConflict
+	case http.StatusPreconditionFailed:
+		return domain.ProblemVersionMismatch
+	case http.StatusRequestEntityTooLarge:
+		return domain.ProblemPayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return domain.ProblemUnsupportedMediaType
+	case http.StatusUnprocessableEntity:
+		return domain.ProblemValidationFailed
+	case http.StatusPreconditionRequired:
+		return domain.ProblemPreconditionRequired
+	case http.StatusTooManyRequests:
+		return domain.ProblemRateLimited
+	}
+	if status < http.StatusInternalServerError {
+		return domain.ProblemBadRequest
+	}
+	return domain.ProblemInternal
+}
+
+func httpErrorDetail(httpErr *echo.HTTPError) string {
+	if httpErr.Code >= http.StatusInternalServerError {
+		return "The server could not complete the request"
+	}
+	if message, ok := httpErr.Message.(string); ok {
+		return message
+	}
+	return fmt.Sprint(httpErr.Message)
+}
+
+// describe says what happened to the resource, e.g. "Post not found", or
+// to an unnamed item
+func describe(resource, what string) string {
+	if resource == "" {
+		resource = "item"
+	}
+	return strings.ToUpper(resource[:1]) + resource[1:] + " " + what
+}
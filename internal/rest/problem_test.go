@@ -0,0 +1,78 @@
+package rest_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   domain.ProblemCode
+		detail string
+	}{
+		{"pgx no rows", fmt.Errorf("get post: %w", pgx.ErrNoRows), http.StatusNotFound, domain.ProblemNotFound, "Item not found"},
+		{"domain not found", domain.ErrUserNotFound, http.StatusNotFound, domain.ProblemNotFound, "Item not found"},
+		{"version mismatch", domain.ErrPreconditionFailed, http.StatusPreconditionFailed, domain.ProblemVersionMismatch, "Item was modified since the version in If-Match"},
+		{"forbidden", domain.ErrForbidden, http.StatusForbidden, domain.ProblemForbidden, "you are not allowed to do this"},
+		{"conflict", domain.ErrConflict, http.StatusConflict, domain.ProblemAlreadyExists, "Item already exists"},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, http.StatusConflict, domain.ProblemAlreadyExists, "Item already exists"},
+		{"foreign key violation", &pgconn.PgError{Code: "23503"}, http.StatusUnprocessableEntity, domain.ProblemInvalidReference, "The request refers to an item that does not exist"},
+		{"bad param", fmt.Errorf("%w: title is required", domain.ErrBadParamInput), http.StatusBadRequest, domain.ProblemValidationFailed, "given Param is not valid: title is required"},
+		{"echo error", echo.NewHTTPError(http.StatusUnauthorized, "missing token"), http.StatusUnauthorized, domain.ProblemUnauthorized, "missing token"},
+		{"unknown error", fmt.Errorf("connection refused"), http.StatusInternalServerError, domain.ProblemInternal, "The server could not complete the request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = rest.HTTPErrorHandler
+			e.GET("/posts/:id", func(c echo.Context) error { return tt.err })
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts/1", nil))
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, domain.ProblemContentType, rec.Header().Get(echo.HeaderContentType))
+
+			var problem domain.Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, tt.status, problem.Status)
+			assert.Equal(t, tt.code, problem.Code)
+			assert.Equal(t, tt.code.Type(), problem.Type)
+			assert.Equal(t, http.StatusText(tt.status), problem.Title)
+			assert.Equal(t, tt.detail, problem.Detail)
+			assert.Equal(t, "/posts/1", problem.Instance)
+		})
+	}
+
+	t.Run("field errors are listed", func(t *testing.T) {
+		e := echo.New()
+		e.HTTPErrorHandler = rest.HTTPErrorHandler
+		e.PATCH("/posts/:id", func(c echo.Context) error {
+			return domain.FieldErrors{{Field: "title", Message: "must not be empty"}}
+		})
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/posts/1", nil))
+
+		var problem domain.Problem
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, domain.ProblemValidationFailed, problem.Code)
+		assert.Equal(t, domain.FieldErrors{{Field: "title", Message: "must not be empty"}}, problem.Errors)
+	})
+}
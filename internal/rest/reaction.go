@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type ReactionService interface {
+	React(ctx context.Context, target domain.ReactionTarget, targetID uuid.UUID, userID string, reactionType domain.ReactionType) (*domain.ReactionSummary, error)
+	Unreact(ctx context.Context, target domain.ReactionTarget, targetID uuid.UUID, userID string, reactionType domain.ReactionType) (*domain.ReactionSummary, error)
+}
+
+type ReactionHandler struct {
+	Service ReactionService
+	Target  domain.ReactionTarget
+}
+
+// NewReactionHandler registers the reaction routes of target on a posts or
+// comments group, e.g. PUT /posts/:id/reactions/:type
+func NewReactionHandler(e *echo.Group, target domain.ReactionTarget, svc ReactionService) {
+	handler := &ReactionHandler{
+		Service: svc,
+		Target:  target,
+	}
+	e.PUT("/:id/reactions/:type", handler.React)
+	e.DELETE("/:id/reactions/:type", handler.Unreact)
+}
+
+// React godoc
+// @Summary Add reaction
+// @Description react to a post or comment, reacting twice with the same type is a no-op
+// @Tags reactions
+// @Produce  json
+// @Param   id    path  string  true  "Post or comment ID"
+// @Param   type  path  string  true  "Reaction type" Enums(like, love, laugh, wow, sad, angry)
+// @Success 200 {object} domain.ResponseSingleData[domain.ReactionSummary]
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /posts/{id}/reactions/{type} [put]
+// @Router /comments/{id}/reactions/{type} [put]
+func (h *ReactionHandler) React(c echo.Context) error {
+	return h.handle(c, h.Service.React, "Reaction successfully added")
+}
+
+// Unreact godoc
+// @Summary Remove reaction
+// @Description remove the caller's reaction of the given type from a post or comment
+// @Tags reactions
+// @Produce  json
+// @Param   id    path  string  true  "Post or comment ID"
+// @Param   type  path  string  true  "Reaction type" Enums(like, love, laugh, wow, sad, angry)
+// @Success 200 {object} domain.ResponseSingleData[domain.ReactionSummary]
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /posts/{id}/reactions/{type} [delete]
+// @Router /comments/{id}/reactions/{type} [delete]
+func (h *ReactionHandler) Unreact(c echo.Context) error {
+	return h.handle(c, h.Service.Unreact, "Reaction successfully removed")
+}
+
+type reactionFunc func(ctx context.Context, target domain.ReactionTarget, targetID uuid.UUID, userID string, reactionType domain.ReactionType) (*domain.ReactionSummary, error)
+
+func (h *ReactionHandler) handle(c echo.Context, fn reactionFunc, message string) error {
+	caller := middleware.GetCallerFromEcho(c)
+	if caller == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "A valid user token is required to react")
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return badRequest("Invalid " + string(h.Target) + " ID format")
+	}
+
+	reactionType := domain.ReactionType(c.Param("type"))
+	if !reactionType.IsValid() {
+		return badRequest("Unsupported reaction type")
+	}
+
+	ctx := c.Request().Context()
+	summary, err := fn(ctx, h.Target, id, caller.ID, reactionType)
+	if err != nil {
+		return forResource(string(h.Target), err)
+	}
+
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.ReactionSummary]{
+		Data:    *summary,
+		Code:    http.StatusOK,
+		Message: message,
+	})
+}
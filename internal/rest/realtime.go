@@ -0,0 +1,207 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/realtime"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// wsWriteWait bounds a single write to the connection
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is how long the connection may stay silent, pings from
+	// the server are answered with pongs so a live client never hits it
+	wsPongWait    = 60 * time.Second
+	wsPingPeriod  = wsPongWait * 9 / 10
+	wsMaxReadSize = 4096
+)
+
+type RealtimeHub interface {
+	Register() *realtime.Client
+	Unregister(client *realtime.Client)
+	Subscribe(client *realtime.Client, channel string) error
+	Unsubscribe(client *realtime.Client, channel string)
+	Reply(client *realtime.Client, msg realtime.Message) error
+}
+
+// SubscriptionRequest is what clients send over the socket to change their
+// subscriptions
+type SubscriptionRequest struct {
+	Action  string `json:"action" enums:"subscribe,unsubscribe"`
+	Channel string `json:"channel" example:"post:3f2b6c1e-8d4a-4b5e-9f1a-2c3d4e5f6a7b"`
+}
+
+type RealtimeHandler struct {
+	Hub      RealtimeHub
+	Upgrader websocket.Upgrader
+}
+
+// NewRealtimeHandler registers the websocket endpoint, e.g. GET /ws
+func NewRealtimeHandler(e *echo.Group, hub RealtimeHub) {
+	handler := &RealtimeHandler{
+		Hub: hub,
+		Upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			// Browsers cannot send the Authorization header on a websocket,
+			// so the token travels in the URL and CORS does not apply
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+	e.GET("", handler.Connect)
+}
+
+// Connect godoc
+// @Summary Real-time post and comment events
+// @Description Upgrades to a websocket streaming created, updated and deleted events of posts and comments.
+// @Description Subscribe with ?channel= or by sending {"action":"subscribe","channel":"post:<id>"};
+// @Description "user:<id>" channels carry the activity of a user and are limited to that user and moderators.
+// @Description Browsers pass the token as ?access_token=. Clients that fall behind are disconnected with close code 1013.
+// @Tags realtime
+// @Param   channel       query  []string  false  "Channels to subscribe to right away"  collectionFormat(multi)
+// @Param   access_token  query  string    false  "JWT, when the Authorization header cannot be set"
+// @Success 101 {object} realtime.Message
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 403 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /ws [get]
+func (h *RealtimeHandler) Connect(c echo.Context) error {
+	caller := middleware.GetCallerFromEcho(c)
+	if caller == nil {
+		return unauthorized()
+	}
+
+	channels := c.QueryParams()["channel"]
+	for _, channel := range channels {
+		if err := authorizeChannel(caller, channel); err != nil {
+			return channelError(err)
+		}
+	}
+
+	conn, err := h.Upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		// The upgrader already answered the client
+		return nil
+	}
+
+	client := h.Hub.Register()
+	go h.writePump(conn, client)
+
+	for _, channel := range channels {
+		h.subscribe(client, channel)
+	}
+	h.readPump(conn, client, caller)
+	return nil
+}
+
+// readPump handles subscription requests until the connection fails, then
+// unregisters the client, which ends writePump
+func (h *RealtimeHandler) readPump(conn *websocket.Conn, client *realtime.Client, caller *domain.Caller) {
+	defer h.Hub.Unregister(client)
+
+	conn.SetReadLimit(wsMaxReadSize)
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var req SubscriptionRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			_ = h.Hub.Reply(client, realtime.Message{Type: realtime.MessageError, Message: "invalid message"})
+			continue
+		}
+
+		switch req.Action {
+		case "subscribe":
+			if err := authorizeChannel(caller, req.Channel); err != nil {
+				_ = h.Hub.Reply(client, realtime.Message{Type: realtime.MessageError, Channel: req.Channel, Message: err.Error()})
+				continue
+			}
+			h.subscribe(client, req.Channel)
+		case "unsubscribe":
+			h.Hub.Unsubscribe(client, req.Channel)
+			_ = h.Hub.Reply(client, realtime.Message{Type: realtime.MessageUnsubscribed, Channel: req.Channel})
+		default:
+			_ = h.Hub.Reply(client, realtime.Message{Type: realtime.MessageError, Message: "unknown action"})
+		}
+	}
+}
+
+func (h *RealtimeHandler) subscribe(client *realtime.Client, channel string) {
+	if err := h.Hub.Subscribe(client, channel); err != nil {
+		_ = h.Hub.Reply(client, realtime.Message{Type: realtime.MessageError, Channel: channel, Message: err.Error()})
+		return
+	}
+	_ = h.Hub.Reply(client, realtime.Message{Type: realtime.MessageSubscribed, Channel: channel})
+}
+
+// writePump writes queued messages and keeps the connection alive with
+// pings. It closes the connection once the client's queue is closed.
+func (h *RealtimeHandler) writePump(conn *websocket.Conn, client *realtime.Client) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-client.Send():
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				code, reason := websocket.CloseNormalClosure, ""
+				if errors.Is(client.Err(), realtime.ErrSlowConsumer) {
+					code, reason = websocket.CloseTryAgainLater, "too slow"
+				}
+				_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				h.Hub.Unregister(client)
+				return
+			}
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				h.Hub.Unregister(client)
+				return
+			}
+		}
+	}
+}
+
+var errChannelForbidden = errors.New("not allowed to subscribe to this channel")
+
+// authorizeChannel lets anybody follow a post, but only the user and
+// moderators follow a user's activity
+func authorizeChannel(caller *domain.Caller, channel string) error {
+	kind, id, err := realtime.ParseChannel(channel)
+	if err != nil {
+		return err
+	}
+	if kind == "user" && id != caller.ID && !caller.HasRole(domain.RoleModerator, domain.RoleAdmin) {
+		return errChannelForbidden
+	}
+	return nil
+}
+
+func channelError(err error) error {
+	if errors.Is(err, errChannelForbidden) {
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	return badRequest("Channels must be post:<id> or user:<id>")
+}
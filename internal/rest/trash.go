@@ -0,0 +1,102 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/logging"
+	"github.com/labstack/echo/v4"
+)
+
+type TrashService interface {
+	GetTrash(ctx context.Context, entity domain.TrashEntity, filter *domain.TrashFilter) ([]domain.TrashItem, int, error)
+	CountTrash(ctx context.Context) (domain.TrashSummary, error)
+}
+
+type TrashHandler struct {
+	Service TrashService
+}
+
+// NewTrashHandler registers the trash listings. The group is expected to only
+// let admins through, see middleware.RequireRole.
+func NewTrashHandler(e *echo.Group, svc TrashService) {
+	handler := &TrashHandler{
+		Service: svc,
+	}
+	e.GET("", handler.CountTrash)
+	e.GET("/:entity", handler.GetTrash)
+}
+
+// CountTrash godoc
+// @Summary Trash summary
+// @Description Count the soft-deleted users, posts and comments
+// @Tags trash
+// @Produce  json
+// @Success 200 {object} domain.ResponseSingleData[domain.TrashSummary]
+// @Failure 401 {object} domain.Problem
+// @Failure 403 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /trash [get]
+func (h *TrashHandler) CountTrash(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	summary, err := h.Service.CountTrash(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.TrashSummary]{
+		Data:    summary,
+		Code:    http.StatusOK,
+		Message: "Successfully counted trash",
+	})
+}
+
+// GetTrash godoc
+// @Summary List trash
+// @Description List soft-deleted users, posts or comments, most recently deleted first. Restore them with POST /{entity}/{id}/restore.
+// @Tags trash
+// @Produce  json
+// @Param   entity  path   string  true   "Entity" Enums(users, posts, comments)
+// @Param   page    query  int     false  "Page" default(1)
+// @Param   limit   query  int     false  "Items per page" default(20)
+// @Success 200 {object} domain.PaginatedResponse{data=[]domain.TrashItem}
+// @Failure 400 {object} domain.Problem
+// @Failure 401 {object} domain.Problem
+// @Failure 403 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /trash/{entity} [get]
+func (h *TrashHandler) GetTrash(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	filter := new(domain.TrashFilter)
+	if err := c.Bind(filter); err != nil {
+		logging.LogWarn(ctx, "Failed to bind trash filter", slog.String("error", err.Error()))
+	}
+
+	items, total, err := h.Service.GetTrash(ctx, domain.TrashEntity(c.Param("entity")), filter)
+	if err != nil {
+		if errors.Is(err, domain.ErrBadParamInput) {
+			return withDetail(err, "Trash holds users, posts and comments")
+		}
+		return err
+	}
+	if items == nil {
+		items = []domain.TrashItem{}
+	}
+
+	return c.JSON(http.StatusOK, domain.PaginatedResponse{
+		Data: items,
+		Pagination: domain.PaginationInfo{
+			Page:       filter.Page,
+			Limit:      filter.Limit,
+			Total:      total,
+			TotalPages: (total + filter.Limit - 1) / filter.Limit,
+		},
+	})
+}
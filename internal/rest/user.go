@@ -2,13 +2,13 @@ package rest
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/edwinjordan/MajooTest-Golang/domain"
 	"github.com/edwinjordan/MajooTest-Golang/internal/logging"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -22,7 +22,10 @@ type UserService interface {
 	GetUserList(ctx context.Context, filter *domain.UserFilter) ([]domain.User, error)
 	GetUser(ctx context.Context, id uuid.UUID) (*domain.User, error)
 	UpdateUser(ctx context.Context, id uuid.UUID, user *domain.User) (*domain.User, error)
+	PatchUser(ctx context.Context, id uuid.UUID, patch *domain.UserPatch) (*domain.User, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	RestoreUser(ctx context.Context, id uuid.UUID) (*domain.User, error)
+	BatchUsers(ctx context.Context, req *domain.BatchRequest[domain.UserBatchItem]) (*domain.BatchResult[domain.User], error)
 }
 
 type UserHandler struct {
@@ -37,8 +40,11 @@ func NewUserHandler(e *echo.Group, svc UserService) {
 	e.GET("", handler.GetUserList)
 	e.GET("/:id", handler.GetUser)
 	e.POST("", handler.CreateUser)
-	e.PUT("/:id", handler.UpdateUser)
-	e.DELETE("/:id", handler.DeleteUser)
+	e.PUT("/:id", handler.UpdateUser, middleware.IfMatch())
+	e.PATCH("/:id", handler.PatchUser, middleware.IfMatch())
+	e.DELETE("/:id", handler.DeleteUser, middleware.IfMatch())
+	e.POST("/:id/restore", handler.RestoreUser, middleware.RequireRole(domain.RoleAdmin))
+	e.POST(`\:batch`, handler.BatchUsers)
 }
 
 // GetUser godoc
@@ -47,7 +53,7 @@ func NewUserHandler(e *echo.Group, svc UserService) {
 // @Tags user
 // @Produce  json
 // @Success 200 {array} domain.User
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /users [get]
 func (h *UserHandler) GetUserList(c echo.Context) error {
@@ -60,11 +66,7 @@ func (h *UserHandler) GetUserList(c echo.Context) error {
 
 	users, err := h.Service.GetUserList(ctx, filter)
 	if err != nil {
-		logging.LogError(ctx, err, "get_user_list")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseMultipleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to list users: " + err.Error(),
-		})
+		return forResource("user", err)
 	}
 	if users == nil {
 		users = []domain.User{}
@@ -83,8 +85,10 @@ func (h *UserHandler) GetUserList(c echo.Context) error {
 // @Tags user
 // @Produce  json
 // @Param        id   path      int  true  "Account ID"
+// @Param   If-None-Match  header  string  false  "ETag from an earlier response"
 // @Success 200 {array} domain.User
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Success 304 "Not modified since the ETag in If-None-Match"
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /users/{id} [get]
 func (h *UserHandler) GetUser(c echo.Context) error {
@@ -97,33 +101,18 @@ func (h *UserHandler) GetUser(c echo.Context) error {
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "invalid UUID")
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid user ID format",
-		})
+		return badRequest("Invalid user ID format")
 	}
 
 	span.SetAttributes(attribute.String("user.id", id.String()))
 	user, err := h.Service.GetUser(ctx, id)
 	if err != nil {
 		span.RecordError(err)
-		if errors.Is(err, sql.ErrNoRows) {
-			span.SetStatus(codes.Error, "not found")
-			return c.JSON(http.StatusNotFound, domain.ResponseSingleData[domain.Empty]{
-				Code:    http.StatusNotFound,
-				Message: "User not found",
-			})
-		}
-
 		span.SetStatus(codes.Error, "service error")
-		logging.LogError(ctx, err, "get_user")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to get user: " + err.Error(),
-		})
+		return forResource("user", err)
 	}
 
-	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.User]{
+	return jsonWithETag(c, user.Version, domain.ResponseSingleData[domain.User]{
 		Data:    *user,
 		Code:    http.StatusOK,
 		Message: "Successfully retrieved user",
@@ -138,26 +127,26 @@ func (h *UserHandler) GetUser(c echo.Context) error {
 // @Produce  json
 // @Param   user  body  domain.CreateUserRequest  true  "User data"
 // @Success 201 {object} domain.CreateUserRequest
-// @Failure 400 {object} domain.ResponseSingleData[domain.Empty]
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Failure 400 {object} domain.Problem
+// @Failure 409 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
 // @Router /users [post]
 func (h *UserHandler) CreateUser(c echo.Context) error {
 	var user domain.CreateUserRequest
 	if err := c.Bind(&user); err != nil {
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid request payload",
-		})
+		return badRequest("Invalid request payload")
 	}
 
 	ctx := c.Request().Context()
 	createdUser, err := h.Service.CreateUser(ctx, &user)
 	if err != nil {
-		logging.LogError(ctx, err, "create_user")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to create user: " + err.Error(),
-		})
+		switch {
+		case errors.Is(err, domain.ErrBadParamInput):
+			return withDetail(err, "Username must be 3 to 30 letters, digits or underscores")
+		case errors.Is(err, domain.ErrConflict):
+			return withDetail(err, "Username is already taken")
+		}
+		return forResource("user", err)
 	}
 
 	return c.JSON(http.StatusCreated, domain.ResponseSingleData[domain.User]{
@@ -175,40 +164,34 @@ func (h *UserHandler) CreateUser(c echo.Context) error {
 // @Produce  json
 // @Param   id    path  string             true  "User ID"
 // @Param   user  body  domain.UpdateUserRequest  true  "Updated user data"
+// @Param   If-Match  header  string  false  "ETag of the version being changed"
 // @Success 200 {object} domain.User
-// @Failure 400 {object} domain.ResponseSingleData[domain.Empty]
-// @Failure 404 {object} domain.ResponseSingleData[domain.Empty]
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Failure 400 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 412 {object} domain.Problem
+// @Failure 428 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /users/{id} [put]
 func (h *UserHandler) UpdateUser(c echo.Context) error {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid user ID format",
-		})
+		return badRequest("Invalid user ID format")
 	}
 
 	var user domain.User
 	if err := c.Bind(&user); err != nil {
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid request payload",
-		})
+		return badRequest("Invalid request payload")
 	}
 
 	ctx := c.Request().Context()
 	updatedUser, err := h.Service.UpdateUser(ctx, id, &user)
 	if err != nil {
-		logging.LogError(ctx, err, "update_user")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to update user: " + err.Error(),
-		})
+		return forResource("user", err)
 	}
 
+	c.Response().Header().Set("ETag", middleware.ETag(updatedUser.Version))
 	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.User]{
 		Data:    *updatedUser,
 		Code:    http.StatusOK,
@@ -216,34 +199,78 @@ func (h *UserHandler) UpdateUser(c echo.Context) error {
 	})
 }
 
+// PatchUser godoc
+// @Summary Patch user
+// @Description Change only the supplied fields of a user, see RFC 7396. Fields cannot be removed with null.
+// @Tags user
+// @Accept  application/merge-patch+json
+// @Produce  json
+// @Param   id    path  string  true  "User ID"
+// @Param   patch  body  domain.UserPatch  true  "Fields to change"
+// @Param   If-Match  header  string  false  "ETag of the version being changed"
+// @Success 200 {object} domain.ResponseSingleData[domain.User]
+// @Failure 400 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 409 {object} domain.Problem
+// @Failure 412 {object} domain.Problem
+// @Failure 415 {object} domain.Problem
+// @Failure 428 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /users/{id} [patch]
+func (h *UserHandler) PatchUser(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return badRequest("Invalid user ID format")
+	}
+
+	doc, err := readMergePatch(c)
+	if err != nil {
+		return invalidPatch(err)
+	}
+	patch, err := domain.ParseUserPatch(doc)
+	if err != nil {
+		return invalidPatch(err)
+	}
+
+	ctx := c.Request().Context()
+	patched, err := h.Service.PatchUser(ctx, id, patch)
+	if err != nil {
+		return forResource("user", err)
+	}
+
+	c.Response().Header().Set("ETag", middleware.ETag(patched.Version))
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.User]{
+		Data:    *patched,
+		Code:    http.StatusOK,
+		Message: "User successfully patched",
+	})
+}
+
 // DeleteUser godoc
 // @Summary Delete user
 // @Description delete an existing user entry by ID
 // @Tags user
 // @Produce  json
 // @Param   id   path  string  true  "User ID"
+// @Param   If-Match  header  string  false  "ETag of the version being changed"
 // @Success 204 {object} nil
-// @Failure 404 {object} domain.ResponseSingleData[domain.Empty]
-// @Failure 500 {object} domain.ResponseSingleData[domain.Empty]
+// @Failure 404 {object} domain.Problem
+// @Failure 412 {object} domain.Problem
+// @Failure 428 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
 // @Security ApiKeyAuth
 // @Router /users/{id} [delete]
 func (h *UserHandler) DeleteUsers(c echo.Context) error {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusBadRequest,
-			Message: "Invalid user ID format",
-		})
+		return badRequest("Invalid user ID format")
 	}
 
 	ctx := c.Request().Context()
 	if err := h.Service.DeleteUser(ctx, id); err != nil {
-		logging.LogError(ctx, err, "delete_user")
-		return c.JSON(http.StatusInternalServerError, domain.ResponseSingleData[domain.Empty]{
-			Code:    http.StatusInternalServerError,
-			Message: "Failed to delete user: " + err.Error(),
-		})
+		return forResource("user", err)
 	}
 
 	return c.JSON(http.StatusNoContent, domain.ResponseSingleData[domain.Empty]{
@@ -251,3 +278,69 @@ func (h *UserHandler) DeleteUsers(c echo.Context) error {
 		Message: "User successfully deleted",
 	})
 }
+
+// RestoreUser godoc
+// @Summary Restore user
+// @Description Undelete a soft-deleted user. Admins only.
+// @Tags user
+// @Produce  json
+// @Param   id   path  string  true  "User ID"
+// @Success 200 {object} domain.ResponseSingleData[domain.User]
+// @Failure 400 {object} domain.Problem
+// @Failure 403 {object} domain.Problem
+// @Failure 404 {object} domain.Problem
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /users/{id}/restore [post]
+func (h *UserHandler) RestoreUser(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return badRequest("Invalid user ID format")
+	}
+
+	ctx := c.Request().Context()
+	restored, err := h.Service.RestoreUser(ctx, id)
+	if err != nil {
+		return forResource("user", err)
+	}
+
+	return c.JSON(http.StatusOK, domain.ResponseSingleData[domain.User]{
+		Data:    *restored,
+		Code:    http.StatusOK,
+		Message: "User successfully restored",
+	})
+}
+
+// BatchUsers godoc
+// @Summary Batch users
+// @Description Create, update and delete up to 500 users in one request. An atomic batch applies every item or none, a best_effort batch applies the items it can. Every item is reported by its index, 207 means some items failed and 422 that an atomic batch was rolled back.
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param   batch  body  domain.BatchRequest[domain.UserBatchItem]  true  "Batch items"
+// @Success 200 {object} domain.ResponseSingleData[domain.BatchResult[domain.User]]
+// @Success 207 {object} domain.ResponseSingleData[domain.BatchResult[domain.User]]
+// @Failure 400 {object} domain.Problem
+// @Failure 422 {object} domain.ResponseSingleData[domain.BatchResult[domain.User]]
+// @Failure 500 {object} domain.Problem
+// @Security ApiKeyAuth
+// @Router /users:batch [post]
+func (h *UserHandler) BatchUsers(c echo.Context) error {
+	var req domain.BatchRequest[domain.UserBatchItem]
+	if err := c.Bind(&req); err != nil {
+		return badRequest("Invalid request payload")
+	}
+
+	ctx := c.Request().Context()
+	result, err := h.Service.BatchUsers(ctx, &req)
+	if err != nil {
+		return forResource("user", err)
+	}
+
+	status, message := batchStatus(result)
+	return c.JSON(status, domain.ResponseSingleData[domain.BatchResult[domain.User]]{
+		Data:    *result,
+		Code:    status,
+		Message: message,
+	})
+}
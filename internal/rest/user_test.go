@@ -116,15 +116,15 @@ func TestUserCRUD_E2E(t *testing.T) {
 	resp.Body.Close()
 
 	// Get after delete
-	type ErrType domain.ResponseSingleData[domain.Empty]
-	errE, code := doRequest[ErrType](
+	errE, code := doRequest[domain.Problem](
 		t, http.MethodGet,
 		fmt.Sprintf("%s/api/v1/users/%s", kit.BaseURL, user.ID),
 		nil,
 		map[string]string{"Authorization": "Bearer " + token},
 	)
 	require.Equal(t, http.StatusNotFound, code)
-	require.Equal(t, "User not found", errE.Message)
+	require.Equal(t, "User not found", errE.Detail)
+	require.Equal(t, domain.ProblemNotFound, errE.Code)
 
 	// Hard delete, since delete API uses soft delete
 	_, err = kit.DB.Exec(context.Background(), "DELETE from users where id = $1", user.ID)
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/edwinjordan/MajooTest-Golang/database"
+	"github.com/edwinjordan/MajooTest-Golang/internal/rest"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
@@ -52,6 +53,7 @@ func NewTestKit(t *testing.T) *TestKit {
 	// 2) new Echo instance
 	e := echo.New()
 	e.HideBanner = true
+	e.HTTPErrorHandler = rest.HTTPErrorHandler
 
 	// 3) setup Postgres pool
 	dbPool, err := database.SetupPgxPool()
@@ -34,6 +34,11 @@ import (
 
 	"github.com/edwinjordan/MajooTest-Golang/config"
 	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/csvprogress"
+	"github.com/edwinjordan/MajooTest-Golang/internal/events"
+	"github.com/edwinjordan/MajooTest-Golang/internal/filestore"
+	"github.com/edwinjordan/MajooTest-Golang/internal/pgnotify"
+	"github.com/edwinjordan/MajooTest-Golang/internal/realtime"
 	"github.com/edwinjordan/MajooTest-Golang/internal/repository/postgres"
 	"github.com/edwinjordan/MajooTest-Golang/internal/rest"
 	"github.com/edwinjordan/MajooTest-Golang/internal/rest/middleware"
@@ -65,6 +70,7 @@ func main() {
 
 	e := echo.New()
 	e.HideBanner = true
+	e.HTTPErrorHandler = rest.HTTPErrorHandler
 
 	e.Logger.SetOutput(os.Stdout)
 	e.Logger.SetLevel(0)
@@ -82,7 +88,7 @@ func main() {
 	e.Use(middleware.SecurityHeadersMiddleware())
 	e.Use(middleware.CompressionMiddleware())
 	e.Use(middleware.RateLimitMiddleware(10.0, 20))
-	e.Use(middleware.TimeoutMiddleware(30 * time.Second))
+	e.Use(middleware.TimeoutMiddleware(30*time.Second, "/api/v1/csv/jobs/:job_id/stream"))
 
 	e.GET("/", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, domain.Response{
@@ -97,30 +103,101 @@ func main() {
 	commentRepo := postgres.NewCommentRepository(dbPool)
 	csvRepo := postgres.NewCSVRepository(dbPool)
 	authRepo := postgres.NewAuthRepository(dbPool)
+	reactionRepo := postgres.NewReactionRepository(dbPool)
+	moderationRepo := postgres.NewModerationRepository(dbPool)
+	notificationRepo := postgres.NewNotificationRepository(dbPool)
+	trashRepo := postgres.NewTrashRepository(dbPool)
+	retentionRepo := postgres.NewRetentionRepository(dbPool)
+	idempotencyRepo := postgres.NewIdempotencyRepository(dbPool)
+
+	eventBus := events.NewBus()
+	notificationService := service.NewNotificationService(notificationRepo)
+	eventBus.Subscribe(notificationService.HandleEvent, domain.EventPostCreated, domain.EventCommentCreated)
+
+	// Events reach websocket clients of every replica through Postgres, so
+	// does the progress of CSV jobs for the clients streaming it
+	pgListener := pgnotify.NewListener(dbPool)
+	realtimeHub := realtime.NewHub(config.LoadRealtimeBuffer()).WithNotifier(pgListener)
+	pgListener.Handle(realtime.NotifyChannel, realtimeHub.Receive)
+	csvProgress := csvprogress.NewBroker().WithNotifier(pgListener)
+	pgListener.Handle(csvprogress.NotifyChannel, csvProgress.Receive)
+	go pgListener.Run(ctx)
+	eventBus.Subscribe(realtimeHub.HandleEvent, append(domain.PostEvents, domain.CommentEvents...)...)
 
 	userService := service.NewUserService(userRepo)
-	postsService := service.NewPostsService(postsRepo)
-	commentService := service.NewCommentService(commentRepo)
+	postsService := service.NewPostsService(postsRepo).
+		WithReactions(reactionRepo).
+		WithEvents(eventBus)
+	moderationService := service.NewModerationService(moderationRepo, config.LoadModerationRules()).
+		WithEvents(eventBus)
+	commentService := service.NewCommentService(commentRepo, postsRepo).
+		WithReactions(reactionRepo).
+		WithModeration(moderationService).
+		WithEvents(eventBus)
+	reactionService := service.NewReactionService(reactionRepo)
+	trashService := service.NewTrashService(trashRepo)
+	retentionService := service.NewRetentionService(retentionRepo, config.LoadRetentionPolicy())
+	go retentionService.Run(ctx)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo, config.LoadIdempotencyPolicy())
+	go idempotencyService.Run(ctx)
 	// Create logrus logger for CSV service
 	logger := logrus.New()
 	logger.SetLevel(logrus.InfoLevel)
 	logger.SetFormatter(&logrus.JSONFormatter{})
 
-	csvService := service.NewCSVService(csvRepo, logger)
+	// Uploads pick how their rows are imported with ?type=, only admins
+	// import users
+	userImportDomain := config.LoadUserImportEmailDomain()
+	csvProcessors := service.NewCSVProcessorRegistry().
+		Register("users", service.NewUserRowProcessor(userService), domain.RoleAdmin).
+		Register("hr_users", service.NewHRUserRowProcessor(userService, userImportDomain), domain.RoleAdmin).
+		Register("hr_users_credentials", service.NewHRUserRowProcessor(userService, userImportDomain).WithCredentialUpdates(), domain.RoleAdmin).
+		Register("posts", service.NewPostRowProcessor(postsService)).
+		Register("comments", service.NewCommentRowProcessor(commentService))
+	// Uploads wait in the file store and the csv_jobs queue, so a restart
+	// does not lose them
+	csvStore, err := filestore.NewLocal(config.LoadCSVStorageDir())
+	if err != nil {
+		logging.LogError(context.Background(), err, "csv_storage_setup")
+		os.Exit(1)
+	}
+	csvService := service.NewCSVService(csvRepo, logger).
+		WithProcessors(csvProcessors).
+		WithQueue(csvStore, config.LoadCSVQueuePolicy()).
+		WithProgress(csvProgress)
+	csvQueueDone := make(chan struct{})
+	go func(ctx context.Context) {
+		defer close(csvQueueDone)
+		csvService.Run(ctx)
+	}(ctx)
 	authService := service.NewAuthService(authRepo)
 
+	// Creating posts and comments and uploading CSV files can be retried
+	// safely with an Idempotency-Key
+	idempotency := middleware.Idempotency(idempotencyService)
+
 	apiV1 := e.Group("/api/v1")
 	usersGroup := apiV1.Group("/users", middleware.ValidateUserToken())
-	postsGroup := apiV1.Group("/posts", middleware.ValidateUserToken())
-	commentGroup := apiV1.Group("/comments", middleware.ValidateUserToken())
-	csvGroup := apiV1.Group("/csv", middleware.ValidateUserToken())
+	postsGroup := apiV1.Group("/posts", middleware.ValidateUserToken(), idempotency)
+	commentGroup := apiV1.Group("/comments", middleware.ValidateUserToken(), idempotency)
+	csvGroup := apiV1.Group("/csv", middleware.ValidateUserToken(), idempotency)
+	moderationGroup := apiV1.Group("/moderation", middleware.ValidateUserToken(), middleware.RequireRole(domain.RoleModerator, domain.RoleAdmin))
+	trashGroup := apiV1.Group("/trash", middleware.ValidateUserToken(), middleware.RequireRole(domain.RoleAdmin))
 	authGroup := apiV1.Group("/auth")
+	wsGroup := apiV1.Group("/ws", middleware.ValidateUserToken())
 
 	rest.NewUserHandler(usersGroup, userService)
+	rest.NewNotificationHandler(usersGroup, notificationService)
 	rest.NewPostsHandler(postsGroup, postsService)
 	rest.NewCommentHandler(commentGroup, commentService)
+	rest.NewPostCommentHandler(postsGroup, commentService)
+	rest.NewReactionHandler(postsGroup, domain.ReactionTargetPost, reactionService)
+	rest.NewReactionHandler(commentGroup, domain.ReactionTargetComment, reactionService)
+	rest.NewModerationHandler(moderationGroup, moderationService)
+	rest.NewTrashHandler(trashGroup, trashService)
 	rest.NewCSVHandler(csvGroup, csvService, logger)
 	rest.NewAuthHandler(authGroup, authService)
+	rest.NewRealtimeHandler(wsGroup, realtimeHub)
 
 	// Get host from environment variable, default to 127.0.0.1 if not set
 	host := os.Getenv("APP_HOST")
@@ -151,7 +228,14 @@ func main() {
 	defer cancel()
 
 	logging.LogInfo(ctx, "Shutting down server gracefully...")
+	// Shutdown does not wait for hijacked connections, close the websockets
+	realtimeHub.Close()
 	if err := e.Shutdown(ctx); err != nil {
 		logging.LogError(ctx, err, "server_shutdown")
 	}
+	// CSV workers put the jobs they were on back in the queue
+	select {
+	case <-csvQueueDone:
+	case <-ctx.Done():
+	}
 }
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS content_format VARCHAR(20) NOT NULL DEFAULT 'plain' CHECK (content_format IN ('plain', 'markdown')),
+    ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS content_format VARCHAR(20) NOT NULL DEFAULT 'plain' CHECK (content_format IN ('plain', 'markdown')),
+    ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS content_html,
+    DROP COLUMN IF EXISTS content_format;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS content_html,
+    DROP COLUMN IF EXISTS content_format;
+-- +goose StatementEnd
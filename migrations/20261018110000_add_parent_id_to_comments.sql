@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES comments(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS depth INT NOT NULL DEFAULT 0 CHECK (depth >= 0);
+
+-- Thread queries walk replies by parent and list top level comments per post
+CREATE INDEX IF NOT EXISTS idx_comments_parent_created ON comments(parent_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_comments_post_roots ON comments(post_id, created_at) WHERE parent_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_comments_post_roots;
+DROP INDEX IF EXISTS idx_comments_parent_created;
+
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS depth,
+    DROP COLUMN IF EXISTS parent_id;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Listing and counting the live comments of a post
+CREATE INDEX IF NOT EXISTS idx_comments_post_created ON comments(post_id, created_at) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_comments_post_created;
+-- +goose StatementEnd
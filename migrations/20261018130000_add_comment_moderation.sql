@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin'));
+
+-- Existing comments stay visible
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'approved' CHECK (status IN ('pending', 'approved', 'rejected', 'spam')),
+    ADD COLUMN IF NOT EXISTS moderation_reason TEXT NOT NULL DEFAULT '';
+
+-- Moderator queue and per-user rate checks
+CREATE INDEX IF NOT EXISTS idx_comments_status_created ON comments(status, created_at) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_user_created ON comments(user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS comment_moderation_audit (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    moderator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('approve', 'reject', 'spam', 'flag')),
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_moderation_audit_comment ON comment_moderation_audit(comment_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS comment_moderation_audit;
+
+DROP INDEX IF EXISTS idx_comments_user_created;
+DROP INDEX IF EXISTS idx_comments_status_created;
+
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS moderation_reason,
+    DROP COLUMN IF EXISTS status;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS role;
+-- +goose StatementEnd
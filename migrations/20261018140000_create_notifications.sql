@@ -0,0 +1,70 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Usernames are what @mentions refer to
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS username VARCHAR(30);
+
+-- Existing users get one derived from their email address. A name that is
+-- taken gets the lowest number that makes it unique, cut so it still fits.
+DO $$
+DECLARE
+    u RECORD;
+    base TEXT;
+    candidate TEXT;
+    n INT;
+BEGIN
+    FOR u IN SELECT id, email FROM users WHERE username IS NULL ORDER BY created_at, id LOOP
+        base := COALESCE(NULLIF(LOWER(REGEXP_REPLACE(SPLIT_PART(u.email, '@', 1), '[^a-zA-Z0-9_]', '_', 'g')), ''), 'user');
+        candidate := LEFT(base, 30);
+        n := 1;
+        WHILE EXISTS (SELECT 1 FROM users WHERE LOWER(username) = candidate) LOOP
+            n := n + 1;
+            candidate := LEFT(base, 30 - LENGTH(n::text)) || n::text;
+        END LOOP;
+        UPDATE users SET username = candidate WHERE id = u.id;
+    END LOOP;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users(LOWER(username));
+
+-- Post authors receive a notification when their post gets a comment
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS user_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('comment', 'reply', 'mention')),
+    post_id UUID REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id UUID REFERENCES comments(id) ON DELETE CASCADE,
+    message TEXT NOT NULL DEFAULT '',
+    read_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    on_comment BOOLEAN NOT NULL DEFAULT true,
+    on_reply BOOLEAN NOT NULL DEFAULT true,
+    on_mention BOOLEAN NOT NULL DEFAULT true,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS user_id;
+
+DROP INDEX IF EXISTS idx_users_username;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS username;
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Deleting a post or comment now soft-deletes its comments and replies with
+-- the same deleted_at, which is how restore finds what to bring back. Apply
+-- that to rows deleted before.
+WITH RECURSIVE orphaned AS (
+    SELECT c.id, p.deleted_at
+    FROM comments c
+    JOIN posts p ON p.id = c.post_id
+    WHERE p.deleted_at IS NOT NULL AND c.deleted_at IS NULL
+    UNION
+    SELECT c.id, parent.deleted_at
+    FROM comments c
+    JOIN comments parent ON parent.id = c.parent_id
+    WHERE parent.deleted_at IS NOT NULL AND c.deleted_at IS NULL
+    UNION
+    SELECT c.id, o.deleted_at
+    FROM comments c
+    JOIN orphaned o ON o.id = c.parent_id
+    WHERE c.deleted_at IS NULL
+)
+UPDATE comments c
+SET deleted_at = o.deleted_at
+FROM (SELECT DISTINCT ON (id) id, deleted_at FROM orphaned ORDER BY id, deleted_at) o
+WHERE c.id = o.id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Trash listings, newest deletions first
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON comments(deleted_at) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_comments_deleted_at;
+DROP INDEX IF EXISTS idx_posts_deleted_at;
+DROP INDEX IF EXISTS idx_users_deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Responses to POST requests sent with an Idempotency-Key, replayed when the
+-- client retries. A row without completed_at is a request still in flight
+-- until locked_until.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id TEXT NOT NULL DEFAULT '',
+    method VARCHAR(10) NOT NULL,
+    route TEXT NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INT,
+    headers JSONB,
+    body BYTEA,
+    locked_until TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, method, route, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS idempotency_keys;
+-- +goose StatementEnd
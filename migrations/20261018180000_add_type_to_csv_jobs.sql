@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The type of an upload selects the row processor that imports its rows
+ALTER TABLE csv_jobs ADD COLUMN IF NOT EXISTS type VARCHAR(50) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE csv_jobs DROP COLUMN IF EXISTS type;
+-- +goose StatementEnd
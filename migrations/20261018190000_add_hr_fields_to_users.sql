@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Users imported from the HR export keep their employee number, department,
+-- location and a hashed recovery code
+ALTER TABLE users ADD COLUMN IF NOT EXISTS employee_id VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS department TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS location TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS recovery_code TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS recovery_code;
+ALTER TABLE users DROP COLUMN IF EXISTS location;
+ALTER TABLE users DROP COLUMN IF EXISTS department;
+ALTER TABLE users DROP COLUMN IF EXISTS employee_id;
+-- +goose StatementEnd
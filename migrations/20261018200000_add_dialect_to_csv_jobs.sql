@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- How the file of a job is read, sniffed on upload unless the upload names it
+ALTER TABLE csv_jobs ADD COLUMN IF NOT EXISTS delimiter VARCHAR(4) NOT NULL DEFAULT ',';
+ALTER TABLE csv_jobs ADD COLUMN IF NOT EXISTS encoding VARCHAR(20) NOT NULL DEFAULT 'utf-8';
+ALTER TABLE csv_jobs ADD COLUMN IF NOT EXISTS lazy_quotes BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE csv_jobs ADD COLUMN IF NOT EXISTS has_bom BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE csv_jobs DROP COLUMN IF EXISTS has_bom;
+ALTER TABLE csv_jobs DROP COLUMN IF EXISTS lazy_quotes;
+ALTER TABLE csv_jobs DROP COLUMN IF EXISTS encoding;
+ALTER TABLE csv_jobs DROP COLUMN IF EXISTS delimiter;
+-- +goose StatementEnd
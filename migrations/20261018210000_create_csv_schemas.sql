@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Schemas uploads validate their rows against, saved ones are referred to by
+-- name and every job keeps a copy of the one it was uploaded with
+CREATE TABLE IF NOT EXISTS csv_schemas (
+    name VARCHAR(50) PRIMARY KEY,
+    definition JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE csv_jobs ADD COLUMN IF NOT EXISTS schema JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE csv_jobs DROP COLUMN IF EXISTS schema;
+DROP TABLE IF EXISTS csv_schemas;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The rows of a job that failed, with their values as uploaded so they can be
+-- fixed and uploaded again
+CREATE TABLE IF NOT EXISTS csv_job_errors (
+    job_id UUID NOT NULL REFERENCES csv_jobs(id) ON DELETE CASCADE,
+    row_number INT NOT NULL,
+    row_values TEXT[] NOT NULL DEFAULT '{}',
+    error TEXT NOT NULL,
+    field_errors JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (job_id, row_number)
+);
+
+ALTER TABLE csv_jobs ADD COLUMN IF NOT EXISTS headers TEXT[] NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE csv_jobs DROP COLUMN IF EXISTS headers;
+DROP TABLE IF EXISTS csv_job_errors;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Dry runs validate a file without importing it and end as validated with a
+-- summary of what the import would do
+ALTER TABLE csv_jobs
+    ADD COLUMN IF NOT EXISTS dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS summary JSONB;
+
+ALTER TABLE csv_jobs DROP CONSTRAINT IF EXISTS csv_jobs_status_check;
+ALTER TABLE csv_jobs ADD CONSTRAINT csv_jobs_status_check
+    CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'validated'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM csv_jobs WHERE status = 'validated';
+ALTER TABLE csv_jobs DROP CONSTRAINT IF EXISTS csv_jobs_status_check;
+ALTER TABLE csv_jobs ADD CONSTRAINT csv_jobs_status_check
+    CHECK (status IN ('pending', 'processing', 'completed', 'failed'));
+
+ALTER TABLE csv_jobs
+    DROP COLUMN IF EXISTS summary,
+    DROP COLUMN IF EXISTS dry_run;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Uploads are kept in the CSV file store and queued here, workers claim them
+-- with SKIP LOCKED and heartbeat while they process
+ALTER TABLE csv_jobs
+    ADD COLUMN IF NOT EXISTS file_key TEXT,
+    ADD COLUMN IF NOT EXISTS user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS worker_id TEXT,
+    ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_csv_jobs_queue ON csv_jobs(created_at)
+    WHERE status = 'pending' AND file_key IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_csv_jobs_heartbeat ON csv_jobs(heartbeat_at)
+    WHERE status = 'processing';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- user_id is left in place, db/schema.sql has always declared it
+DROP INDEX IF EXISTS idx_csv_jobs_heartbeat;
+DROP INDEX IF EXISTS idx_csv_jobs_queue;
+
+ALTER TABLE csv_jobs
+    DROP COLUMN IF EXISTS attempts,
+    DROP COLUMN IF EXISTS heartbeat_at,
+    DROP COLUMN IF EXISTS worker_id,
+    DROP COLUMN IF EXISTS file_key;
+-- +goose StatementEnd
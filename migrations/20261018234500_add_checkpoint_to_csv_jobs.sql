@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Every row up to checkpoint_row is done, checkpoint_offset is where the next
+-- row starts in the decoded file. Rows done past it are kept in csv_job_rows
+-- so a resumed job does not import them twice.
+ALTER TABLE csv_jobs
+    ADD COLUMN IF NOT EXISTS checkpoint_row INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS checkpoint_offset BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS checkpoint_processed BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS checkpoint_failed BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS csv_job_rows (
+    job_id UUID NOT NULL REFERENCES csv_jobs(id) ON DELETE CASCADE,
+    row_number INT NOT NULL,
+    failed BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (job_id, row_number)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS csv_job_rows;
+
+ALTER TABLE csv_jobs
+    DROP COLUMN IF EXISTS checkpoint_failed,
+    DROP COLUMN IF EXISTS checkpoint_processed,
+    DROP COLUMN IF EXISTS checkpoint_offset,
+    DROP COLUMN IF EXISTS checkpoint_row;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Cancelled jobs stop where they are, like failed ones they can be retried
+ALTER TABLE csv_jobs DROP CONSTRAINT IF EXISTS csv_jobs_status_check;
+ALTER TABLE csv_jobs ADD CONSTRAINT csv_jobs_status_check
+    CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'validated', 'cancelled'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE csv_jobs SET status = 'failed', error_message = 'processing cancelled' WHERE status = 'cancelled';
+ALTER TABLE csv_jobs DROP CONSTRAINT IF EXISTS csv_jobs_status_check;
+ALTER TABLE csv_jobs ADD CONSTRAINT csv_jobs_status_check
+    CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'validated'));
+-- +goose StatementEnd
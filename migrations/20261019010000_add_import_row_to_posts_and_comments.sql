@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Posts and comments imported from a CSV job keep the row they came from, a
+-- row that runs again after a crash finds its record instead of adding one.
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS import_job_id UUID,
+    ADD COLUMN IF NOT EXISTS import_row INT;
+
+ALTER TABLE comments
+    ADD COLUMN IF NOT EXISTS import_job_id UUID,
+    ADD COLUMN IF NOT EXISTS import_row INT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_import_row
+    ON posts(import_job_id, import_row) WHERE import_job_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_comments_import_row
+    ON comments(import_job_id, import_row) WHERE import_job_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_comments_import_row;
+DROP INDEX IF EXISTS idx_posts_import_row;
+
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS import_row,
+    DROP COLUMN IF EXISTS import_job_id;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS import_row,
+    DROP COLUMN IF EXISTS import_job_id;
+-- +goose StatementEnd
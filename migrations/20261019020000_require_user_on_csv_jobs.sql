@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Jobs created before uploads were scoped have no uploader. They go to the
+-- first admin, who sees every job anyway. Without an admin to hand them to
+-- the migration stops, so no job history is lost.
+UPDATE csv_jobs
+SET user_id = (SELECT id FROM users WHERE role = 'admin' AND deleted_at IS NULL ORDER BY created_at, id LIMIT 1)
+WHERE user_id IS NULL;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM csv_jobs WHERE user_id IS NULL) THEN
+        RAISE EXCEPTION 'csv_jobs has jobs without an uploader and there is no admin to assign them to, create an admin or set csv_jobs.user_id and migrate again';
+    END IF;
+END
+$$;
+
+ALTER TABLE csv_jobs
+    ALTER COLUMN user_id SET NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE csv_jobs
+    ALTER COLUMN user_id DROP NOT NULL;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Users imported from a CSV job keep the row they came from, like posts and
+-- comments, so a resumed job does not fail rows that created their user.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS import_job_id UUID,
+    ADD COLUMN IF NOT EXISTS import_row INT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_import_row
+    ON users(import_job_id, import_row) WHERE import_job_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_import_row;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS import_row,
+    DROP COLUMN IF EXISTS import_job_id;
+-- +goose StatementEnd
@@ -17,13 +17,13 @@ func SeedUsers(db *sql.DB) error {
 
 	// Insert users with password hashes
 	result, err := db.Exec(`
-        INSERT INTO users (name, email, password) VALUES
-        ('Alice Johnson', 'alice@example.com', $1),
-        ('Bob Smith', 'bob@example.com', $1),
-        ('Charlie Brown', 'charlie@example.com', $1),
-        ('Diana Prince', 'diana@example.com', $1),
-        ('John Doe', 'john@example.com', $1),
-        ('Jane Smith', 'jane@example.com', $1)
+        INSERT INTO users (name, email, username, password, role) VALUES
+        ('Alice Johnson', 'alice@example.com', 'alice', $1, 'admin'),
+        ('Bob Smith', 'bob@example.com', 'bob', $1, 'user'),
+        ('Charlie Brown', 'charlie@example.com', 'charlie', $1, 'user'),
+        ('Diana Prince', 'diana@example.com', 'diana', $1, 'moderator'),
+        ('John Doe', 'john@example.com', 'john', $1, 'user'),
+        ('Jane Smith', 'jane@example.com', 'jane', $1, 'user')
         ON CONFLICT (email) DO NOTHING;
     `, password)
 
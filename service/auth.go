@@ -27,7 +27,7 @@ func (as *AuthService) Login(ctx context.Context, email, password string) (*doma
 		return nil, err
 	}
 
-	token, refreshToken, err := utils.GenerateToken(user.ID, user.Email)
+	token, refreshToken, err := utils.GenerateToken(user.ID, user.Email, user.Role)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/google/uuid"
+)
+
+// batchApplier writes the prepared items of a batch, results[i] reporting on
+// items[i]
+type batchApplier[Item, Data any] func(ctx context.Context, items []Item, atomic bool) ([]domain.BatchItemResult[Data], error)
+
+// runBatch prepares every item, hands the ones that passed to apply and
+// merges the outcomes into one result in request order. prepare fails an
+// item with domain.ErrBadParamInput, any other error aborts the batch. An
+// atomic batch with a failed item is not applied at all.
+func runBatch[Item, Data any](
+	ctx context.Context,
+	req *domain.BatchRequest[Item],
+	describe func(*Item) (domain.BatchOp, string),
+	prepare func(ctx context.Context, item *Item) error,
+	apply batchApplier[Item, Data],
+) (*domain.BatchResult[Data], error) {
+	mode := req.Mode.OrDefault()
+	if !mode.IsValid() {
+		return nil, fmt.Errorf("%w: mode must be atomic or best_effort", domain.ErrBadParamInput)
+	}
+	if len(req.Items) == 0 || len(req.Items) > domain.MaxBatchItems {
+		return nil, fmt.Errorf("%w: a batch holds 1 to %d items", domain.ErrBadParamInput, domain.MaxBatchItems)
+	}
+
+	result := &domain.BatchResult[Data]{Mode: mode, Items: make([]domain.BatchItemResult[Data], len(req.Items))}
+	var (
+		ready   []Item
+		indexes []int
+	)
+	for i := range req.Items {
+		op, id := describe(&req.Items[i])
+		result.Items[i] = domain.BatchItemResult[Data]{Index: i, Op: op, ID: id}
+		if err := prepare(ctx, &req.Items[i]); err != nil {
+			if !errors.Is(err, domain.ErrBadParamInput) {
+				return nil, err
+			}
+			result.Items[i].Fail(strings.TrimPrefix(err.Error(), domain.ErrBadParamInput.Error()+": "))
+			continue
+		}
+		ready = append(ready, req.Items[i])
+		indexes = append(indexes, i)
+	}
+
+	atomic := mode == domain.BatchModeAtomic
+	if len(ready) > 0 && (!atomic || len(ready) == len(req.Items)) {
+		applied, err := apply(ctx, ready, atomic)
+		if err != nil {
+			return nil, err
+		}
+		for k, outcome := range applied {
+			outcome.Index = indexes[k]
+			result.Items[indexes[k]] = outcome
+		}
+	} else if atomic {
+		for _, i := range indexes {
+			result.Items[i].Fail(domain.BatchRolledBackReason)
+		}
+	}
+
+	result.Tally()
+	return result, nil
+}
+
+// checkBatchItem validates what every batch item shares, the operation and,
+// for updates and deletes, the id of the record
+func checkBatchItem(op domain.BatchOp, id string) error {
+	if !op.IsValid() {
+		return fmt.Errorf("%w: op must be create, update or delete", domain.ErrBadParamInput)
+	}
+	if op == domain.BatchOpCreate {
+		return nil
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("%w: id must be a UUID", domain.ErrBadParamInput)
+	}
+	return nil
+}
+
+// requireFields fails when any of the named values is blank
+func requireFields(fields ...string) error {
+	for i := 0; i+1 < len(fields); i += 2 {
+		if strings.TrimSpace(fields[i+1]) == "" {
+			return fmt.Errorf("%w: %s is required", domain.ErrBadParamInput, fields[i])
+		}
+	}
+	return nil
+}
@@ -2,8 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/logging"
+	"github.com/edwinjordan/MajooTest-Golang/internal/render"
 	"github.com/google/uuid"
 )
 
@@ -12,30 +17,214 @@ type CommentRepository interface {
 	GetCommentList(ctx context.Context, filter *domain.CommentFilter) ([]domain.Comment, error)
 	GetComment(ctx context.Context, id uuid.UUID) (*domain.Comment, error)
 	UpdateComment(ctx context.Context, id uuid.UUID, comment *domain.Comment) (*domain.Comment, error)
-	DeleteComment(ctx context.Context, id uuid.UUID) error
+	PatchComment(ctx context.Context, id uuid.UUID, version int, patch *domain.CommentPatch) (*domain.Comment, error)
+	DeleteComment(ctx context.Context, id uuid.UUID, version int) error
+	GetCommentThreads(ctx context.Context, postID uuid.UUID, filter *domain.CommentThreadFilter) ([]domain.Comment, int, error)
+	GetPostComments(ctx context.Context, postID uuid.UUID, filter *domain.CommentThreadFilter) ([]domain.Comment, int, error)
+	RestoreComment(ctx context.Context, id uuid.UUID) (*domain.Comment, int, error)
+	ApplyCommentBatch(ctx context.Context, items []domain.CommentBatchItem, atomic bool) ([]domain.BatchItemResult[domain.Comment], error)
 }
 
 type CommentService struct {
 	commentsRepo CommentRepository
+	postsRepo    PostsRepository
+	reactionRepo ReactionRepository
+	moderation   *ModerationService
+	events       EventPublisher
 }
 
-func NewCommentService(n CommentRepository) *CommentService {
+func NewCommentService(n CommentRepository, p PostsRepository) *CommentService {
 	return &CommentService{
 		commentsRepo: n,
+		postsRepo:    p,
 	}
 }
 
+// WithReactions makes read operations include reaction counts for the caller
+func (ns *CommentService) WithReactions(r ReactionRepository) *CommentService {
+	ns.reactionRepo = r
+	return ns
+}
+
+// WithModeration screens new comments with the spam rules, comments that are
+// not approved stay hidden until a moderator reviews them
+func (ns *CommentService) WithModeration(m *ModerationService) *CommentService {
+	ns.moderation = m
+	return ns
+}
+
+// WithEvents publishes an event for every comment that goes live and for
+// every later change to it
+func (ns *CommentService) WithEvents(p EventPublisher) *CommentService {
+	ns.events = p
+	return ns
+}
+
 func (ns *CommentService) CreateComment(
 	ctx context.Context,
 	u *domain.CreateCommentRequest,
 ) (*domain.Comment, error) {
+	if err := ns.ValidateComment(ctx, u); err != nil {
+		return nil, err
+	}
+
+	var decision *domain.ModerationDecision
+	var err error
+	if ns.moderation != nil {
+		decision, err = ns.moderation.Screen(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+		u.Status = decision.Status
+		u.ModerationReason = decision.Reason
+	}
+
+	u.ContentFormat = u.ContentFormat.OrDefault()
+	contentHTML, err := render.Content(u.ContentFormat, u.Body)
+	if err != nil {
+		return nil, err
+	}
+	u.ContentHTML = contentHTML
+
 	createdComment, err := ns.commentsRepo.CreateComment(ctx, u)
 	if err != nil {
 		return nil, err
 	}
+
+	if ns.moderation != nil {
+		// The comment is stored either way, a missing audit record must not
+		// make the client retry and post it twice
+		if err := ns.moderation.RecordDecision(ctx, createdComment, domain.ModerationStatusPending, decision); err != nil {
+			logging.LogError(ctx, err, "record_moderation_decision")
+		}
+	}
+
+	// Comments held back by moderation must not notify anybody yet
+	if ns.events != nil && (createdComment.Status == "" || createdComment.Status == domain.ModerationStatusApproved) {
+		ns.events.Publish(ctx, domain.Event{
+			Type:            domain.EventCommentCreated,
+			ActorID:         createdComment.UserID,
+			PostID:          createdComment.PostID,
+			CommentID:       createdComment.ID,
+			ParentCommentID: u.ParentID,
+			Body:            createdComment.Body,
+			OccurredAt:      createdComment.CreatedAt,
+		})
+	}
 	return createdComment, nil
 }
 
+// ReplyToComment creates a reply nested under the comment parentID
+func (ns *CommentService) ReplyToComment(
+	ctx context.Context,
+	parentID uuid.UUID,
+	u *domain.CreateReplyRequest,
+) (*domain.Comment, error) {
+	return ns.CreateComment(ctx, &domain.CreateCommentRequest{
+		UserID:        u.UserID,
+		ParentID:      parentID.String(),
+		Body:          u.Body,
+		ContentFormat: u.ContentFormat,
+	})
+}
+
+// GetCommentThreads returns a page of top level comments of a post with
+// their replies nested underneath
+func (us *CommentService) GetCommentThreads(
+	ctx context.Context,
+	postID uuid.UUID,
+	filter *domain.CommentThreadFilter,
+) ([]domain.Comment, int, error) {
+	if filter == nil {
+		filter = &domain.CommentThreadFilter{}
+	}
+	filter.Normalize()
+
+	flat, total, err := us.commentsRepo.GetCommentThreads(ctx, postID, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]string, len(flat))
+	for i := range flat {
+		if err := fillCommentHTML(&flat[i]); err != nil {
+			return nil, 0, err
+		}
+		ids[i] = flat[i].ID
+	}
+	err = attachReactions(ctx, us.reactionRepo, domain.ReactionTargetComment, ids, func(i int, summary *domain.ReactionSummary) {
+		flat[i].Reactions = summary
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return buildCommentTree(flat), total, nil
+}
+
+// GetPostComments returns a page of every comment of a post, replies
+// included, without nesting them
+func (us *CommentService) GetPostComments(
+	ctx context.Context,
+	postID uuid.UUID,
+	filter *domain.CommentThreadFilter,
+) ([]domain.Comment, int, error) {
+	if filter == nil {
+		filter = &domain.CommentThreadFilter{}
+	}
+	filter.Normalize()
+
+	comments, total, err := us.commentsRepo.GetPostComments(ctx, postID, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]string, len(comments))
+	for i := range comments {
+		if err := fillCommentHTML(&comments[i]); err != nil {
+			return nil, 0, err
+		}
+		ids[i] = comments[i].ID
+	}
+	err = attachReactions(ctx, us.reactionRepo, domain.ReactionTargetComment, ids, func(i int, summary *domain.ReactionSummary) {
+		comments[i].Reactions = summary
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return comments, total, nil
+}
+
+// buildCommentTree nests replies under their parents. Replies whose parent is
+// not part of flat are dropped.
+func buildCommentTree(flat []domain.Comment) []domain.Comment {
+	children := make(map[string][]int, len(flat))
+	var roots []int
+	for i, c := range flat {
+		if c.ParentID == nil {
+			roots = append(roots, i)
+			continue
+		}
+		children[*c.ParentID] = append(children[*c.ParentID], i)
+	}
+
+	var build func(i int) domain.Comment
+	build = func(i int) domain.Comment {
+		node := flat[i]
+		for _, child := range children[node.ID] {
+			node.Replies = append(node.Replies, build(child))
+		}
+		return node
+	}
+
+	tree := make([]domain.Comment, 0, len(roots))
+	for _, i := range roots {
+		tree = append(tree, build(i))
+	}
+	return tree
+}
+
 func (us *CommentService) GetComment(
 	ctx context.Context,
 	id uuid.UUID,
@@ -44,6 +233,17 @@ func (us *CommentService) GetComment(
 	if err != nil {
 		return nil, err
 	}
+	if comment != nil {
+		if err := fillCommentHTML(comment); err != nil {
+			return nil, err
+		}
+		err = attachReactions(ctx, us.reactionRepo, domain.ReactionTargetComment, []string{comment.ID}, func(_ int, summary *domain.ReactionSummary) {
+			comment.Reactions = summary
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 	return comment, nil
 }
 
@@ -55,6 +255,19 @@ func (us *CommentService) GetCommentList(
 	if err != nil {
 		return nil, err
 	}
+	ids := make([]string, len(comments))
+	for i := range comments {
+		if err := fillCommentHTML(&comments[i]); err != nil {
+			return nil, err
+		}
+		ids[i] = comments[i].ID
+	}
+	err = attachReactions(ctx, us.reactionRepo, domain.ReactionTargetComment, ids, func(i int, summary *domain.ReactionSummary) {
+		comments[i].Reactions = summary
+	})
+	if err != nil {
+		return nil, err
+	}
 	return comments, nil
 }
 
@@ -72,18 +285,130 @@ func (us *CommentService) UpdateComment(
 	if existing == nil {
 		return nil, domain.ErrUserNotFound
 	}
+	if err := checkVersion(ctx, existing.Version, u.Version); err != nil {
+		return nil, err
+	}
+
+	var decision *domain.ModerationDecision
+	if us.moderation != nil && u.Body != existing.Body {
+		decision, err = us.moderation.ScreenEdit(ctx, existing.UserID, u.Body)
+		if err != nil {
+			return nil, err
+		}
+		existing.Status, existing.ModerationReason = decision.Status, decision.Reason
+	}
 
 	existing.PostID = u.PostID
 	existing.UserID = u.UserID
 	existing.Body = u.Body
+	if u.ContentFormat != "" {
+		existing.ContentFormat = u.ContentFormat
+	}
+	existing.ContentFormat = existing.ContentFormat.OrDefault()
+	existing.ContentHTML, err = render.Content(existing.ContentFormat, existing.Body)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err = us.commentsRepo.UpdateComment(ctx, id, existing)
+	updated, err := us.commentsRepo.UpdateComment(ctx, id, existing)
 	if err != nil {
 		return nil, err
 	}
+	if updated != nil {
+		existing.Version = updated.Version
+		existing.UpdatedAt = updated.UpdatedAt
+	}
+	us.recordEdit(ctx, existing, decision)
+
+	if us.events != nil && (existing.Status == "" || existing.Status == domain.ModerationStatusApproved) {
+		event := domain.Event{
+			Type:       domain.EventCommentUpdated,
+			ActorID:    actorID(ctx),
+			PostID:     existing.PostID,
+			CommentID:  existing.ID,
+			Body:       existing.Body,
+			OccurredAt: time.Now(),
+		}
+		if existing.ParentID != nil {
+			event.ParentCommentID = *existing.ParentID
+		}
+		us.events.Publish(ctx, event)
+	}
 	return existing, nil
 }
 
+// PatchComment applies a merge-patch to a comment, re-rendering the body when
+// it or its format changes. An empty patch returns the comment unchanged.
+func (us *CommentService) PatchComment(
+	ctx context.Context,
+	id uuid.UUID,
+	patch *domain.CommentPatch,
+) (*domain.Comment, error) {
+
+	existing, err := us.commentsRepo.GetComment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, domain.ErrUserNotFound
+	}
+	if err := checkVersion(ctx, existing.Version, patch.Version); err != nil {
+		return nil, err
+	}
+	if patch.IsEmpty() {
+		return existing, nil
+	}
+
+	if patch.Body != nil || patch.ContentFormat != nil {
+		body, format := existing.Body, existing.ContentFormat
+		if patch.Body != nil {
+			body = *patch.Body
+		}
+		if patch.ContentFormat != nil {
+			format = *patch.ContentFormat
+		}
+		html, err := render.Content(format, body)
+		if err != nil {
+			return nil, err
+		}
+		patch.ContentHTML = &html
+	}
+
+	var decision *domain.ModerationDecision
+	if us.moderation != nil && patch.Body != nil && *patch.Body != existing.Body {
+		decision, err = us.moderation.ScreenEdit(ctx, existing.UserID, *patch.Body)
+		if err != nil {
+			return nil, err
+		}
+		if decision.Status != existing.Status {
+			patch.Status, patch.ModerationReason = &decision.Status, &decision.Reason
+		}
+	}
+
+	patched, err := us.commentsRepo.PatchComment(ctx, id, existing.Version, patch)
+	if err != nil {
+		return nil, err
+	}
+	patched.ReplyCount = existing.ReplyCount
+	us.recordEdit(ctx, patched, decision)
+
+	if us.events != nil && (patched.Status == "" || patched.Status == domain.ModerationStatusApproved) {
+		event := domain.Event{
+			Type:       domain.EventCommentUpdated,
+			ActorID:    actorID(ctx),
+			PostID:     patched.PostID,
+			CommentID:  patched.ID,
+			Body:       patched.Body,
+			OccurredAt: time.Now(),
+		}
+		if patched.ParentID != nil {
+			event.ParentCommentID = *patched.ParentID
+		}
+		us.events.Publish(ctx, event)
+	}
+	return patched, nil
+}
+
 func (us *CommentService) DeleteComment(
 	ctx context.Context,
 	id uuid.UUID,
@@ -96,10 +421,213 @@ func (us *CommentService) DeleteComment(
 	if comment == nil {
 		return domain.ErrUserNotFound
 	}
+	if err := domain.CheckVersion(ctx, comment.Version); err != nil {
+		return err
+	}
 
-	err = us.commentsRepo.DeleteComment(ctx, id)
+	err = us.commentsRepo.DeleteComment(ctx, id, comment.Version)
 	if err != nil {
 		return err
 	}
+
+	if us.events != nil {
+		us.events.Publish(ctx, domain.Event{
+			Type:       domain.EventCommentDeleted,
+			ActorID:    actorID(ctx),
+			PostID:     comment.PostID,
+			CommentID:  comment.ID,
+			OccurredAt: time.Now(),
+		})
+	}
+	return nil
+}
+
+// RestoreComment undeletes a soft-deleted comment together with the replies
+// that were deleted with it. Comments of deleted posts or replies to deleted
+// comments cannot be restored on their own.
+func (us *CommentService) RestoreComment(ctx context.Context, id uuid.UUID) (*domain.RestoredComment, error) {
+	comment, replies, err := us.commentsRepo.RestoreComment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.RestoredComment{Comment: *comment, RestoredReplies: replies}, nil
+}
+
+// fillCommentHTML renders comments stored before content_html was persisted
+func fillCommentHTML(c *domain.Comment) error {
+	if c.ContentHTML != "" || c.Body == "" {
+		return nil
+	}
+	contentHTML, err := render.Content(c.ContentFormat, c.Body)
+	if err != nil {
+		return err
+	}
+	c.ContentHTML = contentHTML
+	return nil
+}
+
+// BatchComments creates, updates and deletes comments in bulk, see runBatch.
+// New comments are screened like single ones and replies may leave out the
+// post. Comments held back by moderation publish no event.
+func (ns *CommentService) BatchComments(
+	ctx context.Context,
+	req *domain.BatchRequest[domain.CommentBatchItem],
+) (*domain.BatchResult[domain.Comment], error) {
+	describe := func(item *domain.CommentBatchItem) (domain.BatchOp, string) {
+		return item.Op, item.ID
+	}
+	prepare := func(ctx context.Context, item *domain.CommentBatchItem) error {
+		if err := checkBatchItem(item.Op, item.ID); err != nil {
+			return err
+		}
+		switch item.Op {
+		case domain.BatchOpDelete:
+			return nil
+		case domain.BatchOpCreate:
+			if item.PostID == "" && item.ParentID == "" {
+				return fmt.Errorf("%w: post_id or parent_id is required", domain.ErrBadParamInput)
+			}
+			for _, id := range []string{item.PostID, item.ParentID} {
+				if _, err := uuid.Parse(id); id != "" && err != nil {
+					return fmt.Errorf("%w: post_id and parent_id must be UUIDs", domain.ErrBadParamInput)
+				}
+			}
+			if err := requireFields("user_id", item.UserID, "body", item.Body); err != nil {
+				return err
+			}
+			if ns.moderation != nil {
+				decision, err := ns.moderation.Screen(ctx, &domain.CreateCommentRequest{UserID: item.UserID, Body: item.Body})
+				if err != nil {
+					return err
+				}
+				item.Status, item.ModerationReason = decision.Status, decision.Reason
+			}
+		case domain.BatchOpUpdate:
+			if err := requireFields("body", item.Body); err != nil {
+				return err
+			}
+			if ns.moderation != nil {
+				existing, err := ns.commentsRepo.GetComment(ctx, uuid.MustParse(item.ID))
+				if errors.Is(err, domain.ErrNotFound) {
+					return fmt.Errorf("%w: comment not found", domain.ErrBadParamInput)
+				}
+				if err != nil {
+					return err
+				}
+				if item.Body != existing.Body {
+					decision, err := ns.moderation.ScreenEdit(ctx, existing.UserID, item.Body)
+					if err != nil {
+						return err
+					}
+					item.Status, item.ModerationReason = decision.Status, decision.Reason
+				}
+			}
+		}
+
+		item.ContentFormat = item.ContentFormat.OrDefault()
+		contentHTML, err := render.Content(item.ContentFormat, item.Body)
+		if err != nil {
+			return err
+		}
+		item.ContentHTML = contentHTML
+		return nil
+	}
+
+	result, err := runBatch(ctx, req, describe, prepare, ns.commentsRepo.ApplyCommentBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range result.Items {
+		item := &result.Items[i]
+		if !item.OK {
+			continue
+		}
+		if item.Op == domain.BatchOpCreate && ns.moderation != nil {
+			decision := &domain.ModerationDecision{Status: item.Data.Status, Reason: req.Items[i].ModerationReason}
+			if err := ns.moderation.RecordDecision(ctx, item.Data, domain.ModerationStatusPending, decision); err != nil {
+				logging.LogError(ctx, err, "record_moderation_decision")
+			}
+		}
+		if item.Op == domain.BatchOpUpdate && req.Items[i].Status != "" {
+			ns.recordEdit(ctx, item.Data, &domain.ModerationDecision{Status: item.Data.Status, Reason: req.Items[i].ModerationReason})
+		}
+		if ns.events != nil && (item.Data.Status == "" || item.Data.Status == domain.ModerationStatusApproved) {
+			event := domain.Event{
+				ActorID:    actorID(ctx),
+				PostID:     item.Data.PostID,
+				CommentID:  item.ID,
+				Body:       item.Data.Body,
+				OccurredAt: time.Now(),
+			}
+			if item.Data.ParentID != nil {
+				event.ParentCommentID = *item.Data.ParentID
+			}
+			switch item.Op {
+			case domain.BatchOpCreate:
+				event.Type, event.ActorID, event.OccurredAt = domain.EventCommentCreated, item.Data.UserID, item.Data.CreatedAt
+			case domain.BatchOpUpdate:
+				event.Type = domain.EventCommentUpdated
+			case domain.BatchOpDelete:
+				event.Type = domain.EventCommentDeleted
+			}
+			ns.events.Publish(ctx, event)
+		}
+		if item.Op == domain.BatchOpDelete {
+			item.Data = nil
+		}
+	}
+	return result, nil
+}
+
+// recordEdit audits an edit the spam rules held back, like CreateComment the
+// edit is stored already and a missing audit record only gets logged
+func (ns *CommentService) recordEdit(ctx context.Context, comment *domain.Comment, decision *domain.ModerationDecision) {
+	if decision == nil {
+		return
+	}
+	if err := ns.moderation.RecordDecision(ctx, comment, domain.ModerationStatusApproved, decision); err != nil {
+		logging.LogError(ctx, err, "record_moderation_decision")
+	}
+}
+
+// ValidateComment checks the post and parent a new comment points at and
+// fills in its depth, the post of a reply defaults to that of its parent.
+func (ns *CommentService) ValidateComment(ctx context.Context, u *domain.CreateCommentRequest) error {
+	if u.ParentID != "" {
+		parentID, err := uuid.Parse(u.ParentID)
+		if err != nil {
+			return domain.ErrBadParamInput
+		}
+		parent, err := ns.commentsRepo.GetComment(ctx, parentID)
+		if err != nil {
+			return err
+		}
+		if parent == nil {
+			return domain.ErrNotFound
+		}
+		if u.PostID == "" {
+			u.PostID = parent.PostID
+		}
+		if parent.PostID != u.PostID {
+			return domain.ErrBadParamInput
+		}
+		if parent.Depth+1 > domain.MaxCommentDepth {
+			return domain.ErrCommentTooDeep
+		}
+		u.Depth = parent.Depth + 1
+	}
+
+	postID, err := uuid.Parse(u.PostID)
+	if err != nil {
+		return domain.ErrBadParamInput
+	}
+	exists, err := ns.postsRepo.PostExists(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return domain.ErrNotFound
+	}
 	return nil
 }
@@ -16,10 +16,10 @@ import (
 func TestCommentService_CreateComment(t *testing.T) {
 	mockCommentsRepo := new(mocks.CommentRepository)
 	// mockUserRepo := new(mocks.UserRepository)
-	// mockPostsRepo := new(mocks.PostsRepository)
+	mockPostsRepo := new(mocks.PostsRepository)
 
 	//userService := service.NewUserService(mockUserRepo)
-	commentsService := service.NewCommentService(mockCommentsRepo)
+	commentsService := service.NewCommentService(mockCommentsRepo, mockPostsRepo)
 	//postsService := service.NewPostsService(mockPostsRepo)
 
 	ctx := context.Background()
@@ -66,6 +66,7 @@ func TestCommentService_CreateComment(t *testing.T) {
 	}
 
 	t.Run("Successfully creates a comment", func(t *testing.T) {
+		mockPostsRepo.On("PostExists", mock.Anything, uuid.MustParse(expectedPosts.ID)).Return(true, nil).Once()
 		mockCommentsRepo.On("CreateComment", mock.Anything, reqComment).Return(expectedComments, nil).Once()
 
 		comments, err := commentsService.CreateComment(ctx, reqComment)
@@ -82,9 +83,11 @@ func TestCommentService_CreateComment(t *testing.T) {
 
 	t.Run("Returns error when repository fails", func(t *testing.T) {
 		mockCommentsRepo = new(mocks.CommentRepository)
-		commentsService = service.NewCommentService(mockCommentsRepo)
+		mockPostsRepo = new(mocks.PostsRepository)
+		commentsService = service.NewCommentService(mockCommentsRepo, mockPostsRepo)
 
 		repoErr := errors.New("database error")
+		mockPostsRepo.On("PostExists", mock.Anything, uuid.MustParse(expectedPosts.ID)).Return(true, nil).Once()
 		mockCommentsRepo.On("CreateComment", mock.Anything, reqComment).Return(nil, repoErr).Once()
 
 		comments, err := commentsService.CreateComment(ctx, reqComment)
@@ -99,7 +102,7 @@ func TestCommentService_CreateComment(t *testing.T) {
 
 func TestCommentService_GetComment(t *testing.T) {
 	mockCommentsRepo := new(mocks.CommentRepository)
-	commentsService := service.NewCommentService(mockCommentsRepo)
+	commentsService := service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
 
 	ctx := context.Background()
 
@@ -154,7 +157,7 @@ func TestCommentService_GetComment(t *testing.T) {
 
 	t.Run("Returns error when repository fails", func(t *testing.T) {
 		mockCommentsRepo = new(mocks.CommentRepository)
-		commentsService = service.NewCommentService(mockCommentsRepo)
+		commentsService = service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
 
 		repoErr := errors.New("network error")
 		mockCommentsRepo.On("GetComment", mock.Anything, commentID).Return(nil, repoErr).Once()
@@ -170,7 +173,7 @@ func TestCommentService_GetComment(t *testing.T) {
 
 	t.Run("Returns nil when comment not found in repository", func(t *testing.T) {
 		mockCommentsRepo = new(mocks.CommentRepository)
-		commentsService = service.NewCommentService(mockCommentsRepo)
+		commentsService = service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
 
 		mockCommentsRepo.On("GetComment", mock.Anything, commentID).Return(nil, nil).Once()
 
@@ -185,7 +188,7 @@ func TestCommentService_GetComment(t *testing.T) {
 
 func TestCommentService_UpdateComment(t *testing.T) {
 	mockCommentsRepo := new(mocks.CommentRepository)
-	commentsService := service.NewCommentService(mockCommentsRepo)
+	commentsService := service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
 
 	ctx := context.Background()
 
@@ -231,10 +234,12 @@ func TestCommentService_UpdateComment(t *testing.T) {
 		mockCommentsRepo.On("GetComment", mock.Anything, commentID).Return(existingComment, nil).Once()
 
 		expectedUpdatedComment := &domain.Comment{
-			ID:     commentID.String(),
-			PostID: expectedPosts.ID,
-			UserID: expectedUser.ID,
-			Body:   updateReq.Body,
+			ID:            commentID.String(),
+			PostID:        expectedPosts.ID,
+			UserID:        expectedUser.ID,
+			Body:          updateReq.Body,
+			ContentFormat: domain.ContentFormatPlain,
+			ContentHTML:   "<p>Updated Comment</p>\n",
 		}
 		mockCommentsRepo.On("UpdateComment", mock.Anything, commentID, expectedUpdatedComment).Return(expectedUpdatedComment, nil).Once()
 
@@ -249,7 +254,7 @@ func TestCommentService_UpdateComment(t *testing.T) {
 
 	t.Run("Returns ErrCommentNotFound if comment does not exist", func(t *testing.T) {
 		mockCommentsRepo = new(mocks.CommentRepository)
-		commentsService = service.NewCommentService(mockCommentsRepo)
+		commentsService = service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
 
 		mockCommentsRepo.On("GetComment", mock.Anything, commentID).Return(nil, nil).Once()
 
@@ -263,7 +268,7 @@ func TestCommentService_UpdateComment(t *testing.T) {
 
 	t.Run("Returns error if GetComment fails", func(t *testing.T) {
 		mockCommentsRepo = new(mocks.CommentRepository)
-		commentsService = service.NewCommentService(mockCommentsRepo)
+		commentsService = service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
 
 		repoErr := errors.New("get comment repo error")
 		mockCommentsRepo.On("GetComment", mock.Anything, commentID).Return(nil, repoErr).Once()
@@ -279,16 +284,18 @@ func TestCommentService_UpdateComment(t *testing.T) {
 
 	t.Run("Returns error if UpdateComment fails", func(t *testing.T) {
 		mockCommentsRepo = new(mocks.CommentRepository)
-		commentsService = service.NewCommentService(mockCommentsRepo)
+		commentsService = service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
 
 		mockCommentsRepo.On("GetComment", mock.Anything, commentID).Return(existingComment, nil).Once()
 
 		repoErr := errors.New("update comment repo error")
 		expectedUpdatedComment := &domain.Comment{
-			ID:     commentID.String(),
-			PostID: updateReq.PostID,
-			UserID: updateReq.UserID,
-			Body:   updateReq.Body,
+			ID:            commentID.String(),
+			PostID:        updateReq.PostID,
+			UserID:        updateReq.UserID,
+			Body:          updateReq.Body,
+			ContentFormat: domain.ContentFormatPlain,
+			ContentHTML:   "<p>Updated Comment</p>\n",
 		}
 		mockCommentsRepo.On("UpdateComment", mock.Anything, commentID, expectedUpdatedComment).Return(nil, repoErr).Once()
 
@@ -304,7 +311,7 @@ func TestCommentService_UpdateComment(t *testing.T) {
 
 func TestCommentService_DeleteComment(t *testing.T) {
 	mockCommentsRepo := new(mocks.CommentRepository)
-	commentsService := service.NewCommentService(mockCommentsRepo)
+	commentsService := service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
 
 	ctx := context.Background()
 
@@ -318,15 +325,16 @@ func TestCommentService_DeleteComment(t *testing.T) {
 
 	userID := uuid.New()
 	existingUser := &domain.Comment{
-		ID:     userID.String(),
-		PostID: expectedPosts.ID,
-		UserID: expectedUser.ID,
-		Body:   "Some Comment",
+		ID:      userID.String(),
+		PostID:  expectedPosts.ID,
+		UserID:  expectedUser.ID,
+		Body:    "Some Comment",
+		Version: 3,
 	}
 
 	t.Run("Successfully deletes a comment", func(t *testing.T) {
 		mockCommentsRepo.On("GetComment", mock.Anything, userID).Return(existingUser, nil).Once()
-		mockCommentsRepo.On("DeleteComment", mock.Anything, userID).Return(nil).Once()
+		mockCommentsRepo.On("DeleteComment", mock.Anything, userID, existingUser.Version).Return(nil).Once()
 
 		err := commentsService.DeleteComment(ctx, userID)
 
@@ -336,7 +344,7 @@ func TestCommentService_DeleteComment(t *testing.T) {
 
 	t.Run("Returns ErrCommentNotFound if comment does not exist", func(t *testing.T) {
 		mockCommentsRepo = new(mocks.CommentRepository)
-		commentsService = service.NewCommentService(mockCommentsRepo)
+		commentsService = service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
 
 		mockCommentsRepo.On("GetComment", mock.Anything, userID).Return(nil, nil).Once()
 
@@ -348,7 +356,7 @@ func TestCommentService_DeleteComment(t *testing.T) {
 
 	t.Run("Returns error if DeleteComment fails", func(t *testing.T) {
 		mockCommentsRepo = new(mocks.CommentRepository)
-		commentsService = service.NewCommentService(mockCommentsRepo)
+		commentsService = service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
 
 		repoErr := errors.New("get comment repo error during delete")
 		mockCommentsRepo.On("GetComment", mock.Anything, userID).Return(nil, repoErr).Once()
@@ -362,11 +370,11 @@ func TestCommentService_DeleteComment(t *testing.T) {
 
 	t.Run("Returns error if DeleteComment fails", func(t *testing.T) {
 		mockCommentsRepo = new(mocks.CommentRepository)
-		commentsService = service.NewCommentService(mockCommentsRepo)
+		commentsService = service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
 
 		mockCommentsRepo.On("GetComment", mock.Anything, userID).Return(existingUser, nil).Once()
 		repoErr := errors.New("delete comment repo error")
-		mockCommentsRepo.On("DeleteComment", mock.Anything, userID).Return(repoErr).Once()
+		mockCommentsRepo.On("DeleteComment", mock.Anything, userID, existingUser.Version).Return(repoErr).Once()
 
 		err := commentsService.DeleteComment(ctx, userID)
 
@@ -378,7 +386,7 @@ func TestCommentService_DeleteComment(t *testing.T) {
 
 func TestCommentService_GetCommentList(t *testing.T) {
 	mockCommentsRepo := new(mocks.CommentRepository)
-	commentsService := service.NewCommentService(mockCommentsRepo)
+	commentsService := service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
 
 	ctx := context.Background()
 	filter := &domain.CommentFilter{
@@ -404,7 +412,7 @@ func TestCommentService_GetCommentList(t *testing.T) {
 
 	t.Run("Returns empty list when no comments found", func(t *testing.T) {
 		mockCommentsRepo = new(mocks.CommentRepository)
-		commentsService = service.NewCommentService(mockCommentsRepo)
+		commentsService = service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
 
 		mockCommentsRepo.On("GetCommentList", mock.Anything, filter).Return([]domain.Comment{}, nil).Once()
 
@@ -419,7 +427,7 @@ func TestCommentService_GetCommentList(t *testing.T) {
 
 	t.Run("Returns error when repository fails", func(t *testing.T) {
 		mockCommentsRepo = new(mocks.CommentRepository)
-		commentsService = service.NewCommentService(mockCommentsRepo)
+		commentsService = service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
 
 		repoErr := errors.New("get comment list database error")
 		mockCommentsRepo.On("GetCommentList", mock.Anything, filter).Return(nil, repoErr).Once()
@@ -433,3 +441,38 @@ func TestCommentService_GetCommentList(t *testing.T) {
 		mockCommentsRepo.AssertExpectations(t)
 	})
 }
+
+func TestCommentService_BatchComments(t *testing.T) {
+	ctx := context.Background()
+	postID := uuid.New().String()
+	commentID := uuid.New().String()
+
+	mockCommentRepo := new(mocks.CommentRepository)
+	commentService := service.NewCommentService(mockCommentRepo, new(mocks.PostsRepository))
+
+	req := &domain.BatchRequest[domain.CommentBatchItem]{
+		Mode: domain.BatchModeBestEffort,
+		Items: []domain.CommentBatchItem{
+			{Op: domain.BatchOpCreate, UserID: uuid.New().String(), Body: "orphan"},
+			{Op: domain.BatchOpCreate, PostID: postID, UserID: uuid.New().String(), Body: "hello"},
+			{Op: domain.BatchOpDelete, ID: commentID},
+		},
+	}
+
+	mockCommentRepo.On("ApplyCommentBatch", mock.Anything, mock.MatchedBy(func(items []domain.CommentBatchItem) bool {
+		return len(items) == 2 && items[0].ContentHTML == "<p>hello</p>\n"
+	}), false).Return([]domain.BatchItemResult[domain.Comment]{
+		{Index: 0, Op: domain.BatchOpCreate, ID: "new-id", OK: true, Data: &domain.Comment{ID: "new-id", PostID: postID, Status: domain.ModerationStatusApproved}},
+		{Index: 1, Op: domain.BatchOpDelete, ID: commentID, OK: true, Data: &domain.Comment{ID: commentID, PostID: postID}},
+	}, nil).Once()
+
+	result, err := commentService.BatchComments(ctx, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Succeeded)
+	assert.Equal(t, "post_id or parent_id is required", result.Items[0].Error)
+	assert.Equal(t, "new-id", result.Items[1].Data.ID)
+	assert.True(t, result.Items[2].OK)
+	assert.Nil(t, result.Items[2].Data)
+	mockCommentRepo.AssertExpectations(t)
+}
@@ -0,0 +1,159 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/service"
+	"github.com/edwinjordan/MajooTest-Golang/service/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCommentService_ReplyToComment(t *testing.T) {
+	ctx := context.Background()
+	postID := uuid.New().String()
+	parentID := uuid.New()
+	userID := uuid.New().String()
+
+	t.Run("Successfully replies to a comment", func(t *testing.T) {
+		mockCommentsRepo := new(mocks.CommentRepository)
+		mockPostsRepo := new(mocks.PostsRepository)
+		commentsService := service.NewCommentService(mockCommentsRepo, mockPostsRepo)
+
+		parent := &domain.Comment{ID: parentID.String(), PostID: postID, Depth: 1}
+		mockCommentsRepo.On("GetComment", mock.Anything, parentID).Return(parent, nil).Once()
+		mockPostsRepo.On("PostExists", mock.Anything, uuid.MustParse(postID)).Return(true, nil).Once()
+		mockCommentsRepo.On("CreateComment", mock.Anything, mock.MatchedBy(func(req *domain.CreateCommentRequest) bool {
+			return req.PostID == postID && req.ParentID == parentID.String() && req.Depth == 2
+		})).Return(&domain.Comment{ID: uuid.New().String(), PostID: postID, ParentID: &parent.ID, Depth: 2}, nil).Once()
+
+		reply, err := commentsService.ReplyToComment(ctx, parentID, &domain.CreateReplyRequest{
+			UserID: userID,
+			Body:   "reply",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, reply.Depth)
+		assert.Equal(t, parent.ID, *reply.ParentID)
+
+		mockCommentsRepo.AssertExpectations(t)
+		mockPostsRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects replies beyond the maximum depth", func(t *testing.T) {
+		mockCommentsRepo := new(mocks.CommentRepository)
+		commentsService := service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
+
+		parent := &domain.Comment{ID: parentID.String(), PostID: postID, Depth: domain.MaxCommentDepth}
+		mockCommentsRepo.On("GetComment", mock.Anything, parentID).Return(parent, nil).Once()
+
+		reply, err := commentsService.ReplyToComment(ctx, parentID, &domain.CreateReplyRequest{
+			UserID: userID,
+			Body:   "too deep",
+		})
+
+		assert.ErrorIs(t, err, domain.ErrCommentTooDeep)
+		assert.Nil(t, reply)
+
+		mockCommentsRepo.AssertNotCalled(t, "CreateComment", mock.Anything, mock.Anything)
+		mockCommentsRepo.AssertExpectations(t)
+	})
+
+	t.Run("Returns ErrNotFound when the parent does not exist", func(t *testing.T) {
+		mockCommentsRepo := new(mocks.CommentRepository)
+		commentsService := service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
+
+		mockCommentsRepo.On("GetComment", mock.Anything, parentID).Return(nil, nil).Once()
+
+		reply, err := commentsService.ReplyToComment(ctx, parentID, &domain.CreateReplyRequest{
+			UserID: userID,
+			Body:   "orphan",
+		})
+
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		assert.Nil(t, reply)
+
+		mockCommentsRepo.AssertExpectations(t)
+	})
+}
+
+func TestCommentService_GetCommentThreads(t *testing.T) {
+	ctx := context.Background()
+	postID := uuid.New()
+
+	root := uuid.New().String()
+	child := uuid.New().String()
+	grandchild := uuid.New().String()
+	flat := []domain.Comment{
+		{ID: root, PostID: postID.String(), ContentHTML: "<p>root</p>", ReplyCount: 1},
+		{ID: child, PostID: postID.String(), ParentID: &root, Depth: 1, ContentHTML: "<p>child</p>", ReplyCount: 1},
+		{ID: grandchild, PostID: postID.String(), ParentID: &child, Depth: 2, ContentHTML: "<p>grandchild</p>"},
+	}
+
+	mockCommentsRepo := new(mocks.CommentRepository)
+	commentsService := service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
+
+	mockCommentsRepo.On("GetCommentThreads", mock.Anything, postID, &domain.CommentThreadFilter{Page: 1, Limit: 20, RepliesLimit: 5}).
+		Return(flat, 1, nil).Once()
+
+	threads, total, err := commentsService.GetCommentThreads(ctx, postID, &domain.CommentThreadFilter{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Len(t, threads, 1)
+	assert.Equal(t, root, threads[0].ID)
+	assert.Len(t, threads[0].Replies, 1)
+	assert.Equal(t, child, threads[0].Replies[0].ID)
+	assert.Len(t, threads[0].Replies[0].Replies, 1)
+	assert.Equal(t, grandchild, threads[0].Replies[0].Replies[0].ID)
+
+	mockCommentsRepo.AssertExpectations(t)
+}
+
+func TestCommentService_CreateComment_PostNotFound(t *testing.T) {
+	mockCommentsRepo := new(mocks.CommentRepository)
+	mockPostsRepo := new(mocks.PostsRepository)
+	commentsService := service.NewCommentService(mockCommentsRepo, mockPostsRepo)
+
+	postID := uuid.New()
+	mockPostsRepo.On("PostExists", mock.Anything, postID).Return(false, nil).Once()
+
+	comment, err := commentsService.CreateComment(context.Background(), &domain.CreateCommentRequest{
+		PostID: postID.String(),
+		UserID: uuid.New().String(),
+		Body:   "on a deleted post",
+	})
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Nil(t, comment)
+
+	mockCommentsRepo.AssertNotCalled(t, "CreateComment", mock.Anything, mock.Anything)
+	mockPostsRepo.AssertExpectations(t)
+}
+
+func TestCommentService_GetPostComments(t *testing.T) {
+	mockCommentsRepo := new(mocks.CommentRepository)
+	commentsService := service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
+
+	postID := uuid.New()
+	root := uuid.New().String()
+	comments := []domain.Comment{
+		{ID: root, PostID: postID.String(), ContentHTML: "<p>root</p>", ReplyCount: 1},
+		{ID: uuid.New().String(), PostID: postID.String(), ParentID: &root, Depth: 1, ContentHTML: "<p>reply</p>"},
+	}
+
+	mockCommentsRepo.On("GetPostComments", mock.Anything, postID, &domain.CommentThreadFilter{Page: 2, Limit: 10, RepliesLimit: 5}).
+		Return(comments, 12, nil).Once()
+
+	result, total, err := commentsService.GetPostComments(context.Background(), postID, &domain.CommentThreadFilter{Page: 2, Limit: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 12, total)
+	assert.Len(t, result, 2)
+	assert.Empty(t, result[0].Replies)
+
+	mockCommentsRepo.AssertExpectations(t)
+}
@@ -1,12 +1,17 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
+	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,6 +20,8 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/csvdialect"
+	"github.com/edwinjordan/MajooTest-Golang/internal/csvschema"
 )
 
 const (
@@ -32,8 +39,19 @@ const (
 
 type csvService struct {
 	csvRepo    domain.CSVRepository
+	processors *CSVProcessorRegistry
 	logger     *logrus.Logger
 	workerPool int
+	// store and queue are set when uploads are queued for the workers of Run
+	// instead of processed by the replica that received them
+	store domain.CSVFileStore
+	queue domain.CSVQueuePolicy
+	// progress pushes the progress of jobs to their streams
+	progress ProgressBroker
+
+	// running cancels the jobs processed on this replica by id
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
 }
 
 // CSVWorkerPool manages concurrent CSV processing
@@ -45,23 +63,55 @@ type CSVWorkerPool struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	logger     *logrus.Logger
+	processor  domain.RowProcessor
+	schema     *csvschema.Schema
+	// dryRun validates rows instead of importing them
+	dryRun bool
 }
 
 // NewCSVService creates a new CSV service
 func NewCSVService(csvRepo domain.CSVRepository, logger *logrus.Logger) *csvService {
 	return &csvService{
 		csvRepo:    csvRepo,
+		processors: NewCSVProcessorRegistry(),
 		logger:     logger,
 		workerPool: DefaultWorkerPoolSize,
+		running:    map[string]context.CancelFunc{},
 	}
 }
 
+// WithProcessors sets the row processors that uploads choose from by type
+func (s *csvService) WithProcessors(r *CSVProcessorRegistry) *csvService {
+	s.processors = r
+	return s
+}
+
 // UploadAndProcessCSV handles multiple CSV file uploads and starts processing
-func (s *csvService) UploadAndProcessCSV(ctx context.Context, files []*multipart.FileHeader) (*domain.CSVUploadResponse, error) {
+func (s *csvService) UploadAndProcessCSV(ctx context.Context, opts domain.CSVUploadOptions, files []*multipart.FileHeader) (*domain.CSVUploadResponse, error) {
 
 	if len(files) == 0 {
 		return nil, domain.ErrBadParamInput
 	}
+	if err := opts.Dialect.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.processors.Lookup(opts.Type); err != nil {
+		return nil, err
+	}
+	if !s.processors.Allows(opts.Type, domain.CallerFromContext(ctx)) {
+		return nil, fmt.Errorf("%w: your role cannot upload %s files", domain.ErrForbidden, opts.Type)
+	}
+	uploader, err := csvUploader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := s.uploadSchema(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Processing outlives the request but keeps its values, processors
+	// attribute imported records to the uploader
+	processCtx := context.WithoutCancel(ctx)
 
 	var jobs []domain.CSVJob
 	var wg sync.WaitGroup
@@ -80,15 +130,41 @@ func (s *csvService) UploadAndProcessCSV(ctx context.Context, files []*multipart
 				return
 			}
 
+			dialect, err := sniffDialect(fh, opts.Dialect)
+			if err != nil {
+				errChan <- err
+				return
+			}
+
 			// Create job
 			job := &domain.CSVJob{}
 			job.ID = uuid.New().String()
 			job.Filename = fh.Filename
+			job.Type = opts.Type
+			job.Dialect = dialect
+			job.Schema = schema
+			job.DryRun = opts.DryRun
 			job.Status = domain.CSVJobStatusPending
+			job.UserID = uploader
+
+			// Queued uploads are stored before the job exists, a worker may
+			// claim it as soon as it is created. Dry runs are queued too, the
+			// summary lands on the job.
+			queued := s.store != nil
+			if queued {
+				if err := s.storeUpload(ctx, job, fh); err != nil {
+					s.logger.WithError(err).Error("Failed to store CSV file")
+					errChan <- err
+					return
+				}
+			}
 
 			// Create job in database
 			if err := s.csvRepo.CreateJob(ctx, job); err != nil {
 				s.logger.WithError(err).Error("Failed to create CSV job")
+				if queued {
+					s.store.Delete(context.WithoutCancel(ctx), job.FileKey)
+				}
 				errChan <- err
 				return
 			}
@@ -97,22 +173,26 @@ func (s *csvService) UploadAndProcessCSV(ctx context.Context, files []*multipart
 			mu.Lock()
 			jobs = append(jobs, *job)
 			mu.Unlock()
+			if queued {
+				return
+			}
 
-			// Start processing asynchronously
-			go func(jobID string, fileHeader *multipart.FileHeader) {
+			// Without a file store the upload is processed here, and lost
+			// if the replica stops before it is done
+			go func(job domain.CSVJob, fileHeader *multipart.FileHeader) {
 				file, err := fileHeader.Open()
 				if err != nil {
-					s.logger.WithError(err).WithField("job_id", jobID).Error("Failed to open CSV file")
+					s.logger.WithError(err).WithField("job_id", job.ID).Error("Failed to open CSV file")
 					errMsg := err.Error()
-					s.csvRepo.UpdateJobStatus(context.Background(), jobID, domain.CSVJobStatusFailed, &errMsg)
+					s.failJob(processCtx, &job, errMsg)
 					return
 				}
 				defer file.Close()
 
-				if err := s.ProcessCSVFile(context.Background(), jobID, file); err != nil {
-					s.logger.WithError(err).WithField("job_id", jobID).Error("CSV processing failed")
+				if err := s.ProcessCSVFile(processCtx, &job, file); err != nil {
+					s.logger.WithError(err).WithField("job_id", job.ID).Error("CSV processing failed")
 				}
-			}(job.ID, fh)
+			}(*job, fh)
 
 		}(fileHeader)
 	}
@@ -127,44 +207,200 @@ func (s *csvService) UploadAndProcessCSV(ctx context.Context, files []*multipart
 		}
 	}
 
+	message := fmt.Sprintf("Successfully uploaded %d CSV files for processing", len(jobs))
+	if opts.DryRun {
+		message = fmt.Sprintf("Queued %d CSV files for validation, nothing will be imported", len(jobs))
+	}
 	return &domain.CSVUploadResponse{
 		Jobs:    jobs,
-		Message: fmt.Sprintf("Successfully uploaded %d CSV files for processing", len(jobs)),
+		Message: message,
 	}, nil
 }
 
+// uploadSchema returns the schema sent with an upload or the saved one it
+// names, nil when rows are not validated
+func (s *csvService) uploadSchema(ctx context.Context, opts domain.CSVUploadOptions) (*domain.CSVSchema, error) {
+	switch {
+	case opts.Schema != nil && opts.SchemaName != "":
+		return nil, fmt.Errorf("%w: send a schema or the name of a saved one, not both", domain.ErrBadParamInput)
+	case opts.Schema != nil:
+		if err := opts.Schema.Validate(); err != nil {
+			return nil, err
+		}
+		return opts.Schema, nil
+	case opts.SchemaName != "":
+		schema, err := s.csvRepo.GetSchema(ctx, opts.SchemaName)
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, fmt.Errorf("%w: no schema is saved as %s", domain.ErrBadParamInput, opts.SchemaName)
+		}
+		return schema, err
+	}
+	return nil, nil
+}
+
+// sniffDialect reads the start of an uploaded file to complete the dialect
+// asked for by the upload
+func sniffDialect(fh *multipart.FileHeader, override domain.CSVDialect) (domain.CSVDialect, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return override, err
+	}
+	defer file.Close()
+
+	_, dialect, err := csvdialect.Open(file, override)
+	return dialect, err
+}
+
 // ProcessCSVFile processes a single CSV file using worker pool pattern
-func (s *csvService) ProcessCSVFile(ctx context.Context, jobID string, reader io.Reader) error {
+func (s *csvService) ProcessCSVFile(ctx context.Context, csvJob *domain.CSVJob, reader io.Reader) error {
+	jobID := csvJob.ID
+	ctx, stop := s.track(ctx, jobID)
+	defer stop()
+
+	processor, err := s.processors.Lookup(csvJob.Type)
+	if err != nil {
+		errMsg := err.Error()
+		s.failJob(ctx, csvJob, errMsg)
+		return err
+	}
+
 	// Update job status to processing
-	if err := s.csvRepo.UpdateJobStatus(ctx, jobID, domain.CSVJobStatusProcessing, nil); err != nil {
+	if err := s.csvRepo.UpdateJobStatus(ctx, jobID, csvJob.WorkerID, domain.CSVJobStatusProcessing, nil); err != nil {
 		return fmt.Errorf("failed to update job status: %w", err)
 	}
+	csvJob.Status = domain.CSVJobStatusProcessing
+	s.publish(ctx, csvJob)
 
-	// Create CSV reader
-	csvReader := csv.NewReader(reader)
+	// Create CSV reader, the dialect of the job overrides what is sniffed
+	decoded, dialect, err := csvdialect.Open(reader, csvJob.Dialect)
+	if err != nil {
+		errMsg := err.Error()
+		s.failJob(ctx, csvJob, errMsg)
+		return err
+	}
+	csvReader := csvdialect.NewReader(decoded, dialect)
 	csvReader.ReuseRecord = true // Memory optimization
 
 	// Read headers
 	headers, err := csvReader.Read()
 	if err != nil {
 		errMsg := "failed to read CSV headers"
-		s.csvRepo.UpdateJobStatus(ctx, jobID, domain.CSVJobStatusFailed, &errMsg)
+		s.failJob(ctx, csvJob, errMsg)
 		return fmt.Errorf("failed to read CSV headers: %w", err)
 	}
+	original := make([]string, len(headers))
+	for i, h := range headers {
+		original[i] = strings.TrimSpace(h)
+	}
+	if err := s.csvRepo.SetJobHeaders(ctx, jobID, original); err != nil {
+		s.logger.WithError(err).WithField("job_id", jobID).Error("Failed to store CSV headers")
+	}
+	headers = normalizeHeaders(headers)
+	fields := headers
+	var schema *csvschema.Schema
+	if csvJob.Schema != nil {
+		if schema, err = csvschema.Compile(csvJob.Schema); err == nil {
+			err = schema.CheckHeaders(headers)
+		}
+		if err != nil {
+			errMsg := err.Error()
+			s.failJob(ctx, csvJob, errMsg)
+			return err
+		}
+		fields = schema.Fields(headers)
+	}
+	if err := processor.CheckHeaders(fields); err != nil {
+		errMsg := err.Error()
+		s.failJob(ctx, csvJob, errMsg)
+		return err
+	}
 
 	// Create worker pool
-	pool := s.createWorkerPool(ctx, jobID)
+	pool := s.createWorkerPool(ctx, processor, schema)
+	pool.dryRun = csvJob.DryRun
 	defer pool.Close()
 
+	// A job that ran before continues from its checkpoint, rows done past it
+	// are not processed again
+	checkpoint := csvJob.Checkpoint
+	doneRows := map[int]bool{}
+	if !csvJob.DryRun {
+		done, err := s.csvRepo.ListDoneRows(ctx, jobID)
+		if err != nil {
+			return fmt.Errorf("failed to load finished rows: %w", err)
+		}
+		for _, row := range done {
+			doneRows[row.RowNumber] = row.Failed
+		}
+	}
+
 	// Start result processor
-	var processedRows, failedRows int64
+	processedRows, failedRows := checkpoint.Processed, checkpoint.Failed
 	var totalRows int64
 
+	// Failed rows are stored in batches, up to domain.MaxCSVJobErrors
+	var rowErrors []domain.CSVJobError
+	storedErrors := int(checkpoint.Failed)
+	for _, failed := range doneRows {
+		if failed {
+			storedErrors++
+		}
+	}
+	flushErrors := func(ctx context.Context) {
+		if len(rowErrors) == 0 {
+			return
+		}
+		if err := s.csvRepo.AddJobErrors(ctx, rowErrors); err != nil {
+			s.logger.WithError(err).WithField("job_id", jobID).Error("Failed to store CSV row errors")
+		}
+		rowErrors = rowErrors[:0]
+	}
+
+	// The checkpoint moves over rows once every row before them is done too,
+	// rows done out of order wait in finished. Errors are flushed first so
+	// the checkpoint never covers a row whose error is lost.
+	finished := map[int]domain.CSVWorkerResult{}
+	var newlyDone []domain.CSVRowOutcome
+	advance := func(result domain.CSVWorkerResult) {
+		finished[result.RowNumber] = result
+		for {
+			next, ok := finished[checkpoint.Row+1]
+			if !ok {
+				return
+			}
+			delete(finished, next.RowNumber)
+			checkpoint.Row, checkpoint.Offset = next.RowNumber, next.Offset
+			if next.Result.Success {
+				checkpoint.Processed++
+			} else {
+				checkpoint.Failed++
+			}
+		}
+	}
+	flush := func(ctx context.Context) {
+		flushErrors(ctx)
+		if csvJob.DryRun {
+			return
+		}
+		if err := s.csvRepo.SaveCheckpoint(ctx, jobID, csvJob.WorkerID, checkpoint, newlyDone); err != nil {
+			s.logger.WithError(err).WithField("job_id", jobID).Error("Failed to save CSV checkpoint")
+			return
+		}
+		newlyDone = newlyDone[:0]
+	}
+
+	// Dry runs add up what the rows would do, only the result processor
+	// touches the summary until it is done
+	summary := &domain.CSVDryRunSummary{ErrorsByType: map[string]int64{}, Errors: []domain.CSVJobError{}}
+
 	resultProcessor := make(chan bool)
 	go func() {
 		defer close(resultProcessor)
 		ticker := time.NewTicker(ProgressUpdateInterval)
 		defer ticker.Stop()
+		publishTicker := time.NewTicker(ProgressPublishInterval)
+		defer publishTicker.Stop()
+		published := int64(-1)
 
 		for {
 			select {
@@ -173,6 +409,22 @@ func (s *csvService) ProcessCSVFile(ctx context.Context, jobID string, reader io
 					return
 				}
 
+				if csvJob.DryRun {
+					addDryRunResult(summary, result)
+				} else {
+					advance(result)
+				}
+				if result.Resumed {
+					if result.Result.Success {
+						atomic.AddInt64(&processedRows, 1)
+					} else {
+						atomic.AddInt64(&failedRows, 1)
+					}
+					continue
+				}
+				if !csvJob.DryRun {
+					newlyDone = append(newlyDone, domain.CSVRowOutcome{RowNumber: result.RowNumber, Failed: !result.Result.Success})
+				}
 				if result.Result.Success {
 					atomic.AddInt64(&processedRows, 1)
 				} else {
@@ -181,14 +433,34 @@ func (s *csvService) ProcessCSVFile(ctx context.Context, jobID string, reader io
 						"job_id":     jobID,
 						"row_number": result.RowNumber,
 						"error":      result.Result.Error,
-					}).Warn("CSV row processing failed")
+					}).Debug("CSV row processing failed")
+					if storedErrors < domain.MaxCSVJobErrors {
+						storedErrors++
+						rowErrors = append(rowErrors, domain.CSVJobError{
+							JobID:     jobID,
+							RowNumber: result.RowNumber,
+							Values:    result.Data,
+							Error:     result.Result.Error,
+							Errors:    result.Result.Errors,
+						})
+					}
+				}
+
+			case <-publishTicker.C:
+				// Streams hear about progress sooner than the database
+				current := atomic.LoadInt64(&processedRows)
+				failed := atomic.LoadInt64(&failedRows)
+				if current+failed != published {
+					published = current + failed
+					s.publish(ctx, &domain.CSVJob{ID: jobID, Status: domain.CSVJobStatusProcessing, ProcessedRows: current, FailedRows: failed})
 				}
 
 			case <-ticker.C:
 				// Periodic progress update
+				flush(ctx)
 				current := atomic.LoadInt64(&processedRows)
 				failed := atomic.LoadInt64(&failedRows)
-				if err := s.csvRepo.UpdateJobProgress(ctx, jobID, current, failed); err != nil {
+				if err := s.csvRepo.UpdateJobProgress(ctx, jobID, csvJob.WorkerID, current, failed); err != nil {
 					s.logger.WithError(err).Error("Failed to update job progress")
 				}
 
@@ -197,30 +469,91 @@ func (s *csvService) ProcessCSVFile(ctx context.Context, jobID string, reader io
 			}
 		}
 	}()
+	// A cancelled job keeps what is done for the next attempt
+	cancelled := func() error {
+		<-resultProcessor
+		stopped := context.WithoutCancel(ctx)
+		flush(stopped)
+		// A queued job is released or already belongs to another worker,
+		// see processQueued. Anything else is not picked up again.
+		if csvJob.FileKey == "" {
+			s.failJob(stopped, csvJob, "processing cancelled")
+		}
+		return ctx.Err()
+	}
 
-	// Read and send jobs to workers
+	// Skip the rows before the checkpoint, the file must still be the one
+	// the checkpoint was taken of
 	rowNumber := 0
+	for rowNumber < checkpoint.Row {
+		if _, err := csvReader.Read(); err == io.EOF {
+			break
+		}
+		rowNumber++
+	}
+	if checkpoint.Row > 0 && (rowNumber < checkpoint.Row || csvReader.InputOffset() != checkpoint.Offset) {
+		close(pool.jobChan)
+		pool.wg.Wait()
+		close(pool.resultChan)
+		<-resultProcessor
+		errMsg := "the file changed since the checkpoint of the job"
+		s.failJob(ctx, csvJob, errMsg)
+		return fmt.Errorf("%w: %s", domain.ErrCSVFileInvalid, errMsg)
+	}
+	totalRows = int64(rowNumber)
+
+	// Read and send jobs to workers
 	for {
 		record, err := csvReader.Read()
 		if err == io.EOF {
 			break
 		}
-		if err != nil {
-			s.logger.WithError(err).WithField("job_id", jobID).Error("Error reading CSV row")
-			atomic.AddInt64(&failedRows, 1)
-			continue
-		}
 
 		rowNumber++
 		totalRows++
+		offset := csvReader.InputOffset()
+
+		if failed, ok := doneRows[rowNumber]; ok {
+			select {
+			case pool.resultChan <- domain.CSVWorkerResult{
+				JobID:     jobID,
+				RowNumber: rowNumber,
+				Offset:    offset,
+				Result:    domain.CSVProcessingResult{RowNumber: rowNumber, Success: !failed},
+				Resumed:   true,
+			}:
+			case <-ctx.Done():
+				return cancelled()
+			}
+			continue
+		}
 
 		// Create a copy of the record to avoid race conditions
 		recordCopy := make([]string, len(record))
 		copy(recordCopy, record)
 
+		if err != nil {
+			// Rows that cannot be parsed skip the workers but are reported
+			// like the ones that fail processing
+			s.logger.WithError(err).WithField("job_id", jobID).Debug("Error reading CSV row")
+			select {
+			case pool.resultChan <- domain.CSVWorkerResult{
+				JobID:     jobID,
+				RowNumber: rowNumber,
+				Offset:    offset,
+				Data:      recordCopy,
+				Result:    domain.CSVProcessingResult{RowNumber: rowNumber, Error: err.Error(), ErrorType: domain.CSVErrorParse},
+			}:
+			case <-ctx.Done():
+				return cancelled()
+			}
+			continue
+		}
+
 		job := domain.CSVWorkerJob{
 			JobID:     jobID,
 			RowNumber: rowNumber,
+			Offset:    offset,
 			Data:      recordCopy,
 			Headers:   headers,
 		}
@@ -229,9 +562,7 @@ func (s *csvService) ProcessCSVFile(ctx context.Context, jobID string, reader io
 		case pool.jobChan <- job:
 			// Job sent successfully
 		case <-ctx.Done():
-			errMsg := "processing cancelled"
-			s.csvRepo.UpdateJobStatus(ctx, jobID, domain.CSVJobStatusFailed, &errMsg)
-			return ctx.Err()
+			return cancelled()
 		}
 	}
 
@@ -242,14 +573,32 @@ func (s *csvService) ProcessCSVFile(ctx context.Context, jobID string, reader io
 
 	// Wait for result processor to finish
 	<-resultProcessor
+	if ctx.Err() != nil {
+		return cancelled()
+	}
+	flush(ctx)
 
 	// Final progress update and job completion
 	finalProcessed := atomic.LoadInt64(&processedRows)
 	finalFailed := atomic.LoadInt64(&failedRows)
 
-	if err := s.csvRepo.CompleteJob(ctx, jobID, totalRows, finalProcessed, finalFailed); err != nil {
-		return fmt.Errorf("failed to complete job: %w", err)
+	if csvJob.DryRun {
+		summary.Rows = totalRows
+		sortDryRunErrors(summary)
+		if err := s.csvRepo.CompleteDryRun(ctx, jobID, csvJob.WorkerID, summary); err != nil {
+			return fmt.Errorf("failed to complete job: %w", err)
+		}
+		csvJob.Status = domain.CSVJobStatusValidated
+		csvJob.Summary = summary
+		csvJob.TotalRows, csvJob.ProcessedRows, csvJob.FailedRows = totalRows, totalRows, finalFailed
+	} else {
+		if err := s.csvRepo.CompleteJob(ctx, jobID, csvJob.WorkerID, totalRows, finalProcessed, finalFailed); err != nil {
+			return fmt.Errorf("failed to complete job: %w", err)
+		}
+		csvJob.Status = domain.CSVJobStatusCompleted
+		csvJob.TotalRows, csvJob.ProcessedRows, csvJob.FailedRows = totalRows, finalProcessed, finalFailed
 	}
+	s.publish(ctx, csvJob)
 
 	s.logger.WithFields(logrus.Fields{
 		"job_id":         jobID,
@@ -264,8 +613,23 @@ func (s *csvService) ProcessCSVFile(ctx context.Context, jobID string, reader io
 	return nil
 }
 
+// track lets CancelJob stop a job processed on this replica until the
+// returned function is called
+func (s *csvService) track(ctx context.Context, jobID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	s.running[jobID] = cancel
+	s.mu.Unlock()
+	return ctx, func() {
+		s.mu.Lock()
+		delete(s.running, jobID)
+		s.mu.Unlock()
+		cancel()
+	}
+}
+
 // createWorkerPool creates and starts a worker pool
-func (s *csvService) createWorkerPool(ctx context.Context, jobID string) *CSVWorkerPool {
+func (s *csvService) createWorkerPool(ctx context.Context, processor domain.RowProcessor, schema *csvschema.Schema) *CSVWorkerPool {
 	poolCtx, cancel := context.WithCancel(ctx)
 
 	pool := &CSVWorkerPool{
@@ -276,6 +640,8 @@ func (s *csvService) createWorkerPool(ctx context.Context, jobID string) *CSVWor
 		ctx:        poolCtx,
 		cancel:     cancel,
 		logger:     s.logger,
+		processor:  processor,
+		schema:     schema,
 	}
 
 	// Start workers
@@ -302,11 +668,18 @@ func (wp *CSVWorkerPool) worker(workerID int) {
 			}
 
 			result := wp.processRow(job)
+			if wp.ctx.Err() != nil {
+				// The row may have been cut short, it runs again when the
+				// job is resumed
+				return
+			}
 
 			select {
 			case wp.resultChan <- domain.CSVWorkerResult{
 				JobID:     job.JobID,
 				RowNumber: job.RowNumber,
+				Offset:    job.Offset,
+				Data:      job.Data,
 				Result:    result,
 			}:
 			case <-wp.ctx.Done():
@@ -319,10 +692,8 @@ func (wp *CSVWorkerPool) worker(workerID int) {
 	}
 }
 
-// processRow processes a single CSV row
+// processRow hands a single CSV row to the processor of the job
 func (wp *CSVWorkerPool) processRow(job domain.CSVWorkerJob) domain.CSVProcessingResult {
-	// Simulate data processing and validation
-	data := make(map[string]interface{})
 
 	// Basic validation - ensure we have data for all headers
 	if len(job.Data) != len(job.Headers) {
@@ -330,22 +701,49 @@ func (wp *CSVWorkerPool) processRow(job domain.CSVWorkerJob) domain.CSVProcessin
 			RowNumber: job.RowNumber,
 			Success:   false,
 			Error:     fmt.Sprintf("column count mismatch: expected %d, got %d", len(job.Headers), len(job.Data)),
+			ErrorType: domain.CSVErrorParse,
 		}
 	}
 
 	// Map headers to data
+	row := make(map[string]string, len(job.Headers))
+	data := make(map[string]interface{}, len(job.Headers))
 	for i, header := range job.Headers {
-		if i < len(job.Data) {
-			data[header] = job.Data[i]
+		row[header] = strings.TrimSpace(job.Data[i])
+		data[header] = job.Data[i]
+	}
+
+	if wp.schema != nil {
+		var err error
+		if row, err = wp.schema.Apply(row); err != nil {
+			result := domain.CSVProcessingResult{
+				RowNumber: job.RowNumber,
+				Success:   false,
+				Error:     err.Error(),
+				ErrorType: domain.CSVErrorSchema,
+			}
+			errors.As(err, &result.Errors)
+			return result
 		}
 	}
 
-	// Simulate some processing time and potential errors
-	if job.RowNumber%100 == 0 { // Simulate 1% error rate
+	var action domain.CSVRowAction
+	var err error
+	if wp.dryRun {
+		action, err = wp.processor.ValidateRow(wp.ctx, row)
+	} else {
+		// A row that runs again after a crash finds the record it created
+		err = wp.processor.ProcessRow(domain.WithCSVRow(wp.ctx, domain.CSVRowKey{JobID: job.JobID, Row: job.RowNumber}), row)
+		if errors.Is(err, domain.ErrCSVRowImported) {
+			err = nil
+		}
+	}
+	if err != nil {
 		return domain.CSVProcessingResult{
 			RowNumber: job.RowNumber,
 			Success:   false,
-			Error:     "simulated processing error",
+			Error:     err.Error(),
+			ErrorType: rowErrorType(err),
 		}
 	}
 
@@ -353,9 +751,75 @@ func (wp *CSVWorkerPool) processRow(job domain.CSVWorkerJob) domain.CSVProcessin
 		RowNumber: job.RowNumber,
 		Success:   true,
 		Data:      data,
+		Action:    action,
+	}
+}
+
+// rowErrorType is the kind of failure a dry run counts a processor error as
+func rowErrorType(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrConflict):
+		return domain.CSVErrorConflict
+	case errors.Is(err, domain.ErrNotFound), errors.Is(err, domain.ErrUserNotFound):
+		return domain.CSVErrorNotFound
+	case errors.Is(err, domain.ErrBadParamInput), errors.Is(err, domain.ErrCommentTooDeep):
+		return domain.CSVErrorValidation
+	}
+	return domain.CSVErrorOther
+}
+
+// addDryRunResult counts a row in the summary of a dry run. Rows finish out
+// of order, so more errors than listed are kept until sortDryRunErrors picks
+// the first ones.
+func addDryRunResult(summary *domain.CSVDryRunSummary, result domain.CSVWorkerResult) {
+	if result.Result.Success {
+		switch result.Result.Action {
+		case domain.CSVRowInsert:
+			summary.WouldInsert++
+		case domain.CSVRowUpdate:
+			summary.WouldUpdate++
+		}
+		return
+	}
+
+	summary.Failed++
+	errorType := result.Result.ErrorType
+	if errorType == "" {
+		errorType = domain.CSVErrorOther
+	}
+	summary.ErrorsByType[errorType]++
+	summary.Errors = append(summary.Errors, domain.CSVJobError{
+		JobID:     result.JobID,
+		RowNumber: result.RowNumber,
+		Values:    result.Data,
+		Error:     result.Result.Error,
+		Errors:    result.Result.Errors,
+	})
+	if len(summary.Errors) >= 2*domain.CSVDryRunErrors {
+		sortDryRunErrors(summary)
 	}
 }
 
+// sortDryRunErrors keeps the first domain.CSVDryRunErrors failed rows
+func sortDryRunErrors(summary *domain.CSVDryRunSummary) {
+	sort.Slice(summary.Errors, func(i, j int) bool {
+		return summary.Errors[i].RowNumber < summary.Errors[j].RowNumber
+	})
+	if len(summary.Errors) > domain.CSVDryRunErrors {
+		summary.Errors = summary.Errors[:domain.CSVDryRunErrors]
+	}
+}
+
+// normalizeHeaders trims and lower cases the header row so processors look
+// columns up by their plain name
+func normalizeHeaders(headers []string) []string {
+	normalized := make([]string, len(headers))
+	for i, h := range headers {
+		normalized[i] = strings.ToLower(strings.TrimSpace(h))
+	}
+	return normalized
+}
+
 // Close closes the worker pool
 func (wp *CSVWorkerPool) Close() {
 	wp.cancel()
@@ -363,11 +827,15 @@ func (wp *CSVWorkerPool) Close() {
 
 // GetJobProgress retrieves the progress of a CSV processing job
 func (s *csvService) GetJobProgress(ctx context.Context, jobID uuid.UUID) (*domain.CSVProcessingProgress, error) {
-	job, err := s.csvRepo.GetJobByID(ctx, jobID)
+	job, err := s.visibleJob(ctx, jobID)
 	if err != nil {
 		return nil, err
 	}
+	return jobProgress(job), nil
+}
 
+// jobProgress describes how far job got
+func jobProgress(job *domain.CSVJob) *domain.CSVProcessingProgress {
 	var successRate float64
 	if job.TotalRows > 0 {
 		successRate = float64(job.ProcessedRows-job.FailedRows) / float64(job.TotalRows) * 100
@@ -390,6 +858,10 @@ func (s *csvService) GetJobProgress(ctx context.Context, jobID uuid.UUID) (*doma
 		progress.Message = "Job is currently being processed"
 	case domain.CSVJobStatusCompleted:
 		progress.Message = "Job processing completed successfully"
+	case domain.CSVJobStatusValidated:
+		progress.Message = "Dry run completed, nothing was imported"
+	case domain.CSVJobStatusCancelled:
+		progress.Message = "Job was cancelled"
 	case domain.CSVJobStatusFailed:
 		progress.Message = "Job processing failed"
 		if job.ErrorMessage != nil {
@@ -397,10 +869,296 @@ func (s *csvService) GetJobProgress(ctx context.Context, jobID uuid.UUID) (*doma
 		}
 	}
 
-	return progress, nil
+	return progress
 }
 
 // GetUserJobs retrieves all CSV jobs for a user
 func (s *csvService) GetUserJobs(ctx context.Context) ([]*domain.CSVJob, error) {
-	return s.csvRepo.GetJobsByUserID(ctx)
+	caller := domain.CallerFromContext(ctx)
+	switch {
+	case caller == nil || caller.ID == "":
+		return []*domain.CSVJob{}, nil
+	case caller.HasRole(domain.RoleAdmin):
+		return s.csvRepo.GetJobsByUserID(ctx, "")
+	}
+	return s.csvRepo.GetJobsByUserID(ctx, caller.ID)
+}
+
+// visibleJob returns a job the caller uploaded, admins see every job. Jobs
+// of other users are not found rather than forbidden, so their ids do not
+// leak.
+func (s *csvService) visibleJob(ctx context.Context, jobID uuid.UUID) (*domain.CSVJob, error) {
+	job, err := s.csvRepo.GetJobByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	caller := domain.CallerFromContext(ctx)
+	if caller == nil || caller.ID == "" || (caller.ID != job.UserID && !caller.HasRole(domain.RoleAdmin)) {
+		return nil, domain.ErrCSVJobNotFound
+	}
+	return job, nil
+}
+
+// GetJobErrors returns a page of the rows a job failed on
+func (s *csvService) GetJobErrors(ctx context.Context, jobID uuid.UUID, page, limit int) (*domain.PaginatedResponse, error) {
+	if _, err := s.visibleJob(ctx, jobID); err != nil {
+		return nil, err
+	}
+
+	rowErrors, total, err := s.csvRepo.ListJobErrors(ctx, jobID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.PaginatedResponse{
+		Data: rowErrors,
+		Pagination: domain.PaginationInfo{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: (total + limit - 1) / limit,
+		},
+	}, nil
+}
+
+// jobErrorsPageSize is how many failed rows WriteJobErrorsCSV reads at once
+const jobErrorsPageSize = 1000
+
+// ResumeJob queues a failed job again, it continues from its last checkpoint
+// instead of the first row
+func (s *csvService) ResumeJob(ctx context.Context, jobID uuid.UUID) (*domain.CSVJob, error) {
+	if _, err := s.visibleJob(ctx, jobID); err != nil {
+		return nil, err
+	}
+
+	job, err := s.csvRepo.ResumeJob(ctx, jobID)
+	if !errors.Is(err, domain.ErrNotFound) {
+		return job, err
+	}
+
+	return nil, s.jobStateError(ctx, jobID, func(job *domain.CSVJob) string {
+		if job.Status != domain.CSVJobStatusFailed && job.Status != domain.CSVJobStatusCancelled {
+			return fmt.Sprintf("only failed or cancelled jobs can be resumed, this one is %s", job.Status)
+		}
+		return "the file of this job was not kept, upload it again"
+	})
+}
+
+// CancelJob stops a pending or processing job. A job processed on this
+// replica stops right away, one on another replica at its next heartbeat.
+func (s *csvService) CancelJob(ctx context.Context, jobID uuid.UUID) (*domain.CSVJob, error) {
+	if _, err := s.visibleJob(ctx, jobID); err != nil {
+		return nil, err
+	}
+
+	job, err := s.csvRepo.CancelJob(ctx, jobID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, s.jobStateError(ctx, jobID, func(job *domain.CSVJob) string {
+			return fmt.Sprintf("only pending or processing jobs can be cancelled, this one is %s", job.Status)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if cancel, ok := s.running[job.ID]; ok {
+		cancel()
+	}
+	s.mu.Unlock()
+	s.publish(ctx, job)
+	return job, nil
+}
+
+// RetryJob queues a failed or cancelled job again from its first row. With
+// failedRows a new job imports only the rows a finished job failed on.
+func (s *csvService) RetryJob(ctx context.Context, jobID uuid.UUID, failedRows bool) (*domain.CSVJob, error) {
+	if failedRows {
+		return s.retryFailedRows(ctx, jobID)
+	}
+
+	if _, err := s.visibleJob(ctx, jobID); err != nil {
+		return nil, err
+	}
+
+	job, err := s.csvRepo.RetryJob(ctx, jobID)
+	if !errors.Is(err, domain.ErrNotFound) {
+		return job, err
+	}
+	return nil, s.jobStateError(ctx, jobID, func(job *domain.CSVJob) string {
+		switch {
+		case job.DryRun:
+			return "dry runs cannot be retried, upload the file again"
+		case job.Status != domain.CSVJobStatusFailed && job.Status != domain.CSVJobStatusCancelled:
+			return fmt.Sprintf("only failed or cancelled jobs can be retried, this one is %s", job.Status)
+		}
+		return "the file of this job was not kept, upload it again"
+	})
+}
+
+// retryFailedRows queues a new job with the stored failed rows of a job, in
+// its dialect and with its schema. Jobs that failed more rows than
+// domain.MaxCSVJobErrors cannot be retried this way.
+func (s *csvService) retryFailedRows(ctx context.Context, jobID uuid.UUID) (*domain.CSVJob, error) {
+	job, err := s.visibleJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case job.DryRun:
+		return nil, fmt.Errorf("%w: dry runs import nothing, upload the file without dry_run", domain.ErrCSVJobState)
+	case !job.Status.Finished():
+		return nil, fmt.Errorf("%w: failed rows can be retried once the job is done, this one is %s", domain.ErrCSVJobState, job.Status)
+	case s.store == nil:
+		return nil, fmt.Errorf("%w: failed rows can only be retried when uploads are queued", domain.ErrCSVJobState)
+	}
+
+	var file bytes.Buffer
+	rows, err := s.writeJobErrors(ctx, job, &file, false)
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, fmt.Errorf("%w: this job has no failed rows", domain.ErrCSVJobState)
+	}
+	if int64(rows) < job.FailedRows {
+		// A retry of the kept rows would silently drop the others
+		return nil, fmt.Errorf("%w: only %d of the %d failed rows were kept, fix the file and upload it again", domain.ErrCSVJobState, rows, job.FailedRows)
+	}
+
+	retry := &domain.CSVJob{
+		ID:       uuid.New().String(),
+		Filename: strings.TrimSuffix(job.Filename, filepath.Ext(job.Filename)) + "-failed.csv",
+		Type:     job.Type,
+		Dialect:  domain.CSVDialect{Delimiter: job.Dialect.Delimiter, Encoding: domain.CSVEncodingUTF8},
+		Schema:   job.Schema,
+		UserID:   job.UserID,
+		FileKey:  newFileKey(),
+		Status:   domain.CSVJobStatusPending,
+	}
+	if err := s.store.Save(ctx, retry.FileKey, &file); err != nil {
+		return nil, err
+	}
+	if err := s.csvRepo.CreateJob(ctx, retry); err != nil {
+		s.store.Delete(context.WithoutCancel(ctx), retry.FileKey)
+		return nil, err
+	}
+	return retry, nil
+}
+
+// DeleteJob deletes a job that is done running, its errors and its stored
+// file
+func (s *csvService) DeleteJob(ctx context.Context, jobID uuid.UUID) error {
+	if _, err := s.visibleJob(ctx, jobID); err != nil {
+		return err
+	}
+
+	job, err := s.csvRepo.DeleteJob(ctx, jobID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return s.jobStateError(ctx, jobID, func(job *domain.CSVJob) string {
+			return fmt.Sprintf("the job is %s, cancel it before deleting it", job.Status)
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if job.FileKey != "" && s.store != nil {
+		if err := s.store.Delete(ctx, job.FileKey); err != nil {
+			s.logger.WithError(err).WithField("job_id", job.ID).Error("Failed to delete stored CSV file")
+		}
+	}
+	return nil
+}
+
+// jobStateError explains with explain why a job cannot be changed, or
+// reports that it does not exist
+func (s *csvService) jobStateError(ctx context.Context, jobID uuid.UUID, explain func(job *domain.CSVJob) string) error {
+	job, err := s.csvRepo.GetJobByID(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", domain.ErrCSVJobState, explain(job))
+}
+
+// WriteJobErrorsCSV writes the rows a job failed on to w as CSV, with the
+// headers and delimiter of the upload and an error column, so they can be
+// corrected and uploaded again
+func (s *csvService) WriteJobErrorsCSV(ctx context.Context, jobID uuid.UUID, w io.Writer) error {
+	job, err := s.visibleJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	_, err = s.writeJobErrors(ctx, job, w, true)
+	return err
+}
+
+// writeJobErrors writes the stored failed rows of job, with or without the
+// error column, and returns how many there were
+func (s *csvService) writeJobErrors(ctx context.Context, job *domain.CSVJob, w io.Writer, errorColumn bool) (int, error) {
+	writer := csv.NewWriter(w)
+	if comma := job.Dialect.Comma(); comma != 0 {
+		writer.Comma = comma
+	}
+
+	width := len(job.Headers)
+	headers := append([]string{}, job.Headers...)
+	if errorColumn {
+		headers = append(headers, "error")
+	}
+	if err := writer.Write(headers); err != nil {
+		return 0, err
+	}
+
+	jobID := uuid.MustParse(job.ID)
+	rows := 0
+	for offset := 0; ; offset += jobErrorsPageSize {
+		rowErrors, _, err := s.csvRepo.ListJobErrors(ctx, jobID, jobErrorsPageSize, offset)
+		if err != nil {
+			return rows, err
+		}
+		for _, rowErr := range rowErrors {
+			record := make([]string, width, width+1)
+			copy(record, rowErr.Values)
+			if errorColumn {
+				record = append(record, rowErr.Error)
+			}
+			if err := writer.Write(record); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+		if len(rowErrors) < jobErrorsPageSize {
+			break
+		}
+	}
+
+	writer.Flush()
+	return rows, writer.Error()
+}
+
+// SaveSchema saves a schema for uploads to refer to by name
+func (s *csvService) SaveSchema(ctx context.Context, schema *domain.CSVSchema) error {
+	if !domain.IsValidCSVSchemaName(schema.Name) {
+		return fmt.Errorf("%w: name must be 1 to 50 lower case letters, digits, dashes or underscores", domain.ErrBadParamInput)
+	}
+	if err := schema.Validate(); err != nil {
+		return err
+	}
+	return s.csvRepo.SaveSchema(ctx, schema)
+}
+
+// GetSchema returns a saved schema
+func (s *csvService) GetSchema(ctx context.Context, name string) (*domain.CSVSchema, error) {
+	return s.csvRepo.GetSchema(ctx, name)
+}
+
+// ListSchemas returns the saved schemas
+func (s *csvService) ListSchemas(ctx context.Context) ([]domain.CSVSchema, error) {
+	return s.csvRepo.ListSchemas(ctx)
+}
+
+// DeleteSchema removes a saved schema
+func (s *csvService) DeleteSchema(ctx context.Context, name string) error {
+	return s.csvRepo.DeleteSchema(ctx, name)
 }
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/render"
+)
+
+// CSVProcessorRegistry maps the type of a CSV upload to the RowProcessor
+// that imports its rows. Processors are registered at start up, teams add
+// their own types next to the built in users, posts and comments.
+type CSVProcessorRegistry struct {
+	mu         sync.RWMutex
+	processors map[string]domain.RowProcessor
+	roles      map[string][]domain.Role
+}
+
+func NewCSVProcessorRegistry() *CSVProcessorRegistry {
+	return &CSVProcessorRegistry{processors: map[string]domain.RowProcessor{}, roles: map[string][]domain.Role{}}
+}
+
+// Register adds the processor for a CSV type. With roles only callers
+// holding one of them may upload files of the type. Registering a type twice
+// is a programming error and panics.
+func (r *CSVProcessorRegistry) Register(csvType string, p domain.RowProcessor, roles ...domain.Role) *CSVProcessorRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if csvType == "" || p == nil {
+		panic("csv: Register needs a type and a processor")
+	}
+	if _, dup := r.processors[csvType]; dup {
+		panic("csv: Register called twice for type " + csvType)
+	}
+	r.processors[csvType] = p
+	if len(roles) > 0 {
+		r.roles[csvType] = roles
+	}
+	return r
+}
+
+// Allows reports whether caller may upload files of a CSV type
+func (r *CSVProcessorRegistry) Allows(csvType string, caller *domain.Caller) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roles, restricted := r.roles[csvType]
+	return !restricted || caller.HasRole(roles...)
+}
+
+// Lookup returns the processor of a CSV type, domain.ErrCSVUnknownType names
+// the types that are registered
+func (r *CSVProcessorRegistry) Lookup(csvType string) (domain.RowProcessor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if p, ok := r.processors[csvType]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("%w: type must be one of %s", domain.ErrCSVUnknownType, strings.Join(r.typesLocked(), ", "))
+}
+
+// Types lists the registered CSV types in alphabetical order
+func (r *CSVProcessorRegistry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.typesLocked()
+}
+
+func (r *CSVProcessorRegistry) typesLocked() []string {
+	types := make([]string, 0, len(r.processors))
+	for t := range r.processors {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// requireHeaders fails when a column the processor needs is missing
+func requireHeaders(headers []string, required ...string) error {
+	present := make(map[string]bool, len(headers))
+	for _, h := range headers {
+		present[h] = true
+	}
+	var missing []string
+	for _, r := range required {
+		if !present[r] {
+			missing = append(missing, r)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing column %s", domain.ErrCSVFileInvalid, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+type UserCreator interface {
+	CreateUser(ctx context.Context, u *domain.CreateUserRequest) (*domain.User, error)
+	FindUserByLogin(ctx context.Context, username, email string) (*domain.User, error)
+}
+
+// UserRowProcessor creates a user from every row with the columns name,
+// email, password and an optional username
+type UserRowProcessor struct {
+	users UserCreator
+}
+
+func NewUserRowProcessor(u UserCreator) *UserRowProcessor {
+	return &UserRowProcessor{users: u}
+}
+
+func (p *UserRowProcessor) CheckHeaders(headers []string) error {
+	return requireHeaders(headers, "name", "email", "password")
+}
+
+func (p *UserRowProcessor) ProcessRow(ctx context.Context, row map[string]string) error {
+	u, err := p.request(row)
+	if err != nil {
+		return err
+	}
+	_, err = p.users.CreateUser(ctx, u)
+	return err
+}
+
+// ValidateRow fails rows whose email or username is taken, the import would
+// refuse to create them
+func (p *UserRowProcessor) ValidateRow(ctx context.Context, row map[string]string) (domain.CSVRowAction, error) {
+	u, err := p.request(row)
+	if err != nil {
+		return "", err
+	}
+	if u.Username != "" && !domain.IsValidUsername(u.Username) {
+		return "", fmt.Errorf("%w: username must be 3 to 30 letters, digits or underscores", domain.ErrBadParamInput)
+	}
+	_, err = p.users.FindUserByLogin(ctx, u.Username, u.Email)
+	switch {
+	case err == nil:
+		return "", fmt.Errorf("%w: a user with this email or username exists", domain.ErrConflict)
+	case errors.Is(err, domain.ErrUserNotFound):
+		return domain.CSVRowInsert, nil
+	}
+	return "", err
+}
+
+func (p *UserRowProcessor) request(row map[string]string) (*domain.CreateUserRequest, error) {
+	if err := requireFields("name", row["name"], "email", row["email"], "password", row["password"]); err != nil {
+		return nil, err
+	}
+	if !domain.IsValidEmail(row["email"]) {
+		return nil, fmt.Errorf("%w: email is not valid", domain.ErrBadParamInput)
+	}
+	return &domain.CreateUserRequest{
+		Name:     row["name"],
+		Email:    row["email"],
+		Username: row["username"],
+		Password: row["password"],
+	}, nil
+}
+
+type PostCreator interface {
+	CreatePosts(ctx context.Context, u *domain.CreatePostsRequest) (*domain.Posts, error)
+}
+
+// PostRowProcessor creates a post of the uploader from every row with the
+// columns title, content and an optional content_format
+type PostRowProcessor struct {
+	posts PostCreator
+}
+
+func NewPostRowProcessor(p PostCreator) *PostRowProcessor {
+	return &PostRowProcessor{posts: p}
+}
+
+func (p *PostRowProcessor) CheckHeaders(headers []string) error {
+	return requireHeaders(headers, "title", "content")
+}
+
+func (p *PostRowProcessor) ProcessRow(ctx context.Context, row map[string]string) error {
+	post, err := p.request(ctx, row)
+	if err != nil {
+		return err
+	}
+	_, err = p.posts.CreatePosts(ctx, post)
+	return err
+}
+
+// ValidateRow checks the row renders, every valid row is a new post
+func (p *PostRowProcessor) ValidateRow(ctx context.Context, row map[string]string) (domain.CSVRowAction, error) {
+	post, err := p.request(ctx, row)
+	if err != nil {
+		return "", err
+	}
+	if _, err := render.Content(post.ContentFormat.OrDefault(), post.Content); err != nil {
+		return "", err
+	}
+	return domain.CSVRowInsert, nil
+}
+
+func (p *PostRowProcessor) request(ctx context.Context, row map[string]string) (*domain.CreatePostsRequest, error) {
+	uploader, err := csvUploader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireFields("title", row["title"], "content", row["content"]); err != nil {
+		return nil, err
+	}
+	format := domain.ContentFormat(row["content_format"])
+	if !format.OrDefault().IsValid() {
+		return nil, fmt.Errorf("%w: content_format must be plain or markdown", domain.ErrBadParamInput)
+	}
+	return &domain.CreatePostsRequest{
+		Title:         row["title"],
+		Content:       row["content"],
+		ContentFormat: format,
+		UserID:        uploader,
+	}, nil
+}
+
+type CommentCreator interface {
+	CreateComment(ctx context.Context, u *domain.CreateCommentRequest) (*domain.Comment, error)
+	ValidateComment(ctx context.Context, u *domain.CreateCommentRequest) error
+}
+
+// CommentRowProcessor creates a comment of the uploader from every row with
+// the columns post_id, body and the optional parent_id and content_format
+type CommentRowProcessor struct {
+	comments CommentCreator
+}
+
+func NewCommentRowProcessor(c CommentCreator) *CommentRowProcessor {
+	return &CommentRowProcessor{comments: c}
+}
+
+func (p *CommentRowProcessor) CheckHeaders(headers []string) error {
+	return requireHeaders(headers, "post_id", "body")
+}
+
+func (p *CommentRowProcessor) ProcessRow(ctx context.Context, row map[string]string) error {
+	comment, err := p.request(ctx, row)
+	if err != nil {
+		return err
+	}
+	_, err = p.comments.CreateComment(ctx, comment)
+	return err
+}
+
+// ValidateRow checks the post and parent of the row exist and its body
+// renders, every valid row is a new comment
+func (p *CommentRowProcessor) ValidateRow(ctx context.Context, row map[string]string) (domain.CSVRowAction, error) {
+	comment, err := p.request(ctx, row)
+	if err != nil {
+		return "", err
+	}
+	if err := p.comments.ValidateComment(ctx, comment); err != nil {
+		return "", err
+	}
+	if _, err := render.Content(comment.ContentFormat.OrDefault(), comment.Body); err != nil {
+		return "", err
+	}
+	return domain.CSVRowInsert, nil
+}
+
+func (p *CommentRowProcessor) request(ctx context.Context, row map[string]string) (*domain.CreateCommentRequest, error) {
+	uploader, err := csvUploader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireFields("post_id", row["post_id"], "body", row["body"]); err != nil {
+		return nil, err
+	}
+	format := domain.ContentFormat(row["content_format"])
+	if !format.OrDefault().IsValid() {
+		return nil, fmt.Errorf("%w: content_format must be plain or markdown", domain.ErrBadParamInput)
+	}
+	return &domain.CreateCommentRequest{
+		PostID:        row["post_id"],
+		ParentID:      row["parent_id"],
+		UserID:        uploader,
+		Body:          row["body"],
+		ContentFormat: format,
+	}, nil
+}
+
+// csvUploader is the author of imported posts and comments, rows never
+// choose one themselves
+func csvUploader(ctx context.Context) (string, error) {
+	caller := domain.CallerFromContext(ctx)
+	if caller == nil || caller.ID == "" {
+		return "", fmt.Errorf("%w: the uploader of the file is unknown", domain.ErrBadParamInput)
+	}
+	return caller.ID, nil
+}
+
+type UserImporter interface {
+	ImportUser(ctx context.Context, u *domain.UserImport) (*domain.User, error)
+	FindUserByLogin(ctx context.Context, username, email string) (*domain.User, error)
+}
+
+// HRUserRowProcessor imports the HR export with the columns Username,
+// Identifier, One-time password, Recovery code, First name, Last name,
+// Department and Location. The one-time password becomes the password of
+// new users. Rows without an Email column get an address at emailDomain.
+type HRUserRowProcessor struct {
+	users       UserImporter
+	emailDomain string
+	// updateCredentials replaces the email and recovery code of existing
+	// users too
+	updateCredentials bool
+}
+
+func NewHRUserRowProcessor(u UserImporter, emailDomain string) *HRUserRowProcessor {
+	return &HRUserRowProcessor{users: u, emailDomain: emailDomain}
+}
+
+// WithCredentialUpdates lets rows replace the email and recovery code of the
+// users they update, which hands the account to whoever holds them
+func (p *HRUserRowProcessor) WithCredentialUpdates() *HRUserRowProcessor {
+	p.updateCredentials = true
+	return p
+}
+
+func (p *HRUserRowProcessor) CheckHeaders(headers []string) error {
+	return requireHeaders(headers, "username", "one-time password", "first name", "last name")
+}
+
+func (p *HRUserRowProcessor) ProcessRow(ctx context.Context, row map[string]string) error {
+	_, err := p.users.ImportUser(ctx, p.userImport(row))
+	return err
+}
+
+// ValidateRow says whether the row would update the user with its username
+// or email, or create one
+func (p *HRUserRowProcessor) ValidateRow(ctx context.Context, row map[string]string) (domain.CSVRowAction, error) {
+	u := p.userImport(row)
+	if err := validateUserImport(u); err != nil {
+		return "", err
+	}
+	_, err := p.users.FindUserByLogin(ctx, u.Username, u.Email)
+	switch {
+	case err == nil:
+		return domain.CSVRowUpdate, nil
+	case errors.Is(err, domain.ErrUserNotFound):
+		return domain.CSVRowInsert, nil
+	}
+	return "", err
+}
+
+func (p *HRUserRowProcessor) userImport(row map[string]string) *domain.UserImport {
+	email := row["email"]
+	if email == "" && row["username"] != "" {
+		email = strings.ToLower(row["username"]) + "@" + p.emailDomain
+	}
+	return &domain.UserImport{
+		Username:     row["username"],
+		Email:        email,
+		Name:         strings.TrimSpace(row["first name"] + " " + row["last name"]),
+		EmployeeID:   row["identifier"],
+		Department:   row["department"],
+		Location:     row["location"],
+		Password:     row["one-time password"],
+		RecoveryCode: row["recovery code"],
+
+		UpdateCredentials: p.updateCredentials,
+	}
+}
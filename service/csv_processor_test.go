@@ -0,0 +1,223 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/service"
+	"github.com/edwinjordan/MajooTest-Golang/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCSVProcessorRegistry(t *testing.T) {
+	posts := service.NewPostRowProcessor(service.NewPostsService(new(mocks.PostsRepository)))
+	users := service.NewUserRowProcessor(service.NewUserService(new(mocks.UserRepository)))
+	registry := service.NewCSVProcessorRegistry().
+		Register("users", users).
+		Register("posts", posts)
+
+	t.Run("Looks processors up by type", func(t *testing.T) {
+		p, err := registry.Lookup("posts")
+
+		assert.NoError(t, err)
+		assert.Same(t, posts, p)
+		assert.Equal(t, []string{"posts", "users"}, registry.Types())
+	})
+
+	t.Run("Names the registered types for an unknown one", func(t *testing.T) {
+		_, err := registry.Lookup("orders")
+
+		assert.ErrorIs(t, err, domain.ErrCSVUnknownType)
+		assert.EqualError(t, err, "unknown CSV type: type must be one of posts, users")
+	})
+
+	t.Run("Refuses to register a type twice", func(t *testing.T) {
+		assert.Panics(t, func() { registry.Register("posts", posts) })
+	})
+}
+
+func TestPostRowProcessor(t *testing.T) {
+	ctx := domain.WithCaller(context.Background(), &domain.Caller{ID: "uploader-id"})
+
+	t.Run("Creates the post as the uploader", func(t *testing.T) {
+		mockPostsRepo := new(mocks.PostsRepository)
+		p := service.NewPostRowProcessor(service.NewPostsService(mockPostsRepo))
+
+		mockPostsRepo.On("CreatePosts", mock.Anything, mock.MatchedBy(func(req *domain.CreatePostsRequest) bool {
+			return req.UserID == "uploader-id" && req.Title == "Hello" && req.ContentFormat == domain.ContentFormatPlain
+		})).Return(&domain.Posts{}, nil).Once()
+
+		err := p.ProcessRow(ctx, map[string]string{"title": "Hello", "content": "World"})
+
+		assert.NoError(t, err)
+		mockPostsRepo.AssertExpectations(t)
+	})
+
+	t.Run("Fails rows without a title", func(t *testing.T) {
+		mockPostsRepo := new(mocks.PostsRepository)
+		p := service.NewPostRowProcessor(service.NewPostsService(mockPostsRepo))
+
+		err := p.ProcessRow(ctx, map[string]string{"title": " ", "content": "World"})
+
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+		mockPostsRepo.AssertNotCalled(t, "CreatePosts", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Fails when the uploader is unknown", func(t *testing.T) {
+		p := service.NewPostRowProcessor(service.NewPostsService(new(mocks.PostsRepository)))
+
+		err := p.ProcessRow(context.Background(), map[string]string{"title": "Hello", "content": "World"})
+
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+	})
+
+	t.Run("Needs title and content columns", func(t *testing.T) {
+		p := service.NewPostRowProcessor(service.NewPostsService(new(mocks.PostsRepository)))
+
+		assert.NoError(t, p.CheckHeaders([]string{"title", "content", "content_format"}))
+		assert.ErrorIs(t, p.CheckHeaders([]string{"title"}), domain.ErrCSVFileInvalid)
+	})
+}
+
+func TestUserRowProcessor(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Creates a user", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		p := service.NewUserRowProcessor(service.NewUserService(mockUserRepo))
+
+		mockUserRepo.On("CreateUser", mock.Anything, &domain.CreateUserRequest{
+			Name: "Ana", Email: "ana@example.com", Password: "Secret123!",
+		}).Return(&domain.User{}, nil).Once()
+
+		err := p.ProcessRow(ctx, map[string]string{"name": "Ana", "email": "ana@example.com", "password": "Secret123!"})
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("Reports a row that created its user before", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		p := service.NewUserRowProcessor(service.NewUserService(mockUserRepo))
+
+		mockUserRepo.On("CreateUser", mock.Anything, mock.Anything).Return(nil, domain.ErrCSVRowImported).Once()
+
+		err := p.ProcessRow(ctx, map[string]string{"name": "Ana", "email": "ana@example.com", "password": "Secret123!"})
+
+		assert.ErrorIs(t, err, domain.ErrCSVRowImported)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("Fails rows with an invalid email", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		p := service.NewUserRowProcessor(service.NewUserService(mockUserRepo))
+
+		err := p.ProcessRow(ctx, map[string]string{"name": "Ana", "email": "ana", "password": "Secret123!"})
+
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+		mockUserRepo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserRowProcessor_ValidateRow(t *testing.T) {
+	mockUserRepo := new(mocks.UserRepository)
+	p := service.NewUserRowProcessor(service.NewUserService(mockUserRepo))
+
+	mockUserRepo.On("FindUserByLogin", mock.Anything, "", "ana@example.com").Return(&domain.User{}, nil).Once()
+
+	_, err := p.ValidateRow(context.Background(), map[string]string{"name": "Ana", "email": "ana@example.com", "password": "Secret123!"})
+
+	assert.ErrorIs(t, err, domain.ErrConflict)
+	mockUserRepo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+}
+
+func TestHRUserRowProcessor(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Maps the HR columns onto the user", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		p := service.NewHRUserRowProcessor(service.NewUserService(mockUserRepo), "example.com")
+
+		mockUserRepo.On("ImportUser", mock.Anything, &domain.UserImport{
+			Username:     "booker12",
+			Email:        "booker12@example.com",
+			Name:         "Rachel Booker",
+			EmployeeID:   "9012",
+			Department:   "Sales",
+			Location:     "Manchester",
+			Password:     "12se74",
+			RecoveryCode: "rb9012",
+		}).Return(&domain.User{}, nil).Once()
+
+		err := p.ProcessRow(ctx, map[string]string{
+			"username": "booker12", "identifier": "9012", "one-time password": "12se74", "recovery code": "rb9012",
+			"first name": "Rachel", "last name": "Booker", "department": "Sales", "location": "Manchester",
+		})
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("Replaces the credentials of existing users only when asked to", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		p := service.NewHRUserRowProcessor(service.NewUserService(mockUserRepo), "example.com").WithCredentialUpdates()
+
+		mockUserRepo.On("ImportUser", mock.Anything, mock.MatchedBy(func(u *domain.UserImport) bool {
+			return u.Username == "booker12" && u.UpdateCredentials
+		})).Return(&domain.User{}, nil).Once()
+
+		err := p.ProcessRow(ctx, map[string]string{
+			"username": "booker12", "one-time password": "12se74", "recovery code": "rb9012",
+			"first name": "Rachel", "last name": "Booker",
+		})
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("Fails rows with an invalid username", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		p := service.NewHRUserRowProcessor(service.NewUserService(mockUserRepo), "example.com")
+
+		err := p.ProcessRow(ctx, map[string]string{
+			"username": "r.booker", "one-time password": "12se74", "first name": "Rachel", "last name": "Booker",
+		})
+
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+		mockUserRepo.AssertNotCalled(t, "ImportUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Dry runs say whether the user would be updated or created", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		p := service.NewHRUserRowProcessor(service.NewUserService(mockUserRepo), "example.com")
+		row := func(username string) map[string]string {
+			return map[string]string{
+				"username": username, "one-time password": "12se74", "first name": "Rachel", "last name": "Booker",
+			}
+		}
+
+		mockUserRepo.On("FindUserByLogin", mock.Anything, "booker12", "booker12@example.com").Return(&domain.User{}, nil).Once()
+		mockUserRepo.On("FindUserByLogin", mock.Anything, "grey07", "grey07@example.com").Return(nil, domain.ErrUserNotFound).Once()
+
+		action, err := p.ValidateRow(ctx, row("booker12"))
+		assert.NoError(t, err)
+		assert.Equal(t, domain.CSVRowUpdate, action)
+
+		action, err = p.ValidateRow(ctx, row("grey07"))
+		assert.NoError(t, err)
+		assert.Equal(t, domain.CSVRowInsert, action)
+
+		mockUserRepo.AssertExpectations(t)
+		mockUserRepo.AssertNotCalled(t, "ImportUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Reads the header of the HR export", func(t *testing.T) {
+		p := service.NewHRUserRowProcessor(nil, "example.com")
+
+		assert.NoError(t, p.CheckHeaders([]string{
+			"username", "identifier", "one-time password", "recovery code", "first name", "last name", "department", "location",
+		}))
+	})
+}
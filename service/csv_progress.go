@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+const (
+	// ProgressPublishInterval is how often a running job pushes its progress
+	ProgressPublishInterval = 500 * time.Millisecond
+	// ProgressResyncInterval is how often a stream rereads its job, in case
+	// a notification was lost
+	ProgressResyncInterval = 30 * time.Second
+)
+
+// ProgressBroker delivers the progress of CSV jobs to the streams following
+// them
+type ProgressBroker interface {
+	Publish(ctx context.Context, event domain.CSVProgressEvent)
+	Subscribe(jobID string) (<-chan domain.CSVProgressEvent, func())
+}
+
+// WithProgress pushes the progress of jobs through b, streams no longer
+// poll the database for it
+func (s *csvService) WithProgress(b ProgressBroker) *csvService {
+	s.progress = b
+	return s
+}
+
+// progressEvent is the state of job as a stream event, numbered by when the
+// job was last updated
+func progressEvent(job *domain.CSVJob) domain.CSVProgressEvent {
+	event := domain.CSVProgressEvent{
+		ID:       job.UpdatedAt.UnixMilli(),
+		JobID:    job.ID,
+		Type:     domain.CSVEventProgress,
+		Progress: *jobProgress(job),
+	}
+	if job.Status.Finished() {
+		event.Type = string(job.Status)
+		event.Summary = job.Summary
+	}
+	return event
+}
+
+// publish tells the streams of job about its state
+func (s *csvService) publish(ctx context.Context, job *domain.CSVJob) {
+	if s.progress != nil {
+		s.progress.Publish(ctx, progressEvent(job))
+	}
+}
+
+// failJob marks a job failed and tells the streams following it
+func (s *csvService) failJob(ctx context.Context, job *domain.CSVJob, errMsg string) {
+	job.Status = domain.CSVJobStatusFailed
+	job.ErrorMessage = &errMsg
+	if err := s.csvRepo.UpdateJobStatus(ctx, job.ID, job.WorkerID, domain.CSVJobStatusFailed, &errMsg); err != nil {
+		s.logger.WithError(err).WithField("job_id", job.ID).Error("Failed to mark CSV job failed")
+	}
+	s.publish(ctx, job)
+}
+
+// StreamProgress sends the state of a job, then every change to it, until
+// the job is done or ctx ends. Events up to lastEventID were seen by the
+// client already and are skipped. Without a broker the job is reread every
+// ProgressUpdateInterval.
+func (s *csvService) StreamProgress(ctx context.Context, jobID uuid.UUID, lastEventID int64) (<-chan domain.CSVProgressEvent, error) {
+	job, err := s.visibleJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	var live <-chan domain.CSVProgressEvent
+	unsubscribe := func() {}
+	resyncEvery := ProgressUpdateInterval
+	if s.progress != nil {
+		live, unsubscribe = s.progress.Subscribe(job.ID)
+		resyncEvery = ProgressResyncInterval
+	}
+
+	events := make(chan domain.CSVProgressEvent)
+	go func() {
+		defer close(events)
+		defer unsubscribe()
+
+		// emit sends event unless the client saw it and reports whether
+		// the stream is over
+		emit := func(event domain.CSVProgressEvent) bool {
+			if event.ID > lastEventID {
+				select {
+				case events <- event:
+					lastEventID = event.ID
+				case <-ctx.Done():
+					return true
+				}
+			}
+			return event.Final()
+		}
+
+		if emit(progressEvent(job)) {
+			return
+		}
+		resync := time.NewTicker(resyncEvery)
+		defer resync.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-live:
+				if !ok || emit(event) {
+					return
+				}
+			case <-resync.C:
+				job, err := s.csvRepo.GetJobByID(ctx, jobID)
+				if err != nil {
+					if ctx.Err() == nil {
+						s.logger.WithError(err).WithField("job_id", jobID).Error("Failed to reread streamed CSV job")
+					}
+					return
+				}
+				if emit(progressEvent(job)) {
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+// WithQueue keeps uploads in store and queues them in the database, Run
+// processes them. Jobs survive a restart of the replica that received them.
+func (s *csvService) WithQueue(store domain.CSVFileStore, policy domain.CSVQueuePolicy) *csvService {
+	defaults := domain.DefaultCSVQueuePolicy()
+	if policy.PollInterval <= 0 {
+		policy.PollInterval = defaults.PollInterval
+	}
+	if policy.Heartbeat <= 0 {
+		policy.Heartbeat = defaults.Heartbeat
+	}
+	if policy.StaleAfter <= policy.Heartbeat {
+		policy.StaleAfter = 6 * policy.Heartbeat
+	}
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaults.MaxAttempts
+	}
+	s.store = store
+	s.queue = policy
+	return s
+}
+
+// storeUpload saves the file of a job under a new key
+func (s *csvService) storeUpload(ctx context.Context, job *domain.CSVJob, fh *multipart.FileHeader) error {
+	file, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	key := newFileKey()
+	if err := s.store.Save(ctx, key, file); err != nil {
+		return err
+	}
+	job.FileKey = key
+	return nil
+}
+
+func newFileKey() string {
+	return uuid.New().String() + ".csv"
+}
+
+// Run processes queued uploads with the workers of the queue policy until
+// ctx is done. Jobs left behind by a replica that stopped heartbeating are
+// put back in the queue on start and then every StaleAfter.
+func (s *csvService) Run(ctx context.Context) {
+	if s.store == nil {
+		return
+	}
+
+	host, _ := os.Hostname()
+	var wg sync.WaitGroup
+	for i := 0; i < s.queue.Workers; i++ {
+		wg.Add(1)
+		go func(workerID string) {
+			defer wg.Done()
+			s.work(ctx, workerID)
+		}(fmt.Sprintf("%s-%d-%d", host, os.Getpid(), i))
+	}
+
+	s.requeueStale(ctx)
+	ticker := time.NewTicker(s.queue.StaleAfter)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+			s.requeueStale(ctx)
+		}
+	}
+}
+
+func (s *csvService) requeueStale(ctx context.Context) {
+	requeued, failed, err := s.csvRepo.RequeueStaleJobs(ctx, time.Now().Add(-s.queue.StaleAfter), s.queue.MaxAttempts)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.WithError(err).Error("Failed to requeue stale CSV jobs")
+		}
+		return
+	}
+	if requeued > 0 || failed > 0 {
+		s.logger.WithFields(logrus.Fields{
+			"requeued": requeued,
+			"failed":   failed,
+		}).Warn("Recovered CSV jobs whose worker stopped")
+	}
+}
+
+// work claims and processes one job at a time, polling while the queue is
+// empty
+func (s *csvService) work(ctx context.Context, workerID string) {
+	for {
+		job, err := s.csvRepo.ClaimJob(ctx, workerID)
+		switch {
+		case err == nil:
+			s.processQueued(ctx, workerID, job)
+			continue
+		case ctx.Err() != nil:
+			return
+		case !errors.Is(err, domain.ErrNotFound):
+			s.logger.WithError(err).WithField("worker_id", workerID).Error("Failed to claim CSV job")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.queue.PollInterval):
+		}
+	}
+}
+
+// processQueued processes a claimed job as its uploader, heartbeating until
+// it is done. A job taken away from the worker is abandoned, one the worker
+// is stopped on goes back to the queue.
+func (s *csvService) processQueued(ctx context.Context, workerID string, job *domain.CSVJob) {
+	log := s.logger.WithFields(logrus.Fields{"job_id": job.ID, "worker_id": workerID})
+
+	jobCtx, cancel := context.WithCancel(domain.WithCaller(ctx, &domain.Caller{ID: job.UserID}))
+	defer cancel()
+
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(s.queue.Heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-jobCtx.Done():
+				return
+			case <-ticker.C:
+				err := s.csvRepo.HeartbeatJob(jobCtx, job.ID, workerID)
+				if errors.Is(err, domain.ErrNotFound) {
+					log.Warn("CSV job was cancelled or taken away from the worker")
+					cancel()
+					return
+				}
+				if err != nil && !errors.Is(err, context.Canceled) {
+					log.WithError(err).Error("Failed to heartbeat CSV job")
+				}
+			}
+		}
+	}()
+	defer func() {
+		cancel()
+		<-heartbeatDone
+	}()
+
+	file, err := s.store.Open(jobCtx, job.FileKey)
+	if err != nil {
+		log.WithError(err).Error("Failed to open stored CSV file")
+		errMsg := "the uploaded file is no longer available"
+		s.failJob(jobCtx, job, errMsg)
+		return
+	}
+	defer file.Close()
+
+	err = s.ProcessCSVFile(jobCtx, job, file)
+	if ctx.Err() != nil {
+		// The replica is stopping, another one picks the job up again
+		if err := s.csvRepo.ReleaseJob(context.WithoutCancel(ctx), job.ID, workerID); err != nil {
+			log.WithError(err).Error("Failed to release CSV job")
+		}
+		return
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.WithError(err).Error("CSV processing failed")
+	}
+}
@@ -0,0 +1,1004 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/csvprogress"
+	"github.com/edwinjordan/MajooTest-Golang/service"
+)
+
+// memoryCSVRepository keeps a single job in memory, methods the tests do not
+// reach are left to the embedded interface
+type memoryCSVRepository struct {
+	domain.CSVRepository
+
+	mu     sync.Mutex
+	job    domain.CSVJob
+	errors map[int]domain.CSVJobError
+	done   map[int]bool
+	// created are the jobs CreateJob was called with
+	created []domain.CSVJob
+	deleted bool
+}
+
+// uploaderCtx is a request of the user that uploaded the test jobs
+var uploaderCtx = domain.WithCaller(context.Background(), &domain.Caller{ID: "uploader-id"})
+
+// newMemoryCSVRepository keeps job, which belongs to the user of uploaderCtx
+// unless it names another
+func newMemoryCSVRepository(job domain.CSVJob) *memoryCSVRepository {
+	if job.UserID == "" {
+		job.UserID = "uploader-id"
+	}
+	return &memoryCSVRepository{job: job, errors: map[int]domain.CSVJobError{}, done: map[int]bool{}}
+}
+
+func (r *memoryCSVRepository) GetJobByID(ctx context.Context, id uuid.UUID) (*domain.CSVJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id.String() != r.job.ID {
+		return nil, domain.ErrNotFound
+	}
+	job := r.job
+	return &job, nil
+}
+
+func (r *memoryCSVRepository) GetJobsByUserID(ctx context.Context, userID string) ([]*domain.CSVJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if userID != "" && userID != r.job.UserID {
+		return nil, nil
+	}
+	job := r.job
+	return []*domain.CSVJob{&job}, nil
+}
+
+func (r *memoryCSVRepository) UpdateJobProgress(ctx context.Context, jobID, workerID string, processedRows, failedRows int64) error {
+	return nil
+}
+
+func (r *memoryCSVRepository) CreateJob(ctx context.Context, job *domain.CSVJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.created = append(r.created, *job)
+	return nil
+}
+
+func (r *memoryCSVRepository) UpdateJobStatus(ctx context.Context, jobID, workerID string, status domain.CSVJobStatus, errorMessage *string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		// Like the database, a write on a cancelled context goes nowhere
+		return err
+	}
+	if r.job.Status != domain.CSVJobStatusCancelled && workerID == r.job.WorkerID {
+		r.job.Status = status
+	}
+	return nil
+}
+
+func (r *memoryCSVRepository) CompleteJob(ctx context.Context, jobID, workerID string, totalRows, processedRows, failedRows int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.job.Status == domain.CSVJobStatusCancelled || workerID != r.job.WorkerID {
+		return nil
+	}
+	r.job.Status = domain.CSVJobStatusCompleted
+	r.job.TotalRows, r.job.ProcessedRows, r.job.FailedRows = totalRows, processedRows, failedRows
+	r.done = map[int]bool{}
+	return nil
+}
+
+func (r *memoryCSVRepository) SaveCheckpoint(ctx context.Context, jobID, workerID string, checkpoint domain.CSVCheckpoint, done []domain.CSVRowOutcome) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if workerID != r.job.WorkerID {
+		return nil
+	}
+	r.job.Checkpoint = checkpoint
+	for _, row := range done {
+		r.done[row.RowNumber] = row.Failed
+	}
+	for row := range r.done {
+		if row <= checkpoint.Row {
+			delete(r.done, row)
+		}
+	}
+	return nil
+}
+
+func (r *memoryCSVRepository) ListDoneRows(ctx context.Context, jobID string) ([]domain.CSVRowOutcome, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var done []domain.CSVRowOutcome
+	for row, failed := range r.done {
+		done = append(done, domain.CSVRowOutcome{RowNumber: row, Failed: failed})
+	}
+	return done, nil
+}
+
+func (r *memoryCSVRepository) ResumeJob(ctx context.Context, id uuid.UUID) (*domain.CSVJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id.String() != r.job.ID || !r.job.Status.Finished() || r.job.FileKey == "" {
+		return nil, domain.ErrNotFound
+	}
+	if r.job.Status != domain.CSVJobStatusFailed && r.job.Status != domain.CSVJobStatusCancelled {
+		return nil, domain.ErrNotFound
+	}
+	r.job.Status = domain.CSVJobStatusPending
+	r.job.Attempts = 0
+	job := r.job
+	return &job, nil
+}
+
+func (r *memoryCSVRepository) CancelJob(ctx context.Context, id uuid.UUID) (*domain.CSVJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id.String() != r.job.ID || r.job.Status.Finished() {
+		return nil, domain.ErrNotFound
+	}
+	r.job.Status = domain.CSVJobStatusCancelled
+	job := r.job
+	return &job, nil
+}
+
+func (r *memoryCSVRepository) DeleteJob(ctx context.Context, id uuid.UUID) (*domain.CSVJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id.String() != r.job.ID || !r.job.Status.Finished() {
+		return nil, domain.ErrNotFound
+	}
+	r.deleted = true
+	job := r.job
+	return &job, nil
+}
+
+func (r *memoryCSVRepository) CompleteDryRun(ctx context.Context, jobID, workerID string, summary *domain.CSVDryRunSummary) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.job.Status == domain.CSVJobStatusCancelled || workerID != r.job.WorkerID {
+		return nil
+	}
+	r.job.Status = domain.CSVJobStatusValidated
+	r.job.Summary = summary
+	return nil
+}
+
+func (r *memoryCSVRepository) SetJobHeaders(ctx context.Context, jobID string, headers []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.job.Headers = headers
+	return nil
+}
+
+func (r *memoryCSVRepository) AddJobErrors(ctx context.Context, errs []domain.CSVJobError) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range errs {
+		r.errors[e.RowNumber] = e
+	}
+	return nil
+}
+
+func (r *memoryCSVRepository) ListJobErrors(ctx context.Context, jobID uuid.UUID, limit, offset int) ([]domain.CSVJobError, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	all := make([]domain.CSVJobError, 0, len(r.errors))
+	for _, e := range r.errors {
+		all = append(all, e)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].RowNumber < all[j].RowNumber })
+	if offset > len(all) {
+		offset = len(all)
+	}
+	end := offset + limit
+	if end > len(all) {
+		end = len(all)
+	}
+	return all[offset:end], len(all), nil
+}
+
+func (r *memoryCSVRepository) ClaimJob(ctx context.Context, workerID string) (*domain.CSVJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.job.Status != domain.CSVJobStatusPending || r.job.FileKey == "" {
+		return nil, domain.ErrNotFound
+	}
+	r.job.Status = domain.CSVJobStatusProcessing
+	r.job.WorkerID = workerID
+	r.job.Attempts++
+	job := r.job
+	return &job, nil
+}
+
+func (r *memoryCSVRepository) HeartbeatJob(ctx context.Context, jobID, workerID string) error {
+	return nil
+}
+
+func (r *memoryCSVRepository) ReleaseJob(ctx context.Context, jobID, workerID string) error {
+	if err := r.UpdateJobStatus(ctx, jobID, workerID, domain.CSVJobStatusPending, nil); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.job.WorkerID = ""
+	return nil
+}
+
+func (r *memoryCSVRepository) RequeueStaleJobs(ctx context.Context, staleBefore time.Time, maxAttempts int) (int, int, error) {
+	return 0, 0, nil
+}
+
+func (r *memoryCSVRepository) status() domain.CSVJobStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.job.Status
+}
+
+// memoryFileStore keeps files in a map
+type memoryFileStore struct {
+	files map[string]string
+}
+
+func (m *memoryFileStore) Save(ctx context.Context, key string, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	m.files[key] = string(b)
+	return err
+}
+
+func (m *memoryFileStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	content, ok := m.files[key]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return io.NopCloser(strings.NewReader(content)), nil
+}
+
+func (m *memoryFileStore) Delete(ctx context.Context, key string) error {
+	delete(m.files, key)
+	return nil
+}
+
+// uploaderProcessor records who every row was imported as
+type uploaderProcessor struct {
+	mu        sync.Mutex
+	uploaders []string
+}
+
+func (p *uploaderProcessor) CheckHeaders(headers []string) error { return nil }
+
+func (p *uploaderProcessor) ProcessRow(ctx context.Context, row map[string]string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.uploaders = append(p.uploaders, domain.CallerFromContext(ctx).ID)
+	return nil
+}
+
+func (p *uploaderProcessor) ValidateRow(ctx context.Context, row map[string]string) (domain.CSVRowAction, error) {
+	return domain.CSVRowInsert, nil
+}
+
+// recordingProcessor records the names it imported
+type recordingProcessor struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (p *recordingProcessor) CheckHeaders(headers []string) error { return nil }
+
+func (p *recordingProcessor) ProcessRow(ctx context.Context, row map[string]string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.names = append(p.names, row["name"])
+	return nil
+}
+
+func (p *recordingProcessor) ValidateRow(ctx context.Context, row map[string]string) (domain.CSVRowAction, error) {
+	return domain.CSVRowInsert, nil
+}
+
+// blockingProcessor holds every row until the job is cancelled
+type blockingProcessor struct {
+	started chan struct{}
+	once    sync.Once
+}
+
+func (p *blockingProcessor) CheckHeaders(headers []string) error { return nil }
+
+func (p *blockingProcessor) ProcessRow(ctx context.Context, row map[string]string) error {
+	p.once.Do(func() { close(p.started) })
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (p *blockingProcessor) ValidateRow(ctx context.Context, row map[string]string) (domain.CSVRowAction, error) {
+	return domain.CSVRowInsert, p.ProcessRow(ctx, row)
+}
+
+// importOnceProcessor records the rows it is passed and reports rows it saw
+// before as imported already
+type importOnceProcessor struct {
+	mu   sync.Mutex
+	rows map[domain.CSVRowKey]int
+}
+
+func (p *importOnceProcessor) CheckHeaders(headers []string) error { return nil }
+
+func (p *importOnceProcessor) ProcessRow(ctx context.Context, row map[string]string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := domain.CSVRowFromContext(ctx)
+	if key == nil {
+		return errors.New("row is missing from the context")
+	}
+	p.rows[*key]++
+	if p.rows[*key] > 1 {
+		return domain.ErrCSVRowImported
+	}
+	return nil
+}
+
+func (p *importOnceProcessor) ValidateRow(ctx context.Context, row map[string]string) (domain.CSVRowAction, error) {
+	return domain.CSVRowInsert, nil
+}
+
+// nameProcessor fails rows without a name, names it already knows are
+// updated
+type nameProcessor struct {
+	known map[string]bool
+}
+
+func (nameProcessor) CheckHeaders(headers []string) error { return nil }
+
+func (p nameProcessor) ProcessRow(ctx context.Context, row map[string]string) error {
+	_, err := p.ValidateRow(ctx, row)
+	return err
+}
+
+func (p nameProcessor) ValidateRow(ctx context.Context, row map[string]string) (domain.CSVRowAction, error) {
+	switch {
+	case row["name"] == "":
+		return "", fmt.Errorf("%w: name is required", domain.ErrBadParamInput)
+	case row["name"] == "Eve":
+		return "", errors.New("name is banned")
+	case p.known[row["name"]]:
+		return domain.CSVRowUpdate, nil
+	}
+	return domain.CSVRowInsert, nil
+}
+
+func TestCSVService_JobErrors(t *testing.T) {
+	job := domain.CSVJob{
+		ID:      uuid.NewString(),
+		Type:    "people",
+		Dialect: domain.CSVDialect{Delimiter: ";", Encoding: domain.CSVEncodingUTF8},
+	}
+	repo := newMemoryCSVRepository(job)
+	svc := service.NewCSVService(repo, logrus.New()).
+		WithProcessors(service.NewCSVProcessorRegistry().Register("people", nameProcessor{}))
+
+	input := "Name;City\nAna;Porto\n;Lisbon\nBea;Braga;extra\nCarl;Faro\n"
+	require.NoError(t, svc.ProcessCSVFile(context.Background(), &job, strings.NewReader(input)))
+
+	jobID := uuid.MustParse(job.ID)
+
+	t.Run("Stores the rows that failed", func(t *testing.T) {
+		page, err := svc.GetJobErrors(uploaderCtx, jobID, 1, 10)
+
+		require.NoError(t, err)
+		assert.Equal(t, domain.PaginationInfo{Page: 1, Limit: 10, Total: 2, TotalPages: 1}, page.Pagination)
+		rowErrors := page.Data.([]domain.CSVJobError)
+		require.Len(t, rowErrors, 2)
+		assert.Equal(t, 2, rowErrors[0].RowNumber)
+		assert.Equal(t, []string{"", "Lisbon"}, rowErrors[0].Values)
+		assert.Equal(t, 3, rowErrors[1].RowNumber)
+		assert.Equal(t, []string{"Bea", "Braga", "extra"}, rowErrors[1].Values)
+		assert.Equal(t, int64(2), repo.job.FailedRows)
+	})
+
+	t.Run("Writes them back in the dialect of the upload", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, svc.WriteJobErrorsCSV(uploaderCtx, jobID, &out))
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, "Name;City;error", lines[0])
+		assert.Equal(t, ";Lisbon;given Param is not valid: name is required", lines[1])
+		assert.True(t, strings.HasPrefix(lines[2], "Bea;Braga;"))
+	})
+
+	t.Run("Reports an unknown job", func(t *testing.T) {
+		_, err := svc.GetJobErrors(uploaderCtx, uuid.New(), 1, 10)
+
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+}
+
+func TestCSVService_DryRun(t *testing.T) {
+	job := domain.CSVJob{
+		ID:      uuid.NewString(),
+		Type:    "people",
+		Dialect: domain.CSVDialect{Delimiter: ",", Encoding: domain.CSVEncodingUTF8},
+		DryRun:  true,
+	}
+	repo := newMemoryCSVRepository(job)
+	processor := nameProcessor{known: map[string]bool{"Ana": true}}
+	svc := service.NewCSVService(repo, logrus.New()).
+		WithProcessors(service.NewCSVProcessorRegistry().Register("people", processor))
+
+	input := "name,city\nAna,Porto\nBea,Braga\n,Lisbon\nEve,Faro\nCarl,Evora,extra\nDan,Beja\n"
+	require.NoError(t, svc.ProcessCSVFile(context.Background(), &job, strings.NewReader(input)))
+
+	assert.Equal(t, domain.CSVJobStatusValidated, job.Status)
+	require.NotNil(t, job.Summary)
+	assert.Equal(t, int64(6), job.Summary.Rows)
+	assert.Equal(t, int64(2), job.Summary.WouldInsert)
+	assert.Equal(t, int64(1), job.Summary.WouldUpdate)
+	assert.Equal(t, int64(3), job.Summary.Failed)
+	assert.Equal(t, map[string]int64{
+		domain.CSVErrorValidation: 1,
+		domain.CSVErrorOther:      1,
+		domain.CSVErrorParse:      1,
+	}, job.Summary.ErrorsByType)
+	require.Len(t, job.Summary.Errors, 3)
+	assert.Equal(t, []int{3, 4, 5}, []int{
+		job.Summary.Errors[0].RowNumber,
+		job.Summary.Errors[1].RowNumber,
+		job.Summary.Errors[2].RowNumber,
+	})
+	assert.Equal(t, domain.CSVJobStatusValidated, repo.job.Status)
+}
+
+func TestCSVService_DryRun_CutShort(t *testing.T) {
+	job := domain.CSVJob{
+		ID:      uuid.NewString(),
+		Type:    "people",
+		Dialect: domain.CSVDialect{Delimiter: ",", Encoding: domain.CSVEncodingUTF8},
+		DryRun:  true,
+	}
+	repo := newMemoryCSVRepository(job)
+	processor := &blockingProcessor{started: make(chan struct{})}
+	svc := service.NewCSVService(repo, logrus.New()).
+		WithProcessors(service.NewCSVProcessorRegistry().Register("people", processor))
+
+	// The replica stops while it runs a dry run it keeps no file for
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- svc.ProcessCSVFile(ctx, &job, strings.NewReader("name\nAna\nBea\n"))
+	}()
+	<-processor.started
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("the dry run did not stop")
+	}
+	assert.Equal(t, domain.CSVJobStatusFailed, repo.status())
+}
+
+func TestCSVService_Run(t *testing.T) {
+	repo := newMemoryCSVRepository(domain.CSVJob{
+		ID:      uuid.NewString(),
+		Type:    "people",
+		Dialect: domain.CSVDialect{Delimiter: ",", Encoding: domain.CSVEncodingUTF8},
+		UserID:  "uploader-id",
+		FileKey: "upload.csv",
+		Status:  domain.CSVJobStatusPending,
+	})
+	store := &memoryFileStore{files: map[string]string{"upload.csv": "name\nAna\nBea\n"}}
+	processor := &uploaderProcessor{}
+	svc := service.NewCSVService(repo, logrus.New()).
+		WithProcessors(service.NewCSVProcessorRegistry().Register("people", processor)).
+		WithQueue(store, domain.CSVQueuePolicy{Workers: 1, PollInterval: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return repo.status() == domain.CSVJobStatusCompleted
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Equal(t, int64(2), repo.job.ProcessedRows)
+	assert.Equal(t, []string{"uploader-id", "uploader-id"}, processor.uploaders)
+}
+
+func TestCSVService_UploadAndProcessCSV_DryRun(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("files", "people.csv")
+	require.NoError(t, err)
+	_, err = part.Write([]byte("name\nAna\n"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(1 << 20)
+	require.NoError(t, err)
+
+	repo := newMemoryCSVRepository(domain.CSVJob{})
+	store := &memoryFileStore{files: map[string]string{}}
+	processor := &uploaderProcessor{}
+	svc := service.NewCSVService(repo, logrus.New()).
+		WithProcessors(service.NewCSVProcessorRegistry().Register("people", processor)).
+		WithQueue(store, domain.CSVQueuePolicy{})
+
+	res, err := svc.UploadAndProcessCSV(uploaderCtx, domain.CSVUploadOptions{Type: "people", DryRun: true}, form.File["files"])
+	require.NoError(t, err)
+
+	// The request only queues the dry run, a worker validates the rows
+	require.Len(t, res.Jobs, 1)
+	assert.True(t, res.Jobs[0].DryRun)
+	assert.Equal(t, domain.CSVJobStatusPending, res.Jobs[0].Status)
+	assert.Equal(t, "name\nAna\n", store.files[res.Jobs[0].FileKey])
+	assert.Empty(t, processor.uploaders)
+}
+
+func TestCSVService_Run_DryRun(t *testing.T) {
+	repo := newMemoryCSVRepository(domain.CSVJob{
+		ID:      uuid.NewString(),
+		Type:    "people",
+		Dialect: domain.CSVDialect{Delimiter: ",", Encoding: domain.CSVEncodingUTF8},
+		FileKey: "upload.csv",
+		DryRun:  true,
+		Status:  domain.CSVJobStatusPending,
+	})
+	store := &memoryFileStore{files: map[string]string{"upload.csv": "name\nAna\n"}}
+	svc := service.NewCSVService(repo, logrus.New()).
+		WithProcessors(service.NewCSVProcessorRegistry().Register("people", nameProcessor{known: map[string]bool{"Ana": true}})).
+		WithQueue(store, domain.CSVQueuePolicy{Workers: 1, PollInterval: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return repo.status() == domain.CSVJobStatusValidated
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	require.NotNil(t, repo.job.Summary)
+	assert.Equal(t, int64(1), repo.job.Summary.WouldUpdate)
+}
+
+func TestCSVService_ProcessCSVFile_Resume(t *testing.T) {
+	input := "name\nAna\nBea\nCarl\nDan\n"
+	newJob := func(checkpoint domain.CSVCheckpoint) domain.CSVJob {
+		return domain.CSVJob{
+			ID:         uuid.NewString(),
+			Type:       "people",
+			Dialect:    domain.CSVDialect{Delimiter: ",", Encoding: domain.CSVEncodingUTF8},
+			Checkpoint: checkpoint,
+		}
+	}
+
+	t.Run("Continues after the checkpoint and skips rows done past it", func(t *testing.T) {
+		job := newJob(domain.CSVCheckpoint{Row: 1, Offset: int64(len("name\nAna\n")), Processed: 1})
+		repo := newMemoryCSVRepository(job)
+		repo.done[3] = true
+		processor := &recordingProcessor{}
+		svc := service.NewCSVService(repo, logrus.New()).
+			WithProcessors(service.NewCSVProcessorRegistry().Register("people", processor))
+
+		require.NoError(t, svc.ProcessCSVFile(context.Background(), &job, strings.NewReader(input)))
+
+		sort.Strings(processor.names)
+		assert.Equal(t, []string{"Bea", "Dan"}, processor.names)
+		assert.Equal(t, domain.CSVJobStatusCompleted, repo.job.Status)
+		assert.Equal(t, int64(4), repo.job.TotalRows)
+		assert.Equal(t, int64(3), repo.job.ProcessedRows)
+		assert.Equal(t, int64(1), repo.job.FailedRows)
+		assert.Equal(t, domain.CSVCheckpoint{Row: 4, Offset: int64(len(input)), Processed: 3, Failed: 1}, repo.job.Checkpoint)
+		assert.Empty(t, repo.done)
+	})
+
+	t.Run("Fails when the file changed since the checkpoint", func(t *testing.T) {
+		job := newJob(domain.CSVCheckpoint{Row: 1, Offset: 3, Processed: 1})
+		repo := newMemoryCSVRepository(job)
+		processor := &recordingProcessor{}
+		svc := service.NewCSVService(repo, logrus.New()).
+			WithProcessors(service.NewCSVProcessorRegistry().Register("people", processor))
+
+		err := svc.ProcessCSVFile(context.Background(), &job, strings.NewReader(input))
+
+		assert.ErrorIs(t, err, domain.ErrCSVFileInvalid)
+		assert.Empty(t, processor.names)
+		assert.Equal(t, domain.CSVJobStatusFailed, repo.job.Status)
+	})
+}
+
+func TestCSVService_ProcessCSVFile_TakenAway(t *testing.T) {
+	// The job went back to the queue and another worker claimed it while
+	// this one was still processing
+	job := domain.CSVJob{
+		ID:       uuid.NewString(),
+		Type:     "people",
+		Dialect:  domain.CSVDialect{Delimiter: ",", Encoding: domain.CSVEncodingUTF8},
+		FileKey:  "upload.csv",
+		WorkerID: "stale-worker",
+		Status:   domain.CSVJobStatusProcessing,
+	}
+	stored := job
+	stored.WorkerID = "new-worker"
+	stored.Checkpoint = domain.CSVCheckpoint{Row: 1, Offset: int64(len("name\nAna\n")), Processed: 1}
+	repo := newMemoryCSVRepository(stored)
+	svc := service.NewCSVService(repo, logrus.New()).
+		WithProcessors(service.NewCSVProcessorRegistry().Register("people", &recordingProcessor{}))
+
+	require.NoError(t, svc.ProcessCSVFile(context.Background(), &job, strings.NewReader("name\nAna\nBea\n")))
+
+	assert.Equal(t, domain.CSVJobStatusProcessing, repo.job.Status)
+	assert.Equal(t, stored.Checkpoint, repo.job.Checkpoint)
+	assert.Equal(t, int64(0), repo.job.ProcessedRows)
+}
+
+func TestCSVService_ProcessCSVFile_RowImported(t *testing.T) {
+	job := domain.CSVJob{
+		ID:      uuid.NewString(),
+		Type:    "people",
+		Dialect: domain.CSVDialect{Delimiter: ",", Encoding: domain.CSVEncodingUTF8},
+	}
+	repo := newMemoryCSVRepository(job)
+	// The first two rows ran before the replica crashed without a checkpoint
+	processor := &importOnceProcessor{rows: map[domain.CSVRowKey]int{
+		{JobID: job.ID, Row: 1}: 1,
+		{JobID: job.ID, Row: 2}: 1,
+	}}
+	svc := service.NewCSVService(repo, logrus.New()).
+		WithProcessors(service.NewCSVProcessorRegistry().Register("people", processor))
+
+	require.NoError(t, svc.ProcessCSVFile(context.Background(), &job, strings.NewReader("name\nAna\nBea\nCarl\n")))
+
+	assert.Equal(t, map[domain.CSVRowKey]int{
+		{JobID: job.ID, Row: 1}: 2,
+		{JobID: job.ID, Row: 2}: 2,
+		{JobID: job.ID, Row: 3}: 1,
+	}, processor.rows)
+	assert.Equal(t, domain.CSVJobStatusCompleted, repo.job.Status)
+	assert.Equal(t, int64(3), repo.job.ProcessedRows)
+	assert.Equal(t, int64(0), repo.job.FailedRows)
+}
+
+func TestCSVService_ResumeJob(t *testing.T) {
+	tests := []struct {
+		name    string
+		job     domain.CSVJob
+		wantErr error
+	}{
+		{
+			name: "Queues a failed job again",
+			job:  domain.CSVJob{Status: domain.CSVJobStatusFailed, FileKey: "upload.csv", Attempts: 3},
+		},
+		{
+			name:    "Refuses a job that did not fail",
+			job:     domain.CSVJob{Status: domain.CSVJobStatusCompleted, FileKey: "upload.csv"},
+			wantErr: domain.ErrCSVJobState,
+		},
+		{
+			name:    "Refuses a job whose file was not kept",
+			job:     domain.CSVJob{Status: domain.CSVJobStatusFailed},
+			wantErr: domain.ErrCSVJobState,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.job.ID = uuid.NewString()
+			repo := newMemoryCSVRepository(tt.job)
+			svc := service.NewCSVService(repo, logrus.New())
+
+			job, err := svc.ResumeJob(uploaderCtx, uuid.MustParse(tt.job.ID))
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, tt.job.Status, repo.status())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, domain.CSVJobStatusPending, job.Status)
+			assert.Equal(t, 0, job.Attempts)
+		})
+	}
+
+	t.Run("Reports an unknown job", func(t *testing.T) {
+		svc := service.NewCSVService(newMemoryCSVRepository(domain.CSVJob{ID: uuid.NewString()}), logrus.New())
+
+		_, err := svc.ResumeJob(uploaderCtx, uuid.New())
+
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+}
+
+func TestCSVService_CancelJob(t *testing.T) {
+	t.Run("Stops a job processed on this replica", func(t *testing.T) {
+		job := domain.CSVJob{
+			ID:      uuid.NewString(),
+			Type:    "people",
+			Dialect: domain.CSVDialect{Delimiter: ",", Encoding: domain.CSVEncodingUTF8},
+			Status:  domain.CSVJobStatusPending,
+		}
+		repo := newMemoryCSVRepository(job)
+		processor := &blockingProcessor{started: make(chan struct{})}
+		svc := service.NewCSVService(repo, logrus.New()).
+			WithProcessors(service.NewCSVProcessorRegistry().Register("people", processor))
+
+		done := make(chan error)
+		go func() {
+			done <- svc.ProcessCSVFile(context.Background(), &job, strings.NewReader("name\nAna\nBea\n"))
+		}()
+		<-processor.started
+
+		cancelled, err := svc.CancelJob(uploaderCtx, uuid.MustParse(job.ID))
+		require.NoError(t, err)
+		assert.Equal(t, domain.CSVJobStatusCancelled, cancelled.Status)
+
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, context.Canceled)
+		case <-time.After(time.Second):
+			t.Fatal("processing did not stop")
+		}
+		assert.Equal(t, domain.CSVJobStatusCancelled, repo.status())
+	})
+
+	t.Run("Refuses a job that is done", func(t *testing.T) {
+		job := domain.CSVJob{ID: uuid.NewString(), Status: domain.CSVJobStatusCompleted}
+		svc := service.NewCSVService(newMemoryCSVRepository(job), logrus.New())
+
+		_, err := svc.CancelJob(uploaderCtx, uuid.MustParse(job.ID))
+
+		assert.ErrorIs(t, err, domain.ErrCSVJobState)
+	})
+}
+
+func TestCSVService_RetryJob(t *testing.T) {
+	t.Run("Queues the failed rows as a new job", func(t *testing.T) {
+		job := domain.CSVJob{
+			ID:       uuid.NewString(),
+			Filename: "people.csv",
+			Type:     "people",
+			Dialect:  domain.CSVDialect{Delimiter: ";", Encoding: domain.CSVEncodingLatin1},
+			Headers:  []string{"Name", "City"},
+			UserID:   "uploader-id",
+			Status:   domain.CSVJobStatusCompleted,
+		}
+		repo := newMemoryCSVRepository(job)
+		repo.errors[2] = domain.CSVJobError{JobID: job.ID, RowNumber: 2, Values: []string{"", "Lisbon"}, Error: "name is required"}
+		repo.errors[4] = domain.CSVJobError{JobID: job.ID, RowNumber: 4, Values: []string{"Eve", "Faro;Sul"}, Error: "name is banned"}
+		store := &memoryFileStore{files: map[string]string{}}
+		svc := service.NewCSVService(repo, logrus.New()).WithQueue(store, domain.CSVQueuePolicy{Workers: 1})
+
+		retry, err := svc.RetryJob(uploaderCtx, uuid.MustParse(job.ID), true)
+
+		require.NoError(t, err)
+		require.Len(t, repo.created, 1)
+		assert.Equal(t, retry.ID, repo.created[0].ID)
+		assert.Equal(t, "people-failed.csv", retry.Filename)
+		assert.Equal(t, domain.CSVDialect{Delimiter: ";", Encoding: domain.CSVEncodingUTF8}, retry.Dialect)
+		assert.Equal(t, "uploader-id", retry.UserID)
+		assert.Equal(t, domain.CSVJobStatusPending, retry.Status)
+		assert.Equal(t, "Name;City\n;Lisbon\nEve;\"Faro;Sul\"\n", store.files[retry.FileKey])
+	})
+
+	t.Run("Refuses a job without failed rows", func(t *testing.T) {
+		job := domain.CSVJob{ID: uuid.NewString(), Status: domain.CSVJobStatusCompleted, Headers: []string{"name"}}
+		repo := newMemoryCSVRepository(job)
+		svc := service.NewCSVService(repo, logrus.New()).WithQueue(&memoryFileStore{files: map[string]string{}}, domain.CSVQueuePolicy{})
+
+		_, err := svc.RetryJob(uploaderCtx, uuid.MustParse(job.ID), true)
+
+		assert.ErrorIs(t, err, domain.ErrCSVJobState)
+		assert.Empty(t, repo.created)
+	})
+
+	t.Run("Refuses a job that failed more rows than were kept", func(t *testing.T) {
+		job := domain.CSVJob{ID: uuid.NewString(), Status: domain.CSVJobStatusCompleted, Headers: []string{"name"}, FailedRows: domain.MaxCSVJobErrors + 1}
+		repo := newMemoryCSVRepository(job)
+		repo.errors[2] = domain.CSVJobError{JobID: job.ID, RowNumber: 2, Values: []string{""}, Error: "name is required"}
+		store := &memoryFileStore{files: map[string]string{}}
+		svc := service.NewCSVService(repo, logrus.New()).WithQueue(store, domain.CSVQueuePolicy{})
+
+		_, err := svc.RetryJob(uploaderCtx, uuid.MustParse(job.ID), true)
+
+		assert.ErrorIs(t, err, domain.ErrCSVJobState)
+		assert.Empty(t, repo.created)
+		assert.Empty(t, store.files)
+	})
+
+	t.Run("Refuses a running job", func(t *testing.T) {
+		job := domain.CSVJob{ID: uuid.NewString(), Status: domain.CSVJobStatusProcessing}
+		svc := service.NewCSVService(newMemoryCSVRepository(job), logrus.New()).WithQueue(&memoryFileStore{files: map[string]string{}}, domain.CSVQueuePolicy{})
+
+		_, err := svc.RetryJob(uploaderCtx, uuid.MustParse(job.ID), true)
+
+		assert.ErrorIs(t, err, domain.ErrCSVJobState)
+	})
+}
+
+func TestCSVService_DeleteJob(t *testing.T) {
+	t.Run("Deletes a finished job and its file", func(t *testing.T) {
+		job := domain.CSVJob{ID: uuid.NewString(), FileKey: "upload.csv", Status: domain.CSVJobStatusFailed}
+		repo := newMemoryCSVRepository(job)
+		store := &memoryFileStore{files: map[string]string{"upload.csv": "name\nAna\n"}}
+		svc := service.NewCSVService(repo, logrus.New()).WithQueue(store, domain.CSVQueuePolicy{})
+
+		require.NoError(t, svc.DeleteJob(uploaderCtx, uuid.MustParse(job.ID)))
+
+		assert.True(t, repo.deleted)
+		assert.Empty(t, store.files)
+	})
+
+	t.Run("Refuses a running job", func(t *testing.T) {
+		job := domain.CSVJob{ID: uuid.NewString(), FileKey: "upload.csv", Status: domain.CSVJobStatusProcessing}
+		repo := newMemoryCSVRepository(job)
+		store := &memoryFileStore{files: map[string]string{"upload.csv": "name\nAna\n"}}
+		svc := service.NewCSVService(repo, logrus.New()).WithQueue(store, domain.CSVQueuePolicy{})
+
+		err := svc.DeleteJob(uploaderCtx, uuid.MustParse(job.ID))
+
+		assert.ErrorIs(t, err, domain.ErrCSVJobState)
+		assert.False(t, repo.deleted)
+		assert.Len(t, store.files, 1)
+	})
+}
+
+func TestCSVService_JobOwner(t *testing.T) {
+	job := domain.CSVJob{ID: uuid.NewString(), Status: domain.CSVJobStatusCompleted}
+	svc := service.NewCSVService(newMemoryCSVRepository(job), logrus.New())
+	jobID := uuid.MustParse(job.ID)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		visible bool
+	}{
+		{name: "The uploader sees the job", ctx: uploaderCtx, visible: true},
+		{
+			name:    "Admins see every job",
+			ctx:     domain.WithCaller(context.Background(), &domain.Caller{ID: "admin-id", Role: domain.RoleAdmin}),
+			visible: true,
+		},
+		{name: "Other users do not", ctx: domain.WithCaller(context.Background(), &domain.Caller{ID: "other-id"})},
+		{name: "Anonymous requests do not", ctx: context.Background()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, err := svc.GetUserJobs(tt.ctx)
+			require.NoError(t, err)
+			_, progressErr := svc.GetJobProgress(tt.ctx, jobID)
+			deleteErr := svc.DeleteJob(tt.ctx, jobID)
+
+			if tt.visible {
+				assert.Len(t, jobs, 1)
+				assert.NoError(t, progressErr)
+				assert.NoError(t, deleteErr)
+				return
+			}
+			assert.Empty(t, jobs)
+			assert.ErrorIs(t, progressErr, domain.ErrCSVJobNotFound)
+			assert.ErrorIs(t, deleteErr, domain.ErrCSVJobNotFound)
+		})
+	}
+}
+
+func TestCSVService_StreamProgress(t *testing.T) {
+	newJob := func(status domain.CSVJobStatus) domain.CSVJob {
+		return domain.CSVJob{
+			ID:        uuid.NewString(),
+			Type:      "people",
+			Dialect:   domain.CSVDialect{Delimiter: ",", Encoding: domain.CSVEncodingUTF8},
+			Status:    status,
+			UpdatedAt: time.Now().Add(-time.Minute),
+		}
+	}
+	next := func(t *testing.T, events <-chan domain.CSVProgressEvent) (domain.CSVProgressEvent, bool) {
+		t.Helper()
+		select {
+		case event, ok := <-events:
+			return event, ok
+		case <-time.After(time.Second):
+			t.Fatal("expected an event")
+			return domain.CSVProgressEvent{}, false
+		}
+	}
+
+	t.Run("Starts with the job and ends with the event of its status", func(t *testing.T) {
+		job := newJob(domain.CSVJobStatusPending)
+		broker := csvprogress.NewBroker()
+		svc := service.NewCSVService(newMemoryCSVRepository(job), logrus.New()).
+			WithProcessors(service.NewCSVProcessorRegistry().Register("people", nameProcessor{})).
+			WithProgress(broker)
+
+		events, err := svc.StreamProgress(uploaderCtx, uuid.MustParse(job.ID), 0)
+		require.NoError(t, err)
+		first, _ := next(t, events)
+		assert.Equal(t, domain.CSVEventProgress, first.Type)
+		assert.Equal(t, domain.CSVJobStatusPending, first.Progress.Status)
+
+		go svc.ProcessCSVFile(context.Background(), &job, strings.NewReader("name\nAna\n\nBea\n,\n"))
+
+		var last domain.CSVProgressEvent
+		for {
+			event, ok := next(t, events)
+			if !ok {
+				break
+			}
+			assert.Greater(t, event.ID, first.ID)
+			last = event
+		}
+		assert.Equal(t, string(domain.CSVJobStatusCompleted), last.Type)
+		assert.Equal(t, int64(3), last.Progress.TotalRows)
+		assert.Equal(t, int64(2), last.Progress.ProcessedRows)
+		assert.Equal(t, int64(1), last.Progress.FailedRows)
+	})
+
+	t.Run("Skips the events the client saw", func(t *testing.T) {
+		job := newJob(domain.CSVJobStatusProcessing)
+		broker := csvprogress.NewBroker()
+		svc := service.NewCSVService(newMemoryCSVRepository(job), logrus.New()).WithProgress(broker)
+
+		events, err := svc.StreamProgress(uploaderCtx, uuid.MustParse(job.ID), job.UpdatedAt.UnixMilli())
+		require.NoError(t, err)
+		broker.Publish(context.Background(), domain.CSVProgressEvent{
+			JobID:    job.ID,
+			Type:     string(domain.CSVJobStatusCancelled),
+			Progress: domain.CSVProcessingProgress{JobID: job.ID, Status: domain.CSVJobStatusCancelled},
+		})
+
+		event, _ := next(t, events)
+		assert.Equal(t, string(domain.CSVJobStatusCancelled), event.Type)
+		_, ok := next(t, events)
+		assert.False(t, ok)
+	})
+
+	t.Run("Sends a finished job once", func(t *testing.T) {
+		job := newJob(domain.CSVJobStatusValidated)
+		job.Summary = &domain.CSVDryRunSummary{Rows: 2, WouldInsert: 2}
+		svc := service.NewCSVService(newMemoryCSVRepository(job), logrus.New())
+
+		events, err := svc.StreamProgress(uploaderCtx, uuid.MustParse(job.ID), 0)
+		require.NoError(t, err)
+
+		event, _ := next(t, events)
+		assert.Equal(t, string(domain.CSVJobStatusValidated), event.Type)
+		assert.Equal(t, job.Summary, event.Summary)
+		_, ok := next(t, events)
+		assert.False(t, ok)
+	})
+
+	t.Run("Hides the jobs of other users", func(t *testing.T) {
+		job := newJob(domain.CSVJobStatusProcessing)
+		svc := service.NewCSVService(newMemoryCSVRepository(job), logrus.New())
+		other := domain.WithCaller(context.Background(), &domain.Caller{ID: "other-id"})
+
+		_, err := svc.StreamProgress(other, uuid.MustParse(job.ID), 0)
+
+		assert.ErrorIs(t, err, domain.ErrCSVJobNotFound)
+	})
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"context"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+// EventPublisher delivers domain events to whoever subscribed to them
+type EventPublisher interface {
+	Publish(ctx context.Context, event domain.Event)
+}
+
+// actorID returns the id of the user performing the current request, if any
+func actorID(ctx context.Context) string {
+	if caller := domain.CallerFromContext(ctx); caller != nil {
+		return caller.ID
+	}
+	return ""
+}
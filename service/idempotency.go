@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/logging"
+)
+
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, rec *domain.IdempotencyRecord, lockedUntil time.Time) (*domain.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, rec *domain.IdempotencyRecord) error
+	Release(ctx context.Context, rec *domain.IdempotencyRecord) error
+	PurgeExpired(ctx context.Context, before time.Time) (int, error)
+}
+
+// IdempotencyService remembers the responses of requests sent with an
+// Idempotency-Key so that retries get the same response instead of running
+// the request again
+type IdempotencyService struct {
+	idempotencyRepo IdempotencyRepository
+	policy          domain.IdempotencyPolicy
+}
+
+func NewIdempotencyService(r IdempotencyRepository, policy domain.IdempotencyPolicy) *IdempotencyService {
+	defaults := domain.DefaultIdempotencyPolicy()
+	if policy.TTL <= 0 {
+		policy.TTL = defaults.TTL
+	}
+	if policy.Lease <= 0 {
+		policy.Lease = defaults.Lease
+	}
+	return &IdempotencyService{
+		idempotencyRepo: r,
+		policy:          policy,
+	}
+}
+
+// Begin claims the key of rec. It returns nil when the caller should run the
+// request and then Complete or Release rec, or the completed record whose
+// response should be replayed. A key still held by another request fails
+// with ErrIdempotencyInFlight, one used for a different payload with
+// ErrIdempotencyKeyReused.
+func (s *IdempotencyService) Begin(ctx context.Context, rec *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	now := time.Now()
+	rec.ExpiresAt = now.Add(s.policy.TTL)
+
+	existing, reserved, err := s.idempotencyRepo.Reserve(ctx, rec, now.Add(s.policy.Lease))
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+	if existing.RequestHash != rec.RequestHash {
+		return nil, domain.ErrIdempotencyKeyReused
+	}
+	if !existing.IsCompleted() {
+		return nil, domain.ErrIdempotencyInFlight
+	}
+	return existing, nil
+}
+
+// Complete stores the response of a request claimed with Begin
+func (s *IdempotencyService) Complete(ctx context.Context, rec *domain.IdempotencyRecord) error {
+	return s.idempotencyRepo.Complete(ctx, rec)
+}
+
+// Release frees a key claimed with Begin without storing a response
+func (s *IdempotencyService) Release(ctx context.Context, rec *domain.IdempotencyRecord) error {
+	return s.idempotencyRepo.Release(ctx, rec)
+}
+
+// Run deletes expired keys on the policy's interval until ctx is done
+func (s *IdempotencyService) Run(ctx context.Context) {
+	if s.policy.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.idempotencyRepo.PurgeExpired(ctx, time.Now())
+			if err != nil {
+				if ctx.Err() == nil {
+					logging.LogError(ctx, err, "idempotency_purge")
+				}
+				continue
+			}
+			logging.LogInfo(ctx, "Purged expired idempotency keys", slog.Int("count", purged))
+		}
+	}
+}
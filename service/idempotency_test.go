@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/service"
+	"github.com/edwinjordan/MajooTest-Golang/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIdempotencyService_Begin(t *testing.T) {
+	ctx := context.Background()
+	policy := domain.IdempotencyPolicy{TTL: time.Hour, Lease: time.Minute}
+	newRecord := func() *domain.IdempotencyRecord {
+		return &domain.IdempotencyRecord{Key: "k1", UserID: "u1", Method: "POST", Route: "/api/v1/posts", RequestHash: "abc"}
+	}
+
+	t.Run("Runs the request when the key is claimed", func(t *testing.T) {
+		mockRepo := new(mocks.IdempotencyRepository)
+		idempotencyService := service.NewIdempotencyService(mockRepo, policy)
+		rec := newRecord()
+
+		mockRepo.On("Reserve", mock.Anything, rec, mock.MatchedBy(func(lockedUntil time.Time) bool {
+			return time.Until(lockedUntil) > 59*time.Second && time.Until(lockedUntil) <= time.Minute
+		})).Return(rec, true, nil).Once()
+
+		stored, err := idempotencyService.Begin(ctx, rec)
+
+		assert.NoError(t, err)
+		assert.Nil(t, stored)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), rec.ExpiresAt, time.Second)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Returns the completed record for replay", func(t *testing.T) {
+		mockRepo := new(mocks.IdempotencyRepository)
+		idempotencyService := service.NewIdempotencyService(mockRepo, policy)
+		completedAt := time.Now()
+		existing := newRecord()
+		existing.StatusCode = 201
+		existing.Body = []byte(`{"code":201}`)
+		existing.CompletedAt = &completedAt
+
+		mockRepo.On("Reserve", mock.Anything, mock.Anything, mock.Anything).Return(existing, false, nil).Once()
+
+		stored, err := idempotencyService.Begin(ctx, newRecord())
+
+		assert.NoError(t, err)
+		assert.Same(t, existing, stored)
+	})
+
+	t.Run("Rejects a retry while the first request is in flight", func(t *testing.T) {
+		mockRepo := new(mocks.IdempotencyRepository)
+		idempotencyService := service.NewIdempotencyService(mockRepo, policy)
+
+		mockRepo.On("Reserve", mock.Anything, mock.Anything, mock.Anything).Return(newRecord(), false, nil).Once()
+
+		stored, err := idempotencyService.Begin(ctx, newRecord())
+
+		assert.ErrorIs(t, err, domain.ErrIdempotencyInFlight)
+		assert.Nil(t, stored)
+	})
+
+	t.Run("Rejects a key reused for a different payload", func(t *testing.T) {
+		mockRepo := new(mocks.IdempotencyRepository)
+		idempotencyService := service.NewIdempotencyService(mockRepo, policy)
+		completedAt := time.Now()
+		existing := newRecord()
+		existing.RequestHash = "other"
+		existing.CompletedAt = &completedAt
+
+		mockRepo.On("Reserve", mock.Anything, mock.Anything, mock.Anything).Return(existing, false, nil).Once()
+
+		stored, err := idempotencyService.Begin(ctx, newRecord())
+
+		assert.ErrorIs(t, err, domain.ErrIdempotencyKeyReused)
+		assert.Nil(t, stored)
+	})
+}
@@ -101,16 +101,16 @@ func (_c *CommentRepository_CreateComment_Call) RunAndReturn(run func(ctx contex
 }
 
 // DeleteComment provides a mock function for the type CommentRepository
-func (_mock *CommentRepository) DeleteComment(ctx context.Context, id uuid.UUID) error {
-	ret := _mock.Called(ctx, id)
+func (_mock *CommentRepository) DeleteComment(ctx context.Context, id uuid.UUID, version int) error {
+	ret := _mock.Called(ctx, id, version)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteComment")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
-		r0 = returnFunc(ctx, id)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID, int) error); ok {
+		r0 = returnFunc(ctx, id, version)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -125,11 +125,12 @@ type CommentRepository_DeleteComment_Call struct {
 // DeleteComment is a helper method to define mock.On call
 //   - ctx context.Context
 //   - id uuid.UUID
-func (_e *CommentRepository_Expecter) DeleteComment(ctx interface{}, id interface{}) *CommentRepository_DeleteComment_Call {
-	return &CommentRepository_DeleteComment_Call{Call: _e.mock.On("DeleteComment", ctx, id)}
+//   - version int
+func (_e *CommentRepository_Expecter) DeleteComment(ctx interface{}, id interface{}, version interface{}) *CommentRepository_DeleteComment_Call {
+	return &CommentRepository_DeleteComment_Call{Call: _e.mock.On("DeleteComment", ctx, id, version)}
 }
 
-func (_c *CommentRepository_DeleteComment_Call) Run(run func(ctx context.Context, id uuid.UUID)) *CommentRepository_DeleteComment_Call {
+func (_c *CommentRepository_DeleteComment_Call) Run(run func(ctx context.Context, id uuid.UUID, version int)) *CommentRepository_DeleteComment_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -139,9 +140,14 @@ func (_c *CommentRepository_DeleteComment_Call) Run(run func(ctx context.Context
 		if args[1] != nil {
 			arg1 = args[1].(uuid.UUID)
 		}
+		var arg2 int
+		if args[2] != nil {
+			arg2 = args[2].(int)
+		}
 		run(
 			arg0,
 			arg1,
+			arg2,
 		)
 	})
 	return _c
@@ -152,7 +158,7 @@ func (_c *CommentRepository_DeleteComment_Call) Return(err error) *CommentReposi
 	return _c
 }
 
-func (_c *CommentRepository_DeleteComment_Call) RunAndReturn(run func(ctx context.Context, id uuid.UUID) error) *CommentRepository_DeleteComment_Call {
+func (_c *CommentRepository_DeleteComment_Call) RunAndReturn(run func(ctx context.Context, id uuid.UUID, version int) error) *CommentRepository_DeleteComment_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -366,3 +372,391 @@ func (_c *CommentRepository_UpdateComment_Call) RunAndReturn(run func(ctx contex
 	_c.Call.Return(run)
 	return _c
 }
+
+// GetCommentThreads provides a mock function for the type CommentRepository
+func (_mock *CommentRepository) GetCommentThreads(ctx context.Context, postID uuid.UUID, filter *domain.CommentThreadFilter) ([]domain.Comment, int, error) {
+	ret := _mock.Called(ctx, postID, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCommentThreads")
+	}
+
+	var r0 []domain.Comment
+	var r1 int
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID, *domain.CommentThreadFilter) ([]domain.Comment, int, error)); ok {
+		return returnFunc(ctx, postID, filter)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID, *domain.CommentThreadFilter) []domain.Comment); ok {
+		r0 = returnFunc(ctx, postID, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Comment)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uuid.UUID, *domain.CommentThreadFilter) int); ok {
+		r1 = returnFunc(ctx, postID, filter)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+	if returnFunc, ok := ret.Get(2).(func(context.Context, uuid.UUID, *domain.CommentThreadFilter) error); ok {
+		r2 = returnFunc(ctx, postID, filter)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
+}
+
+// CommentRepository_GetCommentThreads_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetCommentThreads'
+type CommentRepository_GetCommentThreads_Call struct {
+	*mock.Call
+}
+
+// GetCommentThreads is a helper method to define mock.On call
+//   - ctx context.Context
+//   - postID uuid.UUID
+//   - filter *domain.CommentThreadFilter
+func (_e *CommentRepository_Expecter) GetCommentThreads(ctx interface{}, postID interface{}, filter interface{}) *CommentRepository_GetCommentThreads_Call {
+	return &CommentRepository_GetCommentThreads_Call{Call: _e.mock.On("GetCommentThreads", ctx, postID, filter)}
+}
+
+func (_c *CommentRepository_GetCommentThreads_Call) Run(run func(ctx context.Context, postID uuid.UUID, filter *domain.CommentThreadFilter)) *CommentRepository_GetCommentThreads_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uuid.UUID
+		if args[1] != nil {
+			arg1 = args[1].(uuid.UUID)
+		}
+		var arg2 *domain.CommentThreadFilter
+		if args[2] != nil {
+			arg2 = args[2].(*domain.CommentThreadFilter)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *CommentRepository_GetCommentThreads_Call) Return(comments []domain.Comment, n int, err error) *CommentRepository_GetCommentThreads_Call {
+	_c.Call.Return(comments, n, err)
+	return _c
+}
+
+func (_c *CommentRepository_GetCommentThreads_Call) RunAndReturn(run func(ctx context.Context, postID uuid.UUID, filter *domain.CommentThreadFilter) ([]domain.Comment, int, error)) *CommentRepository_GetCommentThreads_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetPostComments provides a mock function for the type CommentRepository
+func (_mock *CommentRepository) GetPostComments(ctx context.Context, postID uuid.UUID, filter *domain.CommentThreadFilter) ([]domain.Comment, int, error) {
+	ret := _mock.Called(ctx, postID, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPostComments")
+	}
+
+	var r0 []domain.Comment
+	var r1 int
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID, *domain.CommentThreadFilter) ([]domain.Comment, int, error)); ok {
+		return returnFunc(ctx, postID, filter)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID, *domain.CommentThreadFilter) []domain.Comment); ok {
+		r0 = returnFunc(ctx, postID, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Comment)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uuid.UUID, *domain.CommentThreadFilter) int); ok {
+		r1 = returnFunc(ctx, postID, filter)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+	if returnFunc, ok := ret.Get(2).(func(context.Context, uuid.UUID, *domain.CommentThreadFilter) error); ok {
+		r2 = returnFunc(ctx, postID, filter)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
+}
+
+// CommentRepository_GetPostComments_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetPostComments'
+type CommentRepository_GetPostComments_Call struct {
+	*mock.Call
+}
+
+// GetPostComments is a helper method to define mock.On call
+//   - ctx context.Context
+//   - postID uuid.UUID
+//   - filter *domain.CommentThreadFilter
+func (_e *CommentRepository_Expecter) GetPostComments(ctx interface{}, postID interface{}, filter interface{}) *CommentRepository_GetPostComments_Call {
+	return &CommentRepository_GetPostComments_Call{Call: _e.mock.On("GetPostComments", ctx, postID, filter)}
+}
+
+func (_c *CommentRepository_GetPostComments_Call) Run(run func(ctx context.Context, postID uuid.UUID, filter *domain.CommentThreadFilter)) *CommentRepository_GetPostComments_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uuid.UUID
+		if args[1] != nil {
+			arg1 = args[1].(uuid.UUID)
+		}
+		var arg2 *domain.CommentThreadFilter
+		if args[2] != nil {
+			arg2 = args[2].(*domain.CommentThreadFilter)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *CommentRepository_GetPostComments_Call) Return(comments []domain.Comment, total int, err error) *CommentRepository_GetPostComments_Call {
+	_c.Call.Return(comments, total, err)
+	return _c
+}
+
+func (_c *CommentRepository_GetPostComments_Call) RunAndReturn(run func(ctx context.Context, postID uuid.UUID, filter *domain.CommentThreadFilter) ([]domain.Comment, int, error)) *CommentRepository_GetPostComments_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RestoreComment provides a mock function for the type CommentRepository
+func (_mock *CommentRepository) RestoreComment(ctx context.Context, id uuid.UUID) (*domain.Comment, int, error) {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreComment")
+	}
+
+	var r0 *domain.Comment
+	var r1 int
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID) (*domain.Comment, int, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID) *domain.Comment); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Comment)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uuid.UUID) int); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+	if returnFunc, ok := ret.Get(2).(func(context.Context, uuid.UUID) error); ok {
+		r2 = returnFunc(ctx, id)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
+}
+
+// CommentRepository_RestoreComment_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RestoreComment'
+type CommentRepository_RestoreComment_Call struct {
+	*mock.Call
+}
+
+// RestoreComment is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uuid.UUID
+func (_e *CommentRepository_Expecter) RestoreComment(ctx interface{}, id interface{}) *CommentRepository_RestoreComment_Call {
+	return &CommentRepository_RestoreComment_Call{Call: _e.mock.On("RestoreComment", ctx, id)}
+}
+
+func (_c *CommentRepository_RestoreComment_Call) Run(run func(ctx context.Context, id uuid.UUID)) *CommentRepository_RestoreComment_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uuid.UUID
+		if args[1] != nil {
+			arg1 = args[1].(uuid.UUID)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *CommentRepository_RestoreComment_Call) Return(comment *domain.Comment, replies int, err error) *CommentRepository_RestoreComment_Call {
+	_c.Call.Return(comment, replies, err)
+	return _c
+}
+
+func (_c *CommentRepository_RestoreComment_Call) RunAndReturn(run func(ctx context.Context, id uuid.UUID) (*domain.Comment, int, error)) *CommentRepository_RestoreComment_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PatchComment provides a mock function for the type CommentRepository
+func (_mock *CommentRepository) PatchComment(ctx context.Context, id uuid.UUID, version int, patch *domain.CommentPatch) (*domain.Comment, error) {
+	ret := _mock.Called(ctx, id, version, patch)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PatchComment")
+	}
+
+	var r0 *domain.Comment
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID, int, *domain.CommentPatch) (*domain.Comment, error)); ok {
+		return returnFunc(ctx, id, version, patch)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID, int, *domain.CommentPatch) *domain.Comment); ok {
+		r0 = returnFunc(ctx, id, version, patch)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Comment)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uuid.UUID, int, *domain.CommentPatch) error); ok {
+		r1 = returnFunc(ctx, id, version, patch)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// CommentRepository_PatchComment_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PatchComment'
+type CommentRepository_PatchComment_Call struct {
+	*mock.Call
+}
+
+// PatchComment is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uuid.UUID
+//   - version int
+//   - patch *domain.CommentPatch
+func (_e *CommentRepository_Expecter) PatchComment(ctx interface{}, id interface{}, version interface{}, patch interface{}) *CommentRepository_PatchComment_Call {
+	return &CommentRepository_PatchComment_Call{Call: _e.mock.On("PatchComment", ctx, id, version, patch)}
+}
+
+func (_c *CommentRepository_PatchComment_Call) Run(run func(ctx context.Context, id uuid.UUID, version int, patch *domain.CommentPatch)) *CommentRepository_PatchComment_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uuid.UUID
+		if args[1] != nil {
+			arg1 = args[1].(uuid.UUID)
+		}
+		var arg2 int
+		if args[2] != nil {
+			arg2 = args[2].(int)
+		}
+		var arg3 *domain.CommentPatch
+		if args[3] != nil {
+			arg3 = args[3].(*domain.CommentPatch)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *CommentRepository_PatchComment_Call) Return(r *domain.Comment, err error) *CommentRepository_PatchComment_Call {
+	_c.Call.Return(r, err)
+	return _c
+}
+
+func (_c *CommentRepository_PatchComment_Call) RunAndReturn(run func(ctx context.Context, id uuid.UUID, version int, patch *domain.CommentPatch) (*domain.Comment, error)) *CommentRepository_PatchComment_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ApplyCommentBatch provides a mock function for the type CommentRepository
+func (_mock *CommentRepository) ApplyCommentBatch(ctx context.Context, items []domain.CommentBatchItem, atomic bool) ([]domain.BatchItemResult[domain.Comment], error) {
+	ret := _mock.Called(ctx, items, atomic)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ApplyCommentBatch")
+	}
+
+	var r0 []domain.BatchItemResult[domain.Comment]
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []domain.CommentBatchItem, bool) ([]domain.BatchItemResult[domain.Comment], error)); ok {
+		return returnFunc(ctx, items, atomic)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []domain.CommentBatchItem, bool) []domain.BatchItemResult[domain.Comment]); ok {
+		r0 = returnFunc(ctx, items, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.BatchItemResult[domain.Comment])
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, []domain.CommentBatchItem, bool) error); ok {
+		r1 = returnFunc(ctx, items, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// CommentRepository_ApplyCommentBatch_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ApplyCommentBatch'
+type CommentRepository_ApplyCommentBatch_Call struct {
+	*mock.Call
+}
+
+// ApplyCommentBatch is a helper method to define mock.On call
+//   - ctx context.Context
+//   - items []domain.CommentBatchItem
+//   - atomic bool
+func (_e *CommentRepository_Expecter) ApplyCommentBatch(ctx interface{}, items interface{}, atomic interface{}) *CommentRepository_ApplyCommentBatch_Call {
+	return &CommentRepository_ApplyCommentBatch_Call{Call: _e.mock.On("ApplyCommentBatch", ctx, items, atomic)}
+}
+
+func (_c *CommentRepository_ApplyCommentBatch_Call) Run(run func(ctx context.Context, items []domain.CommentBatchItem, atomic bool)) *CommentRepository_ApplyCommentBatch_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 []domain.CommentBatchItem
+		if args[1] != nil {
+			arg1 = args[1].([]domain.CommentBatchItem)
+		}
+		var arg2 bool
+		if args[2] != nil {
+			arg2 = args[2].(bool)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *CommentRepository_ApplyCommentBatch_Call) Return(r []domain.BatchItemResult[domain.Comment], err error) *CommentRepository_ApplyCommentBatch_Call {
+	_c.Call.Return(r, err)
+	return _c
+}
+
+func (_c *CommentRepository_ApplyCommentBatch_Call) RunAndReturn(run func(ctx context.Context, items []domain.CommentBatchItem, atomic bool) ([]domain.BatchItemResult[domain.Comment], error)) *CommentRepository_ApplyCommentBatch_Call {
+	_c.Call.Return(run)
+	return _c
+}
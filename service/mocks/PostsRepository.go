@@ -108,16 +108,16 @@ func (_c *PostsRepository_CreatePosts_Call) RunAndReturn(run func(ctx context.Co
 }
 
 // DeletePost provides a mock function for the type PostsRepository
-func (_mock *PostsRepository) DeletePosts(ctx context.Context, id uuid.UUID) error {
-	ret := _mock.Called(ctx, id)
+func (_mock *PostsRepository) DeletePosts(ctx context.Context, id uuid.UUID, version int) error {
+	ret := _mock.Called(ctx, id, version)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeletePost")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
-		r0 = returnFunc(ctx, id)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID, int) error); ok {
+		r0 = returnFunc(ctx, id, version)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -132,11 +132,12 @@ type PostsRepository_DeletePosts_Call struct {
 // DeleteUser is a helper method to define mock.On call
 //   - ctx context.Context
 //   - id uuid.UUID
-func (_e *PostsRepository_Expecter) DeletePosts(ctx interface{}, id interface{}) *PostsRepository_DeletePosts_Call {
-	return &PostsRepository_DeletePosts_Call{Call: _e.mock.On("DeletePost", ctx, id)}
+//   - version int
+func (_e *PostsRepository_Expecter) DeletePosts(ctx interface{}, id interface{}, version interface{}) *PostsRepository_DeletePosts_Call {
+	return &PostsRepository_DeletePosts_Call{Call: _e.mock.On("DeletePost", ctx, id, version)}
 }
 
-func (_c *PostsRepository_DeletePosts_Call) Run(run func(ctx context.Context, id uuid.UUID)) *PostsRepository_DeletePosts_Call {
+func (_c *PostsRepository_DeletePosts_Call) Run(run func(ctx context.Context, id uuid.UUID, version int)) *PostsRepository_DeletePosts_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -146,9 +147,14 @@ func (_c *PostsRepository_DeletePosts_Call) Run(run func(ctx context.Context, id
 		if args[1] != nil {
 			arg1 = args[1].(uuid.UUID)
 		}
+		var arg2 int
+		if args[2] != nil {
+			arg2 = args[2].(int)
+		}
 		run(
 			arg0,
 			arg1,
+			arg2,
 		)
 	})
 	return _c
@@ -159,7 +165,7 @@ func (_c *PostsRepository_DeletePosts_Call) Return(err error) *PostsRepository_D
 	return _c
 }
 
-func (_c *PostsRepository_DeletePosts_Call) RunAndReturn(run func(ctx context.Context, id uuid.UUID) error) *PostsRepository_DeletePosts_Call {
+func (_c *PostsRepository_DeletePosts_Call) RunAndReturn(run func(ctx context.Context, id uuid.UUID, version int) error) *PostsRepository_DeletePosts_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -373,3 +379,297 @@ func (_c *PostsRepository_UpdatePosts_Call) RunAndReturn(run func(ctx context.Co
 	_c.Call.Return(run)
 	return _c
 }
+
+// PostExists provides a mock function for the type PostsRepository
+func (_mock *PostsRepository) PostExists(ctx context.Context, id uuid.UUID) (bool, error) {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PostExists")
+	}
+
+	var r0 bool
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID) (bool, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID) bool); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// PostsRepository_PostExists_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PostExists'
+type PostsRepository_PostExists_Call struct {
+	*mock.Call
+}
+
+// PostExists is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uuid.UUID
+func (_e *PostsRepository_Expecter) PostExists(ctx interface{}, id interface{}) *PostsRepository_PostExists_Call {
+	return &PostsRepository_PostExists_Call{Call: _e.mock.On("PostExists", ctx, id)}
+}
+
+func (_c *PostsRepository_PostExists_Call) Run(run func(ctx context.Context, id uuid.UUID)) *PostsRepository_PostExists_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uuid.UUID
+		if args[1] != nil {
+			arg1 = args[1].(uuid.UUID)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *PostsRepository_PostExists_Call) Return(exists bool, err error) *PostsRepository_PostExists_Call {
+	_c.Call.Return(exists, err)
+	return _c
+}
+
+func (_c *PostsRepository_PostExists_Call) RunAndReturn(run func(ctx context.Context, id uuid.UUID) (bool, error)) *PostsRepository_PostExists_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RestorePosts provides a mock function for the type PostsRepository
+func (_mock *PostsRepository) RestorePosts(ctx context.Context, id uuid.UUID) (*domain.Posts, int, error) {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestorePosts")
+	}
+
+	var r0 *domain.Posts
+	var r1 int
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID) (*domain.Posts, int, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID) *domain.Posts); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Posts)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uuid.UUID) int); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+	if returnFunc, ok := ret.Get(2).(func(context.Context, uuid.UUID) error); ok {
+		r2 = returnFunc(ctx, id)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
+}
+
+// PostsRepository_RestorePosts_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RestorePosts'
+type PostsRepository_RestorePosts_Call struct {
+	*mock.Call
+}
+
+// RestorePosts is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uuid.UUID
+func (_e *PostsRepository_Expecter) RestorePosts(ctx interface{}, id interface{}) *PostsRepository_RestorePosts_Call {
+	return &PostsRepository_RestorePosts_Call{Call: _e.mock.On("RestorePosts", ctx, id)}
+}
+
+func (_c *PostsRepository_RestorePosts_Call) Run(run func(ctx context.Context, id uuid.UUID)) *PostsRepository_RestorePosts_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uuid.UUID
+		if args[1] != nil {
+			arg1 = args[1].(uuid.UUID)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *PostsRepository_RestorePosts_Call) Return(post *domain.Posts, comments int, err error) *PostsRepository_RestorePosts_Call {
+	_c.Call.Return(post, comments, err)
+	return _c
+}
+
+func (_c *PostsRepository_RestorePosts_Call) RunAndReturn(run func(ctx context.Context, id uuid.UUID) (*domain.Posts, int, error)) *PostsRepository_RestorePosts_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PatchPosts provides a mock function for the type PostsRepository
+func (_mock *PostsRepository) PatchPosts(ctx context.Context, id uuid.UUID, version int, patch *domain.PostsPatch) (*domain.Posts, error) {
+	ret := _mock.Called(ctx, id, version, patch)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PatchPosts")
+	}
+
+	var r0 *domain.Posts
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID, int, *domain.PostsPatch) (*domain.Posts, error)); ok {
+		return returnFunc(ctx, id, version, patch)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID, int, *domain.PostsPatch) *domain.Posts); ok {
+		r0 = returnFunc(ctx, id, version, patch)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Posts)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uuid.UUID, int, *domain.PostsPatch) error); ok {
+		r1 = returnFunc(ctx, id, version, patch)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// PostsRepository_PatchPosts_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PatchPosts'
+type PostsRepository_PatchPosts_Call struct {
+	*mock.Call
+}
+
+// PatchPosts is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uuid.UUID
+//   - version int
+//   - patch *domain.PostsPatch
+func (_e *PostsRepository_Expecter) PatchPosts(ctx interface{}, id interface{}, version interface{}, patch interface{}) *PostsRepository_PatchPosts_Call {
+	return &PostsRepository_PatchPosts_Call{Call: _e.mock.On("PatchPosts", ctx, id, version, patch)}
+}
+
+func (_c *PostsRepository_PatchPosts_Call) Run(run func(ctx context.Context, id uuid.UUID, version int, patch *domain.PostsPatch)) *PostsRepository_PatchPosts_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uuid.UUID
+		if args[1] != nil {
+			arg1 = args[1].(uuid.UUID)
+		}
+		var arg2 int
+		if args[2] != nil {
+			arg2 = args[2].(int)
+		}
+		var arg3 *domain.PostsPatch
+		if args[3] != nil {
+			arg3 = args[3].(*domain.PostsPatch)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *PostsRepository_PatchPosts_Call) Return(r *domain.Posts, err error) *PostsRepository_PatchPosts_Call {
+	_c.Call.Return(r, err)
+	return _c
+}
+
+func (_c *PostsRepository_PatchPosts_Call) RunAndReturn(run func(ctx context.Context, id uuid.UUID, version int, patch *domain.PostsPatch) (*domain.Posts, error)) *PostsRepository_PatchPosts_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ApplyPostsBatch provides a mock function for the type PostsRepository
+func (_mock *PostsRepository) ApplyPostsBatch(ctx context.Context, items []domain.PostsBatchItem, atomic bool) ([]domain.BatchItemResult[domain.Posts], error) {
+	ret := _mock.Called(ctx, items, atomic)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ApplyPostsBatch")
+	}
+
+	var r0 []domain.BatchItemResult[domain.Posts]
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []domain.PostsBatchItem, bool) ([]domain.BatchItemResult[domain.Posts], error)); ok {
+		return returnFunc(ctx, items, atomic)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []domain.PostsBatchItem, bool) []domain.BatchItemResult[domain.Posts]); ok {
+		r0 = returnFunc(ctx, items, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.BatchItemResult[domain.Posts])
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, []domain.PostsBatchItem, bool) error); ok {
+		r1 = returnFunc(ctx, items, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// PostsRepository_ApplyPostsBatch_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ApplyPostsBatch'
+type PostsRepository_ApplyPostsBatch_Call struct {
+	*mock.Call
+}
+
+// ApplyPostsBatch is a helper method to define mock.On call
+//   - ctx context.Context
+//   - items []domain.PostsBatchItem
+//   - atomic bool
+func (_e *PostsRepository_Expecter) ApplyPostsBatch(ctx interface{}, items interface{}, atomic interface{}) *PostsRepository_ApplyPostsBatch_Call {
+	return &PostsRepository_ApplyPostsBatch_Call{Call: _e.mock.On("ApplyPostsBatch", ctx, items, atomic)}
+}
+
+func (_c *PostsRepository_ApplyPostsBatch_Call) Run(run func(ctx context.Context, items []domain.PostsBatchItem, atomic bool)) *PostsRepository_ApplyPostsBatch_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 []domain.PostsBatchItem
+		if args[1] != nil {
+			arg1 = args[1].([]domain.PostsBatchItem)
+		}
+		var arg2 bool
+		if args[2] != nil {
+			arg2 = args[2].(bool)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *PostsRepository_ApplyPostsBatch_Call) Return(r []domain.BatchItemResult[domain.Posts], err error) *PostsRepository_ApplyPostsBatch_Call {
+	_c.Call.Return(r, err)
+	return _c
+}
+
+func (_c *PostsRepository_ApplyPostsBatch_Call) RunAndReturn(run func(ctx context.Context, items []domain.PostsBatchItem, atomic bool) ([]domain.BatchItemResult[domain.Posts], error)) *PostsRepository_ApplyPostsBatch_Call {
+	_c.Call.Return(run)
+	return _c
+}
@@ -108,16 +108,16 @@ func (_c *UserRepository_CreateUser_Call) RunAndReturn(run func(ctx context.Cont
 }
 
 // DeleteUser provides a mock function for the type UserRepository
-func (_mock *UserRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
-	ret := _mock.Called(ctx, id)
+func (_mock *UserRepository) DeleteUser(ctx context.Context, id uuid.UUID, version int) error {
+	ret := _mock.Called(ctx, id, version)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteUser")
 	}
 
 	var r0 error
-	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID) error); ok {
-		r0 = returnFunc(ctx, id)
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID, int) error); ok {
+		r0 = returnFunc(ctx, id, version)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -132,11 +132,12 @@ type UserRepository_DeleteUser_Call struct {
 // DeleteUser is a helper method to define mock.On call
 //   - ctx context.Context
 //   - id uuid.UUID
-func (_e *UserRepository_Expecter) DeleteUser(ctx interface{}, id interface{}) *UserRepository_DeleteUser_Call {
-	return &UserRepository_DeleteUser_Call{Call: _e.mock.On("DeleteUser", ctx, id)}
+//   - version int
+func (_e *UserRepository_Expecter) DeleteUser(ctx interface{}, id interface{}, version interface{}) *UserRepository_DeleteUser_Call {
+	return &UserRepository_DeleteUser_Call{Call: _e.mock.On("DeleteUser", ctx, id, version)}
 }
 
-func (_c *UserRepository_DeleteUser_Call) Run(run func(ctx context.Context, id uuid.UUID)) *UserRepository_DeleteUser_Call {
+func (_c *UserRepository_DeleteUser_Call) Run(run func(ctx context.Context, id uuid.UUID, version int)) *UserRepository_DeleteUser_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 context.Context
 		if args[0] != nil {
@@ -146,9 +147,14 @@ func (_c *UserRepository_DeleteUser_Call) Run(run func(ctx context.Context, id u
 		if args[1] != nil {
 			arg1 = args[1].(uuid.UUID)
 		}
+		var arg2 int
+		if args[2] != nil {
+			arg2 = args[2].(int)
+		}
 		run(
 			arg0,
 			arg1,
+			arg2,
 		)
 	})
 	return _c
@@ -159,7 +165,7 @@ func (_c *UserRepository_DeleteUser_Call) Return(err error) *UserRepository_Dele
 	return _c
 }
 
-func (_c *UserRepository_DeleteUser_Call) RunAndReturn(run func(ctx context.Context, id uuid.UUID) error) *UserRepository_DeleteUser_Call {
+func (_c *UserRepository_DeleteUser_Call) RunAndReturn(run func(ctx context.Context, id uuid.UUID, version int) error) *UserRepository_DeleteUser_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -373,3 +379,367 @@ func (_c *UserRepository_UpdateUser_Call) RunAndReturn(run func(ctx context.Cont
 	_c.Call.Return(run)
 	return _c
 }
+
+// RestoreUser provides a mock function for the type UserRepository
+func (_mock *UserRepository) RestoreUser(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	ret := _mock.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreUser")
+	}
+
+	var r0 *domain.User
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID) (*domain.User, error)); ok {
+		return returnFunc(ctx, id)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID) *domain.User); ok {
+		r0 = returnFunc(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uuid.UUID) error); ok {
+		r1 = returnFunc(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// UserRepository_RestoreUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RestoreUser'
+type UserRepository_RestoreUser_Call struct {
+	*mock.Call
+}
+
+// RestoreUser is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uuid.UUID
+func (_e *UserRepository_Expecter) RestoreUser(ctx interface{}, id interface{}) *UserRepository_RestoreUser_Call {
+	return &UserRepository_RestoreUser_Call{Call: _e.mock.On("RestoreUser", ctx, id)}
+}
+
+func (_c *UserRepository_RestoreUser_Call) Run(run func(ctx context.Context, id uuid.UUID)) *UserRepository_RestoreUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uuid.UUID
+		if args[1] != nil {
+			arg1 = args[1].(uuid.UUID)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *UserRepository_RestoreUser_Call) Return(user *domain.User, err error) *UserRepository_RestoreUser_Call {
+	_c.Call.Return(user, err)
+	return _c
+}
+
+func (_c *UserRepository_RestoreUser_Call) RunAndReturn(run func(ctx context.Context, id uuid.UUID) (*domain.User, error)) *UserRepository_RestoreUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ImportUser provides a mock function for the type UserRepository
+func (_mock *UserRepository) ImportUser(ctx context.Context, user *domain.UserImport) (*domain.User, error) {
+	ret := _mock.Called(ctx, user)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ImportUser")
+	}
+
+	var r0 *domain.User
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *domain.UserImport) (*domain.User, error)); ok {
+		return returnFunc(ctx, user)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *domain.UserImport) *domain.User); ok {
+		r0 = returnFunc(ctx, user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *domain.UserImport) error); ok {
+		r1 = returnFunc(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// UserRepository_ImportUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ImportUser'
+type UserRepository_ImportUser_Call struct {
+	*mock.Call
+}
+
+// ImportUser is a helper method to define mock.On call
+//   - ctx context.Context
+//   - user *domain.UserImport
+func (_e *UserRepository_Expecter) ImportUser(ctx interface{}, user interface{}) *UserRepository_ImportUser_Call {
+	return &UserRepository_ImportUser_Call{Call: _e.mock.On("ImportUser", ctx, user)}
+}
+
+func (_c *UserRepository_ImportUser_Call) Run(run func(ctx context.Context, user *domain.UserImport)) *UserRepository_ImportUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *domain.UserImport
+		if args[1] != nil {
+			arg1 = args[1].(*domain.UserImport)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *UserRepository_ImportUser_Call) Return(r *domain.User, err error) *UserRepository_ImportUser_Call {
+	_c.Call.Return(r, err)
+	return _c
+}
+
+func (_c *UserRepository_ImportUser_Call) RunAndReturn(run func(ctx context.Context, user *domain.UserImport) (*domain.User, error)) *UserRepository_ImportUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PatchUser provides a mock function for the type UserRepository
+func (_mock *UserRepository) PatchUser(ctx context.Context, id uuid.UUID, version int, patch *domain.UserPatch) (*domain.User, error) {
+	ret := _mock.Called(ctx, id, version, patch)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PatchUser")
+	}
+
+	var r0 *domain.User
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID, int, *domain.UserPatch) (*domain.User, error)); ok {
+		return returnFunc(ctx, id, version, patch)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, uuid.UUID, int, *domain.UserPatch) *domain.User); ok {
+		r0 = returnFunc(ctx, id, version, patch)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, uuid.UUID, int, *domain.UserPatch) error); ok {
+		r1 = returnFunc(ctx, id, version, patch)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// UserRepository_PatchUser_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PatchUser'
+type UserRepository_PatchUser_Call struct {
+	*mock.Call
+}
+
+// PatchUser is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id uuid.UUID
+//   - version int
+//   - patch *domain.UserPatch
+func (_e *UserRepository_Expecter) PatchUser(ctx interface{}, id interface{}, version interface{}, patch interface{}) *UserRepository_PatchUser_Call {
+	return &UserRepository_PatchUser_Call{Call: _e.mock.On("PatchUser", ctx, id, version, patch)}
+}
+
+func (_c *UserRepository_PatchUser_Call) Run(run func(ctx context.Context, id uuid.UUID, version int, patch *domain.UserPatch)) *UserRepository_PatchUser_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 uuid.UUID
+		if args[1] != nil {
+			arg1 = args[1].(uuid.UUID)
+		}
+		var arg2 int
+		if args[2] != nil {
+			arg2 = args[2].(int)
+		}
+		var arg3 *domain.UserPatch
+		if args[3] != nil {
+			arg3 = args[3].(*domain.UserPatch)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+		)
+	})
+	return _c
+}
+
+func (_c *UserRepository_PatchUser_Call) Return(r *domain.User, err error) *UserRepository_PatchUser_Call {
+	_c.Call.Return(r, err)
+	return _c
+}
+
+func (_c *UserRepository_PatchUser_Call) RunAndReturn(run func(ctx context.Context, id uuid.UUID, version int, patch *domain.UserPatch) (*domain.User, error)) *UserRepository_PatchUser_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ApplyUserBatch provides a mock function for the type UserRepository
+func (_mock *UserRepository) ApplyUserBatch(ctx context.Context, items []domain.UserBatchItem, atomic bool) ([]domain.BatchItemResult[domain.User], error) {
+	ret := _mock.Called(ctx, items, atomic)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ApplyUserBatch")
+	}
+
+	var r0 []domain.BatchItemResult[domain.User]
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []domain.UserBatchItem, bool) ([]domain.BatchItemResult[domain.User], error)); ok {
+		return returnFunc(ctx, items, atomic)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []domain.UserBatchItem, bool) []domain.BatchItemResult[domain.User]); ok {
+		r0 = returnFunc(ctx, items, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.BatchItemResult[domain.User])
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, []domain.UserBatchItem, bool) error); ok {
+		r1 = returnFunc(ctx, items, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// UserRepository_ApplyUserBatch_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ApplyUserBatch'
+type UserRepository_ApplyUserBatch_Call struct {
+	*mock.Call
+}
+
+// ApplyUserBatch is a helper method to define mock.On call
+//   - ctx context.Context
+//   - items []domain.UserBatchItem
+//   - atomic bool
+func (_e *UserRepository_Expecter) ApplyUserBatch(ctx interface{}, items interface{}, atomic interface{}) *UserRepository_ApplyUserBatch_Call {
+	return &UserRepository_ApplyUserBatch_Call{Call: _e.mock.On("ApplyUserBatch", ctx, items, atomic)}
+}
+
+func (_c *UserRepository_ApplyUserBatch_Call) Run(run func(ctx context.Context, items []domain.UserBatchItem, atomic bool)) *UserRepository_ApplyUserBatch_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 []domain.UserBatchItem
+		if args[1] != nil {
+			arg1 = args[1].([]domain.UserBatchItem)
+		}
+		var arg2 bool
+		if args[2] != nil {
+			arg2 = args[2].(bool)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *UserRepository_ApplyUserBatch_Call) Return(r []domain.BatchItemResult[domain.User], err error) *UserRepository_ApplyUserBatch_Call {
+	_c.Call.Return(r, err)
+	return _c
+}
+
+func (_c *UserRepository_ApplyUserBatch_Call) RunAndReturn(run func(ctx context.Context, items []domain.UserBatchItem, atomic bool) ([]domain.BatchItemResult[domain.User], error)) *UserRepository_ApplyUserBatch_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FindUserByLogin provides a mock function for the type UserRepository
+func (_mock *UserRepository) FindUserByLogin(ctx context.Context, username string, email string) (*domain.User, error) {
+	ret := _mock.Called(ctx, username, email)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindUserByLogin")
+	}
+
+	var r0 *domain.User
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) (*domain.User, error)); ok {
+		return returnFunc(ctx, username, email)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, string) *domain.User); ok {
+		r0 = returnFunc(ctx, username, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.User)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = returnFunc(ctx, username, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// UserRepository_FindUserByLogin_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindUserByLogin'
+type UserRepository_FindUserByLogin_Call struct {
+	*mock.Call
+}
+
+// FindUserByLogin is a helper method to define mock.On call
+//   - ctx context.Context
+//   - username string
+//   - email string
+func (_e *UserRepository_Expecter) FindUserByLogin(ctx interface{}, username interface{}, email interface{}) *UserRepository_FindUserByLogin_Call {
+	return &UserRepository_FindUserByLogin_Call{Call: _e.mock.On("FindUserByLogin", ctx, username, email)}
+}
+
+func (_c *UserRepository_FindUserByLogin_Call) Run(run func(ctx context.Context, username string, email string)) *UserRepository_FindUserByLogin_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *UserRepository_FindUserByLogin_Call) Return(r *domain.User, err error) *UserRepository_FindUserByLogin_Call {
+	_c.Call.Return(r, err)
+	return _c
+}
+
+func (_c *UserRepository_FindUserByLogin_Call) RunAndReturn(run func(ctx context.Context, username string, email string) (*domain.User, error)) *UserRepository_FindUserByLogin_Call {
+	_c.Call.Return(run)
+	return _c
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/logging"
+	"github.com/google/uuid"
+)
+
+type ModerationRepository interface {
+	GetComment(ctx context.Context, id uuid.UUID) (*domain.Comment, error)
+	GetUserCreatedAt(ctx context.Context, userID uuid.UUID) (time.Time, error)
+	CountCommentsSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error)
+	GetModerationQueue(ctx context.Context, filter *domain.ModerationQueueFilter) ([]domain.Comment, int, error)
+	ApplyModeration(ctx context.Context, ids []uuid.UUID, action domain.ModerationAction, moderatorID string, reason string) ([]domain.ModerationAudit, error)
+	RecordAudit(ctx context.Context, audit *domain.ModerationAudit) error
+	GetModerationAudit(ctx context.Context, commentID uuid.UUID) ([]domain.ModerationAudit, error)
+}
+
+type ModerationService struct {
+	moderationRepo ModerationRepository
+	rules          domain.ModerationRules
+	blockedWords   map[string]struct{}
+	blockedPhrases []string
+	now            func() time.Time
+	events         EventPublisher
+}
+
+func NewModerationService(r ModerationRepository, rules domain.ModerationRules) *ModerationService {
+	ms := &ModerationService{
+		moderationRepo: r,
+		rules:          rules,
+		blockedWords:   make(map[string]struct{}, len(rules.BlockedWords)),
+		now:            time.Now,
+	}
+	for _, word := range rules.BlockedWords {
+		word = strings.ToLower(strings.TrimSpace(word))
+		switch {
+		case word == "":
+		case strings.IndexFunc(word, isWordSeparator) >= 0:
+			ms.blockedPhrases = append(ms.blockedPhrases, word)
+		default:
+			ms.blockedWords[word] = struct{}{}
+		}
+	}
+	return ms
+}
+
+// WithEvents publishes the creation of a held comment once a moderator
+// approves it, CreateComment only does so for comments approved right away
+func (ms *ModerationService) WithEvents(p EventPublisher) *ModerationService {
+	ms.events = p
+	return ms
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://|\bwww\.`)
+
+// Screen runs the spam rules against a new comment. Blocked words and
+// exceeding the per-user rate mark it as spam, too many links or a new
+// account hold it for review, anything else is approved right away.
+func (ms *ModerationService) Screen(ctx context.Context, comment *domain.CreateCommentRequest) (*domain.ModerationDecision, error) {
+	return ms.screen(ctx, comment.UserID, comment.Body, true)
+}
+
+// ScreenEdit runs the spam rules against the new body of a comment written
+// by userID. The rate limit is left out, an edit adds no comment.
+func (ms *ModerationService) ScreenEdit(ctx context.Context, userID, body string) (*domain.ModerationDecision, error) {
+	return ms.screen(ctx, userID, body, false)
+}
+
+func (ms *ModerationService) screen(ctx context.Context, user, body string, limitRate bool) (*domain.ModerationDecision, error) {
+	if word, ok := ms.findBlockedWord(body); ok {
+		return &domain.ModerationDecision{Status: domain.ModerationStatusSpam, Reason: "contains blocked word \"" + word + "\""}, nil
+	}
+
+	userID, err := uuid.Parse(user)
+	if err != nil {
+		return nil, domain.ErrBadParamInput
+	}
+
+	if limitRate && ms.rules.RateLimit > 0 && ms.rules.RateWindow > 0 {
+		recent, err := ms.moderationRepo.CountCommentsSince(ctx, userID, ms.now().Add(-ms.rules.RateWindow))
+		if err != nil {
+			return nil, err
+		}
+		if recent >= ms.rules.RateLimit {
+			return &domain.ModerationDecision{Status: domain.ModerationStatusSpam, Reason: "comment rate limit exceeded"}, nil
+		}
+	}
+
+	if ms.rules.MaxLinks >= 0 {
+		if links := len(linkPattern.FindAllStringIndex(body, -1)); links > ms.rules.MaxLinks {
+			return &domain.ModerationDecision{Status: domain.ModerationStatusPending, Reason: "too many links"}, nil
+		}
+	}
+
+	if ms.rules.NewAccountAge > 0 {
+		createdAt, err := ms.moderationRepo.GetUserCreatedAt(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if ms.now().Sub(createdAt) < ms.rules.NewAccountAge {
+			return &domain.ModerationDecision{Status: domain.ModerationStatusPending, Reason: "new account"}, nil
+		}
+	}
+
+	return &domain.ModerationDecision{Status: domain.ModerationStatusApproved}, nil
+}
+
+// RecordDecision audits a comment the spam rules did not approve, from is
+// the status it had before: pending for a new comment, approved for an edit
+func (ms *ModerationService) RecordDecision(ctx context.Context, comment *domain.Comment, from domain.ModerationStatus, decision *domain.ModerationDecision) error {
+	if decision == nil || decision.Status == domain.ModerationStatusApproved {
+		return nil
+	}
+	return ms.moderationRepo.RecordAudit(ctx, &domain.ModerationAudit{
+		CommentID:  comment.ID,
+		Action:     domain.ModerationActionFlag,
+		FromStatus: from,
+		ToStatus:   decision.Status,
+		Reason:     decision.Reason,
+	})
+}
+
+func (ms *ModerationService) GetQueue(ctx context.Context, filter *domain.ModerationQueueFilter) ([]domain.Comment, int, error) {
+	if filter == nil {
+		filter = &domain.ModerationQueueFilter{}
+	}
+	filter.Normalize()
+	if !filter.Status.IsValid() {
+		return nil, 0, domain.ErrBadParamInput
+	}
+
+	comments, total, err := ms.moderationRepo.GetModerationQueue(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range comments {
+		if err := fillCommentHTML(&comments[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return comments, total, nil
+}
+
+// GetComment returns a comment whatever its moderation status, so moderators
+// can look at the ones held back from everybody else
+func (ms *ModerationService) GetComment(ctx context.Context, id uuid.UUID) (*domain.Comment, error) {
+	comment, err := ms.moderationRepo.GetComment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := fillCommentHTML(comment); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// Moderate applies the moderator's action to every comment in the request
+func (ms *ModerationService) Moderate(
+	ctx context.Context,
+	moderatorID string,
+	req *domain.BulkModerationRequest,
+) (*domain.BulkModerationResult, error) {
+	if req.Action.Status() == "" || len(req.CommentIDs) == 0 {
+		return nil, domain.ErrBadParamInput
+	}
+
+	ids := make([]uuid.UUID, 0, len(req.CommentIDs))
+	seen := make(map[uuid.UUID]struct{}, len(req.CommentIDs))
+	for _, raw := range req.CommentIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, domain.ErrBadParamInput
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+
+	audits, err := ms.moderationRepo.ApplyModeration(ctx, ids, req.Action, moderatorID, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	moderated := make(map[string]struct{}, len(audits))
+	for _, audit := range audits {
+		moderated[audit.CommentID] = struct{}{}
+		if audit.ToStatus == domain.ModerationStatusApproved && audit.FromStatus != domain.ModerationStatusApproved {
+			ms.publishApproved(ctx, audit.CommentID)
+		}
+	}
+	result := &domain.BulkModerationResult{Audits: audits, NotFound: []string{}}
+	for _, id := range ids {
+		if _, ok := moderated[id.String()]; !ok {
+			result.NotFound = append(result.NotFound, id.String())
+		}
+	}
+	return result, nil
+}
+
+// publishApproved announces a comment that just went live. The moderation is
+// stored already, a comment that cannot be loaded only gets logged.
+func (ms *ModerationService) publishApproved(ctx context.Context, commentID string) {
+	if ms.events == nil {
+		return
+	}
+	comment, err := ms.moderationRepo.GetComment(ctx, uuid.MustParse(commentID))
+	if err != nil {
+		logging.LogError(ctx, err, "publish_approved_comment")
+		return
+	}
+	event := domain.Event{
+		Type:       domain.EventCommentCreated,
+		ActorID:    comment.UserID,
+		PostID:     comment.PostID,
+		CommentID:  comment.ID,
+		Body:       comment.Body,
+		OccurredAt: comment.CreatedAt,
+	}
+	if comment.ParentID != nil {
+		event.ParentCommentID = *comment.ParentID
+	}
+	ms.events.Publish(ctx, event)
+}
+
+func (ms *ModerationService) GetAuditLog(ctx context.Context, commentID uuid.UUID) ([]domain.ModerationAudit, error) {
+	return ms.moderationRepo.GetModerationAudit(ctx, commentID)
+}
+
+func (ms *ModerationService) findBlockedWord(body string) (string, bool) {
+	if len(ms.blockedWords) == 0 && len(ms.blockedPhrases) == 0 {
+		return "", false
+	}
+
+	lower := strings.ToLower(body)
+	for _, word := range strings.FieldsFunc(lower, isWordSeparator) {
+		if _, ok := ms.blockedWords[word]; ok {
+			return word, true
+		}
+	}
+	for _, phrase := range ms.blockedPhrases {
+		if strings.Contains(lower, phrase) {
+			return phrase, true
+		}
+	}
+	return "", false
+}
+
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
@@ -0,0 +1,328 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/service"
+	"github.com/edwinjordan/MajooTest-Golang/service/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestModerationService_Screen(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	rules := domain.ModerationRules{
+		BlockedWords:  []string{"casino", "free money"},
+		MaxLinks:      1,
+		NewAccountAge: 24 * time.Hour,
+		RateLimit:     3,
+		RateWindow:    time.Minute,
+	}
+
+	t.Run("Approves a regular comment", func(t *testing.T) {
+		mockModerationRepo := new(mocks.ModerationRepository)
+		moderationService := service.NewModerationService(mockModerationRepo, rules)
+
+		mockModerationRepo.On("CountCommentsSince", mock.Anything, userID, mock.AnythingOfType("time.Time")).Return(0, nil).Once()
+		mockModerationRepo.On("GetUserCreatedAt", mock.Anything, userID).Return(time.Now().Add(-48*time.Hour), nil).Once()
+
+		decision, err := moderationService.Screen(ctx, &domain.CreateCommentRequest{UserID: userID.String(), Body: "Nice post, see https://example.com"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ModerationStatusApproved, decision.Status)
+
+		mockModerationRepo.AssertExpectations(t)
+	})
+
+	t.Run("Marks blocked words as spam", func(t *testing.T) {
+		mockModerationRepo := new(mocks.ModerationRepository)
+		moderationService := service.NewModerationService(mockModerationRepo, rules)
+
+		for _, body := range []string{"Visit my CASINO today", "get FREE money now"} {
+			decision, err := moderationService.Screen(ctx, &domain.CreateCommentRequest{UserID: userID.String(), Body: body})
+
+			assert.NoError(t, err)
+			assert.Equal(t, domain.ModerationStatusSpam, decision.Status, body)
+		}
+
+		mockModerationRepo.AssertExpectations(t)
+	})
+
+	t.Run("Marks comments over the rate limit as spam", func(t *testing.T) {
+		mockModerationRepo := new(mocks.ModerationRepository)
+		moderationService := service.NewModerationService(mockModerationRepo, rules)
+
+		mockModerationRepo.On("CountCommentsSince", mock.Anything, userID, mock.AnythingOfType("time.Time")).Return(3, nil).Once()
+
+		decision, err := moderationService.Screen(ctx, &domain.CreateCommentRequest{UserID: userID.String(), Body: "again"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ModerationStatusSpam, decision.Status)
+
+		mockModerationRepo.AssertExpectations(t)
+	})
+
+	t.Run("Holds comments with too many links for review", func(t *testing.T) {
+		mockModerationRepo := new(mocks.ModerationRepository)
+		moderationService := service.NewModerationService(mockModerationRepo, rules)
+
+		mockModerationRepo.On("CountCommentsSince", mock.Anything, userID, mock.AnythingOfType("time.Time")).Return(0, nil).Once()
+
+		decision, err := moderationService.Screen(ctx, &domain.CreateCommentRequest{UserID: userID.String(), Body: "http://a.example and www.b.example"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ModerationStatusPending, decision.Status)
+
+		mockModerationRepo.AssertExpectations(t)
+	})
+
+	t.Run("Holds comments of new accounts for review", func(t *testing.T) {
+		mockModerationRepo := new(mocks.ModerationRepository)
+		moderationService := service.NewModerationService(mockModerationRepo, rules)
+
+		mockModerationRepo.On("CountCommentsSince", mock.Anything, userID, mock.AnythingOfType("time.Time")).Return(0, nil).Once()
+		mockModerationRepo.On("GetUserCreatedAt", mock.Anything, userID).Return(time.Now().Add(-time.Hour), nil).Once()
+
+		decision, err := moderationService.Screen(ctx, &domain.CreateCommentRequest{UserID: userID.String(), Body: "hello"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ModerationStatusPending, decision.Status)
+		assert.Equal(t, "new account", decision.Reason)
+
+		mockModerationRepo.AssertExpectations(t)
+	})
+}
+
+func TestModerationService_Moderate(t *testing.T) {
+	ctx := context.Background()
+	moderatorID := uuid.New().String()
+	found, missing := uuid.New(), uuid.New()
+
+	t.Run("Applies the action and reports missing comments", func(t *testing.T) {
+		mockModerationRepo := new(mocks.ModerationRepository)
+		moderationService := service.NewModerationService(mockModerationRepo, domain.DefaultModerationRules())
+
+		audits := []domain.ModerationAudit{{
+			CommentID:   found.String(),
+			ModeratorID: &moderatorID,
+			Action:      domain.ModerationActionApprove,
+			FromStatus:  domain.ModerationStatusPending,
+			ToStatus:    domain.ModerationStatusApproved,
+		}}
+		mockModerationRepo.On("ApplyModeration", mock.Anything, []uuid.UUID{found, missing}, domain.ModerationActionApprove, moderatorID, "looks fine").
+			Return(audits, nil).Once()
+
+		result, err := moderationService.Moderate(ctx, moderatorID, &domain.BulkModerationRequest{
+			CommentIDs: []string{found.String(), missing.String(), found.String()},
+			Action:     domain.ModerationActionApprove,
+			Reason:     "looks fine",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, audits, result.Audits)
+		assert.Equal(t, []string{missing.String()}, result.NotFound)
+
+		mockModerationRepo.AssertExpectations(t)
+	})
+
+	t.Run("Publishes the creation of approved comments", func(t *testing.T) {
+		mockModerationRepo := new(mocks.ModerationRepository)
+		publisher := &recordingPublisher{}
+		moderationService := service.NewModerationService(mockModerationRepo, domain.DefaultModerationRules()).
+			WithEvents(publisher)
+
+		again := uuid.New()
+		audits := []domain.ModerationAudit{
+			{CommentID: found.String(), FromStatus: domain.ModerationStatusPending, ToStatus: domain.ModerationStatusApproved},
+			{CommentID: again.String(), FromStatus: domain.ModerationStatusApproved, ToStatus: domain.ModerationStatusApproved},
+		}
+		approved := &domain.Comment{ID: found.String(), PostID: uuid.New().String(), UserID: uuid.New().String(), Body: "hi @alice"}
+		mockModerationRepo.On("ApplyModeration", mock.Anything, []uuid.UUID{found, again}, domain.ModerationActionApprove, moderatorID, "").
+			Return(audits, nil).Once()
+		mockModerationRepo.On("GetComment", mock.Anything, found).Return(approved, nil).Once()
+
+		_, err := moderationService.Moderate(ctx, moderatorID, &domain.BulkModerationRequest{
+			CommentIDs: []string{found.String(), again.String()},
+			Action:     domain.ModerationActionApprove,
+		})
+
+		assert.NoError(t, err)
+		if assert.Len(t, publisher.events, 1) {
+			event := publisher.events[0]
+			assert.Equal(t, domain.EventCommentCreated, event.Type)
+			assert.Equal(t, approved.UserID, event.ActorID)
+			assert.Equal(t, approved.PostID, event.PostID)
+			assert.Equal(t, approved.Body, event.Body)
+		}
+		mockModerationRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects unknown actions", func(t *testing.T) {
+		mockModerationRepo := new(mocks.ModerationRepository)
+		moderationService := service.NewModerationService(mockModerationRepo, domain.DefaultModerationRules())
+
+		result, err := moderationService.Moderate(ctx, moderatorID, &domain.BulkModerationRequest{
+			CommentIDs: []string{found.String()},
+			Action:     domain.ModerationActionFlag,
+		})
+
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+		assert.Nil(t, result)
+
+		mockModerationRepo.AssertExpectations(t)
+	})
+}
+
+func TestModerationService_GetComment(t *testing.T) {
+	mockModerationRepo := new(mocks.ModerationRepository)
+	moderationService := service.NewModerationService(mockModerationRepo, domain.DefaultModerationRules())
+
+	id := uuid.New()
+	held := &domain.Comment{ID: id.String(), Body: "buy **now**", ContentFormat: domain.ContentFormatMarkdown, Status: domain.ModerationStatusSpam}
+	mockModerationRepo.On("GetComment", mock.Anything, id).Return(held, nil).Once()
+
+	comment, err := moderationService.GetComment(context.Background(), id)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ModerationStatusSpam, comment.Status)
+	assert.Contains(t, comment.ContentHTML, "<strong>now</strong>")
+	mockModerationRepo.AssertExpectations(t)
+}
+
+func TestCommentService_CreateComment_WithModeration(t *testing.T) {
+	mockCommentsRepo := new(mocks.CommentRepository)
+	mockPostsRepo := new(mocks.PostsRepository)
+	mockModerationRepo := new(mocks.ModerationRepository)
+	moderationService := service.NewModerationService(mockModerationRepo, domain.ModerationRules{
+		BlockedWords: []string{"casino"},
+		MaxLinks:     -1,
+	})
+	commentsService := service.NewCommentService(mockCommentsRepo, mockPostsRepo).WithModeration(moderationService)
+
+	postID := uuid.New()
+	req := &domain.CreateCommentRequest{
+		PostID: postID.String(),
+		UserID: uuid.New().String(),
+		Body:   "best casino in town",
+	}
+	created := &domain.Comment{ID: uuid.New().String(), PostID: postID.String(), Status: domain.ModerationStatusSpam}
+
+	mockPostsRepo.On("PostExists", mock.Anything, postID).Return(true, nil).Once()
+	mockCommentsRepo.On("CreateComment", mock.Anything, mock.MatchedBy(func(r *domain.CreateCommentRequest) bool {
+		return r.Status == domain.ModerationStatusSpam && r.ModerationReason != ""
+	})).Return(created, nil).Once()
+	mockModerationRepo.On("RecordAudit", mock.Anything, mock.MatchedBy(func(a *domain.ModerationAudit) bool {
+		return a.CommentID == created.ID && a.Action == domain.ModerationActionFlag && a.ToStatus == domain.ModerationStatusSpam
+	})).Return(nil).Once()
+
+	comment, err := commentsService.CreateComment(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ModerationStatusSpam, comment.Status)
+
+	mockCommentsRepo.AssertExpectations(t)
+	mockPostsRepo.AssertExpectations(t)
+	mockModerationRepo.AssertExpectations(t)
+}
+
+func TestCommentService_EditComment_WithModeration(t *testing.T) {
+	ctx := context.Background()
+	rules := domain.ModerationRules{
+		BlockedWords: []string{"casino"},
+		MaxLinks:     -1,
+		RateLimit:    1,
+		RateWindow:   time.Hour,
+	}
+	id := uuid.New()
+	existing := func() *domain.Comment {
+		return &domain.Comment{
+			ID:      id.String(),
+			PostID:  uuid.New().String(),
+			UserID:  uuid.New().String(),
+			Status:  domain.ModerationStatusApproved,
+			Body:    "nice post",
+			Version: 2,
+		}
+	}
+	heldFromApproved := mock.MatchedBy(func(a *domain.ModerationAudit) bool {
+		return a.CommentID == id.String() && a.FromStatus == domain.ModerationStatusApproved && a.ToStatus == domain.ModerationStatusSpam
+	})
+
+	t.Run("An update into spam is held back", func(t *testing.T) {
+		mockCommentsRepo := new(mocks.CommentRepository)
+		mockModerationRepo := new(mocks.ModerationRepository)
+		publisher := &recordingPublisher{}
+		commentsService := service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository)).
+			WithModeration(service.NewModerationService(mockModerationRepo, rules)).
+			WithEvents(publisher)
+
+		mockCommentsRepo.On("GetComment", mock.Anything, id).Return(existing(), nil).Once()
+		mockCommentsRepo.On("UpdateComment", mock.Anything, id, mock.MatchedBy(func(c *domain.Comment) bool {
+			return c.Status == domain.ModerationStatusSpam && c.ModerationReason != ""
+		})).Return(&domain.Comment{Version: 3}, nil).Once()
+		mockModerationRepo.On("RecordAudit", mock.Anything, heldFromApproved).Return(nil).Once()
+
+		comment, err := commentsService.UpdateComment(ctx, id, &domain.Comment{Body: "best casino in town"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ModerationStatusSpam, comment.Status)
+		assert.Empty(t, publisher.events)
+		mockCommentsRepo.AssertExpectations(t)
+		mockModerationRepo.AssertExpectations(t)
+	})
+
+	t.Run("A patch into spam is held back without counting toward the rate limit", func(t *testing.T) {
+		mockCommentsRepo := new(mocks.CommentRepository)
+		mockModerationRepo := new(mocks.ModerationRepository)
+		commentsService := service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository)).
+			WithModeration(service.NewModerationService(mockModerationRepo, rules))
+
+		body := "best casino in town"
+		patched := existing()
+		patched.Body, patched.Status = body, domain.ModerationStatusSpam
+		mockCommentsRepo.On("GetComment", mock.Anything, id).Return(existing(), nil).Once()
+		mockCommentsRepo.On("PatchComment", mock.Anything, id, 2, mock.MatchedBy(func(p *domain.CommentPatch) bool {
+			return p.Status != nil && *p.Status == domain.ModerationStatusSpam && p.ModerationReason != nil
+		})).Return(patched, nil).Once()
+		mockModerationRepo.On("RecordAudit", mock.Anything, heldFromApproved).Return(nil).Once()
+
+		comment, err := commentsService.PatchComment(ctx, id, &domain.CommentPatch{Body: &body})
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ModerationStatusSpam, comment.Status)
+		mockModerationRepo.AssertNotCalled(t, "CountCommentsSince", mock.Anything, mock.Anything, mock.Anything)
+		mockCommentsRepo.AssertExpectations(t)
+		mockModerationRepo.AssertExpectations(t)
+	})
+
+	t.Run("A batch update into spam is held back", func(t *testing.T) {
+		mockCommentsRepo := new(mocks.CommentRepository)
+		mockModerationRepo := new(mocks.ModerationRepository)
+		commentsService := service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository)).
+			WithModeration(service.NewModerationService(mockModerationRepo, rules))
+
+		held := existing()
+		held.Status = domain.ModerationStatusSpam
+		mockCommentsRepo.On("GetComment", mock.Anything, id).Return(existing(), nil).Once()
+		mockCommentsRepo.On("ApplyCommentBatch", mock.Anything, mock.MatchedBy(func(items []domain.CommentBatchItem) bool {
+			return len(items) == 1 && items[0].Status == domain.ModerationStatusSpam
+		}), true).Return([]domain.BatchItemResult[domain.Comment]{
+			{Index: 0, Op: domain.BatchOpUpdate, ID: id.String(), OK: true, Data: held},
+		}, nil).Once()
+		mockModerationRepo.On("RecordAudit", mock.Anything, heldFromApproved).Return(nil).Once()
+
+		result, err := commentsService.BatchComments(ctx, &domain.BatchRequest[domain.CommentBatchItem]{
+			Items: []domain.CommentBatchItem{{Op: domain.BatchOpUpdate, ID: id.String(), Body: "best casino in town"}},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result.Succeeded)
+		mockCommentsRepo.AssertExpectations(t)
+		mockModerationRepo.AssertExpectations(t)
+	})
+}
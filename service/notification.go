@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/google/uuid"
+)
+
+type NotificationRepository interface {
+	CreateNotifications(ctx context.Context, notifications []domain.Notification) error
+	GetNotifications(ctx context.Context, userID uuid.UUID, filter *domain.NotificationFilter) ([]domain.Notification, int, error)
+	CountUnread(ctx context.Context, userID uuid.UUID) (int, error)
+	MarkRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int64, error)
+	MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error)
+	GetPreferences(ctx context.Context, userIDs []uuid.UUID) (map[string]domain.NotificationPreferences, error)
+	SavePreferences(ctx context.Context, userID uuid.UUID, prefs *domain.NotificationPreferences) error
+	GetUserIDsByUsername(ctx context.Context, usernames []string) (map[string]string, error)
+	GetPostAuthor(ctx context.Context, postID uuid.UUID) (string, error)
+	GetCommentAuthor(ctx context.Context, commentID uuid.UUID) (string, error)
+}
+
+type NotificationService struct {
+	notificationRepo NotificationRepository
+}
+
+func NewNotificationService(r NotificationRepository) *NotificationService {
+	return &NotificationService{
+		notificationRepo: r,
+	}
+}
+
+// mentionPattern matches @username where the @ does not continue a word, so
+// email addresses are not taken for mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_]{3,30})\b`)
+
+// parseMentions returns the distinct lower cased usernames mentioned in body
+func parseMentions(body string) []string {
+	var names []string
+	seen := make(map[string]struct{})
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		name := strings.ToLower(match[1])
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	return names
+}
+
+// HandleEvent turns a domain event into notifications. Every recipient gets
+// at most one notification per event, a mention outranks a reply which
+// outranks a comment on their post. Actors are never notified of their own
+// actions and recipients' preferences are respected.
+func (ns *NotificationService) HandleEvent(ctx context.Context, event domain.Event) error {
+	recipients := make(map[string]domain.NotificationType)
+	var order []string
+	add := func(userID string, t domain.NotificationType) {
+		if userID == "" || userID == event.ActorID {
+			return
+		}
+		if _, ok := recipients[userID]; !ok {
+			order = append(order, userID)
+			recipients[userID] = t
+		}
+	}
+
+	if names := parseMentions(event.Body); len(names) > 0 {
+		ids, err := ns.notificationRepo.GetUserIDsByUsername(ctx, names)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			add(ids[name], domain.NotificationMention)
+		}
+	}
+
+	if event.Type == domain.EventCommentCreated {
+		if parentID, err := uuid.Parse(event.ParentCommentID); err == nil {
+			author, err := ns.notificationRepo.GetCommentAuthor(ctx, parentID)
+			if err != nil {
+				return err
+			}
+			add(author, domain.NotificationReply)
+		}
+		if postID, err := uuid.Parse(event.PostID); err == nil {
+			author, err := ns.notificationRepo.GetPostAuthor(ctx, postID)
+			if err != nil {
+				return err
+			}
+			add(author, domain.NotificationComment)
+		}
+	}
+
+	if len(order) == 0 {
+		return nil
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(order))
+	for _, id := range order {
+		if parsed, err := uuid.Parse(id); err == nil {
+			userIDs = append(userIDs, parsed)
+		}
+	}
+	prefs, err := ns.notificationRepo.GetPreferences(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+
+	notifications := make([]domain.Notification, 0, len(order))
+	for _, userID := range order {
+		t := recipients[userID]
+		pref, ok := prefs[userID]
+		if !ok {
+			pref = domain.DefaultNotificationPreferences()
+		}
+		if !pref.Allows(t) {
+			continue
+		}
+		notifications = append(notifications, newNotification(event, userID, t))
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+	return ns.notificationRepo.CreateNotifications(ctx, notifications)
+}
+
+func newNotification(event domain.Event, userID string, t domain.NotificationType) domain.Notification {
+	n := domain.Notification{
+		UserID: userID,
+		Type:   t,
+	}
+	if event.ActorID != "" {
+		actor := event.ActorID
+		n.ActorID = &actor
+	}
+	if event.PostID != "" {
+		postID := event.PostID
+		n.PostID = &postID
+	}
+	if event.CommentID != "" {
+		commentID := event.CommentID
+		n.CommentID = &commentID
+	}
+
+	switch t {
+	case domain.NotificationMention:
+		if event.Type == domain.EventPostCreated {
+			n.Message = "You were mentioned in a post"
+		} else {
+			n.Message = "You were mentioned in a comment"
+		}
+	case domain.NotificationReply:
+		n.Message = "Someone replied to your comment"
+	case domain.NotificationComment:
+		n.Message = "Someone commented on your post"
+	}
+	return n
+}
+
+// GetNotifications returns a page of the user's inbox, newest first
+func (ns *NotificationService) GetNotifications(
+	ctx context.Context,
+	userID uuid.UUID,
+	filter *domain.NotificationFilter,
+) (*domain.NotificationPage, error) {
+	if filter == nil {
+		filter = &domain.NotificationFilter{}
+	}
+	filter.Normalize()
+
+	notifications, total, err := ns.notificationRepo.GetNotifications(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := ns.notificationRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if notifications == nil {
+		notifications = []domain.Notification{}
+	}
+
+	return &domain.NotificationPage{
+		Data: notifications,
+		Pagination: domain.PaginationInfo{
+			Page:       filter.Page,
+			Limit:      filter.Limit,
+			Total:      total,
+			TotalPages: (total + filter.Limit - 1) / filter.Limit,
+		},
+		UnreadCount: unread,
+	}, nil
+}
+
+func (ns *NotificationService) CountUnread(ctx context.Context, userID uuid.UUID) (int, error) {
+	return ns.notificationRepo.CountUnread(ctx, userID)
+}
+
+// MarkRead marks the given notifications of the user as read, ids of other
+// users' notifications are ignored. The number of notifications changed is
+// returned.
+func (ns *NotificationService) MarkRead(ctx context.Context, userID uuid.UUID, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, domain.ErrBadParamInput
+	}
+
+	parsed := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		u, err := uuid.Parse(id)
+		if err != nil {
+			return 0, domain.ErrBadParamInput
+		}
+		parsed = append(parsed, u)
+	}
+	return ns.notificationRepo.MarkRead(ctx, userID, parsed)
+}
+
+func (ns *NotificationService) MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return ns.notificationRepo.MarkAllRead(ctx, userID)
+}
+
+func (ns *NotificationService) GetPreferences(ctx context.Context, userID uuid.UUID) (*domain.NotificationPreferences, error) {
+	prefs, err := ns.notificationRepo.GetPreferences(ctx, []uuid.UUID{userID})
+	if err != nil {
+		return nil, err
+	}
+	pref, ok := prefs[userID.String()]
+	if !ok {
+		pref = domain.DefaultNotificationPreferences()
+	}
+	return &pref, nil
+}
+
+func (ns *NotificationService) UpdatePreferences(
+	ctx context.Context,
+	userID uuid.UUID,
+	prefs *domain.NotificationPreferences,
+) (*domain.NotificationPreferences, error) {
+	if err := ns.notificationRepo.SavePreferences(ctx, userID, prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
@@ -0,0 +1,142 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/service"
+	"github.com/edwinjordan/MajooTest-Golang/service/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type recordingPublisher struct {
+	events []domain.Event
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event domain.Event) {
+	p.events = append(p.events, event)
+}
+
+func TestNotificationService_HandleEvent(t *testing.T) {
+	ctx := context.Background()
+	actor := uuid.New().String()
+	postAuthor := uuid.New()
+	parentAuthor := uuid.New()
+	mentioned := uuid.New()
+	postID := uuid.New()
+	parentID := uuid.New()
+
+	t.Run("Notifies mentioned users, the parent author and the post author once each", func(t *testing.T) {
+		mockNotificationRepo := new(mocks.NotificationRepository)
+		notificationService := service.NewNotificationService(mockNotificationRepo)
+
+		event := domain.Event{
+			Type:            domain.EventCommentCreated,
+			ActorID:         actor,
+			PostID:          postID.String(),
+			CommentID:       uuid.New().String(),
+			ParentCommentID: parentID.String(),
+			Body:            "Thanks @Carol and @carol, mail me at me@example.com cc @nobody",
+		}
+
+		mockNotificationRepo.On("GetUserIDsByUsername", mock.Anything, []string{"carol", "nobody"}).
+			Return(map[string]string{"carol": mentioned.String()}, nil).Once()
+		mockNotificationRepo.On("GetCommentAuthor", mock.Anything, parentID).Return(parentAuthor.String(), nil).Once()
+		mockNotificationRepo.On("GetPostAuthor", mock.Anything, postID).Return(postAuthor.String(), nil).Once()
+		mockNotificationRepo.On("GetPreferences", mock.Anything, []uuid.UUID{mentioned, parentAuthor, postAuthor}).
+			Return(map[string]domain.NotificationPreferences{}, nil).Once()
+		mockNotificationRepo.On("CreateNotifications", mock.Anything, mock.MatchedBy(func(n []domain.Notification) bool {
+			return len(n) == 3 &&
+				n[0].UserID == mentioned.String() && n[0].Type == domain.NotificationMention &&
+				n[1].UserID == parentAuthor.String() && n[1].Type == domain.NotificationReply &&
+				n[2].UserID == postAuthor.String() && n[2].Type == domain.NotificationComment
+		})).Return(nil).Once()
+
+		err := notificationService.HandleEvent(ctx, event)
+
+		assert.NoError(t, err)
+		mockNotificationRepo.AssertExpectations(t)
+	})
+
+	t.Run("Skips the actor and users who opted out", func(t *testing.T) {
+		mockNotificationRepo := new(mocks.NotificationRepository)
+		notificationService := service.NewNotificationService(mockNotificationRepo)
+
+		event := domain.Event{
+			Type:    domain.EventCommentCreated,
+			ActorID: actor,
+			PostID:  postID.String(),
+			Body:    "first!",
+		}
+
+		prefs := domain.DefaultNotificationPreferences()
+		prefs.OnComment = false
+		mockNotificationRepo.On("GetPostAuthor", mock.Anything, postID).Return(postAuthor.String(), nil).Once()
+		mockNotificationRepo.On("GetPreferences", mock.Anything, []uuid.UUID{postAuthor}).
+			Return(map[string]domain.NotificationPreferences{postAuthor.String(): prefs}, nil).Once()
+
+		err := notificationService.HandleEvent(ctx, event)
+
+		assert.NoError(t, err)
+		mockNotificationRepo.AssertNotCalled(t, "CreateNotifications", mock.Anything, mock.Anything)
+		mockNotificationRepo.AssertExpectations(t)
+
+		selfEvent := domain.Event{Type: domain.EventCommentCreated, ActorID: postAuthor.String(), PostID: postID.String()}
+		mockNotificationRepo.On("GetPostAuthor", mock.Anything, postID).Return(postAuthor.String(), nil).Once()
+
+		err = notificationService.HandleEvent(ctx, selfEvent)
+
+		assert.NoError(t, err)
+		mockNotificationRepo.AssertNotCalled(t, "CreateNotifications", mock.Anything, mock.Anything)
+	})
+}
+
+func TestNotificationService_GetNotifications(t *testing.T) {
+	mockNotificationRepo := new(mocks.NotificationRepository)
+	notificationService := service.NewNotificationService(mockNotificationRepo)
+
+	userID := uuid.New()
+	notifications := []domain.Notification{{ID: uuid.New().String(), UserID: userID.String(), Type: domain.NotificationMention}}
+
+	mockNotificationRepo.On("GetNotifications", mock.Anything, userID, &domain.NotificationFilter{Page: 1, Limit: 20}).
+		Return(notifications, 1, nil).Once()
+	mockNotificationRepo.On("CountUnread", mock.Anything, userID).Return(1, nil).Once()
+
+	page, err := notificationService.GetNotifications(context.Background(), userID, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, notifications, page.Data)
+	assert.Equal(t, 1, page.UnreadCount)
+	assert.Equal(t, 1, page.Pagination.TotalPages)
+
+	mockNotificationRepo.AssertExpectations(t)
+}
+
+func TestCommentService_CreateComment_PublishesEvent(t *testing.T) {
+	mockCommentsRepo := new(mocks.CommentRepository)
+	mockPostsRepo := new(mocks.PostsRepository)
+	publisher := &recordingPublisher{}
+	commentsService := service.NewCommentService(mockCommentsRepo, mockPostsRepo).WithEvents(publisher)
+
+	postID := uuid.New()
+	req := &domain.CreateCommentRequest{PostID: postID.String(), UserID: uuid.New().String(), Body: "hi @dave"}
+	created := &domain.Comment{ID: uuid.New().String(), PostID: postID.String(), UserID: req.UserID, Body: req.Body, Status: domain.ModerationStatusApproved}
+
+	mockPostsRepo.On("PostExists", mock.Anything, postID).Return(true, nil).Once()
+	mockCommentsRepo.On("CreateComment", mock.Anything, req).Return(created, nil).Once()
+
+	_, err := commentsService.CreateComment(context.Background(), req)
+
+	assert.NoError(t, err)
+	if assert.Len(t, publisher.events, 1) {
+		assert.Equal(t, domain.EventCommentCreated, publisher.events[0].Type)
+		assert.Equal(t, created.ID, publisher.events[0].CommentID)
+		assert.Equal(t, req.Body, publisher.events[0].Body)
+	}
+
+	mockCommentsRepo.AssertExpectations(t)
+	mockPostsRepo.AssertExpectations(t)
+}
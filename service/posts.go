@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/render"
 	"github.com/google/uuid"
 )
 
@@ -12,11 +14,17 @@ type PostsRepository interface {
 	GetPostsList(ctx context.Context, filter *domain.PostsFilter) ([]domain.Posts, error)
 	GetPosts(ctx context.Context, id uuid.UUID) (*domain.Posts, error)
 	UpdatePosts(ctx context.Context, id uuid.UUID, posts *domain.Posts) (*domain.Posts, error)
-	DeletePosts(ctx context.Context, id uuid.UUID) error
+	PatchPosts(ctx context.Context, id uuid.UUID, version int, patch *domain.PostsPatch) (*domain.Posts, error)
+	DeletePosts(ctx context.Context, id uuid.UUID, version int) error
+	PostExists(ctx context.Context, id uuid.UUID) (bool, error)
+	RestorePosts(ctx context.Context, id uuid.UUID) (*domain.Posts, int, error)
+	ApplyPostsBatch(ctx context.Context, items []domain.PostsBatchItem, atomic bool) ([]domain.BatchItemResult[domain.Posts], error)
 }
 
 type PostsService struct {
-	postsRepo PostsRepository
+	postsRepo    PostsRepository
+	reactionRepo ReactionRepository
+	events       EventPublisher
 }
 
 func NewPostsService(n PostsRepository) *PostsService {
@@ -25,14 +33,43 @@ func NewPostsService(n PostsRepository) *PostsService {
 	}
 }
 
+// WithReactions makes read operations include reaction counts for the caller
+func (ns *PostsService) WithReactions(r ReactionRepository) *PostsService {
+	ns.reactionRepo = r
+	return ns
+}
+
+// WithEvents publishes an event for every post created, updated or deleted
+func (ns *PostsService) WithEvents(p EventPublisher) *PostsService {
+	ns.events = p
+	return ns
+}
+
 func (ns *PostsService) CreatePosts(
 	ctx context.Context,
 	u *domain.CreatePostsRequest,
 ) (*domain.Posts, error) {
+	u.ContentFormat = u.ContentFormat.OrDefault()
+	contentHTML, err := render.Content(u.ContentFormat, u.Content)
+	if err != nil {
+		return nil, err
+	}
+	u.ContentHTML = contentHTML
+
 	createdPosts, err := ns.postsRepo.CreatePosts(ctx, u)
 	if err != nil {
 		return nil, err
 	}
+
+	if ns.events != nil {
+		ns.events.Publish(ctx, domain.Event{
+			Type:       domain.EventPostCreated,
+			ActorID:    u.UserID,
+			PostID:     createdPosts.ID,
+			Body:       createdPosts.Content,
+			OccurredAt: createdPosts.CreatedAt,
+		})
+	}
 	return createdPosts, nil
 }
 
@@ -44,6 +81,17 @@ func (us *PostsService) GetPosts(
 	if err != nil {
 		return nil, err
 	}
+	if posts != nil {
+		if err := fillPostsHTML(posts); err != nil {
+			return nil, err
+		}
+		err = attachReactions(ctx, us.reactionRepo, domain.ReactionTargetPost, []string{posts.ID}, func(_ int, summary *domain.ReactionSummary) {
+			posts.Reactions = summary
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 	return posts, nil
 }
 
@@ -61,18 +109,98 @@ func (us *PostsService) UpdatePosts(
 	if existing == nil {
 		return nil, domain.ErrUserNotFound
 	}
+	if err := checkVersion(ctx, existing.Version, u.Version); err != nil {
+		return nil, err
+	}
 
 	existing.Title = u.Title
 	existing.Slug = u.Slug
 	existing.Content = u.Content
+	if u.ContentFormat != "" {
+		existing.ContentFormat = u.ContentFormat
+	}
+	existing.ContentFormat = existing.ContentFormat.OrDefault()
+	existing.ContentHTML, err = render.Content(existing.ContentFormat, existing.Content)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err = us.postsRepo.UpdatePosts(ctx, id, existing)
+	updated, err := us.postsRepo.UpdatePosts(ctx, id, existing)
 	if err != nil {
 		return nil, err
 	}
+	if updated != nil {
+		existing.Version = updated.Version
+		existing.UpdatedAt = updated.UpdatedAt
+	}
+
+	if us.events != nil {
+		us.events.Publish(ctx, domain.Event{
+			Type:       domain.EventPostUpdated,
+			ActorID:    actorID(ctx),
+			PostID:     existing.ID,
+			Body:       existing.Content,
+			OccurredAt: time.Now(),
+		})
+	}
 	return existing, nil
 }
 
+// PatchPosts applies a merge-patch to a post, re-rendering the content when
+// it or its format changes. An empty patch returns the post unchanged.
+func (us *PostsService) PatchPosts(
+	ctx context.Context,
+	id uuid.UUID,
+	patch *domain.PostsPatch,
+) (*domain.Posts, error) {
+
+	existing, err := us.postsRepo.GetPosts(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, domain.ErrUserNotFound
+	}
+	if err := checkVersion(ctx, existing.Version, patch.Version); err != nil {
+		return nil, err
+	}
+	if patch.IsEmpty() {
+		return existing, nil
+	}
+
+	if patch.Content != nil || patch.ContentFormat != nil {
+		content, format := existing.Content, existing.ContentFormat
+		if patch.Content != nil {
+			content = *patch.Content
+		}
+		if patch.ContentFormat != nil {
+			format = *patch.ContentFormat
+		}
+		html, err := render.Content(format, content)
+		if err != nil {
+			return nil, err
+		}
+		patch.ContentHTML = &html
+	}
+
+	patched, err := us.postsRepo.PatchPosts(ctx, id, existing.Version, patch)
+	if err != nil {
+		return nil, err
+	}
+	patched.CommentCount = existing.CommentCount
+
+	if us.events != nil {
+		us.events.Publish(ctx, domain.Event{
+			Type:       domain.EventPostUpdated,
+			ActorID:    actorID(ctx),
+			PostID:     patched.ID,
+			Body:       patched.Content,
+			OccurredAt: time.Now(),
+		})
+	}
+	return patched, nil
+}
+
 func (us *PostsService) DeletePosts(
 	ctx context.Context,
 	id uuid.UUID,
@@ -85,18 +213,120 @@ func (us *PostsService) DeletePosts(
 	if posts == nil {
 		return domain.ErrUserNotFound
 	}
+	if err := domain.CheckVersion(ctx, posts.Version); err != nil {
+		return err
+	}
 
-	err = us.postsRepo.DeletePosts(ctx, id)
+	err = us.postsRepo.DeletePosts(ctx, id, posts.Version)
 	if err != nil {
 		return err
 	}
+
+	if us.events != nil {
+		us.events.Publish(ctx, domain.Event{
+			Type:       domain.EventPostDeleted,
+			ActorID:    actorID(ctx),
+			PostID:     posts.ID,
+			OccurredAt: time.Now(),
+		})
+	}
 	return nil
 }
 
+// RestorePosts undeletes a soft-deleted post together with the comments that
+// were deleted with it
+func (us *PostsService) RestorePosts(ctx context.Context, id uuid.UUID) (*domain.RestoredPost, error) {
+	post, comments, err := us.postsRepo.RestorePosts(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.RestoredPost{Posts: *post, RestoredComments: comments}, nil
+}
+
 func (us *PostsService) GetPostsList(ctx context.Context, filter *domain.PostsFilter) ([]domain.Posts, error) {
 	postsList, err := us.postsRepo.GetPostsList(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
+	ids := make([]string, len(postsList))
+	for i := range postsList {
+		if err := fillPostsHTML(&postsList[i]); err != nil {
+			return nil, err
+		}
+		ids[i] = postsList[i].ID
+	}
+	err = attachReactions(ctx, us.reactionRepo, domain.ReactionTargetPost, ids, func(i int, summary *domain.ReactionSummary) {
+		postsList[i].Reactions = summary
+	})
+	if err != nil {
+		return nil, err
+	}
 	return postsList, nil
 }
+
+// fillPostsHTML renders posts stored before content_html was persisted
+func fillPostsHTML(p *domain.Posts) error {
+	if p.ContentHTML != "" || p.Content == "" {
+		return nil
+	}
+	contentHTML, err := render.Content(p.ContentFormat, p.Content)
+	if err != nil {
+		return err
+	}
+	p.ContentHTML = contentHTML
+	return nil
+}
+
+// BatchPosts creates, updates and deletes posts in bulk, see runBatch.
+// Created posts belong to the UserID set on their item.
+func (us *PostsService) BatchPosts(
+	ctx context.Context,
+	req *domain.BatchRequest[domain.PostsBatchItem],
+) (*domain.BatchResult[domain.Posts], error) {
+	describe := func(item *domain.PostsBatchItem) (domain.BatchOp, string) {
+		return item.Op, item.ID
+	}
+	prepare := func(ctx context.Context, item *domain.PostsBatchItem) error {
+		if err := checkBatchItem(item.Op, item.ID); err != nil {
+			return err
+		}
+		if item.Op == domain.BatchOpDelete {
+			return nil
+		}
+		if err := requireFields("title", item.Title, "content", item.Content); err != nil {
+			return err
+		}
+
+		item.ContentFormat = item.ContentFormat.OrDefault()
+		contentHTML, err := render.Content(item.ContentFormat, item.Content)
+		if err != nil {
+			return err
+		}
+		item.ContentHTML = contentHTML
+		return nil
+	}
+
+	result, err := runBatch(ctx, req, describe, prepare, us.postsRepo.ApplyPostsBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	if us.events != nil {
+		for _, item := range result.Items {
+			if !item.OK {
+				continue
+			}
+			event := domain.Event{ActorID: actorID(ctx), PostID: item.ID, OccurredAt: time.Now()}
+			switch item.Op {
+			case domain.BatchOpCreate:
+				event.Type, event.Body, event.OccurredAt = domain.EventPostCreated, item.Data.Content, item.Data.CreatedAt
+			case domain.BatchOpUpdate:
+				event.Type, event.Body = domain.EventPostUpdated, item.Data.Content
+			case domain.BatchOpDelete:
+				event.Type = domain.EventPostDeleted
+			}
+			us.events.Publish(ctx, event)
+		}
+	}
+	return result, nil
+}
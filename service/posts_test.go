@@ -65,6 +65,68 @@ func TestPostsService_CreatePosts(t *testing.T) {
 	})
 }
 
+func TestPostsService_CreatePosts_RendersContent(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Renders markdown and strips unsafe markup", func(t *testing.T) {
+		mockPostsRepo := new(mocks.PostsRepository)
+		postsService := service.NewPostsService(mockPostsRepo)
+
+		req := &domain.CreatePostsRequest{
+			Title:         "Markdown Post",
+			Content:       "**bold** <script>alert(1)</script> [link](javascript:alert(1))",
+			ContentFormat: domain.ContentFormatMarkdown,
+		}
+		mockPostsRepo.On("CreatePosts", mock.Anything, req).Return(&domain.Posts{ID: uuid.New().String()}, nil).Once()
+
+		_, err := postsService.CreatePosts(ctx, req)
+
+		assert.NoError(t, err)
+		assert.Contains(t, req.ContentHTML, "<strong>bold</strong>")
+		assert.NotContains(t, req.ContentHTML, "<script>")
+		assert.NotContains(t, req.ContentHTML, "javascript:")
+
+		mockPostsRepo.AssertExpectations(t)
+	})
+
+	t.Run("Escapes plain text content", func(t *testing.T) {
+		mockPostsRepo := new(mocks.PostsRepository)
+		postsService := service.NewPostsService(mockPostsRepo)
+
+		req := &domain.CreatePostsRequest{
+			Title:   "Plain Post",
+			Content: "<b>not bold</b>",
+		}
+		mockPostsRepo.On("CreatePosts", mock.Anything, req).Return(&domain.Posts{ID: uuid.New().String()}, nil).Once()
+
+		_, err := postsService.CreatePosts(ctx, req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.ContentFormatPlain, req.ContentFormat)
+		assert.Equal(t, "<p>&lt;b&gt;not bold&lt;/b&gt;</p>\n", req.ContentHTML)
+
+		mockPostsRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects unknown content format", func(t *testing.T) {
+		mockPostsRepo := new(mocks.PostsRepository)
+		postsService := service.NewPostsService(mockPostsRepo)
+
+		req := &domain.CreatePostsRequest{
+			Title:         "Weird Post",
+			Content:       "content",
+			ContentFormat: "rtf",
+		}
+
+		posts, err := postsService.CreatePosts(ctx, req)
+
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+		assert.Nil(t, posts)
+
+		mockPostsRepo.AssertNotCalled(t, "CreatePosts", mock.Anything, mock.Anything)
+	})
+}
+
 func TestPostsService_GetPosts(t *testing.T) {
 	mockPostsRepo := new(mocks.PostsRepository)
 	postsService := service.NewPostsService(mockPostsRepo)
@@ -146,10 +208,12 @@ func TestPostsService_UpdatePosts(t *testing.T) {
 		mockPostsRepo.On("GetPosts", mock.Anything, postsID).Return(existingPosts, nil).Once()
 
 		expectedUpdatedPosts := &domain.Posts{
-			ID:      postsID.String(),
-			Title:   updateReq.Title,
-			Content: updateReq.Content,
-			Slug:    updateReq.Slug,
+			ID:            postsID.String(),
+			Title:         updateReq.Title,
+			Content:       updateReq.Content,
+			ContentFormat: domain.ContentFormatPlain,
+			ContentHTML:   "<p>new content</p>\n",
+			Slug:          updateReq.Slug,
 		}
 		mockPostsRepo.On("UpdatePosts", mock.Anything, postsID, expectedUpdatedPosts).Return(expectedUpdatedPosts, nil).Once()
 
@@ -202,10 +266,12 @@ func TestPostsService_UpdatePosts(t *testing.T) {
 
 		repoErr := errors.New("update posts repo error")
 		expectedUpdatedPosts := &domain.Posts{
-			ID:      postsID.String(),
-			Title:   updateReq.Title,
-			Content: updateReq.Content,
-			Slug:    updateReq.Slug,
+			ID:            postsID.String(),
+			Title:         updateReq.Title,
+			Content:       updateReq.Content,
+			ContentFormat: domain.ContentFormatPlain,
+			ContentHTML:   "<p>new content</p>\n",
+			Slug:          updateReq.Slug,
 		}
 		mockPostsRepo.On("UpdatePosts", mock.Anything, postsID, expectedUpdatedPosts).Return(nil, repoErr).Once()
 
@@ -217,6 +283,47 @@ func TestPostsService_UpdatePosts(t *testing.T) {
 
 		mockPostsRepo.AssertExpectations(t)
 	})
+
+	t.Run("Returns the new version after updating", func(t *testing.T) {
+		mockPostsRepo = new(mocks.PostsRepository)
+		postsService = service.NewPostsService(mockPostsRepo)
+
+		current := *existingPosts
+		current.Version = 3
+		mockPostsRepo.On("GetPosts", mock.Anything, postsID).Return(&current, nil).Once()
+		mockPostsRepo.On("UpdatePosts", mock.Anything, postsID, mock.MatchedBy(func(p *domain.Posts) bool {
+			return p.Version == 3
+		})).Return(&domain.Posts{ID: postsID.String(), Version: 4}, nil).Once()
+
+		posts, err := postsService.UpdatePosts(domain.WithExpectedVersion(ctx, 3), postsID, updateReq)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, posts.Version)
+
+		mockPostsRepo.AssertExpectations(t)
+	})
+
+	t.Run("Returns ErrPreconditionFailed if the expected version is stale", func(t *testing.T) {
+		mockPostsRepo = new(mocks.PostsRepository)
+		postsService = service.NewPostsService(mockPostsRepo)
+
+		current := *existingPosts
+		current.Version = 3
+		mockPostsRepo.On("GetPosts", mock.Anything, postsID).Return(&current, nil).Twice()
+
+		posts, err := postsService.UpdatePosts(domain.WithExpectedVersion(ctx, 2), postsID, updateReq)
+		assert.ErrorIs(t, err, domain.ErrPreconditionFailed)
+		assert.Nil(t, posts)
+
+		stale := *updateReq
+		stale.Version = 2
+		posts, err = postsService.UpdatePosts(ctx, postsID, &stale)
+		assert.ErrorIs(t, err, domain.ErrPreconditionFailed)
+		assert.Nil(t, posts)
+
+		mockPostsRepo.AssertNotCalled(t, "UpdatePosts", mock.Anything, mock.Anything, mock.Anything)
+		mockPostsRepo.AssertExpectations(t)
+	})
 }
 
 func TestPostService_DeletePost(t *testing.T) {
@@ -230,11 +337,12 @@ func TestPostService_DeletePost(t *testing.T) {
 		Title:   "User to delete",
 		Content: "delete@example.com",
 		Slug:    "user-to-delete",
+		Version: 3,
 	}
 
 	t.Run("Successfully deletes a post", func(t *testing.T) {
 		mockPostsRepo.On("GetPosts", mock.Anything, postsID).Return(existingPosts, nil).Once()
-		mockPostsRepo.On("DeletePosts", mock.Anything, postsID).Return(nil).Once()
+		mockPostsRepo.On("DeletePosts", mock.Anything, postsID, existingPosts.Version).Return(nil).Once()
 
 		err := postsService.DeletePosts(ctx, postsID)
 
@@ -274,7 +382,7 @@ func TestPostService_DeletePost(t *testing.T) {
 
 		mockPostsRepo.On("GetPosts", mock.Anything, postsID).Return(existingPosts, nil).Once()
 		repoErr := errors.New("delete posts repo error")
-		mockPostsRepo.On("DeletePosts", mock.Anything, postsID).Return(repoErr).Once()
+		mockPostsRepo.On("DeletePosts", mock.Anything, postsID, existingPosts.Version).Return(repoErr).Once()
 
 		err := postsService.DeletePosts(ctx, postsID)
 
@@ -282,6 +390,19 @@ func TestPostService_DeletePost(t *testing.T) {
 		assert.Equal(t, repoErr, err)
 		mockPostsRepo.AssertExpectations(t)
 	})
+
+	t.Run("Returns ErrPreconditionFailed if the post changes before it is deleted", func(t *testing.T) {
+		mockPostsRepo = new(mocks.PostsRepository)
+		postsService = service.NewPostsService(mockPostsRepo)
+
+		mockPostsRepo.On("GetPosts", mock.Anything, postsID).Return(existingPosts, nil).Once()
+		mockPostsRepo.On("DeletePosts", mock.Anything, postsID, existingPosts.Version).Return(domain.ErrPreconditionFailed).Once()
+
+		err := postsService.DeletePosts(ctx, postsID)
+
+		assert.ErrorIs(t, err, domain.ErrPreconditionFailed)
+		mockPostsRepo.AssertExpectations(t)
+	})
 }
 
 func TestPostsService_DeletePost(t *testing.T) {
@@ -295,11 +416,12 @@ func TestPostsService_DeletePost(t *testing.T) {
 		Title:   "User to delete",
 		Content: "delete@example.com",
 		Slug:    "user-to-delete",
+		Version: 3,
 	}
 
 	t.Run("Successfully deletes a posts", func(t *testing.T) {
 		mockPostsRepo.On("GetPosts", mock.Anything, postsID).Return(existingPosts, nil).Once()
-		mockPostsRepo.On("DeletePosts", mock.Anything, postsID).Return(nil).Once()
+		mockPostsRepo.On("DeletePosts", mock.Anything, postsID, existingPosts.Version).Return(nil).Once()
 
 		err := postsService.DeletePosts(ctx, postsID)
 
@@ -339,7 +461,7 @@ func TestPostsService_DeletePost(t *testing.T) {
 
 		mockPostsRepo.On("GetPosts", mock.Anything, postsID).Return(existingPosts, nil).Once()
 		repoErr := errors.New("delete posts repo error")
-		mockPostsRepo.On("DeletePosts", mock.Anything, postsID).Return(repoErr).Once()
+		mockPostsRepo.On("DeletePosts", mock.Anything, postsID, existingPosts.Version).Return(repoErr).Once()
 
 		err := postsService.DeletePosts(ctx, postsID)
 
@@ -406,3 +528,227 @@ func TestPostsService_GetPostsList(t *testing.T) {
 		mockPostsRepo.AssertExpectations(t)
 	})
 }
+
+func TestPostsService_PublishesChanges(t *testing.T) {
+	mockPostsRepo := new(mocks.PostsRepository)
+	publisher := &recordingPublisher{}
+	postsService := service.NewPostsService(mockPostsRepo).WithEvents(publisher)
+
+	id := uuid.New()
+	callerID := uuid.New().String()
+	ctx := domain.WithCaller(context.Background(), &domain.Caller{ID: callerID})
+	existing := &domain.Posts{ID: id.String(), Title: "Old", Content: "old"}
+
+	mockPostsRepo.On("GetPosts", mock.Anything, id).Return(existing, nil).Twice()
+	mockPostsRepo.On("UpdatePosts", mock.Anything, id, mock.Anything).Return(existing, nil).Once()
+	mockPostsRepo.On("DeletePosts", mock.Anything, id, existing.Version).Return(nil).Once()
+
+	_, err := postsService.UpdatePosts(ctx, id, &domain.Posts{Title: "New", Content: "new"})
+	assert.NoError(t, err)
+	err = postsService.DeletePosts(ctx, id)
+	assert.NoError(t, err)
+
+	if assert.Len(t, publisher.events, 2) {
+		assert.Equal(t, domain.EventPostUpdated, publisher.events[0].Type)
+		assert.Equal(t, "new", publisher.events[0].Body)
+		assert.Equal(t, domain.EventPostDeleted, publisher.events[1].Type)
+		for _, event := range publisher.events {
+			assert.Equal(t, id.String(), event.PostID)
+			assert.Equal(t, callerID, event.ActorID)
+		}
+	}
+
+	mockPostsRepo.AssertExpectations(t)
+}
+
+func TestPostsService_PatchPosts(t *testing.T) {
+	ctx := context.Background()
+	postsID := uuid.New()
+	existing := func() *domain.Posts {
+		return &domain.Posts{
+			ID:            postsID.String(),
+			Title:         "Old Title",
+			Content:       "old content",
+			ContentFormat: domain.ContentFormatPlain,
+			ContentHTML:   "<p>old content</p>\n",
+			Slug:          "old-title",
+			Version:       2,
+			CommentCount:  4,
+		}
+	}
+
+	t.Run("Only sends the supplied fields", func(t *testing.T) {
+		mockPostsRepo := new(mocks.PostsRepository)
+		postsService := service.NewPostsService(mockPostsRepo)
+
+		patch, err := domain.ParsePostsPatch([]byte(`{"title":"New Title"}`))
+		assert.NoError(t, err)
+
+		mockPostsRepo.On("GetPosts", mock.Anything, postsID).Return(existing(), nil).Once()
+		mockPostsRepo.On("PatchPosts", mock.Anything, postsID, 2, mock.MatchedBy(func(p *domain.PostsPatch) bool {
+			return *p.Title == "New Title" && p.Content == nil && p.ContentFormat == nil && p.ContentHTML == nil
+		})).Return(&domain.Posts{ID: postsID.String(), Title: "New Title", Content: "old content", Version: 3}, nil).Once()
+
+		posts, err := postsService.PatchPosts(ctx, postsID, patch)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "New Title", posts.Title)
+		assert.Equal(t, 3, posts.Version)
+		assert.Equal(t, int64(4), posts.CommentCount)
+		mockPostsRepo.AssertExpectations(t)
+	})
+
+	t.Run("Re-renders the content when the format changes", func(t *testing.T) {
+		mockPostsRepo := new(mocks.PostsRepository)
+		postsService := service.NewPostsService(mockPostsRepo)
+
+		patch, err := domain.ParsePostsPatch([]byte(`{"content_format":"markdown"}`))
+		assert.NoError(t, err)
+
+		mockPostsRepo.On("GetPosts", mock.Anything, postsID).Return(existing(), nil).Once()
+		mockPostsRepo.On("PatchPosts", mock.Anything, postsID, 2, mock.MatchedBy(func(p *domain.PostsPatch) bool {
+			return p.Content == nil && p.ContentHTML != nil && *p.ContentHTML == "<p>old content</p>\n"
+		})).Return(&domain.Posts{ID: postsID.String(), Version: 3}, nil).Once()
+
+		_, err = postsService.PatchPosts(ctx, postsID, patch)
+
+		assert.NoError(t, err)
+		mockPostsRepo.AssertExpectations(t)
+	})
+
+	t.Run("Returns the post unchanged for an empty patch", func(t *testing.T) {
+		mockPostsRepo := new(mocks.PostsRepository)
+		postsService := service.NewPostsService(mockPostsRepo)
+
+		patch, err := domain.ParsePostsPatch([]byte(`{}`))
+		assert.NoError(t, err)
+
+		mockPostsRepo.On("GetPosts", mock.Anything, postsID).Return(existing(), nil).Once()
+
+		posts, err := postsService.PatchPosts(ctx, postsID, patch)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Old Title", posts.Title)
+		mockPostsRepo.AssertNotCalled(t, "PatchPosts", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Returns ErrPreconditionFailed for a stale version", func(t *testing.T) {
+		mockPostsRepo := new(mocks.PostsRepository)
+		postsService := service.NewPostsService(mockPostsRepo)
+
+		patch, err := domain.ParsePostsPatch([]byte(`{"title":"New Title","version":1}`))
+		assert.NoError(t, err)
+
+		mockPostsRepo.On("GetPosts", mock.Anything, postsID).Return(existing(), nil).Once()
+
+		posts, err := postsService.PatchPosts(ctx, postsID, patch)
+
+		assert.ErrorIs(t, err, domain.ErrPreconditionFailed)
+		assert.Nil(t, posts)
+		mockPostsRepo.AssertNotCalled(t, "PatchPosts", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects invalid fields", func(t *testing.T) {
+		_, err := domain.ParsePostsPatch([]byte(`{"title":null,"content":"","content_format":"html","slug":"x"}`))
+
+		var fieldErrs domain.FieldErrors
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+		assert.ErrorAs(t, err, &fieldErrs)
+		assert.Equal(t, domain.FieldErrors{
+			{Field: "title", Message: "cannot be removed"},
+			{Field: "content", Message: "must not be empty"},
+			{Field: "content_format", Message: "must be plain or markdown"},
+			{Field: "slug", Message: "cannot be changed"},
+		}, fieldErrs)
+	})
+}
+
+func TestPostsService_BatchPosts(t *testing.T) {
+	ctx := context.Background()
+	postID := uuid.New().String()
+
+	t.Run("Best effort applies the valid items and reports the others by index", func(t *testing.T) {
+		mockPostsRepo := new(mocks.PostsRepository)
+		postsService := service.NewPostsService(mockPostsRepo)
+
+		req := &domain.BatchRequest[domain.PostsBatchItem]{
+			Mode: domain.BatchModeBestEffort,
+			Items: []domain.PostsBatchItem{
+				{Op: domain.BatchOpCreate, Title: "First", Content: "one"},
+				{Op: domain.BatchOpCreate, Title: "", Content: "no title"},
+				{Op: domain.BatchOpDelete, ID: postID},
+				{Op: domain.BatchOpUpdate, ID: "not-a-uuid", Title: "x", Content: "y"},
+			},
+		}
+
+		mockPostsRepo.On("ApplyPostsBatch", mock.Anything, mock.MatchedBy(func(items []domain.PostsBatchItem) bool {
+			return len(items) == 2 && items[0].ContentHTML == "<p>one</p>\n" && items[1].Op == domain.BatchOpDelete
+		}), false).Return([]domain.BatchItemResult[domain.Posts]{
+			{Index: 0, Op: domain.BatchOpCreate, ID: "new-id", OK: true, Data: &domain.Posts{ID: "new-id"}},
+			{Index: 1, Op: domain.BatchOpDelete, ID: postID, Error: "post not found"},
+		}, nil).Once()
+
+		result, err := postsService.BatchPosts(ctx, req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result.Succeeded)
+		assert.Equal(t, 3, result.Failed)
+		for i, item := range result.Items {
+			assert.Equal(t, i, item.Index)
+		}
+		assert.True(t, result.Items[0].OK)
+		assert.Equal(t, "title is required", result.Items[1].Error)
+		assert.Equal(t, "post not found", result.Items[2].Error)
+		assert.Equal(t, "id must be a UUID", result.Items[3].Error)
+		mockPostsRepo.AssertExpectations(t)
+	})
+
+	t.Run("Atomic batch with an invalid item is not applied", func(t *testing.T) {
+		mockPostsRepo := new(mocks.PostsRepository)
+		postsService := service.NewPostsService(mockPostsRepo)
+
+		req := &domain.BatchRequest[domain.PostsBatchItem]{
+			Items: []domain.PostsBatchItem{
+				{Op: domain.BatchOpCreate, Title: "First", Content: "one"},
+				{Op: "upsert", ID: postID},
+			},
+		}
+
+		result, err := postsService.BatchPosts(ctx, req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.BatchModeAtomic, result.Mode)
+		assert.Equal(t, 0, result.Succeeded)
+		assert.Equal(t, domain.BatchRolledBackReason, result.Items[0].Error)
+		assert.Equal(t, "op must be create, update or delete", result.Items[1].Error)
+		mockPostsRepo.AssertNotCalled(t, "ApplyPostsBatch", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects empty batches and unknown modes", func(t *testing.T) {
+		postsService := service.NewPostsService(new(mocks.PostsRepository))
+
+		_, err := postsService.BatchPosts(ctx, &domain.BatchRequest[domain.PostsBatchItem]{})
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+
+		_, err = postsService.BatchPosts(ctx, &domain.BatchRequest[domain.PostsBatchItem]{
+			Mode:  "sometimes",
+			Items: []domain.PostsBatchItem{{Op: domain.BatchOpDelete, ID: postID}},
+		})
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+	})
+
+	t.Run("Returns repository errors", func(t *testing.T) {
+		mockPostsRepo := new(mocks.PostsRepository)
+		postsService := service.NewPostsService(mockPostsRepo)
+
+		repoErr := errors.New("connection reset")
+		mockPostsRepo.On("ApplyPostsBatch", mock.Anything, mock.Anything, true).Return(nil, repoErr).Once()
+
+		result, err := postsService.BatchPosts(ctx, &domain.BatchRequest[domain.PostsBatchItem]{
+			Items: []domain.PostsBatchItem{{Op: domain.BatchOpDelete, ID: postID}},
+		})
+
+		assert.ErrorIs(t, err, repoErr)
+		assert.Nil(t, result)
+	})
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/google/uuid"
+)
+
+type ReactionRepository interface {
+	TargetExists(ctx context.Context, target domain.ReactionTarget, id uuid.UUID) (bool, error)
+	AddReaction(ctx context.Context, reaction *domain.Reaction) error
+	RemoveReaction(ctx context.Context, reaction *domain.Reaction) error
+	GetReactionSummaries(ctx context.Context, target domain.ReactionTarget, ids []uuid.UUID, viewerID string) (map[string]*domain.ReactionSummary, error)
+}
+
+type ReactionService struct {
+	reactionRepo ReactionRepository
+}
+
+func NewReactionService(r ReactionRepository) *ReactionService {
+	return &ReactionService{
+		reactionRepo: r,
+	}
+}
+
+// React records the user's reaction on a post or comment. Reacting twice with
+// the same type is a no-op. The refreshed summary of the target is returned.
+func (rs *ReactionService) React(
+	ctx context.Context,
+	target domain.ReactionTarget,
+	targetID uuid.UUID,
+	userID string,
+	reactionType domain.ReactionType,
+) (*domain.ReactionSummary, error) {
+	reaction, err := rs.prepare(ctx, target, targetID, userID, reactionType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rs.reactionRepo.AddReaction(ctx, reaction); err != nil {
+		return nil, err
+	}
+	return rs.summary(ctx, target, targetID, userID)
+}
+
+// Unreact removes the user's reaction of the given type, if any
+func (rs *ReactionService) Unreact(
+	ctx context.Context,
+	target domain.ReactionTarget,
+	targetID uuid.UUID,
+	userID string,
+	reactionType domain.ReactionType,
+) (*domain.ReactionSummary, error) {
+	reaction, err := rs.prepare(ctx, target, targetID, userID, reactionType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rs.reactionRepo.RemoveReaction(ctx, reaction); err != nil {
+		return nil, err
+	}
+	return rs.summary(ctx, target, targetID, userID)
+}
+
+func (rs *ReactionService) prepare(
+	ctx context.Context,
+	target domain.ReactionTarget,
+	targetID uuid.UUID,
+	userID string,
+	reactionType domain.ReactionType,
+) (*domain.Reaction, error) {
+	if userID == "" || !reactionType.IsValid() {
+		return nil, domain.ErrBadParamInput
+	}
+
+	exists, err := rs.reactionRepo.TargetExists(ctx, target, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+
+	return &domain.Reaction{
+		UserID:     userID,
+		TargetType: target,
+		TargetID:   targetID.String(),
+		Type:       reactionType,
+	}, nil
+}
+
+func (rs *ReactionService) summary(
+	ctx context.Context,
+	target domain.ReactionTarget,
+	targetID uuid.UUID,
+	userID string,
+) (*domain.ReactionSummary, error) {
+	summaries, err := rs.reactionRepo.GetReactionSummaries(ctx, target, []uuid.UUID{targetID}, userID)
+	if err != nil {
+		return nil, err
+	}
+	if summary, ok := summaries[targetID.String()]; ok {
+		return summary, nil
+	}
+	return domain.NewReactionSummary(), nil
+}
+
+// attachReactions loads the reaction summaries for ids in one query and hands
+// each one to set. Targets without reactions get an empty summary.
+func attachReactions(
+	ctx context.Context,
+	repo ReactionRepository,
+	target domain.ReactionTarget,
+	ids []string,
+	set func(i int, summary *domain.ReactionSummary),
+) error {
+	if repo == nil || len(ids) == 0 {
+		return nil
+	}
+
+	parsed := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if u, err := uuid.Parse(id); err == nil {
+			parsed = append(parsed, u)
+		}
+	}
+
+	viewerID := ""
+	if caller := domain.CallerFromContext(ctx); caller != nil {
+		viewerID = caller.ID
+	}
+
+	summaries, err := repo.GetReactionSummaries(ctx, target, parsed, viewerID)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		summary, ok := summaries[id]
+		if !ok {
+			summary = domain.NewReactionSummary()
+		}
+		set(i, summary)
+	}
+	return nil
+}
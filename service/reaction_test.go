@@ -0,0 +1,129 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/service"
+	"github.com/edwinjordan/MajooTest-Golang/service/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReactionService_React(t *testing.T) {
+	ctx := context.Background()
+	postID := uuid.New()
+	userID := uuid.New().String()
+
+	t.Run("Successfully reacts to a post", func(t *testing.T) {
+		mockReactionRepo := new(mocks.ReactionRepository)
+		reactionService := service.NewReactionService(mockReactionRepo)
+
+		expected := &domain.Reaction{
+			UserID:     userID,
+			TargetType: domain.ReactionTargetPost,
+			TargetID:   postID.String(),
+			Type:       domain.ReactionTypeLike,
+		}
+		summary := &domain.ReactionSummary{
+			Counts: map[domain.ReactionType]int64{domain.ReactionTypeLike: 1},
+			Mine:   []domain.ReactionType{domain.ReactionTypeLike},
+		}
+		mockReactionRepo.On("TargetExists", mock.Anything, domain.ReactionTargetPost, postID).Return(true, nil).Once()
+		mockReactionRepo.On("AddReaction", mock.Anything, expected).Return(nil).Once()
+		mockReactionRepo.On("GetReactionSummaries", mock.Anything, domain.ReactionTargetPost, []uuid.UUID{postID}, userID).
+			Return(map[string]*domain.ReactionSummary{postID.String(): summary}, nil).Once()
+
+		result, err := reactionService.React(ctx, domain.ReactionTargetPost, postID, userID, domain.ReactionTypeLike)
+
+		assert.NoError(t, err)
+		assert.Equal(t, summary, result)
+
+		mockReactionRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects unsupported reaction type", func(t *testing.T) {
+		mockReactionRepo := new(mocks.ReactionRepository)
+		reactionService := service.NewReactionService(mockReactionRepo)
+
+		result, err := reactionService.React(ctx, domain.ReactionTargetPost, postID, userID, "meh")
+
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+		assert.Nil(t, result)
+
+		mockReactionRepo.AssertExpectations(t)
+	})
+
+	t.Run("Returns ErrNotFound when the target does not exist", func(t *testing.T) {
+		mockReactionRepo := new(mocks.ReactionRepository)
+		reactionService := service.NewReactionService(mockReactionRepo)
+
+		mockReactionRepo.On("TargetExists", mock.Anything, domain.ReactionTargetPost, postID).Return(false, nil).Once()
+
+		result, err := reactionService.React(ctx, domain.ReactionTargetPost, postID, userID, domain.ReactionTypeLove)
+
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		assert.Nil(t, result)
+
+		mockReactionRepo.AssertNotCalled(t, "AddReaction", mock.Anything, mock.Anything)
+		mockReactionRepo.AssertExpectations(t)
+	})
+}
+
+func TestReactionService_Unreact(t *testing.T) {
+	ctx := context.Background()
+	commentID := uuid.New()
+	userID := uuid.New().String()
+
+	mockReactionRepo := new(mocks.ReactionRepository)
+	reactionService := service.NewReactionService(mockReactionRepo)
+
+	mockReactionRepo.On("TargetExists", mock.Anything, domain.ReactionTargetComment, commentID).Return(true, nil).Once()
+	mockReactionRepo.On("RemoveReaction", mock.Anything, mock.AnythingOfType("*domain.Reaction")).Return(nil).Once()
+	mockReactionRepo.On("GetReactionSummaries", mock.Anything, domain.ReactionTargetComment, []uuid.UUID{commentID}, userID).
+		Return(map[string]*domain.ReactionSummary{}, nil).Once()
+
+	result, err := reactionService.Unreact(ctx, domain.ReactionTargetComment, commentID, userID, domain.ReactionTypeSad)
+
+	assert.NoError(t, err)
+	assert.Empty(t, result.Counts)
+	assert.Empty(t, result.Mine)
+
+	mockReactionRepo.AssertExpectations(t)
+}
+
+func TestPostsService_GetPostsList_WithReactions(t *testing.T) {
+	mockPostsRepo := new(mocks.PostsRepository)
+	mockReactionRepo := new(mocks.ReactionRepository)
+	postsService := service.NewPostsService(mockPostsRepo).WithReactions(mockReactionRepo)
+
+	viewerID := uuid.New().String()
+	ctx := domain.WithCaller(context.Background(), &domain.Caller{ID: viewerID})
+
+	first, second := uuid.New(), uuid.New()
+	posts := []domain.Posts{
+		{ID: first.String(), Title: "First", ContentHTML: "<p>first</p>"},
+		{ID: second.String(), Title: "Second", ContentHTML: "<p>second</p>"},
+	}
+	firstSummary := &domain.ReactionSummary{
+		Counts: map[domain.ReactionType]int64{domain.ReactionTypeLike: 3},
+		Mine:   []domain.ReactionType{domain.ReactionTypeLike},
+	}
+
+	mockPostsRepo.On("GetPostsList", mock.Anything, mock.Anything).Return(posts, nil).Once()
+	mockReactionRepo.On("GetReactionSummaries", mock.Anything, domain.ReactionTargetPost, []uuid.UUID{first, second}, viewerID).
+		Return(map[string]*domain.ReactionSummary{first.String(): firstSummary}, nil).Once()
+
+	result, err := postsService.GetPostsList(ctx, &domain.PostsFilter{})
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, firstSummary, result[0].Reactions)
+	assert.NotNil(t, result[1].Reactions)
+	assert.Empty(t, result[1].Reactions.Counts)
+
+	mockPostsRepo.AssertExpectations(t)
+	mockReactionRepo.AssertExpectations(t)
+}
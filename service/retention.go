@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/internal/logging"
+)
+
+type RetentionRepository interface {
+	CountExpired(ctx context.Context, entity domain.TrashEntity, before time.Time) (domain.PurgeCount, error)
+	PurgeExpired(ctx context.Context, entity domain.TrashEntity, before time.Time, limit int) (domain.PurgeCount, error)
+}
+
+// RetentionService hard-deletes soft-deleted records once they have been in
+// the trash longer than the retention policy allows
+type RetentionService struct {
+	retentionRepo RetentionRepository
+	policy        domain.RetentionPolicy
+}
+
+func NewRetentionService(r RetentionRepository, policy domain.RetentionPolicy) *RetentionService {
+	if policy.BatchSize <= 0 {
+		policy.BatchSize = domain.DefaultRetentionPolicy().BatchSize
+	}
+	return &RetentionService{
+		retentionRepo: r,
+		policy:        policy,
+	}
+}
+
+// Purge deletes the expired records of every entity with a retention period,
+// children before the records they reference, and returns the count per
+// entity along with the rows the records owned. A dry run only counts the
+// records that are expired now; records waiting on dependents purged in the
+// same run are not included.
+func (rs *RetentionService) Purge(ctx context.Context, dryRun bool) ([]domain.PurgeResult, error) {
+	now := time.Now()
+	var results []domain.PurgeResult
+
+	for _, entity := range domain.PurgeOrder {
+		period := rs.policy.Periods[entity]
+		if period <= 0 {
+			continue
+		}
+
+		result := domain.PurgeResult{Entity: entity, Before: now.Add(-period), DryRun: dryRun}
+		if dryRun {
+			count, err := rs.retentionRepo.CountExpired(ctx, entity, result.Before)
+			if err != nil {
+				return results, fmt.Errorf("count expired %s: %w", entity, err)
+			}
+			result.Add(count)
+		} else {
+			for {
+				purged, err := rs.retentionRepo.PurgeExpired(ctx, entity, result.Before, rs.policy.BatchSize)
+				result.Add(purged)
+				if err != nil {
+					return append(results, result), fmt.Errorf("purge expired %s: %w", entity, err)
+				}
+				if purged.Records == 0 {
+					break
+				}
+			}
+		}
+
+		logging.LogInfo(ctx, "Purged expired soft-deleted records",
+			slog.String("table", string(entity)),
+			slog.Int("count", result.Purged),
+			slog.Time("deleted_before", result.Before),
+			slog.Bool("dry_run", dryRun),
+		)
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// Run purges on the policy's interval until ctx is done. Replicas may run it
+// concurrently, each batch skips rows another replica is deleting.
+func (rs *RetentionService) Run(ctx context.Context) {
+	if rs.policy.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(rs.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := rs.Purge(ctx, false); err != nil && ctx.Err() == nil {
+				logging.LogError(ctx, err, "retention_purge")
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/service"
+	"github.com/edwinjordan/MajooTest-Golang/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRetentionService_Purge(t *testing.T) {
+	ctx := context.Background()
+	policy := domain.RetentionPolicy{
+		Periods: map[domain.TrashEntity]time.Duration{
+			domain.TrashComments: 24 * time.Hour,
+			domain.TrashPosts:    48 * time.Hour,
+			domain.TrashUsers:    0,
+		},
+		BatchSize: 2,
+	}
+
+	t.Run("Purges in batches, children first, skipping entities kept forever", func(t *testing.T) {
+		mockRetentionRepo := new(mocks.RetentionRepository)
+		retentionService := service.NewRetentionService(mockRetentionRepo, policy)
+
+		var order []domain.TrashEntity
+		record := func(args mock.Arguments) { order = append(order, args.Get(1).(domain.TrashEntity)) }
+		mockRetentionRepo.On("PurgeExpired", mock.Anything, domain.TrashComments, mock.AnythingOfType("time.Time"), 2).Return(domain.PurgeCount{Records: 2}, nil).Run(record).Once()
+		mockRetentionRepo.On("PurgeExpired", mock.Anything, domain.TrashComments, mock.AnythingOfType("time.Time"), 2).Return(domain.PurgeCount{Records: 1}, nil).Run(record).Once()
+		mockRetentionRepo.On("PurgeExpired", mock.Anything, domain.TrashComments, mock.AnythingOfType("time.Time"), 2).Return(domain.PurgeCount{Records: 0}, nil).Run(record).Once()
+		mockRetentionRepo.On("PurgeExpired", mock.Anything, domain.TrashPosts, mock.MatchedBy(func(before time.Time) bool {
+			return time.Since(before) >= 48*time.Hour && time.Since(before) < 49*time.Hour
+		}), 2).Return(domain.PurgeCount{Records: 0}, nil).Run(record).Once()
+
+		results, err := retentionService.Purge(ctx, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.TrashEntity{domain.TrashComments, domain.TrashComments, domain.TrashComments, domain.TrashPosts}, order)
+		if assert.Len(t, results, 2) {
+			assert.Equal(t, 3, results[0].Purged)
+			assert.Equal(t, domain.TrashPosts, results[1].Entity)
+			assert.Equal(t, 0, results[1].Purged)
+		}
+
+		mockRetentionRepo.AssertExpectations(t)
+	})
+
+	t.Run("Only counts in a dry run", func(t *testing.T) {
+		mockRetentionRepo := new(mocks.RetentionRepository)
+		retentionService := service.NewRetentionService(mockRetentionRepo, policy)
+
+		mockRetentionRepo.On("CountExpired", mock.Anything, domain.TrashComments, mock.AnythingOfType("time.Time")).Return(domain.PurgeCount{Records: 5}, nil).Once()
+		mockRetentionRepo.On("CountExpired", mock.Anything, domain.TrashPosts, mock.AnythingOfType("time.Time")).Return(domain.PurgeCount{Records: 1}, nil).Once()
+
+		results, err := retentionService.Purge(ctx, true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.PurgeResult{
+			{Entity: domain.TrashComments, Before: results[0].Before, Purged: 5, DryRun: true},
+			{Entity: domain.TrashPosts, Before: results[1].Before, Purged: 1, DryRun: true},
+		}, results)
+
+		mockRetentionRepo.AssertNotCalled(t, "PurgeExpired", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockRetentionRepo.AssertExpectations(t)
+	})
+
+	t.Run("Counts the rows purged users owned", func(t *testing.T) {
+		mockRetentionRepo := new(mocks.RetentionRepository)
+		retentionService := service.NewRetentionService(mockRetentionRepo, domain.RetentionPolicy{
+			Periods:   map[domain.TrashEntity]time.Duration{domain.TrashUsers: 24 * time.Hour},
+			BatchSize: 2,
+		})
+
+		mockRetentionRepo.On("PurgeExpired", mock.Anything, domain.TrashUsers, mock.AnythingOfType("time.Time"), 2).
+			Return(domain.PurgeCount{Records: 2, Owned: map[string]int{"csv_jobs": 1, "reactions": 4, "notifications": 0}}, nil).Once()
+		mockRetentionRepo.On("PurgeExpired", mock.Anything, domain.TrashUsers, mock.AnythingOfType("time.Time"), 2).
+			Return(domain.PurgeCount{Records: 1, Owned: map[string]int{"csv_jobs": 2, "reactions": 0, "notifications": 3}}, nil).Once()
+		mockRetentionRepo.On("PurgeExpired", mock.Anything, domain.TrashUsers, mock.AnythingOfType("time.Time"), 2).
+			Return(domain.PurgeCount{Owned: map[string]int{"csv_jobs": 0, "reactions": 0, "notifications": 0}}, nil).Once()
+
+		results, err := retentionService.Purge(ctx, false)
+
+		assert.NoError(t, err)
+		if assert.Len(t, results, 1) {
+			assert.Equal(t, 3, results[0].Purged)
+			assert.Equal(t, map[string]int{"csv_jobs": 3, "reactions": 4, "notifications": 3}, results[0].Owned)
+		}
+
+		mockRetentionRepo.AssertExpectations(t)
+	})
+
+	t.Run("Stops at the first failing entity and reports what was purged", func(t *testing.T) {
+		mockRetentionRepo := new(mocks.RetentionRepository)
+		retentionService := service.NewRetentionService(mockRetentionRepo, policy)
+
+		mockRetentionRepo.On("PurgeExpired", mock.Anything, domain.TrashComments, mock.AnythingOfType("time.Time"), 2).Return(domain.PurgeCount{Records: 2}, nil).Once()
+		mockRetentionRepo.On("PurgeExpired", mock.Anything, domain.TrashComments, mock.AnythingOfType("time.Time"), 2).Return(domain.PurgeCount{Records: 0}, errors.New("deadlock detected")).Once()
+
+		results, err := retentionService.Purge(ctx, false)
+
+		assert.Error(t, err)
+		if assert.Len(t, results, 1) {
+			assert.Equal(t, 2, results[0].Purged)
+		}
+
+		mockRetentionRepo.AssertExpectations(t)
+	})
+}
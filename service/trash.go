@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+type TrashRepository interface {
+	GetTrash(ctx context.Context, entity domain.TrashEntity, filter *domain.TrashFilter) ([]domain.TrashItem, int, error)
+	CountTrash(ctx context.Context) (domain.TrashSummary, error)
+}
+
+// TrashService lists soft-deleted records, restoring them is up to the
+// service owning the entity
+type TrashService struct {
+	trashRepo TrashRepository
+}
+
+func NewTrashService(t TrashRepository) *TrashService {
+	return &TrashService{
+		trashRepo: t,
+	}
+}
+
+// GetTrash returns a page of the soft-deleted records of entity with their
+// total count
+func (ts *TrashService) GetTrash(ctx context.Context, entity domain.TrashEntity, filter *domain.TrashFilter) ([]domain.TrashItem, int, error) {
+	if !entity.IsValid() {
+		return nil, 0, fmt.Errorf("%w: unknown trash entity %q", domain.ErrBadParamInput, entity)
+	}
+	if filter == nil {
+		filter = new(domain.TrashFilter)
+	}
+	filter.Normalize()
+
+	return ts.trashRepo.GetTrash(ctx, entity, filter)
+}
+
+// CountTrash returns how many records of every entity are in the trash
+func (ts *TrashService) CountTrash(ctx context.Context) (domain.TrashSummary, error) {
+	return ts.trashRepo.CountTrash(ctx)
+}
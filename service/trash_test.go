@@ -0,0 +1,99 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+	"github.com/edwinjordan/MajooTest-Golang/service"
+	"github.com/edwinjordan/MajooTest-Golang/service/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTrashService_GetTrash(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Lists an entity with a normalized filter", func(t *testing.T) {
+		mockTrashRepo := new(mocks.TrashRepository)
+		trashService := service.NewTrashService(mockTrashRepo)
+
+		items := []domain.TrashItem{{Entity: domain.TrashPosts, ID: uuid.New().String(), Label: "Old post", DeletedAt: time.Now()}}
+		mockTrashRepo.On("GetTrash", mock.Anything, domain.TrashPosts, &domain.TrashFilter{Page: 1, Limit: 20}).
+			Return(items, 1, nil).Once()
+
+		result, total, err := trashService.GetTrash(ctx, domain.TrashPosts, &domain.TrashFilter{Limit: 500})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, total)
+		assert.Equal(t, items, result)
+
+		mockTrashRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects unknown entities", func(t *testing.T) {
+		mockTrashRepo := new(mocks.TrashRepository)
+		trashService := service.NewTrashService(mockTrashRepo)
+
+		result, _, err := trashService.GetTrash(ctx, domain.TrashEntity("reactions"), nil)
+
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+		assert.Nil(t, result)
+
+		mockTrashRepo.AssertExpectations(t)
+	})
+}
+
+func TestPostsService_RestorePosts(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+
+	t.Run("Returns the post with the number of restored comments", func(t *testing.T) {
+		mockPostsRepo := new(mocks.PostsRepository)
+		postsService := service.NewPostsService(mockPostsRepo)
+
+		post := &domain.Posts{ID: id.String(), Title: "Back"}
+		mockPostsRepo.On("RestorePosts", mock.Anything, id).Return(post, 3, nil).Once()
+
+		restored, err := postsService.RestorePosts(ctx, id)
+
+		assert.NoError(t, err)
+		assert.Equal(t, *post, restored.Posts)
+		assert.Equal(t, 3, restored.RestoredComments)
+
+		mockPostsRepo.AssertExpectations(t)
+	})
+
+	t.Run("Passes on ErrNotFound", func(t *testing.T) {
+		mockPostsRepo := new(mocks.PostsRepository)
+		postsService := service.NewPostsService(mockPostsRepo)
+
+		mockPostsRepo.On("RestorePosts", mock.Anything, id).Return(nil, 0, domain.ErrNotFound).Once()
+
+		restored, err := postsService.RestorePosts(ctx, id)
+
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		assert.Nil(t, restored)
+
+		mockPostsRepo.AssertExpectations(t)
+	})
+}
+
+func TestCommentService_RestoreComment(t *testing.T) {
+	mockCommentsRepo := new(mocks.CommentRepository)
+	commentsService := service.NewCommentService(mockCommentsRepo, new(mocks.PostsRepository))
+
+	id := uuid.New()
+	comment := &domain.Comment{ID: id.String(), Body: "back"}
+	mockCommentsRepo.On("RestoreComment", mock.Anything, id).Return(comment, 2, nil).Once()
+
+	restored, err := commentsService.RestoreComment(context.Background(), id)
+
+	assert.NoError(t, err)
+	assert.Equal(t, *comment, restored.Comment)
+	assert.Equal(t, 2, restored.RestoredReplies)
+
+	mockCommentsRepo.AssertExpectations(t)
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"fmt"
 	//"{{ package_name }}/domain"
 	//"{{ package_name }}/internal/logging"
 
@@ -16,7 +17,12 @@ type UserRepository interface {
 	GetUserList(ctx context.Context, filter *domain.UserFilter) ([]domain.User, error)
 	GetUser(ctx context.Context, id uuid.UUID) (*domain.User, error)
 	UpdateUser(ctx context.Context, id uuid.UUID, user *domain.User) (*domain.User, error)
-	DeleteUser(ctx context.Context, id uuid.UUID) error
+	PatchUser(ctx context.Context, id uuid.UUID, version int, patch *domain.UserPatch) (*domain.User, error)
+	DeleteUser(ctx context.Context, id uuid.UUID, version int) error
+	RestoreUser(ctx context.Context, id uuid.UUID) (*domain.User, error)
+	ApplyUserBatch(ctx context.Context, items []domain.UserBatchItem, atomic bool) ([]domain.BatchItemResult[domain.User], error)
+	ImportUser(ctx context.Context, user *domain.UserImport) (*domain.User, error)
+	FindUserByLogin(ctx context.Context, username, email string) (*domain.User, error)
 }
 
 type UserService struct {
@@ -34,6 +40,11 @@ func (us *UserService) CreateUser(
 	ctx context.Context,
 	u *domain.CreateUserRequest,
 ) (*domain.User, error) {
+	// Without a username the repository derives one from the email address
+	if u.Username != "" && !domain.IsValidUsername(u.Username) {
+		return nil, domain.ErrBadParamInput
+	}
+
 	createdUser, err := us.userRepo.CreateUser(ctx, u)
 	if err != nil {
 		return nil, err
@@ -41,6 +52,35 @@ func (us *UserService) CreateUser(
 	return createdUser, nil
 }
 
+// ImportUser creates or updates a user from an HR export.
+func (us *UserService) ImportUser(
+	ctx context.Context,
+	u *domain.UserImport,
+) (*domain.User, error) {
+	if err := validateUserImport(u); err != nil {
+		return nil, err
+	}
+	return us.userRepo.ImportUser(ctx, u)
+}
+
+func validateUserImport(u *domain.UserImport) error {
+	if err := requireFields("username", u.Username, "email", u.Email, "name", u.Name, "password", u.Password); err != nil {
+		return err
+	}
+	if !domain.IsValidUsername(u.Username) {
+		return fmt.Errorf("%w: username must be 3 to 30 letters, digits or underscores", domain.ErrBadParamInput)
+	}
+	if !domain.IsValidEmail(u.Email) {
+		return fmt.Errorf("%w: email is not valid", domain.ErrBadParamInput)
+	}
+	return nil
+}
+
+// FindUserByLogin returns the user with the username, or else the email.
+func (us *UserService) FindUserByLogin(ctx context.Context, username, email string) (*domain.User, error) {
+	return us.userRepo.FindUserByLogin(ctx, username, email)
+}
+
 // GetUser fetches a user by ID.
 func (us *UserService) GetUser(
 	ctx context.Context,
@@ -71,18 +111,50 @@ func (us *UserService) UpdateUser(
 	if existing == nil {
 		return nil, domain.ErrUserNotFound
 	}
+	if err := checkVersion(ctx, existing.Version, u.Version); err != nil {
+		return nil, err
+	}
 
 	existing.Name = u.Name
 	existing.Email = u.Email
 
-	_, err = us.userRepo.UpdateUser(ctx, id, existing)
+	updated, err := us.userRepo.UpdateUser(ctx, id, existing)
 	if err != nil {
 		return nil, err
 	}
+	if updated != nil {
+		existing.Version = updated.Version
+		existing.UpdatedAt = updated.UpdatedAt
+	}
 
 	return existing, nil
 }
 
+// PatchUser applies a merge-patch to the profile of a user. An empty patch
+// returns the user unchanged.
+func (us *UserService) PatchUser(
+	ctx context.Context,
+	id uuid.UUID,
+	patch *domain.UserPatch,
+) (*domain.User, error) {
+
+	existing, err := us.userRepo.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, domain.ErrUserNotFound
+	}
+	if err := checkVersion(ctx, existing.Version, patch.Version); err != nil {
+		return nil, err
+	}
+	if patch.IsEmpty() {
+		return existing, nil
+	}
+
+	return us.userRepo.PatchUser(ctx, id, existing.Version, patch)
+}
+
 // DeleteUser removes a user by ID.
 func (us *UserService) DeleteUser(
 	ctx context.Context,
@@ -96,8 +168,11 @@ func (us *UserService) DeleteUser(
 	if user == nil {
 		return domain.ErrUserNotFound
 	}
+	if err := domain.CheckVersion(ctx, user.Version); err != nil {
+		return err
+	}
 
-	err = us.userRepo.DeleteUser(ctx, id)
+	err = us.userRepo.DeleteUser(ctx, id, user.Version)
 	if err != nil {
 		return err
 	}
@@ -114,3 +189,44 @@ func (us *UserService) GetUserList(ctx context.Context, filter *domain.UserFilte
 
 	return users, nil
 }
+
+// RestoreUser undeletes a soft-deleted user
+func (us *UserService) RestoreUser(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	return us.userRepo.RestoreUser(ctx, id)
+}
+
+// BatchUsers creates, updates and deletes users in bulk, see runBatch
+func (us *UserService) BatchUsers(
+	ctx context.Context,
+	req *domain.BatchRequest[domain.UserBatchItem],
+) (*domain.BatchResult[domain.User], error) {
+	describe := func(item *domain.UserBatchItem) (domain.BatchOp, string) {
+		return item.Op, item.ID
+	}
+	prepare := func(ctx context.Context, item *domain.UserBatchItem) error {
+		if err := checkBatchItem(item.Op, item.ID); err != nil {
+			return err
+		}
+		switch item.Op {
+		case domain.BatchOpCreate:
+			if err := requireFields("name", item.Name, "email", item.Email, "password", item.Password); err != nil {
+				return err
+			}
+			if item.Username != "" && !domain.IsValidUsername(item.Username) {
+				return fmt.Errorf("%w: username must be 3 to 30 letters, digits or underscores", domain.ErrBadParamInput)
+			}
+		case domain.BatchOpUpdate:
+			if err := requireFields("name", item.Name, "email", item.Email); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+		if !domain.IsValidEmail(item.Email) {
+			return fmt.Errorf("%w: email must be an email address", domain.ErrBadParamInput)
+		}
+		return nil
+	}
+
+	return runBatch(ctx, req, describe, prepare, us.userRepo.ApplyUserBatch)
+}
@@ -215,14 +215,15 @@ func TestUserService_DeleteUser(t *testing.T) {
 	ctx := context.Background()
 	userID := uuid.New()
 	existingUser := &domain.User{
-		ID:    userID.String(),
-		Name:  "User to delete",
-		Email: "delete@example.com",
+		ID:      userID.String(),
+		Name:    "User to delete",
+		Email:   "delete@example.com",
+		Version: 3,
 	}
 
 	t.Run("Successfully deletes a user", func(t *testing.T) {
 		mockUserRepo.On("GetUser", mock.Anything, userID).Return(existingUser, nil).Once()
-		mockUserRepo.On("DeleteUser", mock.Anything, userID).Return(nil).Once()
+		mockUserRepo.On("DeleteUser", mock.Anything, userID, existingUser.Version).Return(nil).Once()
 
 		err := userService.DeleteUser(ctx, userID)
 
@@ -262,7 +263,7 @@ func TestUserService_DeleteUser(t *testing.T) {
 
 		mockUserRepo.On("GetUser", mock.Anything, userID).Return(existingUser, nil).Once()
 		repoErr := errors.New("delete user repo error")
-		mockUserRepo.On("DeleteUser", mock.Anything, userID).Return(repoErr).Once()
+		mockUserRepo.On("DeleteUser", mock.Anything, userID, existingUser.Version).Return(repoErr).Once()
 
 		err := userService.DeleteUser(ctx, userID)
 
@@ -329,3 +330,52 @@ func TestUserService_GetUserList(t *testing.T) {
 		mockUserRepo.AssertExpectations(t)
 	})
 }
+
+func TestUserService_PatchUser(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	t.Run("Only changes the supplied fields", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		userService := service.NewUserService(mockUserRepo)
+
+		patch, err := domain.ParseUserPatch([]byte(`{"email":"new@example.com"}`))
+		assert.NoError(t, err)
+
+		mockUserRepo.On("GetUser", mock.Anything, userID).Return(&domain.User{ID: userID.String(), Name: "Jane", Email: "old@example.com", Version: 1}, nil).Once()
+		mockUserRepo.On("PatchUser", mock.Anything, userID, 1, patch).Return(&domain.User{ID: userID.String(), Name: "Jane", Email: "new@example.com", Version: 2}, nil).Once()
+
+		user, err := userService.PatchUser(ctx, userID, patch)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Jane", user.Name)
+		assert.Equal(t, "new@example.com", user.Email)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects an invalid email", func(t *testing.T) {
+		_, err := domain.ParseUserPatch([]byte(`{"email":"not an email"}`))
+
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+		assert.EqualError(t, err, "invalid request: email: must be an email address")
+	})
+
+	t.Run("Changes the username, department and location", func(t *testing.T) {
+		patch, err := domain.ParseUserPatch([]byte(`{"username":"jane_doe","department":"Finance","location":""}`))
+		assert.NoError(t, err)
+
+		if assert.NotNil(t, patch.Username) && assert.NotNil(t, patch.Department) && assert.NotNil(t, patch.Location) {
+			assert.Equal(t, "jane_doe", *patch.Username)
+			assert.Equal(t, "Finance", *patch.Department)
+			assert.Equal(t, "", *patch.Location, "an empty location clears it")
+		}
+		assert.False(t, patch.IsEmpty())
+	})
+
+	t.Run("Rejects an invalid username", func(t *testing.T) {
+		_, err := domain.ParseUserPatch([]byte(`{"username":"jane doe"}`))
+
+		assert.ErrorIs(t, err, domain.ErrBadParamInput)
+		assert.EqualError(t, err, "invalid request: username: must be 3 to 30 letters, digits or underscores")
+	})
+}
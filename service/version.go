@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+
+	"github.com/edwinjordan/MajooTest-Golang/domain"
+)
+
+// checkVersion rejects an update unless current matches the version the
+// caller expects, either from an If-Match header or a non-zero version in
+// the request body
+func checkVersion(ctx context.Context, current, requested int) error {
+	if requested != 0 && requested != current {
+		return domain.ErrPreconditionFailed
+	}
+	return domain.CheckVersion(ctx, current)
+}
@@ -11,7 +11,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateToken(userID string, email string) (string, string, error) {
+func GenerateToken(userID string, email string, role domain.Role) (string, string, error) {
 	secret := []byte(os.Getenv("JWT_SECRET"))
 	expiryTime, err := strconv.Atoi(os.Getenv("AUTH_TOKEN_EXPIRY_MINUTES"))
 	if err != nil {
@@ -22,6 +22,8 @@ func GenerateToken(userID string, email string) (string, string, error) {
 	claims := domain.JwtClaim{
 		ID:    userID,
 		Email: email,
+		Role:  role.OrDefault(),
+		Type:  domain.TokenTypeAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute * time.Duration(expiryTime))),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -36,7 +38,8 @@ func GenerateToken(userID string, email string) (string, string, error) {
 
 	// Refresh Token (24 hours)
 	refreshClaims := domain.RefreshClaim{
-		ID: userID,
+		ID:   userID,
+		Type: domain.TokenTypeRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -52,10 +55,12 @@ func GenerateToken(userID string, email string) (string, string, error) {
 	return accessToken, refreshTokenString, nil
 }
 
-func ValidateToken(tokenString string) (*domain.RefreshClaim, error) {
+// ValidateToken parses an access token, refresh tokens and tokens issued
+// before they were told apart are rejected
+func ValidateToken(tokenString string) (*domain.JwtClaim, error) {
 	secret := []byte(os.Getenv("JWT_SECRET"))
 
-	token, err := jwt.ParseWithClaims(tokenString, &domain.RefreshClaim{}, func(token *jwt.Token) (interface{}, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &domain.JwtClaim{}, func(token *jwt.Token) (interface{}, error) {
 		return secret, nil
 	})
 	if err != nil {
@@ -63,10 +68,13 @@ func ValidateToken(tokenString string) (*domain.RefreshClaim, error) {
 		return nil, err
 	}
 
-	claims, ok := token.Claims.(*domain.RefreshClaim)
+	claims, ok := token.Claims.(*domain.JwtClaim)
 	if !ok {
 		slog.Error("Error getting claims")
-		return nil, err
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	if claims.Type != domain.TokenTypeAccess {
+		return nil, jwt.ErrTokenInvalidClaims
 	}
 
 	return claims, nil